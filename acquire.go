@@ -0,0 +1,144 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAcquireTimeout is returned when transaction is not acquired within
+// timeout set with AcquireTimeout.
+var ErrAcquireTimeout = errors.New("dbq: acquire timeout")
+
+// AcquireTimeout limits wait for connection when transaction is acquired,
+// so saturated pool fails fast with ErrAcquireTimeout instead of blocking
+// handlers until their context is done. Timeout covers BEGIN statement as
+// well.
+func AcquireTimeout(d time.Duration) ProviderOption {
+	return func(t *TxProvider) {
+		t.acquireTimeout = d
+	}
+}
+
+// AcquireStats are counters of transactions acquired from provider.
+type AcquireStats struct {
+	// Waiting is number of callers currently waiting for transaction.
+	Waiting int64
+	// Acquired is number of acquired transactions.
+	Acquired int64
+	// Timeouts is number of acquisitions failed with ErrAcquireTimeout.
+	Timeouts int64
+	// WaitDuration is total time spent waiting for transactions and
+	// MaxWait is the longest wait.
+	WaitDuration time.Duration
+	MaxWait      time.Duration
+}
+
+// AcquireCollector is optionally implemented by MetricsCollector set with
+// WithMetrics to observe waits for transactions.
+type AcquireCollector interface {
+	// QueueDepth is called with number of waiting callers whenever it
+	// changes.
+	QueueDepth(n int64)
+	// AcquireDone is called after wait for transaction with error of
+	// acquisition, ErrAcquireTimeout when it timed out.
+	AcquireDone(wait time.Duration, err error)
+}
+
+// acquireCounters are counters of AcquireStats.
+type acquireCounters struct {
+	waiting  int64
+	acquired int64
+	timeouts int64
+	waitNs   int64
+	maxWait  int64
+}
+
+// AcquireStats returns acquisition counters of provider.
+func (t *TxProvider) AcquireStats() AcquireStats {
+	c := &t.acquire
+	return AcquireStats{
+		Waiting:      atomic.LoadInt64(&c.waiting),
+		Acquired:     atomic.LoadInt64(&c.acquired),
+		Timeouts:     atomic.LoadInt64(&c.timeouts),
+		WaitDuration: time.Duration(atomic.LoadInt64(&c.waitNs)),
+		MaxWait:      time.Duration(atomic.LoadInt64(&c.maxWait)),
+	}
+}
+
+// beginTx begins transaction waiting at most acquire timeout. Returned
+// conn is connection reserved for transaction of NativeConnector, it is
+// nil for other connectors. Returned cancel releases context of
+// transaction after it is finished, it is nil without timeout.
+func (t *TxProvider) beginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, *sql.Conn, context.CancelFunc, error) {
+	collector, _ := t.metrics.(AcquireCollector)
+	c := &t.acquire
+	n := atomic.AddInt64(&c.waiting, 1)
+	if collector != nil {
+		collector.QueueDepth(n)
+	}
+	start := time.Now()
+
+	var (
+		cancel context.CancelFunc
+		timer  *time.Timer
+	)
+	if t.acquireTimeout > 0 {
+		// transaction is bound to context it was started with, so context
+		// is canceled by timer instead of WithTimeout deadline.
+		ctx, cancel = context.WithCancel(ctx)
+		timer = time.AfterFunc(t.acquireTimeout, cancel)
+	}
+	var (
+		tx   *sql.Tx
+		conn *sql.Conn
+		err  error
+	)
+	if n, ok := t.conn.(NativeConnector); ok {
+		tx, conn, err = beginNative(ctx, n, opts)
+	} else {
+		tx, err = t.conn.BeginTx(ctx, opts)
+	}
+	if timer != nil && !timer.Stop() {
+		if err == nil {
+			_ = tx.Rollback()
+			if conn != nil {
+				_ = conn.Close()
+			}
+		}
+		tx, conn, err = nil, nil, fmt.Errorf("%w after %v", ErrAcquireTimeout, t.acquireTimeout)
+	}
+
+	wait := time.Since(start)
+	n = atomic.AddInt64(&c.waiting, -1)
+	atomic.AddInt64(&c.waitNs, int64(wait))
+	for {
+		prev := atomic.LoadInt64(&c.maxWait)
+		if int64(wait) <= prev || atomic.CompareAndSwapInt64(&c.maxWait, prev, int64(wait)) {
+			break
+		}
+	}
+	switch {
+	case err == nil:
+		atomic.AddInt64(&c.acquired, 1)
+	case errors.Is(err, ErrAcquireTimeout):
+		atomic.AddInt64(&c.timeouts, 1)
+	}
+	if collector != nil {
+		collector.QueueDepth(n)
+		collector.AcquireDone(wait, err)
+	}
+
+	if err != nil && cancel != nil {
+		cancel()
+		cancel = nil
+	}
+	return tx, conn, cancel, err
+}
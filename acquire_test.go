@@ -0,0 +1,77 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+// saturatedPool blocks until context of BeginTx is done.
+type saturatedPool struct{}
+
+func (saturatedPool) BeginTx(ctx context.Context, _ *sql.TxOptions) (*sql.Tx, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type acquireMetrics struct {
+	fakeMetrics
+	depths []int64
+	errs   []error
+}
+
+func (m *acquireMetrics) QueueDepth(n int64) { m.depths = append(m.depths, n) }
+
+func (m *acquireMetrics) AcquireDone(_ time.Duration, err error) { m.errs = append(m.errs, err) }
+
+func TestAcquireTimeout(t *testing.T) {
+	metrics := &acquireMetrics{}
+	provider := dbq.NewTxProvider(saturatedPool{}, dbq.AcquireTimeout(10*time.Millisecond), dbq.WithMetrics(metrics))
+	start := time.Now()
+	_, err := provider.Acquire(context.Background())
+	if !errors.Is(err, dbq.ErrAcquireTimeout) {
+		t.Fatalf("expected ErrAcquireTimeout, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("acquire should fail fast")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = provider.Acquire(ctx); !errors.Is(err, context.Canceled) || errors.Is(err, dbq.ErrAcquireTimeout) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	stats := provider.AcquireStats()
+	if stats.Timeouts != 1 || stats.Acquired != 0 || stats.Waiting != 0 || stats.MaxWait < 10*time.Millisecond {
+		t.Errorf("bad stats %+v", stats)
+	}
+	if len(metrics.depths) != 4 || metrics.depths[0] != 1 || metrics.depths[1] != 0 {
+		t.Errorf("bad queue depths %v", metrics.depths)
+	}
+	if len(metrics.errs) != 2 || !errors.Is(metrics.errs[0], dbq.ErrAcquireTimeout) {
+		t.Errorf("bad acquire errors %v", metrics.errs)
+	}
+}
+
+func TestAcquireTimeoutNotExpired(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer db.Close()
+
+	provider := dbq.NewTxProvider(db, dbq.AcquireTimeout(time.Second))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		_, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+		return err
+	}))
+	if stats := provider.AcquireStats(); stats.Acquired != 1 || stats.Timeouts != 0 {
+		t.Errorf("bad stats %+v", stats)
+	}
+}
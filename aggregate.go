@@ -0,0 +1,154 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Aggregate is declarative definition of summary table maintained by
+// additive updates, e.g.
+//
+//	var dailySales = dbq.Aggregate{
+//		Table: "daily_sales",
+//		Keys:  []string{"day", "product_id"},
+//		Sums:     []string{"quantity", "amount"},
+//		Distinct: []string{"customers"},
+//		Count:    "orders",
+//	}
+//	...
+//	err := dailySales.Add(ctx, []any{day, productID}, quantity, amount, customerID)
+//
+// Keys must have unique constraint in summary table.
+type Aggregate struct {
+	// Table is summary table name.
+	Table string
+	// Keys are grouping columns.
+	Keys []string
+	// Sums are additive columns, values are passed in the same order.
+	Sums []string
+	// Distinct are hll columns of postgresql-hll extension estimating
+	// number of distinct values, values are passed after values of Sums
+	// and hashed as text. They are supported only on Postgres, read them
+	// with Estimate.
+	Distinct []string
+	// Count is optional column counting aggregated rows.
+	Count string
+}
+
+// Add adds values to sums of row with key, row is inserted when missing.
+// Statement uses ON DUPLICATE KEY UPDATE for MySQL dialect set with
+// WithDialect and ON CONFLICT otherwise.
+func (a Aggregate) Add(ctx TxContext, key []any, values ...any) error {
+	if err := a.check(key, values, len(a.Sums)+len(a.Distinct)); err != nil {
+		return err
+	}
+	d, _ := DialectFromCtx(ctx)
+	if len(a.Distinct) > 0 && d != nil && d != Postgres {
+		return fmt.Errorf("dbq: aggregate %s distinct columns are not supported by %s", a.Table, d.Name())
+	}
+	cols := append(append(append([]string(nil), a.Keys...), a.Sums...), a.Distinct...)
+	args := append(append([]any(nil), key...), values...)
+	if a.Count != "" {
+		cols = append(cols, a.Count)
+		args = append(args, 1)
+	}
+	distinct := func(i int) bool {
+		i -= len(a.Keys) + len(a.Sums)
+		return i >= 0 && i < len(a.Distinct)
+	}
+
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(a.Table)
+	b.WriteString(" (")
+	b.WriteString(strings.Join(cols, ", "))
+	b.WriteString(") VALUES (")
+	for i := range cols {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if distinct(i) {
+			b.WriteString("hll_add(hll_empty(), hll_hash_any(?::text))")
+		} else {
+			b.WriteByte('?')
+		}
+	}
+	b.WriteString(")")
+
+	set := make([]string, 0, len(cols)-len(a.Keys))
+	if d == MySQL {
+		b.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for _, c := range cols[len(a.Keys):] {
+			set = append(set, c+" = "+c+" + VALUES("+c+")")
+		}
+	} else {
+		b.WriteString(" ON CONFLICT (")
+		b.WriteString(strings.Join(a.Keys, ", "))
+		b.WriteString(") DO UPDATE SET ")
+		for i := len(a.Keys); i < len(cols); i++ {
+			c := cols[i]
+			if distinct(i) {
+				set = append(set, c+" = hll_union("+a.Table+"."+c+", excluded."+c+")")
+			} else {
+				set = append(set, c+" = "+a.Table+"."+c+" + excluded."+c)
+			}
+		}
+	}
+	b.WriteString(strings.Join(set, ", "))
+
+	_, err := ctx.Exec(b.String(), args...)
+	return err
+}
+
+// Estimate returns expression estimating number of distinct values of
+// Distinct column, for use in SELECT lists of summary table. Rows grouped
+// by query are merged, e.g. weekly visitors from daily rows:
+//
+//	"SELECT " + daily.Estimate("visitors") + " FROM daily_views WHERE day >= ?"
+func (a Aggregate) Estimate(column string) string {
+	return "hll_cardinality(hll_union_agg(" + column + "))::bigint"
+}
+
+// Remove subtracts values from sums of row with key, it is used when
+// aggregated row is deleted. Values are passed only for Sums, since values
+// can't be removed from estimates of Distinct columns.
+func (a Aggregate) Remove(ctx TxContext, key []any, values ...any) error {
+	if err := a.check(key, values, len(a.Sums)); err != nil {
+		return err
+	}
+	set := make([]string, 0, len(a.Sums)+1)
+	for _, c := range a.Sums {
+		set = append(set, c+" = "+c+" - ?")
+	}
+	if a.Count != "" {
+		set = append(set, a.Count+" = "+a.Count+" - 1")
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	where := make([]string, len(a.Keys))
+	for i, c := range a.Keys {
+		where[i] = c + " = ?"
+	}
+	query := "UPDATE " + a.Table + " SET " + strings.Join(set, ", ") +
+		" WHERE " + strings.Join(where, " AND ")
+	_, err := ctx.Exec(query, append(append([]any(nil), values...), key...)...)
+	return err
+}
+
+func (a Aggregate) check(key, values []any, n int) error {
+	if len(a.Keys) == 0 || len(key) != len(a.Keys) {
+		return fmt.Errorf("dbq: aggregate %s expects %d key values, got %d", a.Table, len(a.Keys), len(key))
+	}
+	if len(a.Sums) == 0 && len(a.Distinct) == 0 && a.Count == "" {
+		return fmt.Errorf("dbq: aggregate %s has no columns to aggregate", a.Table)
+	}
+	if len(values) != n {
+		return fmt.Errorf("dbq: aggregate %s expects %d values, got %d", a.Table, n, len(values))
+	}
+	return nil
+}
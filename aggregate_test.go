@@ -0,0 +1,85 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestAggregate(t *testing.T) {
+	sales := dbq.Aggregate{
+		Table: "daily_sales",
+		Keys:  []string{"day", "product_id"},
+		Sums:  []string{"quantity"},
+		Count: "orders",
+	}
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query: "INSERT INTO daily_sales (day, product_id, quantity, orders) VALUES (?, ?, ?, ?)" +
+				" ON CONFLICT (day, product_id) DO UPDATE SET quantity = daily_sales.quantity + excluded.quantity," +
+				" orders = daily_sales.orders + excluded.orders",
+			Args:         []dbq.RecordedValue{{V: "2022-05-01"}, {V: int64(7)}, {V: int64(3)}, {V: int64(1)}},
+			RowsAffected: 1,
+		},
+		{
+			Query:        "UPDATE daily_sales SET quantity = quantity - ?, orders = orders - 1 WHERE day = ? AND product_id = ?",
+			Args:         []dbq.RecordedValue{{V: int64(3)}, {V: "2022-05-01"}, {V: int64(7)}},
+			RowsAffected: 1,
+		},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		if err := sales.Add(tx, []any{"2022-05-01", 7}); err == nil {
+			t.Error("expected error for missing values")
+		}
+		empty := dbq.Aggregate{Table: "daily_sales", Keys: sales.Keys}
+		if err := empty.Add(tx, []any{"2022-05-01", 7}); err == nil {
+			t.Error("expected error for aggregate without sums and count")
+		}
+		if err := sales.Add(tx, []any{"2022-05-01", 7}, 3); err != nil {
+			return err
+		}
+		return sales.Remove(tx, []any{"2022-05-01", 7}, 3)
+	})
+}
+
+func TestAggregateDistinct(t *testing.T) {
+	views := dbq.Aggregate{
+		Table:    "daily_views",
+		Keys:     []string{"day"},
+		Distinct: []string{"visitors"},
+		Count:    "views",
+	}
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query: "INSERT INTO daily_views (day, visitors, views) VALUES (?, hll_add(hll_empty(), hll_hash_any(?::text)), ?)" +
+				" ON CONFLICT (day) DO UPDATE SET visitors = hll_union(daily_views.visitors, excluded.visitors)," +
+				" views = daily_views.views + excluded.views",
+			Args:         []dbq.RecordedValue{{V: "2022-05-01"}, {V: int64(42)}, {V: int64(1)}},
+			RowsAffected: 1,
+		},
+		{
+			Query:        "UPDATE daily_views SET views = views - 1 WHERE day = ?",
+			Args:         []dbq.RecordedValue{{V: "2022-05-01"}},
+			RowsAffected: 1,
+		},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		if err := views.Add(tx, []any{"2022-05-01"}, 42); err != nil {
+			return err
+		}
+		return views.Remove(tx, []any{"2022-05-01"})
+	})
+	if got := views.Estimate("visitors"); got != "hll_cardinality(hll_union_agg(visitors))::bigint" {
+		t.Errorf("bad estimate %q", got)
+	}
+
+	ctx := dbq.NewDB(context.Background(), nil, dbq.WithDialect(dbq.MySQL))
+	if err := views.Add(ctx, []any{"2022-05-01"}, 42); err == nil {
+		t.Error("expected error for distinct columns on MySQL")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+type hllKeyType struct{}
+
+// ApproxCountDistinct returns aggregate expression estimating number of
+// distinct values of column, for use in SELECT lists of analytics queries.
+// Postgres uses HyperLogLog of postgresql-hll extension when hll is true,
+// SQL Server and DuckDB use their native APPROX_COUNT_DISTINCT, other
+// dialects fall back to exact COUNT(DISTINCT column). Estimates maintained
+// in summary tables are declared with Aggregate.Distinct.
+func ApproxCountDistinct(d Dialect, column string, hll bool) string {
+	switch {
+	case d == Postgres && hll:
+		return "hll_cardinality(hll_add_agg(hll_hash_any(" + column + ")))::bigint"
+	case d == SQLServer:
+		return "APPROX_COUNT_DISTINCT(" + column + ")"
+	case d == DuckDB:
+		return "approx_count_distinct(" + column + ")"
+	}
+	return "COUNT(DISTINCT " + column + ")"
+}
+
+// HasHLL returns true if postgresql-hll extension is installed. Result is
+// remembered for the rest of *Tx. Error of probe is returned, since failed
+// statement aborts Postgres transaction.
+func HasHLL(ctx TxContext) (bool, error) {
+	tx, ok := ctx.(*Tx)
+	if ok {
+		if v, ok := tx.Get(hllKeyType{}); ok {
+			return v.(bool), nil //nolint:forcetypeassert
+		}
+	}
+	n, err := QueryRow[int64](ctx, "SELECT COUNT(*) FROM pg_extension WHERE extname = 'hll'", nil)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		tx.Set(hllKeyType{}, n > 0)
+	}
+	return n > 0, nil
+}
+
+// ApproxDistinct estimates number of distinct values of column in table
+// with ApproxCountDistinct for dialect set with WithDialect, where is
+// optional condition, e.g.
+//
+//	visitors, err := dbq.ApproxDistinct(ctx, "page_views", "visitor_id", "day = ?", day)
+//
+// On Postgres, presence of postgresql-hll is checked with HasHLL.
+func ApproxDistinct(ctx TxContext, table, column, where string, args ...any) (int64, error) {
+	d, _ := DialectFromCtx(ctx)
+	var hll bool
+	if d == Postgres {
+		var err error
+		if hll, err = HasHLL(ctx); err != nil {
+			return 0, err
+		}
+	}
+	query := "SELECT " + ApproxCountDistinct(d, column, hll) + " FROM " + table
+	if where != "" {
+		query += " WHERE " + where
+	}
+	return QueryRow[int64](ctx, query, nil, args...)
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestApproxCountDistinct(t *testing.T) {
+	tests := []struct {
+		dialect dbq.Dialect
+		hll     bool
+		want    string
+	}{
+		{dbq.Postgres, true, "hll_cardinality(hll_add_agg(hll_hash_any(user_id)))::bigint"},
+		{dbq.Postgres, false, "COUNT(DISTINCT user_id)"},
+		{dbq.SQLServer, false, "APPROX_COUNT_DISTINCT(user_id)"},
+		{dbq.DuckDB, false, "approx_count_distinct(user_id)"},
+		{dbq.MySQL, true, "COUNT(DISTINCT user_id)"},
+	}
+	for i, tt := range tests {
+		if got := dbq.ApproxCountDistinct(tt.dialect, "user_id", tt.hll); got != tt.want {
+			t.Errorf("#%d: got %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestApproxDistinct(t *testing.T) {
+	const probe = "SELECT COUNT(*) FROM pg_extension WHERE extname = 'hll'"
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:   probe,
+			Columns: []string{"count"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(1)}}},
+		},
+		{
+			Query:   "SELECT hll_cardinality(hll_add_agg(hll_hash_any(visitor_id)))::bigint FROM page_views WHERE day = $1",
+			Args:    []dbq.RecordedValue{{V: "monday"}},
+			Columns: []string{"count"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(42)}}},
+		},
+		{
+			Query:   "SELECT hll_cardinality(hll_add_agg(hll_hash_any(visitor_id)))::bigint FROM page_views",
+			Columns: []string{"count"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(100)}}},
+		},
+	}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+	provider := dbq.NewTxProvider(db, dbq.WithDialect(dbq.Postgres))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		n, err := dbq.ApproxDistinct(tx, "page_views", "visitor_id", "day = ?", "monday")
+		if err != nil || n != 42 {
+			t.Errorf("bad estimate %d %v", n, err)
+		}
+		// extension probe is remembered
+		n, err = dbq.ApproxDistinct(tx, "page_views", "visitor_id", "")
+		if err != nil || n != 100 {
+			t.Errorf("bad estimate %d %v", n, err)
+		}
+		return nil
+	}))
+}
+
+func TestApproxDistinctProbeError(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{{
+		Query: "SELECT COUNT(*) FROM pg_extension WHERE extname = 'hll'",
+		Err:   "permission denied for table pg_extension",
+	}}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+	provider := dbq.NewTxProvider(db, dbq.WithDialect(dbq.Postgres))
+	err := provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		_, err := dbq.ApproxDistinct(tx, "page_views", "visitor_id", "")
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("probe error should be returned, got %v", err)
+	}
+}
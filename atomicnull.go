@@ -0,0 +1,55 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// AtomicNull is Null[T] safe for concurrent use, e.g. for cached
+// configuration rows reloaded in background. Zero value holds null.
+// AtomicNull must not be copied after first use.
+type AtomicNull[T Type] struct {
+	v atomic.Value
+}
+
+// NewAtomicNull creates AtomicNull holding n.
+func NewAtomicNull[T Type](n Null[T]) *AtomicNull[T] {
+	a := &AtomicNull[T]{}
+	a.Store(n)
+	return a
+}
+
+// Load returns current value.
+func (a *AtomicNull[T]) Load() Null[T] {
+	n, _ := a.v.Load().(Null[T])
+	return n
+}
+
+// Store sets value.
+func (a *AtomicNull[T]) Store(n Null[T]) {
+	a.v.Store(n)
+}
+
+// Swap sets value and returns previous one.
+func (a *AtomicNull[T]) Swap(n Null[T]) Null[T] {
+	old, _ := a.v.Swap(n).(Null[T])
+	return old
+}
+
+// CompareAndSwap sets value to n if current value is old, values are
+// compared like with ==, so invalid values with different Val differ.
+func (a *AtomicNull[T]) CompareAndSwap(old, n Null[T]) bool {
+	if old == (Null[T]{}) && a.v.CompareAndSwap(nil, n) {
+		return true
+	}
+	return a.v.CompareAndSwap(old, n)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a *AtomicNull[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Load())
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestAtomicNull(t *testing.T) {
+	var a dbq.AtomicNull[int64]
+	if a.Load().Valid {
+		t.Error("zero value should be null")
+	}
+	if !a.CompareAndSwap(dbq.Null[int64]{}, dbq.FromValue[int64](1)) {
+		t.Error("swap of empty value should succeed")
+	}
+	if a.CompareAndSwap(dbq.Null[int64]{}, dbq.FromValue[int64](2)) {
+		t.Error("swap with stale old value should fail")
+	}
+	if old := a.Swap(dbq.FromValue[int64](3)); old.Val != 1 {
+		t.Errorf("bad swapped value %v", old)
+	}
+	data, err := json.Marshal(&a)
+	maybePanic(err)
+	assertJSONEquals(t, data, "3", "atomic json")
+
+	a.Store(dbq.Null[int64]{})
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				old := a.Load()
+				if a.CompareAndSwap(old, dbq.FromValue(old.Val+1)) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := a.Load(); n.Val != 100 {
+		t.Errorf("bad counter %v", n)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+)
+
+// Pool is connection pool which can be resized, it is satisfied by *sql.DB.
+type Pool interface {
+	Stats() sql.DBStats
+	SetMaxOpenConns(n int)
+	SetMaxIdleConns(n int)
+}
+
+// AutoscaleConfig configures pool autoscaling.
+type AutoscaleConfig struct {
+	// MinOpen and MaxOpen bound max open connections.
+	MinOpen int
+	MaxOpen int
+	// Step is number of connections added or removed at once, default 1.
+	Step int
+	// Interval between adjustments, default 10s.
+	Interval time.Duration
+	// WaitThreshold is average wait for connection above which pool grows,
+	// default 10ms.
+	WaitThreshold time.Duration
+	// OnChange is called after max open connections changed, when nil
+	// change is logged.
+	OnChange func(from, to int, stats sql.DBStats)
+}
+
+// Autoscaler adjusts MaxOpenConns and MaxIdleConns of pool from observed
+// waits for connection. Pool grows when callers wait for connections longer
+// than threshold and shrinks when less than half of connections are in use
+// without waits. Max idle connections are kept at half of max open ones.
+type Autoscaler struct {
+	pool Pool
+	cfg  AutoscaleConfig
+
+	mu        sync.Mutex
+	open      int
+	waitCount int64
+	waitTime  time.Duration
+}
+
+// NewAutoscaler creates autoscaler for pool, pool is set to MinOpen
+// connections immediately.
+func NewAutoscaler(pool Pool, cfg AutoscaleConfig) *Autoscaler {
+	if cfg.Step <= 0 {
+		cfg.Step = 1
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.WaitThreshold <= 0 {
+		cfg.WaitThreshold = 10 * time.Millisecond
+	}
+	if cfg.MinOpen <= 0 {
+		cfg.MinOpen = 1
+	}
+	if cfg.MaxOpen < cfg.MinOpen {
+		cfg.MaxOpen = cfg.MinOpen
+	}
+	a := &Autoscaler{
+		pool: pool,
+		cfg:  cfg,
+	}
+	stats := pool.Stats()
+	a.waitCount, a.waitTime = stats.WaitCount, stats.WaitDuration
+	a.resize(cfg.MinOpen)
+	return a
+}
+
+// Run adjusts pool every interval until ctx is done.
+func (a *Autoscaler) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.Adjust()
+		}
+	}
+}
+
+// Adjust evaluates pool stats since previous adjustment and resizes pool,
+// it returns current max open connections.
+func (a *Autoscaler) Adjust() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := a.pool.Stats()
+	waits := stats.WaitCount - a.waitCount
+	waited := stats.WaitDuration - a.waitTime
+	a.waitCount, a.waitTime = stats.WaitCount, stats.WaitDuration
+
+	from, to := a.open, a.open
+	switch {
+	case waits > 0 && waited/time.Duration(waits) > a.cfg.WaitThreshold:
+		to = from + a.cfg.Step
+	case waits == 0 && stats.InUse*2 < from:
+		to = from - a.cfg.Step
+	}
+	if to > a.cfg.MaxOpen {
+		to = a.cfg.MaxOpen
+	}
+	if to < a.cfg.MinOpen {
+		to = a.cfg.MinOpen
+	}
+	if to != from {
+		a.resize(to)
+		if a.cfg.OnChange != nil {
+			a.cfg.OnChange(from, to, stats)
+		} else {
+			log.Printf("dbq: pool max open connections changed from %d to %d (waits: %d, in use: %d)",
+				from, to, waits, stats.InUse)
+		}
+	}
+	return a.open
+}
+
+func (a *Autoscaler) resize(open int) {
+	a.open = open
+	idle := open / 2
+	if idle < 1 {
+		idle = 1
+	}
+	a.pool.SetMaxOpenConns(open)
+	a.pool.SetMaxIdleConns(idle)
+}
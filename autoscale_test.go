@@ -0,0 +1,69 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type fakePool struct {
+	stats      sql.DBStats
+	open, idle int
+}
+
+func (p *fakePool) Stats() sql.DBStats {
+	return p.stats
+}
+
+func (p *fakePool) SetMaxOpenConns(n int) {
+	p.open = n
+}
+
+func (p *fakePool) SetMaxIdleConns(n int) {
+	p.idle = n
+}
+
+func TestAutoscaler(t *testing.T) {
+	pool := &fakePool{}
+	var changes int
+	a := dbq.NewAutoscaler(pool, dbq.AutoscaleConfig{
+		MinOpen:  2,
+		MaxOpen:  4,
+		Step:     2,
+		OnChange: func(from, to int, stats sql.DBStats) { changes++ },
+	})
+	if pool.open != 2 || pool.idle != 1 {
+		t.Errorf("bad initial pool %d/%d", pool.open, pool.idle)
+	}
+
+	// slow waits grow the pool up to MaxOpen
+	pool.stats = sql.DBStats{InUse: 2, WaitCount: 10, WaitDuration: time.Second}
+	if n := a.Adjust(); n != 4 || pool.idle != 2 {
+		t.Errorf("pool should grow to 4, got %d/%d", n, pool.idle)
+	}
+	pool.stats = sql.DBStats{InUse: 4, WaitCount: 20, WaitDuration: 2 * time.Second}
+	if n := a.Adjust(); n != 4 {
+		t.Errorf("pool should stay at max 4, got %d", n)
+	}
+
+	// fast waits keep the pool
+	pool.stats = sql.DBStats{InUse: 4, WaitCount: 30, WaitDuration: 2*time.Second + time.Millisecond}
+	if n := a.Adjust(); n != 4 {
+		t.Errorf("pool should stay at 4, got %d", n)
+	}
+
+	// idle pool shrinks down to MinOpen
+	pool.stats.InUse = 1
+	if n := a.Adjust(); n != 2 {
+		t.Errorf("pool should shrink to 2, got %d", n)
+	}
+	if changes != 2 {
+		t.Errorf("expected 2 changes, got %d", changes)
+	}
+}
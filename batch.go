@@ -0,0 +1,218 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxParams are bind parameter limits of dialects.
+var maxParams = map[Dialect]int{
+	Postgres:  65535,
+	MySQL:     65535,
+	SQLite:    32766,
+	SQLServer: 2100,
+	DuckDB:    65535,
+}
+
+// maxRows are limits of rows in single VALUES list of dialects.
+var maxRows = map[Dialect]int{
+	SQLServer: 1000,
+}
+
+// defaultMaxParams is parameter limit when dialect is unknown.
+const defaultMaxParams = 999
+
+// BatchOption configures InsertBatch.
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	columns   []string
+	binder    func(v any) []any
+	maxParams int
+}
+
+// BatchBinder maps rows with binder returning values of columns instead of
+// struct tags, binder must return one value for every column.
+func BatchBinder[T any](columns []string, binder func(*T) []any) BatchOption {
+	return func(o *batchOptions) {
+		o.columns = columns
+		o.binder = func(v any) []any {
+			return binder(v.(*T)) //nolint:forcetypeassert
+		}
+	}
+}
+
+// MaxParams overrides bind parameter limit of dialect.
+func MaxParams(n int) BatchOption {
+	return func(o *batchOptions) {
+		o.maxParams = n
+	}
+}
+
+// InsertBatch inserts rows with multi-row INSERT statements and returns
+// number of inserted rows. Columns are mapped by TagName tags like Insert,
+// identity and generated columns are skipped and absent Default values are
+// written as DEFAULT, for SQLite their columns are left out instead. Rows
+// are split into statements so that bind parameter and row limits of
+// dialect set with WithDialect are respected, e.g.
+//
+//	n, err := dbq.InsertBatch(ctx, "events", events)
+func InsertBatch[T any](ctx TxContext, table string, rows []T, opts ...BatchOption) (int64, error) {
+	var o batchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	columns, values := o.columns, o.binder
+	if values == nil {
+		m, err := structMapOf(reflect.TypeOf((*T)(nil)).Elem())
+		if err != nil {
+			return 0, err
+		}
+		columns, values = structBatchValues(m)
+	}
+	if len(columns) == 0 {
+		return 0, errors.New("dbq: no columns to insert")
+	}
+
+	d, _ := DialectFromCtx(ctx)
+	limit := o.maxParams
+	if limit <= 0 {
+		if limit = maxParams[d]; limit == 0 {
+			limit = defaultMaxParams
+		}
+	}
+	perStmt := limit / len(columns)
+	if perStmt == 0 {
+		return 0, errors.New("dbq: too many columns for parameter limit")
+	}
+	if n := maxRows[d]; n > 0 && perStmt > n {
+		perStmt = n
+	}
+	// SQLite has no DEFAULT in VALUES, so columns with absent values are left
+	// out and statement holds only rows which leave out the same columns.
+	omitDefaults := d == SQLite
+
+	var total int64
+	for start := 0; start < len(rows); {
+		var (
+			b       strings.Builder
+			args    []any
+			omitted []bool
+		)
+		end := start
+		for ; end < len(rows) && end-start < perStmt; end++ {
+			vals := values(&rows[end])
+			if len(vals) != len(columns) {
+				return total, fmt.Errorf("dbq: batch binder returned %d values for %d columns", len(vals), len(columns))
+			}
+			defaults := make([]bool, len(vals))
+			for j, v := range vals {
+				def, ok := v.(defaulter)
+				defaults[j] = ok && def.isDefault()
+			}
+			if end == start {
+				if omitDefaults {
+					omitted = defaults
+				}
+				if !writeInsertPrefix(&b, table, columns, omitted) {
+					end++
+					break
+				}
+			} else if omitDefaults && !reflect.DeepEqual(omitted, defaults) {
+				break
+			} else {
+				b.WriteString(", ")
+			}
+			b.WriteByte('(')
+			n := 0
+			for j, v := range vals {
+				if omitted != nil && omitted[j] {
+					continue
+				}
+				if n++; n > 1 {
+					b.WriteString(", ")
+				}
+				if defaults[j] {
+					b.WriteString("DEFAULT")
+					continue
+				}
+				b.WriteByte('?')
+				args = append(args, v)
+			}
+			b.WriteByte(')')
+		}
+		start = end
+
+		result, err := ctx.Exec(b.String(), args...)
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// writeInsertPrefix writes INSERT statement up to VALUES rows, columns
+// marked in omitted are left out. It returns false when all columns are
+// left out and statement inserting DEFAULT VALUES was written instead.
+func writeInsertPrefix(b *strings.Builder, table string, columns []string, omitted []bool) bool {
+	b.WriteString("INSERT INTO ")
+	b.WriteString(table)
+	n := 0
+	for i, c := range columns {
+		if omitted != nil && omitted[i] {
+			continue
+		}
+		if n++; n == 1 {
+			b.WriteString(" (")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(c)
+	}
+	if n == 0 {
+		b.WriteString(" DEFAULT VALUES")
+		return false
+	}
+	b.WriteString(") VALUES ")
+	return true
+}
+
+// structBatchValues returns insertable columns of struct and function
+// returning their values.
+func structBatchValues(m *structMap) ([]string, func(v any) []any) {
+	var (
+		columns []string
+		fields  []field
+	)
+	for _, f := range m.fields {
+		if !f.generated {
+			columns = append(columns, f.column)
+			fields = append(fields, f)
+		}
+	}
+	return columns, func(v any) []any {
+		rv := reflect.ValueOf(v).Elem()
+		values := make([]any, len(fields))
+		for i, f := range fields {
+			if fv, ok := fieldValue(rv, f.index); ok {
+				values[i] = f.arg(fv.Interface())
+			}
+		}
+		return values
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type event struct {
+	ID   int64                `db:"id,identity"`
+	Name string               `db:"name"`
+	Kind dbq.Optional[string] `db:"kind"`
+}
+
+func TestInsertBatch(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:        "INSERT INTO events (name, kind) VALUES (?, ?), (?, DEFAULT)",
+			Args:         []dbq.RecordedValue{{V: "a"}, {V: "click"}, {V: "b"}},
+			RowsAffected: 2,
+		},
+		{
+			Query:        "INSERT INTO events (name, kind) VALUES (?, ?)",
+			Args:         []dbq.RecordedValue{{V: "c"}, {V: nil}},
+			RowsAffected: 1,
+		},
+		{
+			Query:        "INSERT INTO events (name) VALUES (?), (?), (?)",
+			Args:         []dbq.RecordedValue{{V: "a"}, {V: "b"}, {V: "c"}},
+			RowsAffected: 3,
+		},
+	}}
+	events := []event{
+		{Name: "a", Kind: dbq.OptionalOf("click")},
+		{Name: "b", Kind: dbq.Default[string]()},
+		{Name: "c", Kind: dbq.OptionalNull[string]()},
+	}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		n, err := dbq.InsertBatch(tx, "events", events, dbq.MaxParams(5))
+		if err != nil || n != 3 {
+			t.Errorf("bad batch insert %d %v", n, err)
+		}
+		n, err = dbq.InsertBatch(tx, "events", events, dbq.BatchBinder([]string{"name"}, func(e *event) []any {
+			return []any{e.Name}
+		}))
+		if err != nil || n != 3 {
+			t.Errorf("bad binder batch insert %d %v", n, err)
+		}
+		return nil
+	})
+}
+
+func TestInsertBatchSQLite(t *testing.T) {
+	type counter struct {
+		Kind dbq.Optional[string] `db:"kind"`
+	}
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:        "INSERT INTO events (name, kind) VALUES (?, ?)",
+			Args:         []dbq.RecordedValue{{V: "a"}, {V: "click"}},
+			RowsAffected: 1,
+		},
+		{
+			Query:        "INSERT INTO events (name) VALUES (?), (?)",
+			Args:         []dbq.RecordedValue{{V: "b"}, {V: "c"}},
+			RowsAffected: 2,
+		},
+		{Query: "INSERT INTO counters DEFAULT VALUES", RowsAffected: 1},
+		{Query: "INSERT INTO counters DEFAULT VALUES", RowsAffected: 1},
+	}}
+	events := []event{
+		{Name: "a", Kind: dbq.OptionalOf("click")},
+		{Name: "b", Kind: dbq.Default[string]()},
+		{Name: "c", Kind: dbq.Default[string]()},
+	}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	provider := dbq.NewTxProvider(db, dbq.WithDialect(dbq.SQLite))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		n, err := dbq.InsertBatch(tx, "events", events)
+		if err != nil || n != 3 {
+			t.Errorf("bad batch insert %d %v", n, err)
+		}
+		n, err = dbq.InsertBatch(tx, "counters", []counter{{}, {}})
+		if err != nil || n != 2 {
+			t.Errorf("bad default values insert %d %v", n, err)
+		}
+		return nil
+	}))
+}
+
+func TestInsertBatchSQLServerRows(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{}))
+	defer db.Close()
+
+	var sizes []int
+	capture := func(next dbq.Handler) dbq.Handler {
+		return func(ctx context.Context, stmt *dbq.Statement) (dbq.Outcome, error) {
+			sizes = append(sizes, len(stmt.Args))
+			return dbq.Outcome{Result: driver.RowsAffected(len(stmt.Args))}, nil
+		}
+	}
+	ctx := dbq.NewDB(context.Background(), db, dbq.WithDialect(dbq.SQLServer), dbq.Interceptors(capture))
+	names := make([]string, 1001)
+	binder := dbq.BatchBinder([]string{"name"}, func(name *string) []any {
+		return []any{*name}
+	})
+	n, err := dbq.InsertBatch(ctx, "events", names, binder)
+	if err != nil || n != 1001 || len(sizes) != 2 || sizes[0] != 1000 {
+		t.Errorf("rows should be split by 1000, got %d %v %v", n, sizes, err)
+	}
+
+	_, err = dbq.InsertBatch(ctx, "events", names, dbq.BatchBinder([]string{"name", "kind"}, func(name *string) []any {
+		return []any{*name}
+	}))
+	if err == nil {
+		t.Error("binder returning fewer values than columns should fail")
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Big numbers are scanned from and written as decimal text, which numeric
+// and decimal columns accept, so monetary values keep their precision.
+// Decimal types of other packages which implement sql.Scanner and
+// driver.Valuer, e.g. shopspring decimal.Decimal, can be used with
+// NullAny[T].
+
+var errNotFiniteDecimal = errors.New("null: rational number has no finite decimal representation")
+
+// NullBigInt is nullable arbitrary precision integer, e.g. of numeric(40)
+// column.
+type NullBigInt struct {
+	Val   *big.Int
+	Valid bool // Valid is true if Val is not NULL
+}
+
+// NewNullBigInt creates a new NullBigInt, it is null when v is nil.
+func NewNullBigInt(v *big.Int) NullBigInt {
+	return NullBigInt{
+		Val:   v,
+		Valid: v != nil,
+	}
+}
+
+// Scan implements the Scanner interface.
+func (n *NullBigInt) Scan(value any) error {
+	n.Val, n.Valid = nil, false
+	var i big.Int
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case int64:
+		i.SetInt64(v)
+	case []byte, string:
+		s := strings.TrimSpace(asString(v))
+		if _, ok := i.SetString(s, 10); !ok {
+			return fmt.Errorf("null: couldn't scan %q into big.Int", s)
+		}
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", value, n)
+	}
+	n.Val, n.Valid = &i, true
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (n NullBigInt) Value() (driver.Value, error) {
+	if !n.Valid || n.Val == nil {
+		return nil, nil
+	}
+	return n.Val.String(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, number may be quoted.
+func (n *NullBigInt) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, nullBytes) {
+		n.Val, n.Valid = nil, false
+		return nil
+	}
+	var i big.Int
+	if err := i.UnmarshalJSON(bytes.Trim(data, `"`)); err != nil {
+		return fmt.Errorf("null: couldn't unmarshal JSON: %w", err)
+	}
+	n.Val, n.Valid = &i, true
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, integer is written as number.
+func (n NullBigInt) MarshalJSON() ([]byte, error) {
+	if !n.Valid || n.Val == nil {
+		return []byte("null"), nil
+	}
+	return n.Val.MarshalJSON()
+}
+
+// IsZero returns true for invalid value
+func (n NullBigInt) IsZero() bool {
+	return !n.Valid
+}
+
+// NullBigRat is nullable exact rational number, e.g. of numeric(20, 4)
+// money column. Only numbers with finite decimal representation can be
+// written.
+type NullBigRat struct {
+	Val   *big.Rat
+	Valid bool // Valid is true if Val is not NULL
+}
+
+// NewNullBigRat creates a new NullBigRat, it is null when v is nil.
+func NewNullBigRat(v *big.Rat) NullBigRat {
+	return NullBigRat{
+		Val:   v,
+		Valid: v != nil,
+	}
+}
+
+// Scan implements the Scanner interface. Float values are converted
+// exactly, so they keep representation error of float64.
+func (n *NullBigRat) Scan(value any) error {
+	n.Val, n.Valid = nil, false
+	var r big.Rat
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case int64:
+		r.SetInt64(v)
+	case float64:
+		if r.SetFloat64(v) == nil {
+			return fmt.Errorf("null: couldn't scan %v into big.Rat", v)
+		}
+	case []byte, string:
+		s := strings.TrimSpace(asString(v))
+		if _, ok := r.SetString(s); !ok {
+			return fmt.Errorf("null: couldn't scan %q into big.Rat", s)
+		}
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", value, n)
+	}
+	n.Val, n.Valid = &r, true
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (n NullBigRat) Value() (driver.Value, error) {
+	if !n.Valid || n.Val == nil {
+		return nil, nil
+	}
+	return decimalString(n.Val)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, number may be quoted.
+func (n *NullBigRat) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, nullBytes) {
+		n.Val, n.Valid = nil, false
+		return nil
+	}
+	var r big.Rat
+	if _, ok := r.SetString(string(bytes.Trim(data, `"`))); !ok {
+		return fmt.Errorf("null: couldn't unmarshal JSON: invalid number %s", data)
+	}
+	n.Val, n.Valid = &r, true
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. Number is written as decimal
+// string, so JSON decoders don't round it to float.
+func (n NullBigRat) MarshalJSON() ([]byte, error) {
+	if !n.Valid || n.Val == nil {
+		return []byte("null"), nil
+	}
+	s, err := decimalString(n.Val)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// IsZero returns true for invalid value
+func (n NullBigRat) IsZero() bool {
+	return !n.Valid
+}
+
+// decimalString formats r as exact decimal, error is returned when r has no
+// finite decimal representation, e.g. 1/3.
+func decimalString(r *big.Rat) (string, error) {
+	if r.IsInt() {
+		return r.Num().String(), nil
+	}
+	// denominator of finite decimal has only prime factors 2 and 5.
+	den := new(big.Int).Set(r.Denom())
+	var twos, fives int
+	two, five := big.NewInt(2), big.NewInt(5)
+	mod := new(big.Int)
+	for {
+		q, m := new(big.Int).QuoRem(den, two, mod)
+		if m.Sign() != 0 {
+			break
+		}
+		den, twos = q, twos+1
+	}
+	for {
+		q, m := new(big.Int).QuoRem(den, five, mod)
+		if m.Sign() != 0 {
+			break
+		}
+		den, fives = q, fives+1
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return "", errNotFiniteDecimal
+	}
+	digits := twos
+	if fives > digits {
+		digits = fives
+	}
+	return r.FloatString(digits), nil
+}
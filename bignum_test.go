@@ -0,0 +1,77 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestNullBigInt(t *testing.T) {
+	var n dbq.NullBigInt
+	maybePanic(n.Scan([]byte("123456789012345678901234567890")))
+	if !n.Valid || n.Val.String() != "123456789012345678901234567890" {
+		t.Errorf("bad big int %v", n.Val)
+	}
+	v, err := n.Value()
+	maybePanic(err)
+	if v != "123456789012345678901234567890" {
+		t.Errorf("bad value %v", v)
+	}
+	data, err := json.Marshal(n)
+	maybePanic(err)
+	assertJSONEquals(t, data, "123456789012345678901234567890", "big int json")
+
+	maybePanic(n.Scan(int64(-7)))
+	if n.Val.Int64() != -7 {
+		t.Errorf("bad big int %v", n.Val)
+	}
+	if err = n.Scan("1.5"); err == nil || n.Valid {
+		t.Errorf("fraction should not scan into big int, got %v", n)
+	}
+	maybePanic(n.Scan(nil))
+	if v, _ = n.Value(); v != nil || n.Valid {
+		t.Errorf("null should be written as NULL, got %v", v)
+	}
+	maybePanic(json.Unmarshal([]byte(`"42"`), &n))
+	if !n.Valid || n.Val.Int64() != 42 {
+		t.Errorf("bad unmarshaled big int %v", n)
+	}
+}
+
+func TestNullBigRat(t *testing.T) {
+	var n dbq.NullBigRat
+	maybePanic(n.Scan([]byte("1234567890123456.0125")))
+	v, err := n.Value()
+	maybePanic(err)
+	if v != "1234567890123456.0125" {
+		t.Errorf("bad value %v", v)
+	}
+	data, err := json.Marshal(n)
+	maybePanic(err)
+	assertJSONEquals(t, data, `"1234567890123456.0125"`, "big rat json")
+
+	maybePanic(n.Scan("10"))
+	if v, _ = n.Value(); v != "10" {
+		t.Errorf("bad integer value %v", v)
+	}
+	third := dbq.NewNullBigRat(big.NewRat(1, 3))
+	if _, err = third.Value(); err == nil {
+		t.Error("1/3 should not be written as decimal")
+	}
+	if v, _ = dbq.NewNullBigRat(big.NewRat(1, 8)).Value(); v != "0.125" {
+		t.Errorf("bad value of 1/8: %v", v)
+	}
+	if v, _ = dbq.NewNullBigRat(nil).Value(); v != nil {
+		t.Errorf("nil should be written as NULL, got %v", v)
+	}
+	maybePanic(json.Unmarshal([]byte(`19.99`), &n))
+	if !n.Valid || n.Val.Cmp(big.NewRat(1999, 100)) != 0 {
+		t.Errorf("bad unmarshaled big rat %v", n.Val)
+	}
+}
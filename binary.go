@@ -0,0 +1,281 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"reflect"
+	"time"
+)
+
+// Null implements binary marshaling interfaces of MongoDB driver v2
+// (bson.ValueMarshaler) and fxamacker/cbor (cbor.Marshaler) structurally,
+// so Null values survive archival and messaging without dbq depending on
+// those modules.
+
+// BSON element types used by Null.
+const (
+	bsonDouble   byte = 0x01
+	bsonString   byte = 0x02
+	bsonBoolean  byte = 0x08
+	bsonDateTime byte = 0x09
+	bsonNull     byte = 0x0A
+	bsonInt32    byte = 0x10
+	bsonInt64    byte = 0x12
+)
+
+// MarshalBSONValue implements bson.ValueMarshaler, null is marshaled as
+// BSON null. Time is stored as BSON datetime with millisecond precision.
+func (n Null[T]) MarshalBSONValue() (byte, []byte, error) {
+	if !n.Valid {
+		return bsonNull, nil, nil
+	}
+	if t, ok := any(n.Val).(time.Time); ok {
+		return bsonDateTime, le64(uint64(t.UnixMilli())), nil
+	}
+	v := reflect.ValueOf(n.Val)
+	//nolint:exhaustive
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return bsonBoolean, []byte{1}, nil
+		}
+		return bsonBoolean, []byte{0}, nil
+	case reflect.Uint8, reflect.Int16, reflect.Int32:
+		return bsonInt32, le32(uint32(int32(intOf(v)))), nil
+	case reflect.Int, reflect.Int64:
+		return bsonInt64, le64(uint64(v.Int())), nil
+	case reflect.Float64:
+		return bsonDouble, le64(math.Float64bits(v.Float())), nil
+	case reflect.String:
+		s := v.String()
+		data := le32(uint32(len(s) + 1))
+		return bsonString, append(append(data, s...), 0), nil
+	}
+	return 0, nil, fmt.Errorf("null: can't marshal %T to BSON", n.Val)
+}
+
+// UnmarshalBSONValue implements bson.ValueUnmarshaler.
+func (n *Null[T]) UnmarshalBSONValue(typ byte, data []byte) error {
+	var src any
+	switch {
+	case typ == bsonNull:
+	case typ == bsonBoolean && len(data) == 1:
+		src = data[0] != 0
+	case typ == bsonInt32 && len(data) == 4:
+		src = int64(int32(binary.LittleEndian.Uint32(data)))
+	case (typ == bsonInt64 || typ == bsonDateTime) && len(data) == 8:
+		i := int64(binary.LittleEndian.Uint64(data))
+		src = i
+		if typ == bsonDateTime {
+			src = time.UnixMilli(i).UTC()
+		}
+	case typ == bsonDouble && len(data) == 8:
+		src = math.Float64frombits(binary.LittleEndian.Uint64(data))
+	case typ == bsonString && len(data) >= 5:
+		size := int(binary.LittleEndian.Uint32(data))
+		if size != len(data)-4 || data[len(data)-1] != 0 {
+			return errors.New("null: invalid BSON string")
+		}
+		src = string(data[4 : len(data)-1])
+	default:
+		return fmt.Errorf("null: can't unmarshal BSON type 0x%02x", typ)
+	}
+	return n.Scan(src)
+}
+
+// CBOR major types and simple values used by Null.
+const (
+	cborUint    byte = 0 << 5
+	cborNegInt  byte = 1 << 5
+	cborText    byte = 3 << 5
+	cborTag     byte = 6 << 5
+	cborFalse   byte = 0xf4
+	cborTrue    byte = 0xf5
+	cborNull    byte = 0xf6
+	cborFloat32 byte = 0xfa
+	cborFloat64 byte = 0xfb
+)
+
+// MarshalCBOR implements cbor.Marshaler, null is marshaled as CBOR null and
+// time as RFC 3339 date/time string (tag 0).
+func (n Null[T]) MarshalCBOR() ([]byte, error) {
+	if !n.Valid {
+		return []byte{cborNull}, nil
+	}
+	if t, ok := any(n.Val).(time.Time); ok {
+		s := t.Format(time.RFC3339Nano)
+		return append(cborHead(cborText, uint64(len(s)), cborHead(cborTag, 0, nil)), s...), nil
+	}
+	v := reflect.ValueOf(n.Val)
+	//nolint:exhaustive
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return []byte{cborTrue}, nil
+		}
+		return []byte{cborFalse}, nil
+	case reflect.Uint8, reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := intOf(v); i < 0 {
+			return cborHead(cborNegInt, uint64(-1-i), nil), nil
+		}
+		return cborHead(cborUint, uint64(intOf(v)), nil), nil
+	case reflect.Float64:
+		return be64([]byte{cborFloat64}, math.Float64bits(v.Float())), nil
+	case reflect.String:
+		s := v.String()
+		return append(cborHead(cborText, uint64(len(s)), nil), s...), nil
+	}
+	return nil, fmt.Errorf("null: can't marshal %T to CBOR", n.Val)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler.
+func (n *Null[T]) UnmarshalCBOR(data []byte) error {
+	src, rest, err := cborDecode(data)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("null: trailing CBOR data")
+	}
+	return n.Scan(src)
+}
+
+func intOf(v reflect.Value) int64 {
+	if v.Kind() == reflect.Uint8 {
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+// cborHead appends head of data item of major type with argument n.
+func cborHead(major byte, n uint64, buf []byte) []byte {
+	var size byte
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= math.MaxUint8:
+		size = 1
+	case n <= math.MaxUint16:
+		size = 2
+	case n <= math.MaxUint32:
+		size = 4
+	default:
+		size = 8
+	}
+	buf = append(buf, major|(24+byte(bits.TrailingZeros8(size))))
+	for i := int(size) - 1; i >= 0; i-- {
+		buf = append(buf, byte(n>>(8*i)))
+	}
+	return buf
+}
+
+func le32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func le64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return b
+}
+
+func be64(buf []byte, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return append(buf, b...)
+}
+
+var errCBOR = errors.New("null: invalid or unsupported CBOR data")
+
+// cborDecode decodes single scalar data item supported by Null.
+func cborDecode(data []byte) (any, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, errCBOR
+	}
+	switch data[0] {
+	case cborNull:
+		return nil, data[1:], nil
+	case cborFalse, cborTrue:
+		return data[0] == cborTrue, data[1:], nil
+	case cborFloat32:
+		if len(data) < 5 {
+			return nil, nil, errCBOR
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data[1:]))), data[5:], nil
+	case cborFloat64:
+		if len(data) < 9 {
+			return nil, nil, errCBOR
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data[1:])), data[9:], nil
+	}
+
+	major, info := data[0]&0xe0, data[0]&0x1f
+	var (
+		arg  uint64
+		size int
+	)
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		size = 1 << (info - 24)
+		if len(data) < 1+size {
+			return nil, nil, errCBOR
+		}
+		for _, b := range data[1 : 1+size] {
+			arg = arg<<8 | uint64(b)
+		}
+	default:
+		return nil, nil, errCBOR
+	}
+	rest := data[1+size:]
+
+	switch major {
+	case cborUint:
+		if arg > math.MaxInt64 {
+			return arg, rest, nil
+		}
+		return int64(arg), rest, nil
+	case cborNegInt:
+		if arg > math.MaxInt64 {
+			return nil, nil, errCBOR
+		}
+		return -1 - int64(arg), rest, nil
+	case cborText:
+		if uint64(len(rest)) < arg {
+			return nil, nil, errCBOR
+		}
+		return string(rest[:arg]), rest[arg:], nil
+	case cborTag:
+		v, rest, err := cborDecode(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch t := v.(type) {
+		case string:
+			if arg == 0 {
+				tm, err := time.Parse(time.RFC3339Nano, t)
+				return tm, rest, err
+			}
+		case int64:
+			if arg == 1 {
+				return time.Unix(t, 0).UTC(), rest, nil
+			}
+		case float64:
+			if arg == 1 {
+				sec, frac := math.Modf(t)
+				return time.Unix(int64(sec), int64(frac*1e9)).UTC(), rest, nil
+			}
+		}
+	}
+	return nil, nil, errCBOR
+}
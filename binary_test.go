@@ -0,0 +1,112 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestNullCBOR(t *testing.T) {
+	when := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		marshal func() ([]byte, error)
+		want    string
+	}{
+		{dbq.Null[int]{}.MarshalCBOR, "f6"},
+		{dbq.FromValue(10).MarshalCBOR, "0a"},
+		{dbq.FromValue(500).MarshalCBOR, "1901f4"},
+		{dbq.FromValue[int64](-1000).MarshalCBOR, "3903e7"},
+		{dbq.FromValue[int64](1 << 40).MarshalCBOR, "1b0000010000000000"},
+		{dbq.FromValue(true).MarshalCBOR, "f5"},
+		{dbq.FromValue(1.5).MarshalCBOR, "fb3ff8000000000000"},
+		{dbq.FromValue("abc").MarshalCBOR, "63616263"},
+		{dbq.FromValue(when).MarshalCBOR, "c074323032322d30352d30315431303a30303a30305a"},
+	}
+	for i, tt := range tests {
+		data, err := tt.marshal()
+		if err != nil || hex.EncodeToString(data) != tt.want {
+			t.Errorf("#%d: got %x %v, want %s", i, data, err, tt.want)
+		}
+	}
+
+	var n dbq.Null[int64]
+	for _, tt := range []struct {
+		in   string
+		want dbq.Null[int64]
+	}{
+		{"3903e7", dbq.FromValue[int64](-1000)},
+		{"1b0000010000000000", dbq.FromValue[int64](1 << 40)},
+		{"f6", dbq.Null[int64]{}},
+	} {
+		data, _ := hex.DecodeString(tt.in)
+		if err := n.UnmarshalCBOR(data); err != nil || n != tt.want {
+			t.Errorf("%s: got %v %v", tt.in, n, err)
+		}
+	}
+
+	var tm dbq.Null[time.Time]
+	data, _ := dbq.FromValue(when).MarshalCBOR()
+	if err := tm.UnmarshalCBOR(data); err != nil || !tm.Val.Equal(when) {
+		t.Errorf("bad time %v %v", tm, err)
+	}
+	// epoch based date/time (tag 1)
+	if err := tm.UnmarshalCBOR([]byte{0xc1, 0x1a, 0x62, 0x6e, 0x5a, 0x20}); err != nil || !tm.Val.Equal(when) {
+		t.Errorf("bad epoch time %v %v", tm, err)
+	}
+	var s dbq.Null[string]
+	if err := s.UnmarshalCBOR([]byte{0x63, 'a', 'b'}); err == nil {
+		t.Error("truncated text should fail")
+	}
+}
+
+func TestNullBSON(t *testing.T) {
+	when := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	typ, data, err := dbq.FromValue("abc").MarshalBSONValue()
+	if err != nil || typ != 0x02 || !bytes.Equal(data, []byte{4, 0, 0, 0, 'a', 'b', 'c', 0}) {
+		t.Errorf("bad string %x %x %v", typ, data, err)
+	}
+	if typ, data, _ = (dbq.Null[string]{}).MarshalBSONValue(); typ != 0x0a || len(data) != 0 {
+		t.Errorf("bad null %x %x", typ, data)
+	}
+
+	var s dbq.Null[string]
+	maybePanic(s.UnmarshalBSONValue(0x02, []byte{4, 0, 0, 0, 'a', 'b', 'c', 0}))
+	if s != dbq.FromValue("abc") {
+		t.Errorf("bad string %v", s)
+	}
+	maybePanic(s.UnmarshalBSONValue(0x0a, nil))
+	if s.Valid {
+		t.Error("null should be invalid")
+	}
+
+	roundTrip := func(marshal func() (byte, []byte, error), n interface {
+		UnmarshalBSONValue(byte, []byte) error
+	},
+	) {
+		typ, data, err := marshal()
+		maybePanic(err)
+		maybePanic(n.UnmarshalBSONValue(typ, data))
+	}
+	var (
+		i  dbq.Null[int16]
+		i6 dbq.Null[int64]
+		f  dbq.Null[float64]
+		b  dbq.Null[bool]
+		tm dbq.Null[time.Time]
+	)
+	roundTrip(dbq.FromValue[int16](-7).MarshalBSONValue, &i)
+	roundTrip(dbq.FromValue[int64](1<<40).MarshalBSONValue, &i6)
+	roundTrip(dbq.FromValue(1.5).MarshalBSONValue, &f)
+	roundTrip(dbq.FromValue(true).MarshalBSONValue, &b)
+	roundTrip(dbq.FromValue(when).MarshalBSONValue, &tm)
+	if i.Val != -7 || i6.Val != 1<<40 || f.Val != 1.5 || !b.Val || !tm.Val.Equal(when) {
+		t.Errorf("bad round trip %v %v %v %v %v", i, i6, f, b, tm)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBudgetExceeded is returned for statement exceeding budget set with
+// StatementBudget.
+var ErrBudgetExceeded = errors.New("dbq: statement budget exceeded")
+
+// StatementBudget limits number of statements single transaction may
+// execute, statements over the limit fail with ErrBudgetExceeded. It
+// catches accidental N+1 loops inside transactions during development.
+// Statements of nested transactions count towards budget of outer one,
+// prepared statements and savepoints of nested transactions are not
+// counted.
+func StatementBudget(n int) ProviderOption {
+	return func(t *TxProvider) {
+		t.interceptors = append(t.interceptors, statementBudget(n))
+	}
+}
+
+// statementBudget returns interceptor counting statements of transaction.
+func statementBudget(n int) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			tx, ok := ctx.Value(currentTxKey{}).(*Tx)
+			if !ok || tx.stash == nil || stmt.Op == OpPrepare || isSavepoint(stmt.Query) {
+				return next(ctx, stmt)
+			}
+			tx.stash.mu.Lock()
+			tx.stash.statements++
+			count := tx.stash.statements
+			tx.stash.mu.Unlock()
+			if count > n {
+				return Outcome{}, fmt.Errorf("%w: statement %d of transaction, budget is %d", ErrBudgetExceeded, count, n)
+			}
+			return next(ctx, stmt)
+		}
+	}
+}
+
+// isSavepoint returns true for savepoint statements of nested transactions.
+func isSavepoint(query string) bool {
+	q := strings.ToUpper(strings.TrimSpace(query))
+	return strings.HasPrefix(q, "SAVEPOINT ") || strings.HasPrefix(q, "RELEASE SAVEPOINT ") ||
+		strings.HasPrefix(q, "ROLLBACK TO SAVEPOINT ")
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestStatementBudget(t *testing.T) {
+	rec := &dbq.Recording{
+		Entries: []dbq.RecordedEntry{
+			{Query: "DELETE FROM users"},
+			{Query: "SAVEPOINT dbq_sp_1"},
+			{Query: "DELETE FROM orders"},
+			{Query: "RELEASE SAVEPOINT dbq_sp_1"},
+		},
+	}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	provider := dbq.NewTxProvider(db, dbq.StatementBudget(2))
+	ctx := context.Background()
+	err := provider.Tx(ctx, func(tx dbq.TxContext) error {
+		if _, err := tx.Exec("DELETE FROM users"); err != nil {
+			return err
+		}
+		if err := provider.Tx(tx, func(tx dbq.TxContext) error {
+			_, err := tx.Exec("DELETE FROM orders")
+			return err
+		}); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM audit")
+		return err
+	})
+	if !errors.Is(err, dbq.ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	var dberr *dbq.Error
+	if !errors.As(err, &dberr) || dberr.Query != "DELETE FROM audit" {
+		t.Errorf("expected error of exceeding statement, got %v", err)
+	}
+}
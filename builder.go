@@ -0,0 +1,169 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrNothingToUpdate is returned when patch has no fields to update.
+	ErrNothingToUpdate = errors.New("dbq: nothing to update")
+	// ErrDefaultValue is returned when Default value is used as query
+	// argument instead of in statement builder.
+	ErrDefaultValue = errors.New("dbq: DEFAULT can be used only in statement builders")
+)
+
+// defaulter is implemented by values which can stand for column DEFAULT.
+type defaulter interface {
+	isDefault() bool
+}
+
+func isDefault(v reflect.Value) bool {
+	d, ok := v.Interface().(defaulter)
+	return ok && d.isDefault()
+}
+
+// Insert builds INSERT statement from struct v with all mapped columns
+// except identity and generated ones. Columns with Default value, which is
+// absent Optional, are omitted so database fills them with column DEFAULT.
+func Insert(table string, v any) (string, []any, error) {
+	return insert(table, v, false)
+}
+
+// InsertReturning builds INSERT statement like Insert and appends RETURNING
+// clause with identity and generated columns of v.
+func InsertReturning(table string, v any) (string, []any, error) {
+	return insert(table, v, true)
+}
+
+func insert(table string, v any, returning bool) (string, []any, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	m, err := structMapOf(rv.Type())
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		cols []string
+		args []any
+	)
+	for _, f := range m.fields {
+		if f.generated {
+			continue
+		}
+		fv, ok := fieldValue(rv, f.index)
+		if ok && isDefault(fv) {
+			continue
+		}
+		cols = append(cols, f.column)
+		if ok {
+			args = append(args, f.arg(fv.Interface()))
+		} else {
+			args = append(args, nil)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(table)
+	if len(cols) == 0 {
+		b.WriteString(" DEFAULT VALUES")
+	} else {
+		b.WriteString(" (")
+		b.WriteString(strings.Join(cols, ", "))
+		b.WriteString(") VALUES (")
+		b.WriteString(strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "))
+		b.WriteString(")")
+	}
+	if returning {
+		if generated := m.generatedColumns(); len(generated) > 0 {
+			b.WriteString(" RETURNING ")
+			b.WriteString(strings.Join(generated, ", "))
+		}
+	}
+	return b.String(), args, nil
+}
+
+// generatedColumns returns identity and generated columns.
+func (m *structMap) generatedColumns() []string {
+	var cols []string
+	for _, f := range m.fields {
+		if f.generated {
+			cols = append(cols, f.column)
+		}
+	}
+	return cols
+}
+
+// fieldValue returns struct field, false is returned for field of nil
+// embedded pointer.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// Update builds UPDATE statement from patch struct. Fields are selected like
+// in ApplyPatch: set Optional fields (explicit null included), valid Null
+// fields, non-nil pointers and all other fields, identity and generated
+// columns are never updated. Where clause and its
+// arguments are appended as is, e.g.
+//
+//	query, args, err := dbq.Update("users", patch, "id = ?", id)
+//	...
+//	_, err = dbq.Exec(ctx, query, args...)
+func Update(table string, patch any, where string, whereArgs ...any) (string, []any, error) {
+	pv := reflect.ValueOf(patch)
+	if pv.Kind() == reflect.Pointer {
+		pv = pv.Elem()
+	}
+	m, err := structMapOf(pv.Type())
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		set  []string
+		args []any
+	)
+	for _, f := range m.fields {
+		if f.generated {
+			continue
+		}
+		value, ok := patchFieldValue(pv, f.index)
+		if !ok {
+			continue
+		}
+		set = append(set, f.column+" = ?")
+		args = append(args, f.arg(value))
+	}
+	if len(set) == 0 {
+		return "", nil, ErrNothingToUpdate
+	}
+
+	var b strings.Builder
+	b.WriteString("UPDATE ")
+	b.WriteString(table)
+	b.WriteString(" SET ")
+	b.WriteString(strings.Join(set, ", "))
+	if where != "" {
+		b.WriteString(" WHERE ")
+		b.WriteString(where)
+	}
+	return b.String(), append(args, whereArgs...), nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type order struct {
+	ID    int64   `db:"id,identity"`
+	Price float64 `db:"price"`
+	Qty   int     `db:"qty"`
+	Total float64 `db:"total,generated"`
+}
+
+func TestInsertGenerated(t *testing.T) {
+	query, args, err := dbq.Insert("orders", order{ID: 5, Price: 2.5, Qty: 2, Total: 5})
+	maybePanic(err)
+	if query != "INSERT INTO orders (price, qty) VALUES (?, ?)" {
+		t.Errorf("bad query %q", query)
+	}
+	if !reflect.DeepEqual(args, []any{2.5, 2}) {
+		t.Errorf("bad args %v", args)
+	}
+
+	query, _, err = dbq.InsertReturning("orders", order{Price: 2.5, Qty: 2})
+	maybePanic(err)
+	if query != "INSERT INTO orders (price, qty) VALUES (?, ?) RETURNING id, total" {
+		t.Errorf("bad query %q", query)
+	}
+}
+
+func TestUpdateGenerated(t *testing.T) {
+	query, args, err := dbq.Update("orders", order{ID: 5, Price: 3, Qty: 1, Total: 3}, "id = ?", 5)
+	maybePanic(err)
+	if query != "UPDATE orders SET price = ?, qty = ? WHERE id = ?" {
+		t.Errorf("bad query %q", query)
+	}
+	if !reflect.DeepEqual(args, []any{3.0, 1, 5}) {
+		t.Errorf("bad args %v", args)
+	}
+}
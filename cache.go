@@ -0,0 +1,142 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache stores rows by key, it is implemented by MemoryCache and can be
+// implemented by adapters of external caches.
+type Cache[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, val V)
+	Delete(key K)
+}
+
+type memoryEntry[V any] struct {
+	val     V
+	expires time.Time
+}
+
+func (e memoryEntry[V]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// MemoryCache is in-memory Cache with expiration. Expired entries are
+// removed when they are read and swept once per ttl when values are set.
+type MemoryCache[K comparable, V any] struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[K]memoryEntry[V]
+	swept   time.Time
+}
+
+// NewMemoryCache creates cache whose entries expire after ttl, zero ttl
+// keeps entries until they are deleted.
+func NewMemoryCache[K comparable, V any](ttl time.Duration) *MemoryCache[K, V] {
+	return &MemoryCache[K, V]{
+		ttl:     ttl,
+		entries: make(map[K]memoryEntry[V]),
+	}
+}
+
+// Get returns cached value of key.
+func (c *MemoryCache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	e, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok && e.expired(time.Now()) {
+		c.mu.Lock()
+		// entry could be set again meanwhile.
+		if e, ok = c.entries[key]; ok && e.expired(time.Now()) {
+			delete(c.entries, key)
+			ok = false
+		}
+		c.mu.Unlock()
+	}
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return e.val, true
+}
+
+// Set stores value of key.
+func (c *MemoryCache[K, V]) Set(key K, val V) {
+	now := time.Now()
+	e := memoryEntry[V]{val: val}
+	if c.ttl > 0 {
+		e.expires = now.Add(c.ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl > 0 && now.Sub(c.swept) >= c.ttl {
+		for k, e := range c.entries {
+			if e.expired(now) {
+				delete(c.entries, k)
+			}
+		}
+		c.swept = now
+	}
+	c.entries[key] = e
+}
+
+// Delete removes key.
+func (c *MemoryCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// WriteThrough places val written by transaction into cache under key after
+// transaction is committed, so the next read doesn't miss. Nothing is cached
+// when transaction is rolled back. Outside of *Tx val is cached immediately.
+func WriteThrough[K comparable, V any](ctx TxContext, c Cache[K, V], key K, val V) {
+	tx, ok := ctx.(*Tx)
+	if !ok {
+		c.Set(key, val)
+		return
+	}
+	tx.OnCommit(func() {
+		c.Set(key, val)
+	})
+}
+
+// Invalidate removes key from cache after transaction is committed, e.g.
+// after row is deleted. Outside of *Tx key is removed immediately.
+func Invalidate[K comparable, V any](ctx TxContext, c Cache[K, V], key K) {
+	tx, ok := ctx.(*Tx)
+	if !ok {
+		c.Delete(key)
+		return
+	}
+	tx.OnCommit(func() {
+		c.Delete(key)
+	})
+}
+
+// ExecWriteThrough runs write statement with RETURNING clause like
+// ExecReturning and caches returned canonical row under key(row) after
+// commit. Row is cached unmasked like in GetMany, returned row is masked
+// for role of ctx by tags of T, e.g. in repository Create and Update:
+//
+//	u, err := dbq.ExecWriteThrough(ctx, users, func(u User) int64 { return u.ID },
+//		"UPDATE users SET name = ? WHERE id = ? RETURNING id, name, updated", userBinder, name, id)
+func ExecWriteThrough[K comparable, T any](ctx TxContext, c Cache[K, T], key func(T) K,
+	query string, binder func(*T) []any, args ...any,
+) (T, error) {
+	row, err := execReturning(ctx, query, binder, args, false)
+	if err != nil {
+		return row, err
+	}
+	WriteThrough(ctx, c, key(row), row)
+	if m, ok := ctx.Value(maskerKey{}).(*Masker); ok {
+		role, _ := RoleFromCtx(ctx)
+		m.Apply(role, &row)
+	}
+	return row, nil
+}
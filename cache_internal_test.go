@@ -0,0 +1,24 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheSweep(t *testing.T) {
+	c := NewMemoryCache[int64, string](time.Millisecond)
+	c.Set(1, "john")
+	c.Set(2, "jane")
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := c.Get(1); ok || len(c.entries) != 1 {
+		t.Errorf("expired entry should be removed when read, got %v", c.entries)
+	}
+	c.Set(3, "joe")
+	if _, ok := c.entries[2]; ok || len(c.entries) != 1 {
+		t.Errorf("expired entries should be swept, got %v", c.entries)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestMemoryCache(t *testing.T) {
+	c := dbq.NewMemoryCache[int64, string](time.Millisecond)
+	c.Set(1, "john")
+	if v, ok := c.Get(1); !ok || v != "john" {
+		t.Errorf("bad cached value %q", v)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := c.Get(1); ok {
+		t.Error("entry should expire")
+	}
+	c.Set(2, "jane")
+	c.Delete(2)
+	if _, ok := c.Get(2); ok {
+		t.Error("entry should be deleted")
+	}
+}
+
+func TestExecWriteThrough(t *testing.T) {
+	const query = "UPDATE users SET name = ? WHERE id = ? RETURNING id, name, created"
+	created := time.Unix(100, 0).UTC()
+	entry := dbq.RecordedEntry{
+		Query:   query,
+		Args:    []dbq.RecordedValue{{V: "johnny"}, {V: int64(1)}},
+		Columns: []string{"id", "name", "created"},
+		Rows:    [][]dbq.RecordedValue{{{V: int64(1)}, {V: "johnny"}, {V: created}}},
+	}
+	db := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{Entries: []dbq.RecordedEntry{entry, entry}}))
+	defer db.Close()
+	provider := dbq.NewTxProvider(db)
+
+	var users dbq.Cache[int64, user] = dbq.NewMemoryCache[int64, user](0)
+	id := func(u user) int64 { return u.ID }
+	errFailed := errors.New("failed")
+	err := provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		if _, err := dbq.ExecWriteThrough(tx, users, id, query, userBinder, "johnny", 1); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatal(err)
+	}
+	if _, ok := users.Get(1); ok {
+		t.Error("rolled back row should not be cached")
+	}
+
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		_, err := dbq.ExecWriteThrough(tx, users, id, query, userBinder, "johnny", 1)
+		if _, ok := users.Get(1); ok {
+			t.Error("row should be cached after commit")
+		}
+		return err
+	}))
+	if u, ok := users.Get(1); !ok || u.Name.Val != "johnny" {
+		t.Errorf("bad cached row %+v", u)
+	}
+
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		dbq.Invalidate(tx, users, 1)
+		return nil
+	}))
+	if _, ok := users.Get(1); ok {
+		t.Error("row should be invalidated")
+	}
+}
+
+func TestExecWriteThroughMasked(t *testing.T) {
+	const query = "UPDATE members SET name = ? WHERE id = ? RETURNING id, name"
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{{
+		Query:   query,
+		Args:    []dbq.RecordedValue{{V: "jane"}, {V: int64(3)}},
+		Columns: []string{"id", "name"},
+		Rows:    [][]dbq.RecordedValue{{{V: int64(3)}, {V: "jane"}}},
+	}}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	var members dbq.Cache[int64, member] = dbq.NewMemoryCache[int64, member](0)
+	provider := dbq.NewTxProvider(db, dbq.Masking(dbq.NewMasker().Column("name", dbq.MaskRedact, "admin")))
+	ctx := dbq.WithRole(context.Background(), "support")
+	maybePanic(provider.Tx(ctx, func(tx dbq.TxContext) error {
+		m, err := dbq.ExecWriteThrough(tx, members, func(m member) int64 { return m.ID }, query,
+			func(m *member) []any { return []any{&m.ID, &m.Name} }, "jane", 3)
+		if m.Name != "***" {
+			t.Errorf("returned row should be masked, got %+v", m)
+		}
+		return err
+	}))
+	if m, ok := members.Get(3); !ok || m.Name != "jane" {
+		t.Errorf("row should be cached unmasked, got %+v", m)
+	}
+}
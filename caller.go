@@ -0,0 +1,48 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const pkgPrefix = "github.com/enverbisevac/dbq."
+
+var captureCaller int32
+
+// CaptureCaller turns on or off capturing of file:line where statement was
+// called from. Caller is available in Statement.Caller, capturing has small
+// cost on every statement so it is disabled by default.
+func CaptureCaller(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&captureCaller, v)
+}
+
+// caller returns file:line of the first frame outside of dbq and
+// database/sql packages.
+func caller() string {
+	if atomic.LoadInt32(&captureCaller) == 0 {
+		return ""
+	}
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, pkgPrefix) &&
+			!strings.HasPrefix(frame.Function, "database/sql.") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestCaptureCaller(t *testing.T) {
+	var caller string
+	capture := func(next dbq.Handler) dbq.Handler {
+		return func(ctx context.Context, stmt *dbq.Statement) (dbq.Outcome, error) {
+			caller = stmt.Caller
+			return dbq.Outcome{}, nil
+		}
+	}
+	access := dbq.Intercept(&nopAccess{}, capture)
+
+	_, _ = access.ExecContext(context.Background(), "DELETE FROM t")
+	if caller != "" {
+		t.Errorf("caller should be empty when disabled, got %q", caller)
+	}
+
+	dbq.CaptureCaller(true)
+	defer dbq.CaptureCaller(false)
+
+	_, _ = access.ExecContext(context.Background(), "DELETE FROM t")
+	if !strings.Contains(caller, "caller_test.go:") {
+		t.Errorf("bad caller %q", caller)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrChaos is transient error injected by Chaos.
+var ErrChaos = errors.New("dbq: chaos injected transient error")
+
+// ChaosConfig configures fault injection. Probabilities are in range [0, 1].
+type ChaosConfig struct {
+	// Seed of random generator, the same seed gives the same fault sequence.
+	Seed int64
+	// DelayProbability is probability of delaying statement up to MaxDelay.
+	DelayProbability float64
+	MaxDelay         time.Duration
+	// ErrorProbability is probability of failing statement with Err.
+	ErrorProbability float64
+	// Err is returned for injected errors, ErrChaos is used when nil.
+	Err error
+	// DropProbability is probability of failing statement with
+	// driver.ErrBadConn as if connection was dropped.
+	DropProbability float64
+}
+
+// Chaos is Access decorator which randomly delays statements, returns
+// transient errors or drops connections. It is meant for resilience testing
+// of retry and circuit breaker logic, never for production use.
+type Chaos struct {
+	Access
+	cfg ChaosConfig
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewChaos wraps db with fault injection configured by cfg.
+func NewChaos(db Access, cfg ChaosConfig) *Chaos {
+	if cfg.Err == nil {
+		cfg.Err = ErrChaos
+	}
+	return &Chaos{
+		Access: db,
+		cfg:    cfg,
+		rnd:    rand.New(rand.NewSource(cfg.Seed)), //nolint:gosec
+	}
+}
+
+func (c *Chaos) roll(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rnd.Float64() < probability
+}
+
+func (c *Chaos) delay() time.Duration {
+	if c.cfg.MaxDelay <= 0 || !c.roll(c.cfg.DelayProbability) {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.rnd.Int63n(int64(c.cfg.MaxDelay) + 1))
+}
+
+// inject returns fault for single statement or nil.
+func (c *Chaos) inject(ctx context.Context) error {
+	if d := c.delay(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if c.roll(c.cfg.DropProbability) {
+		return driver.ErrBadConn
+	}
+	if c.roll(c.cfg.ErrorProbability) {
+		return c.cfg.Err
+	}
+	return nil
+}
+
+// PrepareContext prepares statement unless fault is injected.
+func (c *Chaos) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if err := c.inject(ctx); err != nil {
+		return nil, err
+	}
+	return c.Access.PrepareContext(ctx, query)
+}
+
+// ExecContext executes statement unless fault is injected.
+func (c *Chaos) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := c.inject(ctx); err != nil {
+		return nil, err
+	}
+	return c.Access.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs query unless fault is injected.
+func (c *Chaos) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := c.inject(ctx); err != nil {
+		return nil, err
+	}
+	return c.Access.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs query unless fault is injected, injected fault is
+// reported by Scan and Err of returned row.
+func (c *Chaos) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if err := c.inject(ctx); err != nil {
+		return errRow(err)
+	}
+	return c.Access.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type nopAccess struct {
+	calls int
+}
+
+func (a *nopAccess) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	a.calls++
+	return nil, nil
+}
+
+func (a *nopAccess) ExecContext(context.Context, string, ...any) (sql.Result, error) {
+	a.calls++
+	return nil, nil
+}
+
+func (a *nopAccess) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	a.calls++
+	return nil, nil
+}
+
+func (a *nopAccess) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	a.calls++
+	return nil
+}
+
+func TestChaosErrors(t *testing.T) {
+	ctx := context.Background()
+	inner := &nopAccess{}
+
+	chaos := dbq.NewChaos(inner, dbq.ChaosConfig{ErrorProbability: 1})
+	if _, err := chaos.ExecContext(ctx, "DELETE FROM t"); !errors.Is(err, dbq.ErrChaos) {
+		t.Errorf("expected ErrChaos, got %v", err)
+	}
+
+	chaos = dbq.NewChaos(inner, dbq.ChaosConfig{DropProbability: 1})
+	if _, err := chaos.QueryContext(ctx, "SELECT 1"); !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("expected ErrBadConn, got %v", err)
+	}
+	if err := chaos.QueryRowContext(ctx, "SELECT 1").Err(); !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("expected ErrBadConn from row, got %v", err)
+	}
+
+	chaos = dbq.NewChaos(inner, dbq.ChaosConfig{ErrorProbability: 1})
+	var n int
+	if err := chaos.QueryRowContext(ctx, "SELECT 1").Scan(&n); !errors.Is(err, dbq.ErrChaos) {
+		t.Errorf("expected ErrChaos from row, got %v", err)
+	}
+
+	if inner.calls != 0 {
+		t.Errorf("inner access should not be called, got %d calls", inner.calls)
+	}
+
+	chaos = dbq.NewChaos(inner, dbq.ChaosConfig{})
+	if _, err := chaos.ExecContext(ctx, "DELETE FROM t"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("inner access should be called once, got %d calls", inner.calls)
+	}
+}
+
+func TestChaosSeed(t *testing.T) {
+	run := func() []bool {
+		chaos := dbq.NewChaos(&nopAccess{}, dbq.ChaosConfig{Seed: 7, ErrorProbability: 0.5})
+		out := make([]bool, 20)
+		for i := range out {
+			_, err := chaos.ExecContext(context.Background(), "UPDATE t SET a = 1")
+			out[i] = err != nil
+		}
+		return out
+	}
+	a, b := run(), run()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("fault sequences with the same seed differ at %d", i)
+		}
+	}
+}
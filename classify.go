@@ -0,0 +1,457 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import "strings"
+
+// Action is kind of SQL statement.
+type Action int
+
+// Statement actions.
+const (
+	ActionOther Action = iota
+	ActionSelect
+	ActionInsert
+	ActionUpdate
+	ActionDelete
+	ActionDDL
+	// ActionSet changes session settings with SET or RESET, except SET ROLE
+	// and SET SESSION AUTHORIZATION which are ActionOther.
+	ActionSet
+	ActionShow
+	// ActionSavepoint is SAVEPOINT, RELEASE and ROLLBACK TO statement.
+	ActionSavepoint
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionSelect:
+		return "select"
+	case ActionInsert:
+		return "insert"
+	case ActionUpdate:
+		return "update"
+	case ActionDelete:
+		return "delete"
+	case ActionDDL:
+		return "ddl"
+	case ActionSet:
+		return "set"
+	case ActionShow:
+		return "show"
+	case ActionSavepoint:
+		return "savepoint"
+	case ActionOther:
+	}
+	return "other"
+}
+
+// actionKeywords maps leading keyword of statement to action.
+var actionKeywords = map[string]Action{
+	"select":    ActionSelect,
+	"values":    ActionSelect,
+	"table":     ActionSelect,
+	"insert":    ActionInsert,
+	"replace":   ActionInsert,
+	"upsert":    ActionInsert,
+	"update":    ActionUpdate,
+	"merge":     ActionUpdate,
+	"delete":    ActionDelete,
+	"create":    ActionDDL,
+	"alter":     ActionDDL,
+	"drop":      ActionDDL,
+	"truncate":  ActionDDL,
+	"rename":    ActionDDL,
+	"set":       ActionSet,
+	"reset":     ActionSet,
+	"show":      ActionShow,
+	"savepoint": ActionSavepoint,
+	"release":   ActionSavepoint,
+}
+
+// tableKeywords are followed by table name.
+var tableKeywords = map[string]bool{
+	"from":     true,
+	"join":     true,
+	"into":     true,
+	"update":   true,
+	"table":    true,
+	"truncate": true,
+	"using":    true,
+}
+
+// skipWords may appear between table keyword and table name.
+var skipWords = map[string]bool{
+	"only":      true,
+	"if":        true,
+	"not":       true,
+	"exists":    true,
+	"lateral":   true,
+	"temporary": true,
+	"temp":      true,
+}
+
+// clauseKeywords end list of tables after FROM.
+var clauseKeywords = map[string]bool{
+	"where": true, "group": true, "order": true, "limit": true, "having": true,
+	"window": true, "union": true, "except": true, "intersect": true, "on": true,
+	"set": true, "values": true, "returning": true, "offset": true, "fetch": true,
+	"for": true, "join": true, "inner": true, "left": true, "right": true,
+	"full": true, "cross": true, "natural": true, "select": true, "as": true,
+}
+
+// sqlToken is identifier, keyword or punctuation of statement.
+type sqlToken struct {
+	text string
+	word bool
+	// quoted is true for quoted identifiers.
+	quoted bool
+}
+
+// tokenize splits query into tokens skipping literals, comments and
+// parameters.
+func tokenize(query string) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(query[i+1:], closing)
+			if end < 0 {
+				return tokens
+			}
+			name := query[i+1 : i+1+end]
+			i += end + 2
+			// qualified name with quoted parts
+			if n := len(tokens); n > 0 && tokens[n-1].word && strings.HasSuffix(tokens[n-1].text, ".") {
+				tokens[n-1].text += name
+				tokens[n-1].quoted = true
+				continue
+			}
+			tokens = append(tokens, sqlToken{text: name, word: true, quoted: true})
+		case c == '\'' || strings.HasPrefix(query[i:], "--") || strings.HasPrefix(query[i:], "/*") ||
+			dollarTag(query, i) != "":
+			i = skipQuoted(query, i)
+		case isNameStart(c):
+			start := i
+			for i < len(query) && (isNameChar(query[i]) || query[i] == '.' || query[i] == '$') {
+				i++
+			}
+			if n := len(tokens); n > 0 && tokens[n-1].quoted && strings.HasPrefix(query[start:], ".") {
+				tokens[n-1].text += query[start:i]
+				continue
+			}
+			tokens = append(tokens, sqlToken{text: query[start:i], word: true})
+		case c == '.' && len(tokens) > 0 && tokens[len(tokens)-1].word:
+			tokens[len(tokens)-1].text += "."
+			i++
+		case c == '(' || c == ')' || c == ',' || c == ';':
+			tokens = append(tokens, sqlToken{text: query[i : i+1]})
+			i++
+		case c == ':' || c == '@' || c == '$':
+			// named or positional parameter
+			i++
+			for i < len(query) && isNameChar(query[i]) {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+func (t sqlToken) keyword() string {
+	if !t.word || t.quoted {
+		return ""
+	}
+	return strings.ToLower(t.text)
+}
+
+// Tables referenced by statement, as written in statement without quotes.
+type Tables []string
+
+// Contains returns true if table is referenced, comparison is case
+// insensitive and unqualified table matches table in any schema.
+func (t Tables) Contains(table string) bool {
+	for _, name := range t {
+		if strings.EqualFold(name, table) ||
+			strings.EqualFold(name[strings.LastIndexByte(name, '.')+1:], table) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyStatement returns action of statement and tables it references,
+// e.g. for routing, metrics or access control in interceptors:
+//
+//	action, tables := dbq.ClassifyStatement(stmt.Query)
+//	if action == dbq.ActionDelete && tables.Contains("audit") { ... }
+//
+// It is lightweight lexical classifier, not full SQL parser: literals,
+// comments and quoted identifiers are handled, CTE names and table
+// functions are not reported as tables and statement with CTEs is
+// classified by its data modifying statement, if any.
+//
+// SELECT ... INTO is classified as ActionInsert. EXPLAIN is classified as
+// ActionSelect on tables of explained statement, EXPLAIN ANALYZE, which
+// runs the statement, as explained statement. Statements which are not
+// recognized, like DO, CALL, COPY or GRANT, and statements with MySQL
+// executable comments /*! ... */ are ActionOther. Only the first statement
+// of query with several statements is classified, see Policy.Check.
+func ClassifyStatement(query string) (Action, Tables) {
+	s := classify(query)
+	return s.action, s.tables
+}
+
+// statementInfo is result of classify.
+type statementInfo struct {
+	action Action
+	tables Tables
+	// writes is true for SELECT with locking clause or SELECT INTO.
+	writes bool
+}
+
+func classify(query string) statementInfo {
+	if executableComment(query) {
+		// content of comment, which MySQL runs, is not classified.
+		return statementInfo{}
+	}
+	return classifyTokens(tokenize(query))
+}
+
+func classifyTokens(tokens []sqlToken) statementInfo {
+	if len(tokens) == 0 {
+		return statementInfo{}
+	}
+
+	action := ActionOther
+	ctes := make(map[string]bool)
+	first := tokens[0].keyword()
+	switch {
+	case first == "explain":
+		return classifyExplain(tokens)
+	case first == "set" && setsAuthorization(tokens):
+		return statementInfo{}
+	case first == "rollback" && len(tokens) > 1 && tokens[1].keyword() == "to":
+		action = ActionSavepoint
+	}
+	if first == "with" {
+		action = ActionSelect
+		for i, t := range tokens {
+			if i+2 < len(tokens) && t.word && tokens[i+1].keyword() == "as" && tokens[i+2].text == "(" {
+				ctes[strings.ToLower(t.text)] = true
+			}
+		}
+	} else if a, ok := actionKeywords[first]; ok {
+		action = a
+	}
+
+	var (
+		tables    Tables
+		writes    bool
+		seen      = make(map[string]bool)
+		depth     int
+		expect    bool
+		intro     string // keyword followed by expected table
+		fromDepth = -1
+	)
+	add := func(i int) {
+		t := tokens[i]
+		name := strings.ToLower(t.text)
+		// table functions are skipped, INSERT INTO t (columns) and CREATE
+		// TABLE t (columns) are not function calls.
+		if i+1 < len(tokens) && tokens[i+1].text == "(" && intro != "into" && intro != "table" {
+			return
+		}
+		if ctes[name] || seen[name] {
+			return
+		}
+		seen[name] = true
+		tables = append(tables, t.text)
+	}
+	for i, t := range tokens {
+		kw := t.keyword()
+		switch {
+		case t.text == "(":
+			depth++
+			expect = false
+		case t.text == ")":
+			depth--
+			if depth < fromDepth {
+				fromDepth = -1
+			}
+		case t.text == ",":
+			expect = depth == fromDepth
+		case t.text == ";":
+			expect, fromDepth = false, -1
+		case expect && skipWords[kw]:
+		case expect && t.word && !clauseKeywords[kw] && !tableKeywords[kw]:
+			add(i)
+			expect = false
+		case first == "with" && action == ActionSelect && i > 0 && actionKeywords[kw] > ActionSelect &&
+			actionKeywords[kw] < ActionDDL && tokens[i-1].keyword() != "for":
+			// data modifying statement after CTEs
+			action = actionKeywords[kw]
+			expect, intro = kw == "update", kw
+		case tableKeywords[kw]:
+			if !tableKeyword(tokens, i) {
+				writes = writes || kw == "update"
+				continue
+			}
+			writes = writes || kw == "into"
+			if kw == "into" && action == ActionSelect && depth == 0 {
+				// SELECT ... INTO creates table
+				action = ActionInsert
+			}
+			expect, intro = true, kw
+			if kw == "from" || kw == "using" || kw == "table" {
+				fromDepth = depth
+			}
+		case kw == "on" && action == ActionDDL:
+			expect, intro = true, kw
+		case clauseKeywords[kw]:
+			if kw == "for" && i+1 < len(tokens) {
+				next := tokens[i+1].keyword()
+				writes = writes || next == "share" || next == "key"
+			}
+			expect = false
+			if depth == fromDepth {
+				fromDepth = -1
+			}
+		}
+	}
+	return statementInfo{
+		action: action,
+		tables: tables,
+		writes: writes,
+	}
+}
+
+// classifyExplain classifies EXPLAIN by explained statement. Plain EXPLAIN
+// only reads plan, EXPLAIN ANALYZE runs the statement.
+func classifyExplain(tokens []sqlToken) statementInfo {
+	analyze, depth := false, 0
+	for i := 1; i < len(tokens); i++ {
+		kw := tokens[i].keyword()
+		switch {
+		case tokens[i].text == "(":
+			depth++
+		case tokens[i].text == ")":
+			depth--
+		case kw == "analyze" || kw == "analyse":
+			analyze = true
+		case depth == 0 && (kw == "with" || actionKeywords[kw] != ActionOther):
+			s := classifyTokens(tokens[i:])
+			if !analyze {
+				s.action, s.writes = ActionSelect, false
+			}
+			return s
+		}
+	}
+	return statementInfo{}
+}
+
+// setsAuthorization returns true for SET statement which changes role or
+// user of session, like SET ROLE or SET SESSION AUTHORIZATION.
+func setsAuthorization(tokens []sqlToken) bool {
+	for i := 1; i < len(tokens) && i <= 3; i++ {
+		switch tokens[i].keyword() {
+		case "role", "authorization", "password":
+			return true
+		}
+	}
+	return false
+}
+
+// executableComment returns true if query has MySQL executable comment
+// /*! ... */ outside of literals.
+func executableComment(query string) bool {
+	for i := 0; i < len(query); i++ {
+		if strings.HasPrefix(query[i:], "/*!") {
+			return true
+		}
+		if j := skipQuoted(query, i); j > i {
+			i = j - 1
+		}
+	}
+	return false
+}
+
+// tableKeyword returns false for table keyword at i which is not followed by
+// table name, like FOR UPDATE or TABLE in GRANT ... ON TABLE.
+func tableKeyword(tokens []sqlToken, i int) bool {
+	prev := ""
+	if i > 0 {
+		prev = tokens[i-1].keyword()
+	}
+	switch tokens[i].keyword() {
+	case "update":
+		return prev != "for" && prev != "on" && prev != "do" && prev != "key"
+	case "table":
+		return i == 0 || prev == "create" || prev == "alter" || prev == "drop" || prev == "truncate" ||
+			prev == "lock" || prev == "temporary" || prev == "temp" || prev == "unlogged"
+	}
+	return true
+}
+
+// splitStatements splits query at semicolons outside of literals, quoted
+// identifiers and comments, empty statements are dropped. ok is false when
+// literal or comment is not terminated or parentheses are not balanced.
+func splitStatements(query string) (stmts []string, ok bool) {
+	depth, start := 0, 0
+	for i := 0; i < len(query); i++ {
+		if j := skipQuoted(query, i); j > i {
+			if j == len(query) && !terminated(query, i) {
+				return nil, false
+			}
+			i = j - 1
+			continue
+		}
+		switch query[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return nil, false
+			}
+		case ';':
+			if depth > 0 {
+				return nil, false
+			}
+			if stmt := strings.TrimSpace(query[start:i]); stmt != "" {
+				stmts = append(stmts, stmt)
+			}
+			start = i + 1
+		}
+	}
+	if depth != 0 {
+		return nil, false
+	}
+	if stmt := strings.TrimSpace(query[start:]); stmt != "" {
+		stmts = append(stmts, stmt)
+	}
+	return stmts, true
+}
+
+// terminated returns true if literal or comment starting at i, which
+// reaches the end of query, is terminated.
+func terminated(query string, i int) bool {
+	switch c := query[i]; {
+	case c == '\'' || c == '"' || c == '`':
+		return len(query)-i >= 2 && query[len(query)-1] == c
+	case strings.HasPrefix(query[i:], "/*"):
+		return len(query)-i >= 4 && strings.HasSuffix(query, "*/")
+	case c == '$':
+		tag := dollarTag(query, i)
+		return len(query)-i >= 2*len(tag) && strings.HasSuffix(query, tag)
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		query  string
+		action Action
+		tables Tables
+	}{
+		{"SELECT id FROM users WHERE id = ?", ActionSelect, Tables{"users"}},
+		{"select * from users u, public.orders o join items i on i.id = o.item_id", ActionSelect,
+			Tables{"users", "public.orders", "items"}},
+		{`SELECT * FROM "Users" WHERE name = 'from secrets'`, ActionSelect, Tables{"Users"}},
+		{"SELECT * FROM [dbo].[users]", ActionSelect, Tables{"dbo.users"}},
+		{"SELECT * FROM (SELECT id FROM users) u, generate_series(1, 3)", ActionSelect, Tables{"users"}},
+		{"SELECT * FROM users FOR UPDATE", ActionSelect, Tables{"users"}},
+		{"SELECT * INTO users_copy FROM users", ActionInsert, Tables{"users_copy", "users"}},
+		{"INSERT INTO users (name) VALUES (:name) ON CONFLICT (name) DO UPDATE SET name = excluded.name",
+			ActionInsert, Tables{"users"}},
+		{"INSERT INTO archive SELECT * FROM users", ActionInsert, Tables{"archive", "users"}},
+		{"UPDATE ONLY users SET name = $1 FROM teams WHERE teams.id = users.team_id", ActionUpdate,
+			Tables{"users", "teams"}},
+		{"DELETE FROM sessions USING users WHERE users.id = sessions.user_id", ActionDelete,
+			Tables{"sessions", "users"}},
+		{"WITH old AS (SELECT id FROM users) DELETE FROM sessions WHERE user_id IN (SELECT id FROM old)",
+			ActionDelete, Tables{"users", "sessions"}},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", ActionSelect, nil},
+		{"CREATE TABLE IF NOT EXISTS users (id int)", ActionDDL, Tables{"users"}},
+		{"CREATE INDEX users_name ON users USING btree (name)", ActionDDL, Tables{"users"}},
+		{"DROP TABLE a, b", ActionDDL, Tables{"a", "b"}},
+		{"TRUNCATE logs", ActionDDL, Tables{"logs"}},
+		{"-- comment\nSET search_path = x", ActionSet, nil},
+		{"SET SESSION AUTHORIZATION admin", ActionOther, nil},
+		{"SHOW search_path", ActionShow, nil},
+		{"ROLLBACK TO SAVEPOINT sp", ActionSavepoint, nil},
+		{"RELEASE SAVEPOINT sp", ActionSavepoint, nil},
+		{"EXPLAIN (ANALYZE, BUFFERS) DELETE FROM users", ActionDelete, Tables{"users"}},
+		{"EXPLAIN DELETE FROM users", ActionSelect, Tables{"users"}},
+		{"DO $body$ BEGIN DELETE FROM users; END $body$", ActionOther, nil},
+		{"SELECT $$ FROM users $$, a$b FROM t WHERE id = $1", ActionSelect, Tables{"t"}},
+		{"SELECT 1 /*!50000 , (SELECT 1 FROM users) */", ActionOther, nil},
+		{"", ActionOther, nil},
+	}
+	for i, tt := range tests {
+		action, tables := ClassifyStatement(tt.query)
+		if action != tt.action || !reflect.DeepEqual(tables, tt.tables) {
+			t.Errorf("#%d: classify(%q) = %v %q, want %v %q", i, tt.query, action, tables, tt.action, tt.tables)
+		}
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"SELECT * FROM users", true},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", true},
+		{"SELECT 'update' FROM users", true},
+		{"SELECT * FROM users FOR UPDATE", false},
+		{"SELECT * FROM users FOR NO KEY UPDATE", false},
+		{"SELECT * FROM users FOR SHARE", false},
+		{"SELECT * FROM users FOR KEY SHARE", false},
+		{"SELECT * INTO backup FROM users", false},
+		{"WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d", false},
+		{"UPDATE users SET name = ?", false},
+		{"SET search_path = x", false},
+	}
+	for i, tt := range tests {
+		if got := isReadOnly(tt.query); got != tt.want {
+			t.Errorf("#%d: isReadOnly(%q) = %v, want %v", i, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestTablesContains(t *testing.T) {
+	tables := Tables{"public.Users", "orders"}
+	if !tables.Contains("users") || !tables.Contains("PUBLIC.users") || !tables.Contains("orders") || tables.Contains("items") {
+		t.Errorf("bad contains %v", tables)
+	}
+}
+
+func TestSplitStatementsDollarQuoted(t *testing.T) {
+	stmts, ok := splitStatements("DO $$ BEGIN DELETE FROM t; END $$; SELECT $1")
+	if !ok || !reflect.DeepEqual(stmts, []string{"DO $$ BEGIN DELETE FROM t; END $$", "SELECT $1"}) {
+		t.Errorf("bad statements %q", stmts)
+	}
+	if _, ok = splitStatements("DO $fn$ BEGIN END"); ok {
+		t.Error("unterminated dollar quoted literal should not be split")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// informationSchemaForeignKeys lists foreign keys of tables in current
+// schema, %s is expression of current schema of dialect.
+const informationSchemaForeignKeys = `SELECT child.table_name, parent.table_name
+FROM information_schema.referential_constraints rc
+JOIN information_schema.table_constraints child
+	ON child.constraint_schema = rc.constraint_schema AND child.constraint_name = rc.constraint_name
+JOIN information_schema.table_constraints parent
+	ON parent.constraint_schema = rc.unique_constraint_schema AND parent.constraint_name = rc.unique_constraint_name
+WHERE rc.constraint_schema = %s`
+
+// mysqlForeignKeys lists foreign keys of current database, constraint
+// names of MySQL are unique per table only, so referenced table is taken
+// from key_column_usage.
+const mysqlForeignKeys = `SELECT DISTINCT table_name, referenced_table_name
+FROM information_schema.key_column_usage
+WHERE table_schema = DATABASE() AND referenced_table_name IS NOT NULL`
+
+const sqliteForeignKeys = `SELECT m.name, p."table"
+FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) p
+WHERE m.type = 'table'`
+
+// ForeignKeys returns tables referenced by foreign keys of each table in
+// current schema, discovered from information_schema or from SQLite
+// pragmas.
+func ForeignKeys(ctx context.Context, db Access, d Dialect) (map[string][]string, error) {
+	var query string
+	switch d {
+	case SQLite:
+		query = sqliteForeignKeys
+	case MySQL:
+		query = mysqlForeignKeys
+	case SQLServer:
+		query = fmt.Sprintf(informationSchemaForeignKeys, "SCHEMA_NAME()")
+	default:
+		query = fmt.Sprintf(informationSchemaForeignKeys, "current_schema()")
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fks := make(map[string][]string)
+	for rows.Next() {
+		var child, parent string
+		if err = rows.Scan(&child, &parent); err != nil {
+			return nil, err
+		}
+		fks[child] = append(fks[child], parent)
+	}
+	return fks, rows.Err()
+}
+
+// DeleteOrder returns tables together with all tables referencing them
+// ordered so that referencing tables come before referenced ones, fks maps
+// table to referenced tables as returned by ForeignKeys. Error is returned
+// for reference cycles, which need TRUNCATE ... CASCADE.
+func DeleteOrder(tables []string, fks map[string][]string) ([]string, error) {
+	referencedBy := make(map[string][]string)
+	for child, parents := range fks {
+		for _, parent := range parents {
+			if parent != child {
+				referencedBy[parent] = append(referencedBy[parent], child)
+			}
+		}
+	}
+
+	// include tables referencing given tables.
+	included := make(map[string]bool)
+	queue := append([]string(nil), tables...)
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if included[t] {
+			continue
+		}
+		included[t] = true
+		queue = append(queue, referencedBy[t]...)
+	}
+	names := make([]string, 0, len(included))
+	for t := range included {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var (
+		order []string
+		visit func(t string, path []string) error
+	)
+	// referencing tables are visited first, so they are appended first.
+	visit = func(t string, path []string) error {
+		switch state[t] {
+		case visiting:
+			return fmt.Errorf("dbq: foreign key cycle %s", strings.Join(append(path, t), " -> "))
+		case done:
+			return nil
+		}
+		state[t] = visiting
+		children := append([]string(nil), referencedBy[t]...)
+		sort.Strings(children)
+		for _, child := range children {
+			if err := visit(child, append(path[:len(path):len(path)], t)); err != nil {
+				return err
+			}
+		}
+		state[t] = done
+		order = append(order, t)
+		return nil
+	}
+	for _, t := range names {
+		if err := visit(t, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// CleanTables deletes all rows of tables and of tables referencing them in
+// foreign key order, it resets database between integration tests. With
+// cascade, single TRUNCATE ... CASCADE statement is used instead, which is
+// faster on Postgres and handles reference cycles.
+func CleanTables(ctx context.Context, db Access, d Dialect, cascade bool, tables ...string) error {
+	if cascade {
+		_, err := db.ExecContext(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" CASCADE")
+		return err
+	}
+	fks, err := ForeignKeys(ctx, db, d)
+	if err != nil {
+		return err
+	}
+	order, err := DeleteOrder(tables, fks)
+	if err != nil {
+		return err
+	}
+	for _, t := range order {
+		if _, err = db.ExecContext(ctx, "DELETE FROM "+t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
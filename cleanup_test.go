@@ -0,0 +1,59 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestDeleteOrder(t *testing.T) {
+	fks := map[string][]string{
+		"orders":      {"users"},
+		"order_items": {"orders", "products"},
+		"users":       {"users"}, // self reference
+	}
+	order, err := dbq.DeleteOrder([]string{"users", "products"}, fks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, []string{"order_items", "orders", "products", "users"}) {
+		t.Errorf("bad order %v", order)
+	}
+
+	fks["users"] = []string{"orders"}
+	if _, err = dbq.DeleteOrder([]string{"users"}, fks); err == nil {
+		t.Error("expected cycle error")
+	}
+}
+
+func TestCleanTables(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query: `SELECT m.name, p."table"
+FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) p
+WHERE m.type = 'table'`,
+			Columns: []string{"name", "table"},
+			Rows:    [][]dbq.RecordedValue{{{V: "orders"}, {V: "users"}}},
+		},
+		{Query: "DELETE FROM orders"},
+		{Query: "DELETE FROM users"},
+		{Query: "TRUNCATE users CASCADE"},
+	}}
+	replayer := dbq.NewReplayer(rec)
+	db := sql.OpenDB(replayer)
+	defer db.Close()
+
+	ctx := context.Background()
+	maybePanic(dbq.CleanTables(ctx, db, dbq.SQLite, false, "users"))
+	maybePanic(dbq.CleanTables(ctx, db, dbq.Postgres, true, "users"))
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("expected all statements executed, %d remaining", n)
+	}
+}
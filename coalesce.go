@@ -0,0 +1,105 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type coalesceKeyType struct{}
+
+// coalesced is pending additive update of single key.
+type coalesced struct {
+	key   string
+	query string
+	delta any
+	args  []any
+}
+
+// coalescer holds pending updates of transaction in registration order.
+type coalescer struct {
+	pending []*coalesced
+	byKey   map[string]*coalesced
+	// scheduled is true when flush is registered with Defer.
+	scheduled bool
+}
+
+// CoalesceAdd queues additive update, query takes delta as the first
+// argument followed by key arguments, e.g.
+//
+//	dbq.CoalesceAdd(ctx, "UPDATE counters SET n = n + ? WHERE id = ?", 1, id)
+//
+// Deltas of the same query and key are summed and written with single
+// statement just before COMMIT, so hot rows are locked once per
+// transaction. Updates queued by functions registered with Defer are
+// written before COMMIT too, after transaction is finished sql.ErrTxDone
+// is returned. Outside of *Tx statement is executed immediately.
+func CoalesceAdd[N Number](ctx TxContext, query string, delta N, key ...any) error {
+	tx, ok := ctx.(*Tx)
+	if !ok {
+		_, err := ctx.Exec(query, append([]any{delta}, key...)...)
+		return err
+	}
+	if tx.isFinished() {
+		return sql.ErrTxDone
+	}
+
+	v, _ := tx.Get(coalesceKeyType{})
+	c, ok := v.(*coalescer)
+	if !ok {
+		c = &coalescer{byKey: make(map[string]*coalesced)}
+		tx.Set(coalesceKeyType{}, c)
+	}
+	if !c.scheduled {
+		c.scheduled = true
+		tx.Defer(c.flush)
+	}
+
+	// deltas of different types are queued separately.
+	k := fmt.Sprintf("%s%T%#v", query, delta, key)
+	if p, ok := c.byKey[k]; ok {
+		p.delta = p.delta.(N) + delta //nolint:forcetypeassert
+		return nil
+	}
+	p := &coalesced{key: k, query: query, delta: delta, args: key}
+	c.byKey[k] = p
+	c.pending = append(c.pending, p)
+	return nil
+}
+
+// flush writes pending updates, updates queued later schedule flush again.
+func (c *coalescer) flush(ctx TxContext) error {
+	pending := c.pending
+	c.pending = nil
+	c.scheduled = false
+	for _, p := range pending {
+		delete(c.byKey, p.key)
+		if _, err := ctx.Exec(p.query, append([]any{p.delta}, p.args...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot implements rewinder, so updates queued in rolled back savepoint
+// are discarded.
+func (c *coalescer) snapshot() func() {
+	pending := append([]*coalesced(nil), c.pending...)
+	deltas := make([]any, len(pending))
+	for i, p := range pending {
+		deltas[i] = p.delta
+	}
+	scheduled := c.scheduled
+	return func() {
+		c.pending = pending
+		c.scheduled = scheduled
+		c.byKey = make(map[string]*coalesced, len(pending))
+		for i, p := range pending {
+			p.delta = deltas[i]
+			c.byKey[p.key] = p
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestCoalesceAdd(t *testing.T) {
+	const query = "UPDATE counters SET n = n + ? WHERE id = ?"
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{Query: query, Args: []dbq.RecordedValue{{V: int64(3)}, {V: int64(1)}}, RowsAffected: 1},
+		{Query: query, Args: []dbq.RecordedValue{{V: int64(-1)}, {V: int64(2)}}, RowsAffected: 1},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		for _, u := range []struct{ delta, id int64 }{{1, 1}, {-1, 2}, {2, 1}} {
+			if err := dbq.CoalesceAdd(tx, query, u.delta, u.id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func TestCoalesceAddLate(t *testing.T) {
+	const query = "UPDATE counters SET n = n + ? WHERE id = ?"
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{Query: query, Args: []dbq.RecordedValue{{V: int64(1)}, {V: int64(1)}}, RowsAffected: 1},
+		{Query: query, Args: []dbq.RecordedValue{{V: int64(2)}, {V: int64(1)}}, RowsAffected: 1},
+		{Query: query, Args: []dbq.RecordedValue{{V: int64(4)}, {V: int64(3)}}, RowsAffected: 1},
+	}}
+	var lateErr error
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		// deltas of different types don't panic.
+		maybePanic(dbq.CoalesceAdd(tx, query, 1, int64(1)))
+		maybePanic(dbq.CoalesceAdd(tx, query, int64(2), int64(1)))
+		current := tx.(*dbq.Tx)
+		// queued after flush, written by flush scheduled again.
+		current.Defer(func(tx dbq.TxContext) error {
+			return dbq.CoalesceAdd(tx, query, int64(4), int64(3))
+		})
+		current.OnCommit(func() {
+			lateErr = dbq.CoalesceAdd(current, query, int64(8), int64(3))
+		})
+		return nil
+	})
+	if !errors.Is(lateErr, sql.ErrTxDone) {
+		t.Errorf("expected ErrTxDone after commit, got %v", lateErr)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var errCompositeSyntax = errors.New("invalid composite value")
+
+// ParseComposite splits Postgres composite value in text format, e.g.
+// (1,"john doe",,t), into fields. NULL fields are returned as nil and other
+// fields as string.
+func ParseComposite(s string) ([]any, error) {
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return nil, fmt.Errorf("%w: %q", errCompositeSyntax, s)
+	}
+	s = s[1 : len(s)-1]
+
+	var (
+		fields []any
+		field  strings.Builder
+		quoted bool // field contains quoted part so it is not NULL
+	)
+	for i := 0; i <= len(s); i++ {
+		if i == len(s) || s[i] == ',' {
+			if field.Len() == 0 && !quoted {
+				fields = append(fields, nil)
+			} else {
+				fields = append(fields, field.String())
+			}
+			field.Reset()
+			quoted = false
+			continue
+		}
+		switch s[i] {
+		case '"':
+			quoted = true
+			i++
+			for ; i < len(s); i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				} else if s[i] == '"' {
+					if i+1 < len(s) && s[i+1] == '"' {
+						i++
+					} else {
+						break
+					}
+				}
+				field.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, fmt.Errorf("%w: unterminated quote", errCompositeSyntax)
+			}
+		case '\\':
+			if i+1 < len(s) {
+				i++
+			}
+			field.WriteByte(s[i])
+		default:
+			field.WriteByte(s[i])
+		}
+	}
+	return fields, nil
+}
+
+// FormatComposite formats values as Postgres composite literal, nil values
+// are written as NULL. Error of driver.Valuer value is returned.
+func FormatComposite(values ...any) (string, error) {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if valuer, ok := v.(driver.Valuer); ok {
+			var err error
+			if v, err = valuer.Value(); err != nil {
+				return "", fmt.Errorf("composite field %d: %w", i, err)
+			}
+		}
+		var s string
+		switch val := v.(type) {
+		case nil:
+			continue
+		case time.Time:
+			s = val.Format(time.RFC3339Nano)
+		case bool:
+			s = "f"
+			if val {
+				s = "t"
+			}
+		default:
+			s = asString(val)
+		}
+		b.WriteByte('"')
+		for j := 0; j < len(s); j++ {
+			if s[j] == '"' || s[j] == '\\' {
+				b.WriteByte(s[j])
+			}
+			b.WriteByte(s[j])
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte(')')
+	return b.String(), nil
+}
+
+// Composite is Postgres composite value, ROW(a, b, c) or composite typed
+// column, mapped to exported fields of struct T in declaration order.
+type Composite[T any] struct {
+	Val   T
+	Valid bool // Valid is true if composite is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (c *Composite[T]) Scan(value any) error {
+	var zero T
+	c.Val, c.Valid = zero, false
+
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", value, c)
+	}
+
+	fields, err := ParseComposite(s)
+	if err != nil {
+		return err
+	}
+	dests := compositeFields(&c.Val)
+	if len(dests) != len(fields) {
+		return fmt.Errorf("composite has %d fields but %T has %d", len(fields), c.Val, len(dests))
+	}
+	for i, field := range fields {
+		if err = convertAssign(dests[i], field); err != nil {
+			return fmt.Errorf("composite field %d: %w", i, err)
+		}
+	}
+	c.Valid = true
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (c Composite[T]) Value() (driver.Value, error) {
+	if !c.Valid {
+		return nil, nil
+	}
+	dests := compositeFields(&c.Val)
+	values := make([]any, len(dests))
+	for i, dest := range dests {
+		values[i] = reflect.ValueOf(dest).Elem().Interface()
+	}
+	return FormatComposite(values...)
+}
+
+// compositeFields returns pointers to exported fields of struct v.
+func compositeFields(v any) []any {
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return []any{v}
+	}
+	var fields []any
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Type().Field(i).IsExported() {
+			fields = append(fields, rv.Field(i).Addr().Interface())
+		}
+	}
+	return fields
+}
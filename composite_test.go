@@ -0,0 +1,75 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestParseComposite(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []any
+	}{
+		{`(1,john,t)`, []any{"1", "john", "t"}},
+		{`(1,,"")`, []any{"1", nil, ""}},
+		{`("john ""jj"" doe","a\\b",x)`, []any{`john "jj" doe`, `a\b`, "x"}},
+		{`("(1,2)",3)`, []any{"(1,2)", "3"}},
+	}
+	for _, tt := range tests {
+		got, err := dbq.ParseComposite(tt.in)
+		maybePanic(err)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseComposite(%s) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := dbq.ParseComposite(`1,2`); err == nil {
+		t.Error("err should be present for missing parentheses")
+	}
+	if _, err := dbq.ParseComposite(`("1,2)`); err == nil {
+		t.Error("err should be present for unterminated quote")
+	}
+}
+
+type address struct {
+	Street string
+	Number int
+	Zip    dbq.Null[string]
+}
+
+func TestCompositeScan(t *testing.T) {
+	var c dbq.Composite[address]
+	maybePanic(c.Scan([]byte(`("Main ""Old"" St",12,)`)))
+	want := address{Street: `Main "Old" St`, Number: 12}
+	if !c.Valid || c.Val != want {
+		t.Errorf("bad composite %+v", c)
+	}
+
+	v, err := c.Value()
+	maybePanic(err)
+	if v != `("Main ""Old"" St","12",)` {
+		t.Errorf("bad composite literal %v", v)
+	}
+
+	maybePanic(c.Scan(nil))
+	if c.Valid {
+		t.Error("composite should be invalid after NULL")
+	}
+
+	if err = c.Scan(`(1,2)`); err == nil {
+		t.Error("err should be present for field count mismatch")
+	}
+}
+
+func TestFormatCompositeValuerError(t *testing.T) {
+	if _, err := dbq.FormatComposite("a", dbq.Default[int]()); !errors.Is(err, dbq.ErrDefaultValue) {
+		t.Errorf("valuer error should be returned, got %v", err)
+	}
+}
@@ -16,12 +16,27 @@ import (
 
 var errNilPtr = errors.New("destination pointer is nil") // embedded in descriptive error
 
+// Convert copies to dest the value in src, converting it if possible. It
+// applies the same rules as database/sql Scan: src is value returned by
+// driver (int64, float64, bool, []byte, string, time.Time or nil) and dest
+// is pointer to basic type, pointer to pointer, sql.Scanner or type
+// registered with RegisterConverter. An error is returned if the copy would
+// result in loss of information. Custom Scanner implementations can use it
+// to convert their inner values.
+func Convert(dest, src any) error {
+	return convertAssign(dest, src)
+}
+
 // convertAssign copies to dest the value in src, converting it if possible.
 // An error is returned if the copy would result in loss of information.
 // dest should be a pointer type.
 //
 //nolint:exhaustive,gocognit,gocyclo,cyclop,maintidx
 func convertAssign(dest, src any) error {
+	if ok, err := scanConverted(dest, src); ok {
+		return err
+	}
+
 	// Common cases, without reflect.
 	switch s := src.(type) {
 	case string:
@@ -44,6 +59,11 @@ func convertAssign(dest, src any) error {
 			}
 			*d = append((*d)[:0], s...)
 			return nil
+		case *time.Time:
+			if d == nil {
+				return errNilPtr
+			}
+			return parseTime(d, s)
 		}
 	case []byte:
 		switch d := dest.(type) {
@@ -71,6 +91,11 @@ func convertAssign(dest, src any) error {
 			}
 			*d = s
 			return nil
+		case *time.Time:
+			if d == nil {
+				return errNilPtr
+			}
+			return parseTime(d, string(s))
 		}
 	case time.Time:
 		switch d := dest.(type) {
@@ -255,6 +280,26 @@ func convertAssign(dest, src any) error {
 	return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", src, dest)
 }
 
+// timeFormats are text formats of time values returned by drivers, e.g.
+// MySQL DATETIME without parseTime.
+var timeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseTime parses text time value, values without zone are in UTC.
+func parseTime(d *time.Time, s string) error {
+	for _, layout := range timeFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			*d = t
+			return nil
+		}
+	}
+	return fmt.Errorf("converting driver.Value type string (%q) to a time.Time: unsupported format", s)
+}
+
 func strconvErr(err error) error {
 	var ne *strconv.NumError
 	if errors.As(err, &ne) {
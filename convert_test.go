@@ -0,0 +1,106 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type myInt int32
+
+func TestConvert(t *testing.T) {
+	when := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		src     any
+		dest    any
+		want    any
+		wantErr bool
+	}{
+		// int64
+		{src: int64(7), dest: new(int64), want: int64(7)},
+		{src: int64(7), dest: new(int8), want: int8(7)},
+		{src: int64(300), dest: new(int8), wantErr: true},
+		{src: int64(7), dest: new(uint), want: uint(7)},
+		{src: int64(-7), dest: new(uint), wantErr: true},
+		{src: int64(7), dest: new(float64), want: float64(7)},
+		{src: int64(7), dest: new(string), want: "7"},
+		{src: int64(7), dest: new([]byte), want: []byte("7")},
+		{src: int64(1), dest: new(bool), want: true},
+		{src: int64(7), dest: new(myInt), want: myInt(7)},
+		{src: int64(7), dest: new(*int), want: intPtr(7)},
+		{src: int64(7), dest: new(any), want: int64(7)},
+		{src: int64(7), dest: new(dbq.Null[int]), want: dbq.FromValue(7)},
+		// float64
+		{src: 1.5, dest: new(float32), want: float32(1.5)},
+		{src: 1.5, dest: new(string), want: "1.5"},
+		{src: 1.5, dest: new(int), wantErr: true},
+		// bool
+		{src: true, dest: new(bool), want: true},
+		{src: true, dest: new(string), want: "true"},
+		{src: false, dest: new(dbq.Null[bool]), want: dbq.FromValue(false)},
+		// []byte
+		{src: []byte("42"), dest: new(int), want: 42},
+		{src: []byte("4.5"), dest: new(float64), want: 4.5},
+		{src: []byte("abc"), dest: new(string), want: "abc"},
+		{src: []byte("abc"), dest: new([]byte), want: []byte("abc")},
+		{src: []byte("t"), dest: new(bool), want: true},
+		{src: []byte("x"), dest: new(int), wantErr: true},
+		{src: []byte("abc"), dest: new(dbq.Null[string]), want: dbq.FromValue("abc")},
+		{src: []byte("42"), dest: new(dbq.Null[int64]), want: dbq.FromValue[int64](42)},
+		{src: []byte("4.5"), dest: new(dbq.Null[float64]), want: dbq.FromValue(4.5)},
+		{src: []byte("1"), dest: new(dbq.Null[bool]), want: dbq.FromValue(true)},
+		{src: []byte("7"), dest: new(dbq.Null[byte]), want: dbq.FromValue[byte](7)},
+		{src: []byte("2022-05-01 10:00:00"), dest: new(time.Time), want: when},
+		{src: []byte("2022-05-01 10:00:00"), dest: new(dbq.Null[time.Time]), want: dbq.FromValue(when)},
+		{src: []byte("yesterday"), dest: new(time.Time), wantErr: true},
+		// string
+		{src: "42", dest: new(int64), want: int64(42)},
+		{src: "abc", dest: new(string), want: "abc"},
+		{src: "abc", dest: new([]byte), want: []byte("abc")},
+		{src: "false", dest: new(bool), want: false},
+		{src: "abc", dest: new(dbq.Null[string]), want: dbq.FromValue("abc")},
+		{src: "2022-05-01T10:00:00Z", dest: new(time.Time), want: when},
+		// time.Time
+		{src: when, dest: new(time.Time), want: when},
+		{src: when, dest: new(string), want: "2022-05-01T10:00:00Z"},
+		{src: when, dest: new(dbq.Null[time.Time]), want: dbq.FromValue(when)},
+		{src: when, dest: new(int), wantErr: true},
+		// nil
+		{src: nil, dest: new(*int), want: (*int)(nil)},
+		{src: nil, dest: new([]byte), want: []byte(nil)},
+		{src: nil, dest: new(any), want: nil},
+		{src: nil, dest: new(dbq.Null[int]), want: dbq.Null[int]{}},
+		{src: nil, dest: new(int), wantErr: true},
+		{src: nil, dest: new(string), wantErr: true},
+	}
+	for i, tt := range tests {
+		err := dbq.Convert(tt.dest, tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("#%d: Convert(%T, %#v) should fail", i, tt.dest, tt.src)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: Convert(%T, %#v) failed: %v", i, tt.dest, tt.src, err)
+			continue
+		}
+		if got := reflect.ValueOf(tt.dest).Elem().Interface(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: Convert(%T, %#v) = %#v, want %#v", i, tt.dest, tt.src, got, tt.want)
+		}
+	}
+
+	if err := dbq.Convert(1, int64(1)); err == nil {
+		t.Error("err should be present for non pointer destination")
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
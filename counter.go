@@ -0,0 +1,74 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+// Increment atomically adds delta to column of rows matching where and
+// returns new value, e.g.
+//
+//	views, err := dbq.Increment(ctx, "posts", "views", 1, "id = ?", id)
+//
+// New value is read with RETURNING clause, or OUTPUT clause on SQL
+// Server, so where should match single row. NotFoundError is returned
+// when no row matches. MySQL has no RETURNING, row is updated and read
+// again in transaction of ctx, which keeps it locked, and ErrTxRequired is
+// returned outside of transaction.
+func Increment[N Number](ctx TxContext, table, column string, delta N, where string, whereArgs ...any) (N, error) {
+	if where != "" {
+		where = " WHERE " + where
+	}
+	set := "UPDATE " + table + " SET " + column + " = " + column + " + ?"
+	args := append([]any{delta}, whereArgs...)
+	switch d, _ := DialectFromCtx(ctx); d {
+	case MySQL:
+		return updateAndSelect[N](ctx, set+where, args, "SELECT "+column+" FROM "+table+where, whereArgs)
+	case SQLServer:
+		return ExecReturning[N](ctx, set+" OUTPUT INSERTED."+column+where, nil, args...)
+	}
+	return ExecReturning[N](ctx, set+where+" RETURNING "+column, nil, args...)
+}
+
+// UpsertCounter adds delta to counter column of row with key, row is
+// inserted with counter set to delta when missing. New value is returned,
+// e.g.
+//
+//	n, err := dbq.UpsertCounter(ctx, "page_views", "page", page, "n", 1)
+//
+// Key column must have unique constraint, statement uses
+// INSERT ... ON CONFLICT supported by PostgreSQL, SQLite and DuckDB. On
+// MySQL INSERT ... ON DUPLICATE KEY UPDATE is used and row is read again
+// in transaction of ctx like in Increment. On SQL Server MERGE with
+// HOLDLOCK is used, so concurrent upserts of missing row don't conflict.
+func UpsertCounter[N Number](ctx TxContext, table, keyColumn string, key any, column string, delta N) (N, error) {
+	switch d, _ := DialectFromCtx(ctx); d {
+	case MySQL:
+		upsert := "INSERT INTO " + table + " (" + keyColumn + ", " + column + ") VALUES (?, ?)" +
+			" ON DUPLICATE KEY UPDATE " + column + " = " + column + " + VALUES(" + column + ")"
+		return updateAndSelect[N](ctx, upsert, []any{key, delta},
+			"SELECT "+column+" FROM "+table+" WHERE "+keyColumn+" = ?", []any{key})
+	case SQLServer:
+		merge := "MERGE " + table + " WITH (HOLDLOCK) AS t USING (SELECT ? AS k, ? AS d) AS s" +
+			" ON t." + keyColumn + " = s.k" +
+			" WHEN MATCHED THEN UPDATE SET " + column + " = t." + column + " + s.d" +
+			" WHEN NOT MATCHED THEN INSERT (" + keyColumn + ", " + column + ") VALUES (s.k, s.d)" +
+			" OUTPUT INSERTED." + column + ";"
+		return ExecReturning[N](ctx, merge, nil, key, delta)
+	}
+	query := "INSERT INTO " + table + " (" + keyColumn + ", " + column + ") VALUES (?, ?)" +
+		" ON CONFLICT (" + keyColumn + ") DO UPDATE SET " + column + " = " + table + "." + column +
+		" + excluded." + column + " RETURNING " + column
+	return ExecReturning[N](ctx, query, nil, key, delta)
+}
+
+// updateAndSelect runs write statement and reads new value with query in
+// transaction of ctx, for dialects without RETURNING.
+func updateAndSelect[N Number](ctx TxContext, write string, writeArgs []any, query string, args []any) (N, error) {
+	if _, ok := ctx.(*Tx); !ok {
+		return 0, ErrTxRequired
+	}
+	if _, err := ctx.Exec(write, writeArgs...); err != nil {
+		return 0, err
+	}
+	return QueryRow[N](ctx, query, nil, args...)
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestCounters(t *testing.T) {
+	const (
+		increment = "UPDATE posts SET views = views + ? WHERE id = ? RETURNING views"
+		upsert    = "INSERT INTO page_views (page, n) VALUES (?, ?) ON CONFLICT (page) DO UPDATE SET n = page_views.n + excluded.n RETURNING n"
+	)
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:   increment,
+			Args:    []dbq.RecordedValue{{V: int64(1)}, {V: int64(7)}},
+			Columns: []string{"views"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(42)}}},
+		},
+		{
+			Query:   increment,
+			Args:    []dbq.RecordedValue{{V: int64(1)}, {V: int64(8)}},
+			Columns: []string{"views"},
+		},
+		{
+			Query:   upsert,
+			Args:    []dbq.RecordedValue{{V: "/home"}, {V: 2.5}},
+			Columns: []string{"n"},
+			Rows:    [][]dbq.RecordedValue{{{V: 3.5}}},
+		},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		views, err := dbq.Increment(tx, "posts", "views", int64(1), "id = ?", 7)
+		if err != nil || views != 42 {
+			t.Errorf("bad increment %d %v", views, err)
+		}
+		var nf *dbq.NotFoundError
+		if _, err = dbq.Increment(tx, "posts", "views", int64(1), "id = ?", 8); !errors.As(err, &nf) {
+			t.Errorf("expected NotFoundError, got %v", err)
+		}
+		n, err := dbq.UpsertCounter(tx, "page_views", "page", "/home", "n", 2.5)
+		if err != nil || n != 3.5 {
+			t.Errorf("bad upsert counter %v %v", n, err)
+		}
+		return nil
+	})
+}
+
+func TestCountersMySQL(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:        "UPDATE posts SET views = views + ? WHERE id = ?",
+			Args:         []dbq.RecordedValue{{V: int64(1)}, {V: int64(7)}},
+			RowsAffected: 1,
+		},
+		{
+			Query:   "SELECT views FROM posts WHERE id = ?",
+			Args:    []dbq.RecordedValue{{V: int64(7)}},
+			Columns: []string{"views"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(42)}}},
+		},
+		{
+			Query:        "INSERT INTO page_views (page, n) VALUES (?, ?) ON DUPLICATE KEY UPDATE n = n + VALUES(n)",
+			Args:         []dbq.RecordedValue{{V: "/home"}, {V: int64(1)}},
+			RowsAffected: 2,
+		},
+		{
+			Query:   "SELECT n FROM page_views WHERE page = ?",
+			Args:    []dbq.RecordedValue{{V: "/home"}},
+			Columns: []string{"n"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(3)}}},
+		},
+	}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	provider := dbq.NewTxProvider(db, dbq.WithDialect(dbq.MySQL))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		views, err := dbq.Increment(tx, "posts", "views", int64(1), "id = ?", 7)
+		if err != nil || views != 42 {
+			t.Errorf("bad increment %d %v", views, err)
+		}
+		n, err := dbq.UpsertCounter(tx, "page_views", "page", "/home", "n", int64(1))
+		if err != nil || n != 3 {
+			t.Errorf("bad upsert counter %v %v", n, err)
+		}
+		return nil
+	}))
+
+	ctx := dbq.NewDB(context.Background(), db, dbq.WithDialect(dbq.MySQL))
+	if _, err := dbq.Increment(ctx, "posts", "views", int64(1), "id = ?", 7); !errors.Is(err, dbq.ErrTxRequired) {
+		t.Errorf("expected ErrTxRequired outside of transaction, got %v", err)
+	}
+}
+
+func TestCountersSQLServer(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:   "UPDATE posts SET views = views + @p1 OUTPUT INSERTED.views WHERE id = @p2",
+			Args:    []dbq.RecordedValue{{V: int64(1)}, {V: int64(7)}},
+			Columns: []string{"views"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(42)}}},
+		},
+		{
+			Query: "MERGE page_views WITH (HOLDLOCK) AS t USING (SELECT @p1 AS k, @p2 AS d) AS s ON t.page = s.k" +
+				" WHEN MATCHED THEN UPDATE SET n = t.n + s.d" +
+				" WHEN NOT MATCHED THEN INSERT (page, n) VALUES (s.k, s.d) OUTPUT INSERTED.n;",
+			Args:    []dbq.RecordedValue{{V: "/home"}, {V: int64(1)}},
+			Columns: []string{"n"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(3)}}},
+		},
+	}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	provider := dbq.NewTxProvider(db, dbq.WithDialect(dbq.SQLServer))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		views, err := dbq.Increment(tx, "posts", "views", int64(1), "id = ?", 7)
+		if err != nil || views != 42 {
+			t.Errorf("bad increment %d %v", views, err)
+		}
+		n, err := dbq.UpsertCounter(tx, "page_views", "page", "/home", "n", int64(1))
+		if err != nil || n != 3 {
+			t.Errorf("bad upsert counter %v %v", n, err)
+		}
+		return nil
+	}))
+}
@@ -1,3 +1,46 @@
 package dbq
 
+import "context"
+
 type CtxDataSourceKey struct{} // Datasource context key, useful only error handling
+
+// WithDataSource returns context carrying data source name reported in
+// errors, e.g. NotFoundError. TxContext can be labeled with
+// tx.WithValue(CtxDataSourceKey{}, name).
+func WithDataSource(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, CtxDataSourceKey{}, name)
+}
+
+// DataSourceFromCtx returns data source name stored in context.
+func DataSourceFromCtx(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(CtxDataSourceKey{}).(string)
+	return name, ok
+}
+
+type CtxTenantKey struct{} // Tenant context key, used by per-tenant accounting
+
+// WithTenant returns context carrying tenant id used by Quota. TxContext
+// can be labeled with tx.WithValue(CtxTenantKey{}, tenant).
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, CtxTenantKey{}, tenant)
+}
+
+// TenantFromCtx returns tenant id stored in context.
+func TenantFromCtx(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(CtxTenantKey{}).(string)
+	return tenant, ok
+}
+
+type CtxRoleKey struct{} // Role context key, used by result masking
+
+// WithRole returns context carrying role of caller used by Masker. TxContext
+// can be labeled with tx.WithValue(CtxRoleKey{}, role).
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, CtxRoleKey{}, role)
+}
+
+// RoleFromCtx returns role stored in context.
+func RoleFromCtx(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(CtxRoleKey{}).(string)
+	return role, ok
+}
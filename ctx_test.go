@@ -0,0 +1,50 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestDataSource(t *testing.T) {
+	if _, ok := dbq.DataSourceFromCtx(context.Background()); ok {
+		t.Error("data source should be absent")
+	}
+	name, ok := dbq.DataSourceFromCtx(dbq.WithDataSource(context.Background(), "users"))
+	if !ok || name != "users" {
+		t.Errorf("bad data source %q", name)
+	}
+}
+
+func TestQueryRowNotFound(t *testing.T) {
+	const query = "SELECT id FROM users WHERE id = ?"
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{Query: query, Args: []dbq.RecordedValue{{V: int64(9)}}, Columns: []string{"id"}},
+		{Query: query, Args: []dbq.RecordedValue{{V: int64(9)}}, Columns: []string{"id"}},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		var nf *dbq.NotFoundError
+		_, err := dbq.QueryRow[int64](tx, query, nil, 9)
+		if !errors.As(err, &nf) || nf.DataSource != "" {
+			t.Errorf("expected NotFoundError without data source, got %v", err)
+		}
+
+		_, err = dbq.QueryRow[int64](tx.WithValue(dbq.CtxDataSourceKey{}, "users"), query, nil, 9)
+		if !errors.As(err, &nf) || nf.DataSource != "users" {
+			t.Errorf("expected NotFoundError for users, got %v", err)
+		}
+		if nf.Query != query || len(nf.Args) != 1 || nf.Args[0] != 9 {
+			t.Errorf("expected query and args in NotFoundError, got %+v", nf)
+		}
+		if want := `no data found in users for query "` + query + `"`; nf.Error() != want {
+			t.Errorf("bad message %q", nf.Error())
+		}
+		return nil
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"time"
+)
+
+// Cursor streams rows of query one by one, so large result sets can be
+// processed with constant memory. Cursor must be closed, e.g.
+//
+//	cur, err := dbq.QueryIter(ctx, "SELECT id, name FROM users", userBinder)
+//	if err != nil {
+//		return err
+//	}
+//	defer cur.Close()
+//	for cur.Next() {
+//		u := cur.Value()
+//		...
+//	}
+//	return cur.Err()
+type Cursor[T any] struct {
+	ctx    context.Context
+	rows   Rows
+	binder func(*T) []any
+	query  string
+	start  time.Time
+	value  T
+	err    error
+}
+
+// QueryIter runs query and returns cursor over its rows. Rows are scanned
+// like in Query, with RowScanner when *T implements it, with binder
+// otherwise, single column is scanned into T when binder is nil.
+func QueryIter[T any](ctx TxContext, query string, binder func(*T) []any, args ...any) (*Cursor[T], error) {
+	start := time.Now()
+	rows, err := ctx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return NewCursor(ctx, rows, binder, query, start), nil
+}
+
+// NewCursor creates cursor over rows, it is used by adapters of other
+// drivers, query and start are reported in errors.
+func NewCursor[T any](ctx context.Context, rows Rows, binder func(*T) []any, query string, start time.Time) *Cursor[T] {
+	return &Cursor[T]{
+		ctx:    ctx,
+		rows:   maskRows(ctx, query, rows),
+		binder: binder,
+		query:  query,
+		start:  start,
+	}
+}
+
+// Next scans next row, it returns false when there are no more rows or
+// scan failed, see Err. Rows are closed when they are exhausted.
+func (c *Cursor[T]) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	if !c.rows.Next() {
+		if err := closeRows(c.rows, nil); err != nil {
+			c.err = wrapError(OpQuery, c.query, c.start, err)
+		}
+		return false
+	}
+	var value T
+	if err := scanRow(c.rows, &value, c.binder); err != nil {
+		c.err = wrapError(OpQuery, c.query, c.start, closeRows(c.rows, err))
+		return false
+	}
+	localize(c.ctx, c.query, &value)
+	c.value = value
+	return true
+}
+
+// Value returns row scanned by the last Next.
+func (c *Cursor[T]) Value() T {
+	return c.value
+}
+
+// Err returns error which stopped iteration.
+func (c *Cursor[T]) Err() error {
+	return c.err
+}
+
+// Close closes rows, it is safe to call it more than once.
+func (c *Cursor[T]) Close() error {
+	return c.rows.Close()
+}
+
+// QueryChan streams rows of query into channel with buffer buf, producer
+// blocks when consumer falls behind and stops when ctx is done. Error
+// channel receives at most one error and is closed with rows channel, e.g.
+//
+//	rows, errs := dbq.QueryChan(ctx, 100, "SELECT id, name FROM users", userBinder)
+//	for u := range rows {
+//		...
+//	}
+//	if err := <-errs; err != nil {
+//		return err
+//	}
+//
+// Within transaction, channel must be drained or ctx canceled before other
+// statements of transaction run.
+func QueryChan[T any](ctx TxContext, buf int, query string, binder func(*T) []any, args ...any) (<-chan T, <-chan error) {
+	out := make(chan T, buf)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(out)
+
+		cur, err := QueryIter(ctx, query, binder, args...)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer cur.Close()
+		for cur.Next() {
+			if err = ctx.Err(); err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case out <- cur.Value():
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+		if err = cur.Err(); err != nil {
+			errs <- err
+		}
+	}()
+	return out, errs
+}
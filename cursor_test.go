@@ -0,0 +1,98 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestQueryIter(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = rec.Entries[:1]
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		cur, err := dbq.QueryIter(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		var ids []int64
+		for cur.Next() {
+			ids = append(ids, cur.Value().ID)
+		}
+		if err = cur.Err(); err != nil {
+			return err
+		}
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Errorf("bad ids %v", ids)
+		}
+		if cur.Next() {
+			t.Error("exhausted cursor should not advance")
+		}
+		return nil
+	})
+}
+
+func TestQueryIterScanError(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = rec.Entries[:1]
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		cur, err := dbq.QueryIter(tx, "SELECT id, name, created FROM users WHERE id > ?", func(u *user) []any {
+			return []any{&u.ID}
+		}, 0)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		if cur.Next() {
+			t.Error("scan should fail")
+		}
+		if _, ok := cur.Err().(*dbq.Error); !ok {
+			t.Errorf("expected wrapped error, got %v", cur.Err())
+		}
+		return nil
+	})
+}
+
+func TestQueryChan(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = rec.Entries[:1]
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		rows, errs := dbq.QueryChan(tx, 1, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+		var ids []int64
+		for u := range rows {
+			ids = append(ids, u.ID)
+		}
+		if err := <-errs; err != nil {
+			return err
+		}
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Errorf("bad ids %v", ids)
+		}
+		return nil
+	})
+}
+
+func TestQueryChanCancel(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = rec.Entries[:1]
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, errs := dbq.QueryChan(dbq.NewDB(ctx, db), 0, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+	<-rows
+	cancel()
+	for range rows {
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DB implements TxContext outside of transaction, each statement runs on
+// its own pooled connection, e.g.
+//
+//	users, err := dbq.Query(dbq.NewDB(ctx, db), "SELECT id, name FROM users", userBinder)
+type DB struct {
+	context.Context //nolint:containedctx
+	DB              *sql.DB
+
+	interceptors []Interceptor
+	stmts        *stmtLRU
+}
+
+// NewDB creates DB running statements of ctx on db. Options configure it
+// like TxProvider, e.g. with WithDialect or Interceptors. DB created with
+// StatementCache should be closed.
+func NewDB(ctx context.Context, db *sql.DB, opts ...ProviderOption) *DB {
+	provider := NewTxProvider(db, opts...)
+	ctx, interceptors := provider.configure(ctx, db)
+	d := &DB{
+		Context:      ctx,
+		DB:           db,
+		interceptors: interceptors,
+	}
+	if provider.stmtCache != nil {
+		d.stmts = newStmtLRU(provider.stmtCache, db)
+	}
+	return d
+}
+
+// Close closes cached statements, db itself stays open.
+func (d *DB) Close() error {
+	if d.stmts == nil {
+		return nil
+	}
+	return d.stmts.close()
+}
+
+// access returns statement cache of DB or db itself.
+func (d *DB) access() Access {
+	if d.stmts != nil {
+		return d.stmts
+	}
+	return d.DB
+}
+
+// WithValue returns DB with ctx carrying value.
+func (d *DB) WithValue(key, value any) TxContext {
+	return &DB{
+		Context:      context.WithValue(d.Context, key, value),
+		DB:           d.DB,
+		interceptors: d.interceptors,
+		stmts:        d.stmts,
+	}
+}
+
+// Prepare query.
+func (d *DB) Prepare(query string) (*sql.Stmt, error) {
+	out, err := chain(d.access(), d.interceptors)(d.Context, newStatement(OpPrepare, query, nil))
+	return out.Stmt, err
+}
+
+// Exec executes query with args.
+func (d *DB) Exec(query string, args ...any) (sql.Result, error) {
+	out, err := chain(d.access(), d.interceptors)(d.Context, newStatement(OpExec, query, args))
+	return out.Result, err
+}
+
+// Query loads data from db.
+func (d *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	out, err := chain(d.access(), d.interceptors)(d.Context, newStatement(OpQuery, query, args))
+	return out.Rows, err
+}
+
+// QueryRow loads single row from db.
+func (d *DB) QueryRow(query string, args ...any) *sql.Row {
+	return queryRow(chain(d.access(), d.interceptors), d.Context, query, args)
+}
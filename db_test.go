@@ -0,0 +1,37 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestDB(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries[0].Query = "SELECT id, name, created FROM users WHERE id > $1"
+	rec.Entries[1].Query = "INSERT INTO users (name) VALUES ($1)"
+	sqlDB := sql.OpenDB(dbq.NewReplayer(rec))
+	defer sqlDB.Close()
+
+	var ctx dbq.TxContext = dbq.NewDB(context.Background(), sqlDB, dbq.WithDialect(dbq.Postgres))
+	ctx = ctx.WithValue(dbq.CtxDataSourceKey{}, "users")
+	if name, _ := dbq.DataSourceFromCtx(ctx); name != "users" {
+		t.Errorf("bad data source %q", name)
+	}
+	users, err := dbq.Query(ctx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+	if err != nil || len(users) != 2 {
+		t.Fatalf("bad users %v %v", users, err)
+	}
+	if _, err = ctx.Exec("INSERT INTO users (name) VALUES (?)", "jane"); err != nil {
+		t.Fatal(err)
+	}
+	if dbq.FromCtxOr(ctx, nil) == nil {
+		t.Error("context should carry access")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbqotel implements dbq.Tracer with OpenTelemetry tracer, e.g.
+//
+//	provider := dbq.NewTxProvider(db, dbq.WithTracer(dbqotel.New(otel.Tracer("app"))))
+//
+// Spans are started with client span kind. Failed statements and rolled
+// back transactions record error event and set error status of span.
+//
+// It is separate module, dbq doesn't depend on OpenTelemetry.
+package dbqotel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/enverbisevac/dbq"
+)
+
+// Tracer implements dbq.Tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ dbq.Tracer = (*Tracer)(nil)
+
+// New creates dbq.Tracer starting spans with tracer.
+func New(tracer trace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+// Start implements dbq.Tracer.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, dbq.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, Span{span: span}
+}
+
+// Span implements dbq.Span with OpenTelemetry span.
+type Span struct {
+	span trace.Span
+}
+
+// SetAttribute implements dbq.Span.
+func (s Span) SetAttribute(key string, value any) {
+	s.span.SetAttributes(attributeOf(key, value))
+}
+
+// RecordError implements dbq.Span, err is recorded as event and span gets
+// error status.
+func (s Span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End implements dbq.Span.
+func (s Span) End() {
+	s.span.End()
+}
+
+// attributeOf converts attribute value, unknown types are formatted with
+// fmt.Sprint.
+func attributeOf(key string, value any) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case []string:
+		return attribute.StringSlice(key, v)
+	case fmt.Stringer:
+		return attribute.Stringer(key, v)
+	}
+	return attribute.String(key, fmt.Sprint(value))
+}
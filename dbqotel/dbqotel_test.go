@@ -0,0 +1,60 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbqotel_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/enverbisevac/dbq"
+	"github.com/enverbisevac/dbq/dbqotel"
+)
+
+func TestTracer(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{Entries: []dbq.RecordedEntry{
+		{Query: "UPDATE users SET name = $1", Args: []dbq.RecordedValue{{V: "jane"}}, RowsAffected: 1},
+		{Query: "DELETE FROM users", Err: "locked"},
+	}}))
+	defer db.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	provider := dbq.NewTxProvider(db, dbq.WithDialect(dbq.Postgres), dbq.WithTracer(dbqotel.New(tp.Tracer("test"))))
+	err := provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		if _, err := tx.Exec("UPDATE users SET name = ?", "jane"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM users")
+		return err
+	})
+	if err == nil {
+		t.Fatal("delete should fail")
+	}
+
+	status := make(map[string]codes.Code)
+	attrs := make(map[string][]attribute.KeyValue)
+	for _, span := range recorder.Ended() {
+		status[span.Name()] = span.Status().Code
+		attrs[span.Name()] = span.Attributes()
+	}
+	if status["update users"] != codes.Unset || status["delete users"] != codes.Error || status["dbq.tx"] != codes.Error {
+		t.Errorf("bad span statuses %v", status)
+	}
+	found := false
+	for _, kv := range attrs["update users"] {
+		if kv.Key == "db.rows_affected" && kv.Value.AsInt64() == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("missing db.rows_affected in %v", attrs["update users"])
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/enverbisevac/dbq/dbqotel
+
+go 1.18
+
+require (
+	github.com/enverbisevac/dbq v0.0.0
+	go.opentelemetry.io/otel v1.11.2
+	go.opentelemetry.io/otel/sdk v1.11.2
+	go.opentelemetry.io/otel/trace v1.11.2
+)
+
+replace github.com/enverbisevac/dbq => ../
@@ -0,0 +1,368 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbqprom implements dbq.MetricsCollector exposing metrics in
+// Prometheus text exposition format, without depending on Prometheus
+// client library, e.g.
+//
+//	collector := dbqprom.New("app")
+//	provider := dbq.NewTxProvider(db, dbq.WithMetrics(collector))
+//	http.Handle("/metrics", collector)
+//
+// Exposed metrics, prefixed with namespace:
+//
+//	dbq_queries_total{op, outcome}             counter
+//	dbq_query_duration_seconds{op}             histogram
+//	dbq_transactions_total{outcome}            counter
+//	dbq_transaction_duration_seconds{outcome}  histogram
+//	dbq_open_transactions                      gauge
+//	dbq_acquire_queue_depth                    gauge
+//	dbq_acquire_wait_seconds{outcome}          histogram
+//	dbq_shed_total{outcome}                    counter
+//
+// Shed statements are counted when collector is set as Metrics of
+// dbq.ShedConfig. Collector is registered with Prometheus client library
+// registry with promclient.New of separate module
+// github.com/enverbisevac/dbq/dbqprom/promclient.
+package dbqprom
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+// DefaultBuckets are upper bounds of duration histograms in seconds.
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector collects statistics of statements and transactions.
+type Collector struct {
+	namespace string
+	buckets   []float64
+
+	mu         sync.Mutex
+	queries    map[[2]string]uint64
+	queryTimes map[string]*histogram
+	txs        map[string]uint64
+	txTimes    map[string]*histogram
+	open       int64
+	waiting    int64
+	waitTimes  map[string]*histogram
+	shed       map[string]uint64
+}
+
+var (
+	_ dbq.MetricsCollector = (*Collector)(nil)
+	_ dbq.AcquireCollector = (*Collector)(nil)
+	_ dbq.ShedCollector    = (*Collector)(nil)
+)
+
+// New creates collector, namespace prefixes names of metrics and may be
+// empty. Durations are observed in DefaultBuckets.
+func New(namespace string) *Collector {
+	return NewWithBuckets(namespace, DefaultBuckets)
+}
+
+// NewWithBuckets creates collector observing durations in buckets given as
+// sorted upper bounds in seconds.
+func NewWithBuckets(namespace string, buckets []float64) *Collector {
+	return &Collector{
+		namespace:  namespace,
+		buckets:    buckets,
+		queries:    make(map[[2]string]uint64),
+		queryTimes: make(map[string]*histogram),
+		txs:        make(map[string]uint64),
+		txTimes:    make(map[string]*histogram),
+		waitTimes:  make(map[string]*histogram),
+		shed:       make(map[string]uint64),
+	}
+}
+
+// QueryDone implements dbq.MetricsCollector.
+func (c *Collector) QueryDone(op dbq.Op, outcome string, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries[[2]string{op.String(), outcome}]++
+	c.observe(c.queryTimes, op.String(), d)
+}
+
+// TxStarted implements dbq.MetricsCollector.
+func (c *Collector) TxStarted() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.open++
+}
+
+// TxDone implements dbq.MetricsCollector.
+func (c *Collector) TxDone(committed bool, d time.Duration) {
+	outcome := "rolled_back"
+	if committed {
+		outcome = "committed"
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.open--
+	c.txs[outcome]++
+	c.observe(c.txTimes, outcome, d)
+}
+
+// QueueDepth implements dbq.AcquireCollector.
+func (c *Collector) QueueDepth(n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waiting = n
+}
+
+// AcquireDone implements dbq.AcquireCollector.
+func (c *Collector) AcquireDone(wait time.Duration, err error) {
+	outcome := "acquired"
+	switch {
+	case errors.Is(err, dbq.ErrAcquireTimeout):
+		outcome = "timeout"
+	case err != nil:
+		outcome = "error"
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observe(c.waitTimes, outcome, wait)
+}
+
+// Shed implements dbq.ShedCollector.
+func (c *Collector) Shed(rejected bool) {
+	outcome := "delayed"
+	if rejected {
+		outcome = "rejected"
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shed[outcome]++
+}
+
+func (c *Collector) observe(hs map[string]*histogram, label string, d time.Duration) {
+	h, ok := hs[label]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(c.buckets))}
+		hs[label] = h
+	}
+	s := d.Seconds()
+	for i, le := range c.buckets {
+		if s <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += s
+}
+
+// ServeHTTP writes metrics in Prometheus text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = c.WriteTo(w)
+}
+
+// Type is type of metric family.
+type Type string
+
+// Metric types.
+const (
+	Counter   Type = "counter"
+	Gauge     Type = "gauge"
+	Histogram Type = "histogram"
+)
+
+// Family is snapshot of metrics of one name, it is used by adapters of
+// metric libraries, e.g. promclient implementing prometheus.Collector.
+type Family struct {
+	Name string
+	Help string
+	Type Type
+	// Labels are names of labels of samples.
+	Labels  []string
+	Samples []Sample
+}
+
+// Sample is single metric of family.
+type Sample struct {
+	// LabelValues are in order of Family.Labels.
+	LabelValues []string
+	// Value of counter or gauge.
+	Value float64
+	// Count, Sum and Buckets are set for histogram, Buckets are cumulative
+	// counts by upper bound in seconds.
+	Count   uint64
+	Sum     float64
+	Buckets map[float64]uint64
+}
+
+// Families returns snapshot of collected metrics. Families are always
+// returned, also without samples, so they can be described up front.
+func (c *Collector) Families() []Family {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	queries := Family{
+		Name:   c.name("queries_total"),
+		Help:   "Number of executed statements.",
+		Type:   Counter,
+		Labels: []string{"op", "outcome"},
+	}
+	keys := make([][2]string, 0, len(c.queries))
+	for k := range c.queries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		queries.Samples = append(queries.Samples, Sample{
+			LabelValues: []string{k[0], k[1]},
+			Value:       float64(c.queries[k]),
+		})
+	}
+
+	return []Family{
+		queries,
+		c.histograms("query_duration_seconds", "Duration of statements.", "op", c.queryTimes),
+		counters(c.name("transactions_total"), "Number of finished transactions.", c.txs),
+		c.histograms("transaction_duration_seconds", "Duration of transactions.", "outcome", c.txTimes),
+		{
+			Name:    c.name("open_transactions"),
+			Help:    "Number of open transactions.",
+			Type:    Gauge,
+			Samples: []Sample{{Value: float64(c.open)}},
+		},
+		{
+			Name:    c.name("acquire_queue_depth"),
+			Help:    "Number of callers waiting for transaction.",
+			Type:    Gauge,
+			Samples: []Sample{{Value: float64(c.waiting)}},
+		},
+		c.histograms("acquire_wait_seconds", "Wait for transactions.", "outcome", c.waitTimes),
+		counters(c.name("shed_total"), "Number of statements and transactions shed under load.", c.shed),
+	}
+}
+
+// counters returns family of counters by outcome.
+func counters(name, help string, m map[string]uint64) Family {
+	f := Family{Name: name, Help: help, Type: Counter, Labels: []string{"outcome"}}
+	for _, outcome := range sortedKeys(m) {
+		f.Samples = append(f.Samples, Sample{LabelValues: []string{outcome}, Value: float64(m[outcome])})
+	}
+	return f
+}
+
+func (c *Collector) histograms(metric, help, label string, hs map[string]*histogram) Family {
+	f := Family{Name: c.name(metric), Help: help, Type: Histogram, Labels: []string{label}}
+	for _, l := range sortedKeys(hs) {
+		h := hs[l]
+		buckets := make(map[float64]uint64, len(c.buckets))
+		for i, le := range c.buckets {
+			buckets[le] = h.counts[i]
+		}
+		f.Samples = append(f.Samples, Sample{
+			LabelValues: []string{l},
+			Count:       h.count,
+			Sum:         h.sum,
+			Buckets:     buckets,
+		})
+	}
+	return f
+}
+
+// WriteTo writes metrics in Prometheus text exposition format to w.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, f := range c.Families() {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.Name, helpEscaper.Replace(f.Help), f.Name, f.Type)
+		for _, s := range f.Samples {
+			if f.Type != Histogram {
+				fmt.Fprintf(&b, "%s%s %s\n", f.Name, labels(f.Labels, s.LabelValues), formatFloat(s.Value))
+				continue
+			}
+			// le is appended to copies, labels of family are shared.
+			names := append(f.Labels[:len(f.Labels):len(f.Labels)], "le")
+			values := s.LabelValues[:len(s.LabelValues):len(s.LabelValues)]
+			bounds := make([]float64, 0, len(s.Buckets))
+			for le := range s.Buckets {
+				bounds = append(bounds, le)
+			}
+			sort.Float64s(bounds)
+			for _, le := range bounds {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", f.Name, labels(names, append(values, formatFloat(le))), s.Buckets[le])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.Name, labels(names, append(values, "+Inf")), s.Count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", f.Name, labels(f.Labels, s.LabelValues), formatFloat(s.Sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", f.Name, labels(f.Labels, s.LabelValues), s.Count)
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+var (
+	// labelEscaper escapes label values by exposition format rules, only
+	// backslash, double quote and line feed are escaped.
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	// helpEscaper escapes HELP text, only backslash and line feed are
+	// escaped.
+	helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// labels formats label pairs of sample, e.g. {op="query",outcome="ok"}.
+func labels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func (c *Collector) name(metric string) string {
+	if c.namespace == "" {
+		return "dbq_" + metric
+	}
+	return c.namespace + "_dbq_" + metric
+}
+
+// histogram is cumulative histogram of durations in seconds.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbqprom_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+	"github.com/enverbisevac/dbq/dbqprom"
+)
+
+func TestCollector(t *testing.T) {
+	c := dbqprom.NewWithBuckets("app", []float64{0.01, 0.1})
+	c.TxStarted()
+	c.QueryDone(dbq.OpQuery, dbq.QueryOK, 5*time.Millisecond)
+	c.QueryDone(dbq.OpQuery, dbq.QueryOK, 50*time.Millisecond)
+	c.QueryDone(dbq.OpExec, dbq.QueryFailed, time.Second)
+	c.TxDone(false, 2*time.Second)
+	c.TxStarted()
+	c.QueueDepth(3)
+	c.AcquireDone(time.Second, dbq.ErrAcquireTimeout)
+	c.Shed(true)
+	c.Shed(true)
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE app_dbq_queries_total counter",
+		`app_dbq_queries_total{op="exec",outcome="error"} 1`,
+		`app_dbq_queries_total{op="query",outcome="ok"} 2`,
+		"# TYPE app_dbq_query_duration_seconds histogram",
+		`app_dbq_query_duration_seconds_bucket{op="query",le="0.01"} 1`,
+		`app_dbq_query_duration_seconds_bucket{op="query",le="0.1"} 2`,
+		`app_dbq_query_duration_seconds_bucket{op="exec",le="0.1"} 0`,
+		`app_dbq_query_duration_seconds_bucket{op="exec",le="+Inf"} 1`,
+		`app_dbq_query_duration_seconds_sum{op="exec"} 1`,
+		`app_dbq_transactions_total{outcome="rolled_back"} 1`,
+		`app_dbq_transaction_duration_seconds_count{outcome="rolled_back"} 1`,
+		"app_dbq_open_transactions 1",
+		"app_dbq_acquire_queue_depth 3",
+		`app_dbq_acquire_wait_seconds_count{outcome="timeout"} 1`,
+		"# TYPE app_dbq_shed_total counter",
+		`app_dbq_shed_total{outcome="rejected"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, body)
+		}
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("bad content type %q", ct)
+	}
+}
+
+func TestCollectorLabelEscaping(t *testing.T) {
+	c := dbqprom.NewWithBuckets("", []float64{1})
+	c.QueryDone(dbq.OpQuery, "a\"b\\c\nd\té", time.Millisecond)
+
+	var b strings.Builder
+	_, err := c.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := "dbq_queries_total{op=\"query\",outcome=\"a\\\"b\\\\c\\nd\té\"} 1\n"; !strings.Contains(b.String(), line) {
+		t.Errorf("missing %q in\n%s", line, b.String())
+	}
+}
+
+func TestCollectorFamilies(t *testing.T) {
+	c := dbqprom.NewWithBuckets("", []float64{0.01, 0.1})
+	c.QueryDone(dbq.OpExec, dbq.QueryOK, 50*time.Millisecond)
+
+	families := c.Families()
+	if len(families) != 8 {
+		t.Fatalf("expected 8 families, got %d", len(families))
+	}
+	h := families[1]
+	if h.Name != "dbq_query_duration_seconds" || h.Type != dbqprom.Histogram || len(h.Samples) != 1 {
+		t.Fatalf("bad histogram family %+v", h)
+	}
+	if s := h.Samples[0]; s.Count != 1 || s.Buckets[0.01] != 0 || s.Buckets[0.1] != 1 || s.LabelValues[0] != "exec" {
+		t.Errorf("bad histogram sample %+v", s)
+	}
+	if shed := families[7]; shed.Name != "dbq_shed_total" || len(shed.Samples) != 0 {
+		t.Errorf("family without samples should be returned, got %+v", shed)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/enverbisevac/dbq/dbqprom/promclient
+
+go 1.18
+
+require (
+	github.com/enverbisevac/dbq v0.0.0
+	github.com/prometheus/client_golang v1.14.0
+)
+
+replace github.com/enverbisevac/dbq => ../..
@@ -0,0 +1,60 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package promclient adapts dbqprom.Collector to prometheus.Collector of
+// Prometheus client library, so dbq metrics are served together with other
+// metrics of registry, e.g.
+//
+//	collector := dbqprom.New("app")
+//	provider := dbq.NewTxProvider(db, dbq.WithMetrics(collector))
+//	prometheus.MustRegister(promclient.New(collector))
+//
+// It is separate module, dbq and dbqprom don't depend on client library.
+package promclient
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/enverbisevac/dbq/dbqprom"
+)
+
+// Collector implements prometheus.Collector for dbqprom.Collector.
+type Collector struct {
+	collector *dbqprom.Collector
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// New creates prometheus.Collector collecting metrics of c.
+func New(c *dbqprom.Collector) *Collector {
+	return &Collector{collector: c}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, f := range c.collector.Families() {
+		ch <- desc(f)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, f := range c.collector.Families() {
+		d := desc(f)
+		for _, s := range f.Samples {
+			switch f.Type {
+			case dbqprom.Counter:
+				ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, s.Value, s.LabelValues...)
+			case dbqprom.Gauge:
+				ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, s.Value, s.LabelValues...)
+			case dbqprom.Histogram:
+				ch <- prometheus.MustNewConstHistogram(d, s.Count, s.Sum, s.Buckets, s.LabelValues...)
+			}
+		}
+	}
+}
+
+func desc(f dbqprom.Family) *prometheus.Desc {
+	return prometheus.NewDesc(f.Name, f.Help, f.Labels, nil)
+}
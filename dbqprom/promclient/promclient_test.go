@@ -0,0 +1,47 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package promclient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/enverbisevac/dbq"
+	"github.com/enverbisevac/dbq/dbqprom"
+	"github.com/enverbisevac/dbq/dbqprom/promclient"
+)
+
+func TestCollector(t *testing.T) {
+	c := dbqprom.NewWithBuckets("app", []float64{0.01, 0.1})
+	c.TxStarted()
+	c.QueryDone(dbq.OpQuery, dbq.QueryOK, 5*time.Millisecond)
+	c.QueryDone(dbq.OpExec, dbq.QueryFailed, time.Second)
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(promclient.New(c))
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]int)
+	for _, f := range families {
+		got[f.GetName()] = len(f.GetMetric())
+	}
+	for name, n := range map[string]int{
+		"app_dbq_queries_total":          2,
+		"app_dbq_query_duration_seconds": 2,
+		"app_dbq_open_transactions":      1,
+	} {
+		if got[name] != n {
+			t.Errorf("expected %d metrics of %s, got %d", n, name, got[name])
+		}
+	}
+	if _, ok := got["app_dbq_shed_total"]; ok {
+		t.Error("family without samples should not be gathered")
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbqtest implements fake dbq.TxManager, so services depending on
+// transactions can be unit-tested without database, e.g.
+//
+//	manager := dbqtest.New()
+//	svc := NewService(manager)
+//	err := svc.Register(ctx, "john")
+//	// check manager.Statements(), manager.Commits()
+//
+// Statements of fake transactions succeed and queries return no rows,
+// unless failure is set with Fail. Use dbq.NewReplayer when statements
+// should return recorded results.
+package dbqtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+
+	"github.com/enverbisevac/dbq"
+)
+
+// Statement is statement executed in fake transaction.
+type Statement struct {
+	Query string
+	Args  []any
+}
+
+// TxManager is fake dbq.TxManager recording statements and outcome of
+// transactions. It is safe for concurrent use.
+type TxManager struct {
+	provider *dbq.TxProvider
+
+	mu        sync.Mutex
+	stmts     []Statement
+	failures  map[string]error
+	commits   int
+	rollbacks int
+}
+
+var _ dbq.TxManager = (*TxManager)(nil)
+
+// New creates fake transaction manager, opts configure provider of
+// transactions as with dbq.NewTxProvider, e.g. with interceptors under
+// test.
+func New(opts ...dbq.ProviderOption) *TxManager {
+	m := &TxManager{
+		failures: make(map[string]error),
+	}
+	m.provider = dbq.NewTxProvider(sql.OpenDB(connector{m}), opts...)
+	return m
+}
+
+// Tx runs fn in fake transaction.
+func (m *TxManager) Tx(ctx context.Context, fn func(dbq.TxContext) error) error {
+	return m.provider.Tx(ctx, fn)
+}
+
+// Acquire starts fake transaction.
+func (m *TxManager) Acquire(ctx context.Context) (*dbq.Tx, error) {
+	return m.provider.Acquire(ctx)
+}
+
+// Fail makes statements with query fail with err.
+func (m *TxManager) Fail(query string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[query] = err
+}
+
+// Statements returns statements executed so far, including failed ones.
+func (m *TxManager) Statements() []Statement {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stmts := make([]Statement, len(m.stmts))
+	copy(stmts, m.stmts)
+	return stmts
+}
+
+// Commits returns number of committed transactions.
+func (m *TxManager) Commits() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.commits
+}
+
+// Rollbacks returns number of rolled back transactions.
+func (m *TxManager) Rollbacks() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rollbacks
+}
+
+// record records statement and returns its failure.
+func (m *TxManager) record(query string, args []driver.NamedValue) error {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stmts = append(m.stmts, Statement{Query: query, Args: values})
+	return m.failures[query]
+}
+
+type connector struct {
+	m *TxManager
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return conn(c), nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return c
+}
+
+func (c connector) Open(string) (driver.Conn, error) {
+	return conn(c), nil
+}
+
+type conn struct {
+	m *TxManager
+}
+
+func (c conn) Prepare(query string) (driver.Stmt, error) {
+	return stmt{m: c.m, query: query}, nil
+}
+
+func (c conn) Close() error {
+	return nil
+}
+
+func (c conn) Begin() (driver.Tx, error) {
+	return tx(c), nil
+}
+
+func (c conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.m.record(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.m.record(query, args); err != nil {
+		return nil, err
+	}
+	return rows{}, nil
+}
+
+type stmt struct {
+	m     *TxManager
+	query string
+}
+
+func (s stmt) Close() error {
+	return nil
+}
+
+func (s stmt) NumInput() int {
+	return -1
+}
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return conn{s.m}.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return conn{s.m}.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return values
+}
+
+type tx struct {
+	m *TxManager
+}
+
+func (t tx) Commit() error {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	t.m.commits++
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	t.m.rollbacks++
+	return nil
+}
+
+type rows struct{}
+
+func (rows) Columns() []string {
+	return nil
+}
+
+func (rows) Close() error {
+	return nil
+}
+
+func (rows) Next([]driver.Value) error {
+	return io.EOF
+}
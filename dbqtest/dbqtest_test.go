@@ -0,0 +1,70 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbqtest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+	"github.com/enverbisevac/dbq/dbqtest"
+)
+
+// register is service under test depending only on dbq.TxManager.
+func register(ctx context.Context, m dbq.TxManager, name string) error {
+	return m.Tx(ctx, func(tx dbq.TxContext) error {
+		if _, err := tx.Exec("INSERT INTO users (name) VALUES (?)", name); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO audit (event) VALUES (?)", "register")
+		return err
+	})
+}
+
+func TestTxManager(t *testing.T) {
+	m := dbqtest.New()
+	if err := register(context.Background(), m, "john"); err != nil {
+		t.Fatal(err)
+	}
+	want := []dbqtest.Statement{
+		{Query: "INSERT INTO users (name) VALUES (?)", Args: []any{"john"}},
+		{Query: "INSERT INTO audit (event) VALUES (?)", Args: []any{"register"}},
+	}
+	if got := m.Statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got statements %v", got)
+	}
+	if m.Commits() != 1 || m.Rollbacks() != 0 {
+		t.Errorf("got %d commits, %d rollbacks", m.Commits(), m.Rollbacks())
+	}
+
+	failed := errors.New("audit down")
+	m.Fail("INSERT INTO audit (event) VALUES (?)", failed)
+	if err := register(context.Background(), m, "jane"); !errors.Is(err, failed) {
+		t.Errorf("expected failure, got %v", err)
+	}
+	if m.Commits() != 1 || m.Rollbacks() != 1 {
+		t.Errorf("got %d commits, %d rollbacks", m.Commits(), m.Rollbacks())
+	}
+}
+
+func TestTxManagerAcquire(t *testing.T) {
+	m := dbqtest.New()
+	tx, err := m.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := dbq.QueryColumn[string](tx, "SELECT name FROM users")
+	if err != nil || len(names) != 0 {
+		t.Errorf("got %v, %v", names, err)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if m.Rollbacks() != 1 {
+		t.Errorf("got %d rollbacks", m.Rollbacks())
+	}
+}
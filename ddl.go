@@ -0,0 +1,135 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedDDL is returned when dialect can't change constraints of
+// existing table, e.g. SQLite which requires table to be rebuilt.
+var ErrUnsupportedDDL = errors.New("dbq: ddl not supported by dialect")
+
+// Check is CHECK constraint of table, e.g.
+//
+//	dbq.Check{Table: "orders", Name: "orders_total_positive", Expr: "total >= 0"}
+type Check struct {
+	Table string
+	Name  string
+	Expr  string
+}
+
+// Add returns statements adding constraint without blocking writes for
+// the time existing rows are checked. On Postgres constraint is added as
+// NOT VALID, so only new rows are checked, and validated in separate
+// statement which takes lock that doesn't block reads and writes. On SQL
+// Server constraint is added WITH NOCHECK and enabled WITH CHECK. MySQL
+// checks rows when constraint is added.
+func (c Check) Add(d Dialect) ([]string, error) {
+	switch d.Name() {
+	case "postgres":
+		return []string{
+			fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s) NOT VALID", c.Table, c.Name, c.Expr),
+			c.validate(d),
+		}, nil
+	case "sqlserver":
+		return []string{
+			fmt.Sprintf("ALTER TABLE %s WITH NOCHECK ADD CONSTRAINT %s CHECK (%s)", c.Table, c.Name, c.Expr),
+			c.validate(d),
+		}, nil
+	case "mysql":
+		return []string{
+			fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s)", c.Table, c.Name, c.Expr),
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: check constraint on %s", ErrUnsupportedDDL, d.Name())
+}
+
+// Drop returns statement dropping constraint.
+func (c Check) Drop(d Dialect) (string, error) {
+	switch d.Name() {
+	case "postgres", "sqlserver":
+		return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", c.Table, c.Name), nil
+	case "mysql":
+		return fmt.Sprintf("ALTER TABLE %s DROP CHECK %s", c.Table, c.Name), nil
+	}
+	return "", fmt.Errorf("%w: check constraint on %s", ErrUnsupportedDDL, d.Name())
+}
+
+// validate returns statement checking existing rows against constraint.
+func (c Check) validate(d Dialect) string {
+	if d.Name() == "sqlserver" {
+		return fmt.Sprintf("ALTER TABLE %s WITH CHECK CHECK CONSTRAINT %s", c.Table, c.Name)
+	}
+	return fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", c.Table, c.Name)
+}
+
+// NotNull is NOT NULL change of table column. Type is column type, it is
+// required by MySQL and SQL Server which redefine column.
+type NotNull struct {
+	Table  string
+	Column string
+	Type   string
+}
+
+// Set returns statements making column NOT NULL. On Postgres column is
+// first checked with validated CHECK (column IS NOT NULL) constraint, so
+// SET NOT NULL doesn't scan table while holding exclusive lock, and the
+// constraint is dropped afterwards.
+func (n NotNull) Set(d Dialect) ([]string, error) {
+	switch d.Name() {
+	case "postgres":
+		check := Check{Table: n.Table, Name: n.Table + "_" + n.Column + "_not_null", Expr: n.Column + " IS NOT NULL"}
+		stmts, _ := check.Add(d)
+		drop, _ := check.Drop(d)
+		return append(stmts,
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", n.Table, n.Column),
+			drop,
+		), nil
+	case "mysql":
+		if n.Type == "" {
+			return nil, fmt.Errorf("dbq: type of column %s is required by mysql", n.Column)
+		}
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY %s %s NOT NULL", n.Table, n.Column, n.Type)}, nil
+	case "sqlserver":
+		if n.Type == "" {
+			return nil, fmt.Errorf("dbq: type of column %s is required by sqlserver", n.Column)
+		}
+		return []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s NOT NULL", n.Table, n.Column, n.Type)}, nil
+	}
+	return nil, fmt.Errorf("%w: not null on %s", ErrUnsupportedDDL, d.Name())
+}
+
+// Drop returns statement making column nullable.
+func (n NotNull) Drop(d Dialect) (string, error) {
+	switch d.Name() {
+	case "postgres":
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", n.Table, n.Column), nil
+	case "mysql", "sqlserver":
+		if n.Type == "" {
+			return "", fmt.Errorf("dbq: type of column %s is required by %s", n.Column, d.Name())
+		}
+		if d.Name() == "mysql" {
+			return fmt.Sprintf("ALTER TABLE %s MODIFY %s %s NULL", n.Table, n.Column, n.Type), nil
+		}
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s NULL", n.Table, n.Column, n.Type), nil
+	}
+	return "", fmt.Errorf("%w: not null on %s", ErrUnsupportedDDL, d.Name())
+}
+
+// ApplyDDL executes statements in order and stops on first error, which
+// is wrapped with failed statement. Statements returned by Check.Add and
+// NotNull.Set should be applied with db outside of transaction, so lock
+// of each statement is released before next one is executed.
+func ApplyDDL(ctx context.Context, db Access, stmts ...string) error {
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("dbq: ddl %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
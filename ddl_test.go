@@ -0,0 +1,80 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestCheckAdd(t *testing.T) {
+	check := dbq.Check{Table: "orders", Name: "orders_total", Expr: "total >= 0"}
+	tests := []struct {
+		dialect dbq.Dialect
+		want    []string
+	}{
+		{dbq.Postgres, []string{
+			"ALTER TABLE orders ADD CONSTRAINT orders_total CHECK (total >= 0) NOT VALID",
+			"ALTER TABLE orders VALIDATE CONSTRAINT orders_total",
+		}},
+		{dbq.SQLServer, []string{
+			"ALTER TABLE orders WITH NOCHECK ADD CONSTRAINT orders_total CHECK (total >= 0)",
+			"ALTER TABLE orders WITH CHECK CHECK CONSTRAINT orders_total",
+		}},
+		{dbq.MySQL, []string{"ALTER TABLE orders ADD CONSTRAINT orders_total CHECK (total >= 0)"}},
+	}
+	for _, tt := range tests {
+		got, err := check.Add(tt.dialect)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, %v", tt.dialect.Name(), got, err)
+		}
+	}
+	if _, err := check.Add(dbq.SQLite); !errors.Is(err, dbq.ErrUnsupportedDDL) {
+		t.Errorf("expected unsupported ddl, got %v", err)
+	}
+}
+
+func TestNotNullSet(t *testing.T) {
+	n := dbq.NotNull{Table: "users", Column: "email", Type: "VARCHAR(255)"}
+	got, err := n.Set(dbq.Postgres)
+	want := []string{
+		"ALTER TABLE users ADD CONSTRAINT users_email_not_null CHECK (email IS NOT NULL) NOT VALID",
+		"ALTER TABLE users VALIDATE CONSTRAINT users_email_not_null",
+		"ALTER TABLE users ALTER COLUMN email SET NOT NULL",
+		"ALTER TABLE users DROP CONSTRAINT users_email_not_null",
+	}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, %v", got, err)
+	}
+	got, err = n.Set(dbq.MySQL)
+	if err != nil || !reflect.DeepEqual(got, []string{"ALTER TABLE users MODIFY email VARCHAR(255) NOT NULL"}) {
+		t.Errorf("got %q, %v", got, err)
+	}
+	n.Type = ""
+	if _, err = n.Set(dbq.SQLServer); err == nil {
+		t.Error("type should be required")
+	}
+}
+
+func TestApplyDDL(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{Query: "ALTER TABLE orders ADD CONSTRAINT orders_total CHECK (total >= 0) NOT VALID"},
+		{Query: "ALTER TABLE orders VALIDATE CONSTRAINT orders_total", Err: "check violated"},
+	}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	stmts, err := dbq.Check{Table: "orders", Name: "orders_total", Expr: "total >= 0"}.Add(dbq.Postgres)
+	maybePanic(err)
+	err = dbq.ApplyDDL(context.Background(), db, stmts...)
+	if err == nil || err.Error() != `dbq: ddl "ALTER TABLE orders VALIDATE CONSTRAINT orders_total": check violated` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenTx is transaction acquired from TxProvider which is not finished.
+type OpenTx struct {
+	Started time.Time `json:"started"`
+	// Caller is set when enabled with CaptureCaller.
+	Caller string `json:"caller,omitempty"`
+}
+
+var openTxs sync.Map // map[*stash]OpenTx
+
+// OpenTransactions returns transactions acquired from TxProvider which are
+// not committed or rolled back yet, the oldest first.
+func OpenTransactions() []OpenTx {
+	var txs []OpenTx
+	openTxs.Range(func(_, v any) bool {
+		txs = append(txs, v.(OpenTx)) //nolint:forcetypeassert
+		return true
+	})
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].Started.Before(txs[j].Started)
+	})
+	return txs
+}
+
+// DebugOption adds section to DebugHandler.
+type DebugOption func(*debugHandler)
+
+// DebugPool reports stats and health of db under name.
+func DebugPool(name string, db *sql.DB) DebugOption {
+	return func(h *debugHandler) {
+		h.pools[name] = db
+	}
+}
+
+// DebugSlowLog reports tail of slow log.
+func DebugSlowLog(log *SlowLog) DebugOption {
+	return func(h *debugHandler) {
+		h.slow = log
+	}
+}
+
+// DebugSection reports value returned by fn under name, e.g. cache hit
+// rates.
+func DebugSection(name string, fn func() any) DebugOption {
+	return func(h *debugHandler) {
+		h.sections[name] = fn
+	}
+}
+
+type debugHandler struct {
+	pools    map[string]*sql.DB
+	slow     *SlowLog
+	sections map[string]func() any
+}
+
+// DebugHandler returns handler exposing diagnostics as JSON: pool stats,
+// open transactions, slow log tail, custom sections and health. Path
+// ending with /health responds only with health, status is 503 when any
+// pool is unreachable. Handler is meant for internal admin routes, e.g.
+//
+//	mux.Handle("/admin/db/", http.StripPrefix("/admin/db", dbq.DebugHandler(
+//		dbq.DebugPool("main", db),
+//		dbq.DebugSlowLog(slowLog),
+//	)))
+func DebugHandler(opts ...DebugOption) http.Handler {
+	h := &debugHandler{
+		pools:    make(map[string]*sql.DB),
+		sections: make(map[string]func() any),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+type debugReport struct {
+	Health       map[string]string      `json:"health"`
+	Pools        map[string]sql.DBStats `json:"pools,omitempty"`
+	Transactions []OpenTx               `json:"transactions"`
+	SlowQueries  []SlowQuery            `json:"slow_queries,omitempty"`
+	Sections     map[string]any         `json:"sections,omitempty"`
+}
+
+func (h *debugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	health, healthy := h.health(r.Context())
+	status := http.StatusOK
+	if !healthy {
+		status = http.StatusServiceUnavailable
+	}
+
+	var body any = map[string]any{"health": health}
+	if strings.HasSuffix(r.URL.Path, "/health") {
+		writeJSON(w, status, body)
+		return
+	}
+
+	report := debugReport{
+		Health:       health,
+		Pools:        make(map[string]sql.DBStats, len(h.pools)),
+		Transactions: OpenTransactions(),
+		Sections:     make(map[string]any, len(h.sections)),
+	}
+	for name, db := range h.pools {
+		report.Pools[name] = db.Stats()
+	}
+	if h.slow != nil {
+		report.SlowQueries = h.slow.Tail(50)
+	}
+	for name, fn := range h.sections {
+		report.Sections[name] = fn()
+	}
+	writeJSON(w, status, report)
+}
+
+func (h *debugHandler) health(ctx context.Context) (map[string]string, bool) {
+	health := make(map[string]string, len(h.pools))
+	healthy := true
+	for name, db := range h.pools {
+		pctx, cancel := context.WithTimeout(ctx, time.Second)
+		err := db.PingContext(pctx)
+		cancel()
+		if err != nil {
+			health[name] = err.Error()
+			healthy = false
+			continue
+		}
+		health[name] = "ok"
+	}
+	return health, healthy
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestDebugHandler(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer db.Close()
+
+	slow := dbq.NewSlowLog(0, 10)
+	provider := dbq.NewTxProvider(db, dbq.Interceptors(slow.Interceptor()))
+	handler := dbq.DebugHandler(
+		dbq.DebugPool("main", db),
+		dbq.DebugSlowLog(slow),
+		dbq.DebugSection("cache", func() any { return map[string]float64{"hit_rate": 0.5} }),
+	)
+
+	tx, err := provider.Acquire(context.Background())
+	maybePanic(err)
+	_, err = dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+	maybePanic(err)
+
+	var report struct {
+		Health       map[string]string `json:"health"`
+		Transactions []dbq.OpenTx      `json:"transactions"`
+		SlowQueries  []dbq.SlowQuery   `json:"slow_queries"`
+		Sections     map[string]any    `json:"sections"`
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	maybePanic(json.Unmarshal(rec.Body.Bytes(), &report))
+	if rec.Code != http.StatusOK || report.Health["main"] != "ok" || len(report.Transactions) != 1 ||
+		len(report.SlowQueries) != 1 || report.Sections["cache"] == nil {
+		t.Errorf("bad report %d %s", rec.Code, rec.Body)
+	}
+
+	maybePanic(tx.Rollback())
+	if txs := dbq.OpenTransactions(); len(txs) != 0 {
+		t.Errorf("transaction still open %v", txs)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("bad health status %d", rec.Code)
+	}
+}
+
+func TestSlowLogTail(t *testing.T) {
+	slow := dbq.NewSlowLog(0, 2)
+	db := dbq.Intercept(&nopAccess{}, slow.Interceptor())
+	for _, q := range []string{"DELETE FROM a", "DELETE FROM b", "DELETE FROM c"} {
+		_, _ = db.ExecContext(context.Background(), q)
+	}
+	tail := slow.Tail(0)
+	if len(tail) != 2 || tail[0].Query != "DELETE FROM b" || tail[1].Query != "DELETE FROM c" {
+		t.Errorf("bad tail %v", tail)
+	}
+}
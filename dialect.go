@@ -0,0 +1,159 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Dialect describes database specific SQL syntax.
+type Dialect interface {
+	// Name of the database engine.
+	Name() string
+	// Placeholder returns bind parameter for n-th (1-based) argument.
+	Placeholder(n int) string
+}
+
+// Supported dialects, see also DuckDB.
+var (
+	Postgres  Dialect = postgres{}
+	MySQL     Dialect = mysql{}
+	SQLite    Dialect = sqlite{}
+	SQLServer Dialect = sqlServer{}
+)
+
+type postgres struct{}
+
+func (postgres) Name() string {
+	return "postgres"
+}
+
+func (postgres) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgres) EqualFold(column string) string {
+	return "LOWER(" + column + ") = LOWER(?)"
+}
+
+func (postgres) LikeFold(column string) string {
+	return column + ` ILIKE ? ESCAPE '!'`
+}
+
+type mysql struct{}
+
+func (mysql) Name() string {
+	return "mysql"
+}
+
+func (mysql) Placeholder(int) string {
+	return "?"
+}
+
+type sqlite struct{}
+
+func (sqlite) Name() string {
+	return "sqlite"
+}
+
+func (sqlite) Placeholder(int) string {
+	return "?"
+}
+
+func (sqlite) EqualFold(column string) string {
+	return column + " = ? COLLATE NOCASE"
+}
+
+// LikeFold relies on LIKE being case-insensitive for ASCII characters.
+func (sqlite) LikeFold(column string) string {
+	return column + ` LIKE ? ESCAPE '!'`
+}
+
+type sqlServer struct{}
+
+func (sqlServer) Name() string {
+	return "sqlserver"
+}
+
+func (sqlServer) Placeholder(n int) string {
+	return "@p" + strconv.Itoa(n)
+}
+
+// Rebind converts ? placeholders of query to placeholders of dialect d,
+// e.g. $1, $2 for Postgres. Question marks in string literals, quoted
+// identifiers and comments are left as they are, so are Postgres JSON
+// operators ?| and ?&. Literal question mark, e.g. JSON operator ?, is
+// written as ??.
+func Rebind(d Dialect, query string) string {
+	if d == nil || !strings.Contains(query, "?") ||
+		d.Placeholder(1) == "?" && !strings.Contains(query, "??") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if j := skipQuoted(query, i); j > i {
+			b.WriteString(query[i:j])
+			i = j - 1
+			continue
+		}
+		if query[i] != '?' {
+			b.WriteByte(query[i])
+			continue
+		}
+		switch next := byteAt(query, i+1); {
+		case next == '?':
+			b.WriteByte('?')
+			i++
+		case next == '&' || next == '|' && byteAt(query, i+2) != '|':
+			// ?& and ?| operators, ?|| is placeholder followed by
+			// concatenation.
+			b.WriteByte('?')
+		default:
+			n++
+			b.WriteString(d.Placeholder(n))
+		}
+	}
+	return b.String()
+}
+
+// byteAt returns byte of s at i or 0 past its end.
+func byteAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return 0
+}
+
+// Rebinding returns interceptor rebinding statements to dialect d, it
+// should be the last interceptor so others see queries with ? placeholders.
+func Rebinding(d Dialect) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			stmt.Query = Rebind(d, stmt.Query)
+			return next(ctx, stmt)
+		}
+	}
+}
+
+type dialectKey struct{}
+
+// WithDialect sets dialect of provider database. Queries of transactions
+// written with ? placeholders are rebound to dialect after all other
+// interceptors and dialect is available to helpers with DialectFromCtx.
+func WithDialect(d Dialect) ProviderOption {
+	return func(t *TxProvider) {
+		t.dialect = d
+	}
+}
+
+// DialectFromCtx returns dialect of transaction set with WithDialect.
+func DialectFromCtx(ctx context.Context) (Dialect, bool) {
+	d, ok := ctx.Value(dialectKey{}).(Dialect)
+	return d, ok
+}
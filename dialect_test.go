@@ -0,0 +1,65 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestRebind(t *testing.T) {
+	const query = "SELECT '?', \"a?\" FROM t WHERE a = ? AND b IN (?, ?) -- ?"
+	tests := map[dbq.Dialect]string{
+		dbq.Postgres:  "SELECT '?', \"a?\" FROM t WHERE a = $1 AND b IN ($2, $3) -- ?",
+		dbq.SQLServer: "SELECT '?', \"a?\" FROM t WHERE a = @p1 AND b IN (@p2, @p3) -- ?",
+		dbq.MySQL:     query,
+		dbq.SQLite:    query,
+	}
+	for d, want := range tests {
+		if got := dbq.Rebind(d, query); got != want {
+			t.Errorf("%s: got %q, want %q", d.Name(), got, want)
+		}
+	}
+
+	const jsonQuery = "SELECT * FROM t WHERE tags ?| ? AND tags ?& ? AND tags ?? ? AND name = ?||'x'"
+	want := "SELECT * FROM t WHERE tags ?| $1 AND tags ?& $2 AND tags ? $3 AND name = $4||'x'"
+	if got := dbq.Rebind(dbq.Postgres, jsonQuery); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := dbq.Rebind(dbq.SQLite, "SELECT ?? FROM t WHERE a = ?"); got != "SELECT ? FROM t WHERE a = ?" {
+		t.Errorf("escaped question mark should be unescaped, got %q", got)
+	}
+}
+
+func TestProviderDialect(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries[0].Query = "SELECT id, name, created FROM users WHERE id > $1"
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	log := dbq.NewQueryLog()
+	provider := dbq.NewTxProvider(db, dbq.WithDialect(dbq.Postgres), dbq.Interceptors(log.Interceptor()))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		if d, ok := dbq.DialectFromCtx(tx); !ok || d != dbq.Postgres {
+			t.Errorf("bad dialect %v", d)
+		}
+		_, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+		return err
+	}))
+	// interceptors see queries before rebinding.
+	log.AssertSequence(t, "WHERE id > ?")
+}
+
+func TestDialectFold(t *testing.T) {
+	if w, _ := dbq.EqualFold(dbq.SQLite, "email", "a"); w != "email = ? COLLATE NOCASE" {
+		t.Errorf("bad sqlite predicate %q", w)
+	}
+	if w, _ := dbq.ContainsFold(dbq.Postgres, "name", "a"); w != `name ILIKE ? ESCAPE '!'` {
+		t.Errorf("bad postgres predicate %q", w)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"database/sql/driver"
+	"reflect"
+)
+
+// Change is row present in both compared result sets with different
+// values.
+type Change[T any] struct {
+	Old T
+	New T
+	// Fields are columns of struct T which differ, nil for other types.
+	Fields []string
+}
+
+// Diff is difference between two result sets.
+type Diff[T any] struct {
+	// Added are rows present only in the second result set.
+	Added []T
+	// Removed are rows present only in the first result set.
+	Removed []T
+	// Changed are rows present in both result sets with different values.
+	Changed []Change[T]
+}
+
+// Empty returns true when result sets are equal.
+func (d Diff[T]) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffResults compares result sets from and to, e.g. of source and target
+// system in reconciliation job, matching rows by key:
+//
+//	diff := dbq.DiffResults(target, source, func(u User) int64 { return u.ID })
+//	for _, u := range diff.Added { ... } // missing in target
+//
+// Structs are compared field by field of mapped columns, other types as
+// whole. Values with Equal method, like Null, NullAny and time.Time, are
+// compared with it, other driver.Valuer values by their driver value, so
+// null values are equal regardless of Val. Keys are expected to be unique,
+// the last row with duplicate key wins.
+func DiffResults[T any, K comparable](from, to []T, key func(T) K) Diff[T] {
+	var d Diff[T]
+	old := make(map[K]T, len(from))
+	for _, row := range from {
+		old[key(row)] = row
+	}
+	seen := make(map[K]bool, len(to))
+	for _, row := range to {
+		k := key(row)
+		seen[k] = true
+		prev, ok := old[k]
+		if !ok {
+			d.Added = append(d.Added, row)
+			continue
+		}
+		if fields, changed := diffValues(prev, row); changed {
+			d.Changed = append(d.Changed, Change[T]{Old: prev, New: row, Fields: fields})
+		}
+	}
+	for _, row := range from {
+		if !seen[key(row)] {
+			d.Removed = append(d.Removed, row)
+		}
+	}
+	return d
+}
+
+// diffValues returns columns of struct values a and b which differ.
+func diffValues[T any](a, b T) ([]string, bool) {
+	va, vb := reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem()
+	if va.Kind() != reflect.Struct || va.Type() == timeType {
+		return nil, !equalValues(va, vb)
+	}
+	if _, ok := any(&a).(driver.Valuer); ok {
+		return nil, !equalValues(va, vb)
+	}
+	m, err := structMapOf(va.Type())
+	if err != nil {
+		return nil, !equalValues(va, vb)
+	}
+	var fields []string
+	for _, f := range m.fields {
+		fa, okA := fieldValue(va, f.index)
+		fb, okB := fieldValue(vb, f.index)
+		if okA != okB || (okA && !equalValues(fa, fb)) {
+			fields = append(fields, f.column)
+		}
+	}
+	return fields, len(fields) > 0
+}
+
+// equalValues compares values with Equal method, driver value or
+// reflect.DeepEqual.
+func equalValues(a, b reflect.Value) bool {
+	if eq := a.MethodByName("Equal"); eq.IsValid() && eq.Type().NumIn() == 1 &&
+		eq.Type().In(0) == b.Type() && eq.Type().NumOut() == 1 && eq.Type().Out(0).Kind() == reflect.Bool {
+		return eq.Call([]reflect.Value{b})[0].Bool()
+	}
+	if va, ok := a.Interface().(driver.Valuer); ok {
+		vb := b.Interface().(driver.Valuer) //nolint:forcetypeassert
+		x, errA := va.Value()
+		y, errB := vb.Value()
+		if errA == nil && errB == nil {
+			return reflect.DeepEqual(x, y)
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestDiffResults(t *testing.T) {
+	created := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	target := []user{
+		{ID: 1, Name: dbq.FromValue("john"), Created: created},
+		{ID: 2, Name: dbq.NewNull("stale", false), Created: created},
+		{ID: 3, Name: dbq.FromValue("old"), Created: created},
+		{ID: 4, Name: dbq.FromValue("gone"), Created: created},
+	}
+	source := []user{
+		{ID: 5, Name: dbq.FromValue("new"), Created: created},
+		{ID: 1, Name: dbq.FromValue("john"), Created: created.In(time.FixedZone("CET", 3600))},
+		{ID: 2, Name: dbq.Null[string]{}, Created: created},
+		{ID: 3, Name: dbq.FromValue("renamed"), Created: created.Add(time.Hour)},
+	}
+	diff := dbq.DiffResults(target, source, func(u user) int64 { return u.ID })
+
+	if len(diff.Added) != 1 || diff.Added[0].ID != 5 {
+		t.Errorf("bad added %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].ID != 4 {
+		t.Errorf("bad removed %v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 {
+		t.Fatalf("expected only renamed user changed, got %v", diff.Changed)
+	}
+	c := diff.Changed[0]
+	if c.Old.ID != 3 || c.New.Name.Val != "renamed" || !reflect.DeepEqual(c.Fields, []string{"name", "created"}) {
+		t.Errorf("bad change %+v", c)
+	}
+	if diff.Empty() || !dbq.DiffResults(target, target, func(u user) int64 { return u.ID }).Empty() {
+		t.Error("only equal result sets should have empty diff")
+	}
+
+	scalars := dbq.DiffResults([]string{"a", "b"}, []string{"b", "c"}, func(s string) string { return s })
+	if !reflect.DeepEqual(scalars.Added, []string{"c"}) || !reflect.DeepEqual(scalars.Removed, []string{"a"}) {
+		t.Errorf("bad scalar diff %+v", scalars)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DuckDB dialect for local analytical workloads.
+//
+// DuckDB runs in process and allows many connections from single process,
+// but only one process can open database file for writing. Concurrent
+// transactions updating the same rows fail with conflict error at commit
+// instead of blocking, so write paths should be retried or serialized.
+var DuckDB Dialect = duckDB{}
+
+type duckDB struct{}
+
+func (duckDB) Name() string {
+	return "duckdb"
+}
+
+func (duckDB) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// HugeInt is DuckDB 128-bit HUGEINT value.
+type HugeInt struct {
+	big.Int
+}
+
+// Scan implements the Scanner interface.
+func (h *HugeInt) Scan(value any) error {
+	switch v := value.(type) {
+	case *big.Int:
+		if v == nil {
+			return errNilPtr
+		}
+		h.Set(v)
+	case big.Int:
+		h.Set(&v)
+	case int64:
+		h.SetInt64(v)
+	case uint64:
+		h.SetUint64(v)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("converting %v to HugeInt is unsupported", v)
+		}
+		big.NewFloat(v).Int(&h.Int)
+	case string:
+		return h.setString(v)
+	case []byte:
+		return h.setString(string(v))
+	case nil:
+		return errors.New("converting NULL to HugeInt is unsupported")
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", value, h)
+	}
+	return nil
+}
+
+func (h *HugeInt) setString(s string) error {
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("converting %q to HugeInt: invalid syntax", s)
+	}
+	h.Set(i)
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (h HugeInt) Value() (driver.Value, error) {
+	return h.String(), nil
+}
+
+// List is DuckDB LIST value scanned into slice of T.
+type List[T any] []T
+
+// Scan implements the Scanner interface.
+func (l *List[T]) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+	case []T:
+		*l = append((*l)[:0], v...)
+	case []any:
+		list := make(List[T], len(v))
+		for i, elem := range v {
+			if err := convertAssign(&list[i], elem); err != nil {
+				return fmt.Errorf("list element %d: %w", i, err)
+			}
+		}
+		*l = list
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", value, l)
+	}
+	return nil
+}
+
+// Value implements the driver Valuer interface. List is encoded as
+// DuckDB list literal which can be cast to typed list, e.g. $1::INTEGER[].
+func (l List[T]) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, elem := range l {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		switch v := any(elem).(type) {
+		case string:
+			b.WriteString("'" + strings.ReplaceAll(v, "'", "''") + "'")
+		case []byte:
+			b.WriteString("'" + strings.ReplaceAll(string(v), "'", "''") + "'")
+		default:
+			b.WriteString(asString(v))
+		}
+	}
+	b.WriteByte(']')
+	return b.String(), nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestDuckDBPlaceholder(t *testing.T) {
+	if p := dbq.DuckDB.Placeholder(3); p != "$3" {
+		t.Errorf("Placeholder(3) = %q, want $3", p)
+	}
+}
+
+func TestHugeIntScan(t *testing.T) {
+	want, _ := new(big.Int).SetString("170141183460469231731687303715884105727", 10)
+
+	var h dbq.HugeInt
+	maybePanic(h.Scan(want))
+	if h.Cmp(want) != 0 {
+		t.Errorf("bad HugeInt from *big.Int: %v ≠ %v", h.String(), want)
+	}
+
+	maybePanic(h.Scan([]byte("-42")))
+	if h.Int64() != -42 {
+		t.Errorf("bad HugeInt from []byte: %v", h.String())
+	}
+
+	if err := h.Scan("foo"); err == nil {
+		t.Error("err should be present for invalid HugeInt")
+	}
+	if err := h.Scan(math.NaN()); err == nil {
+		t.Error("err should be present for NaN")
+	}
+	if err := h.Scan(math.Inf(1)); err == nil {
+		t.Error("err should be present for infinity")
+	}
+
+	v, err := h.Value()
+	maybePanic(err)
+	if v != "-42" {
+		t.Errorf("bad HugeInt value: %v", v)
+	}
+}
+
+func TestListScan(t *testing.T) {
+	var l dbq.List[int64]
+	maybePanic(l.Scan([]any{int32(1), int64(2), "3"}))
+	if !reflect.DeepEqual(l, dbq.List[int64]{1, 2, 3}) {
+		t.Errorf("bad list: %v", l)
+	}
+
+	maybePanic(l.Scan(nil))
+	if l != nil {
+		t.Errorf("list should be nil, got %v", l)
+	}
+
+	if err := l.Scan([]any{"x"}); err == nil {
+		t.Error("err should be present for invalid element")
+	}
+}
+
+func TestListValue(t *testing.T) {
+	v, err := dbq.List[string]{"a", "it's"}.Value()
+	maybePanic(err)
+	if v != "['a', 'it''s']" {
+		t.Errorf("bad list literal: %v", v)
+	}
+
+	v, err = dbq.List[int]{1, 2}.Value()
+	maybePanic(err)
+	if v != "[1, 2]" {
+		t.Errorf("bad list literal: %v", v)
+	}
+}
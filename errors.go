@@ -1,14 +1,88 @@
 package dbq
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
 
 type NotFoundError struct {
+	// DataSource is optional name of data source, see WithDataSource.
 	DataSource string
+	// Query and Args are statement which returned no rows.
+	Query string
+	Args  []any
 }
 
 func (e NotFoundError) Error() string {
+	msg := "no data found"
 	if e.DataSource != "" {
-		return fmt.Sprintf("no data found in %v", e.DataSource)
+		msg = fmt.Sprintf("no data found in %v", e.DataSource)
+	}
+	if e.Query != "" {
+		msg += fmt.Sprintf(" for query %q", e.Query)
+	}
+	return msg
+}
+
+// notFound returns NotFoundError of query labeled with data source from ctx.
+func notFound(ctx context.Context, query string, args []any) *NotFoundError {
+	name, _ := DataSourceFromCtx(ctx)
+	args, _ = splitOptions(args)
+	return &NotFoundError{
+		DataSource: name,
+		Query:      query,
+		Args:       args,
+	}
+}
+
+// Error is error of statement carrying its context.
+type Error struct {
+	// Op is kind of database call.
+	Op Op
+	// Query is fingerprint of statement, see Fingerprint.
+	Query string
+	// Duration is time from start of call until error.
+	Duration time.Duration
+	// Err is underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("dbq: %s %q failed after %v: %v", e.Op, e.Query, e.Duration, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// wrapError wraps err of statement in Error, errors which are already
+// wrapped and NotFoundError are returned as they are.
+func wrapError(op Op, query string, start time.Time, err error) error {
+	if err == nil {
+		return nil
+	}
+	var (
+		e  *Error
+		nf *NotFoundError
+	)
+	if errors.As(err, &e) || errors.As(err, &nf) {
+		return err
+	}
+	return &Error{
+		Op:       op,
+		Query:    Fingerprint(query),
+		Duration: time.Since(start),
+		Err:      TranslateError(err),
+	}
+}
+
+// wrapErrors wraps errors of statements in Error.
+func wrapErrors(next Handler) Handler {
+	return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+		start := time.Now()
+		out, err := next(ctx, stmt)
+		return out, wrapError(stmt.Op, stmt.Query, start, err)
 	}
-	return "no data found"
 }
@@ -0,0 +1,35 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestErrorWrapping(t *testing.T) {
+	errDriver := errors.New("driver failure")
+	db := dbq.Intercept(&nopAccess{}, func(dbq.Handler) dbq.Handler {
+		return func(context.Context, *dbq.Statement) (dbq.Outcome, error) {
+			return dbq.Outcome{}, errDriver
+		}
+	})
+
+	_, err := db.ExecContext(context.Background(), "DELETE FROM logs WHERE id = 7")
+	var e *dbq.Error
+	if !errors.As(err, &e) || !errors.Is(err, errDriver) {
+		t.Fatalf("expected wrapped error, got %v", err)
+	}
+	if e.Op != dbq.OpExec || e.Query != "DELETE FROM logs WHERE id = ?" {
+		t.Errorf("bad error context %+v", e)
+	}
+
+	if err = db.QueryRowContext(context.Background(), "SELECT 1").Scan(new(int)); !errors.As(err, &e) || e.Op != dbq.OpQueryRow {
+		t.Errorf("expected wrapped row error, got %v", err)
+	}
+}
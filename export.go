@@ -0,0 +1,176 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Anonymizer replaces column value before it is exported, value is nil,
+// int64, float64, bool, string or time.Time.
+type Anonymizer func(v any) any
+
+// AnonymizeHash returns anonymizer replacing non-null values with salted
+// SHA-256 hex digest, equal values have equal digests so exported data can
+// still be joined.
+func AnonymizeHash(salt string) Anonymizer {
+	return func(v any) any {
+		if v == nil {
+			return nil
+		}
+		sum := sha256.Sum256([]byte(salt + exportString(v)))
+		return hex.EncodeToString(sum[:])
+	}
+}
+
+// AnonymizeNull replaces values with null.
+func AnonymizeNull(any) any {
+	return nil
+}
+
+// Exporter streams query results through per-column anonymizers into CSV
+// or newline delimited JSON, e.g. for GDPR safe staging datasets:
+//
+//	exporter := dbq.NewExporter().
+//		Column("email", dbq.AnonymizeHash(salt)).
+//		Column("name", dbq.AnonymizeNull)
+//	n, err := exporter.CSV(ctx, db, w, "SELECT id, email, name FROM users")
+type Exporter struct {
+	anonymizers map[string]Anonymizer
+}
+
+// NewExporter creates exporter without anonymizers.
+func NewExporter() *Exporter {
+	return &Exporter{
+		anonymizers: make(map[string]Anonymizer),
+	}
+}
+
+// Column registers anonymizer of column, it is matched with result columns
+// case insensitively. Export fails when query doesn't return registered
+// column.
+func (e *Exporter) Column(name string, fn Anonymizer) *Exporter {
+	e.anonymizers[strings.ToLower(name)] = fn
+	return e
+}
+
+// CSV writes header and rows of query to w and returns number of rows.
+// Null is written as empty field.
+func (e *Exporter) CSV(ctx context.Context, db Access, w io.Writer, query string, args ...any) (int, error) {
+	cw := csv.NewWriter(w)
+	var record []string
+	n, err := e.export(ctx, db, query, args, func(cols []string) error {
+		record = make([]string, len(cols))
+		return cw.Write(cols)
+	}, func(_ []string, values []any) error {
+		for i, v := range values {
+			record[i] = exportString(v)
+		}
+		return cw.Write(record)
+	})
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	return n, err
+}
+
+// NDJSON writes rows of query to w as JSON objects, one per line, and
+// returns number of rows.
+func (e *Exporter) NDJSON(ctx context.Context, db Access, w io.Writer, query string, args ...any) (int, error) {
+	enc := json.NewEncoder(w)
+	return e.export(ctx, db, query, args, nil, func(cols []string, values []any) error {
+		obj := make(map[string]any, len(cols))
+		for i, col := range cols {
+			obj[col] = values[i]
+		}
+		return enc.Encode(obj)
+	})
+}
+
+func (e *Exporter) export(ctx context.Context, db Access, query string, args []any,
+	header func(cols []string) error, write func(cols []string, values []any) error,
+) (int, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	// every registered column must be in result, so renamed or aliased
+	// column is not exported in clear.
+	anonymizers := make([]Anonymizer, len(cols))
+	matched := make(map[string]bool, len(e.anonymizers))
+	for i, col := range cols {
+		name := strings.ToLower(col)
+		anonymizers[i] = e.anonymizers[name]
+		matched[name] = anonymizers[i] != nil
+	}
+	var missing []string
+	for name := range e.anonymizers {
+		if !matched[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return 0, fmt.Errorf("dbq: anonymized columns %s are not in result of query", strings.Join(missing, ", "))
+	}
+	if header != nil {
+		if err = header(cols); err != nil {
+			return 0, err
+		}
+	}
+
+	values := make([]any, len(cols))
+	dests := make([]any, len(cols))
+	for i := range dests {
+		dests[i] = &values[i]
+	}
+	n := 0
+	for rows.Next() {
+		if err = rows.Scan(dests...); err != nil {
+			return n, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			if anonymizers[i] != nil {
+				v = anonymizers[i](v)
+			}
+			values[i] = v
+		}
+		if err = write(cols, values); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, closeRows(rows, nil)
+}
+
+// exportString formats exported value as text.
+func exportString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return asString(v)
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestExporter(t *testing.T) {
+	const query = "SELECT id, name, created FROM users WHERE id > ?"
+	rec := usersRecording()
+	rec.Entries = []dbq.RecordedEntry{rec.Entries[0], rec.Entries[0], rec.Entries[0]}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	exporter := dbq.NewExporter().
+		Column("Name", dbq.AnonymizeHash("salt")).
+		Column("created", dbq.AnonymizeNull)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	n, err := exporter.CSV(ctx, db, &buf, query, 0)
+	maybePanic(err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if n != 2 || len(lines) != 3 || lines[0] != "id,name,created" {
+		t.Fatalf("bad csv %q", buf.String())
+	}
+	if fields := strings.Split(lines[1], ","); fields[0] != "1" || len(fields[1]) != 64 || fields[2] != "" {
+		t.Errorf("bad csv row %q", lines[1])
+	}
+	if lines[2] != "2,," {
+		t.Errorf("null name should stay null, got %q", lines[2])
+	}
+
+	buf.Reset()
+	n, err = exporter.NDJSON(ctx, db, &buf, query, 0)
+	maybePanic(err)
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if n != 2 || len(lines) != 2 || !strings.Contains(lines[1], `"name":null`) || strings.Contains(lines[0], "john") {
+		t.Errorf("bad ndjson %q", buf.String())
+	}
+
+	buf.Reset()
+	_, err = exporter.Column("email", dbq.AnonymizeNull).CSV(ctx, db, &buf, query, 0)
+	if err == nil || !strings.Contains(err.Error(), "email") || buf.Len() != 0 {
+		t.Errorf("missing anonymized column should fail export, got %v %q", err, buf.String())
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import "strings"
+
+// First loads the first row of query ordered by orderBy, e.g.
+//
+//	login, err := dbq.First(ctx, "SELECT id, at FROM logins WHERE user_id = ?", "at", binder, userID)
+//
+// NotFoundError is returned when query returns no rows.
+func First[T any](ctx TxContext, query, orderBy string, binder func(*T) []any, args ...any) (T, error) {
+	return QueryRow(ctx, limitOne(ctx, query, orderBy), binder, args...)
+}
+
+// Last loads the last row of query ordered by orderBy, ordering is reversed
+// so only single row is read, e.g. "at" becomes "at DESC" and
+// "at DESC, id" becomes "at ASC, id DESC".
+func Last[T any](ctx TxContext, query, orderBy string, binder func(*T) []any, args ...any) (T, error) {
+	return QueryRow(ctx, limitOne(ctx, query, reverseOrder(orderBy)), binder, args...)
+}
+
+func limitOne(ctx TxContext, query, orderBy string) string {
+	query += " ORDER BY " + orderBy
+	if d, _ := DialectFromCtx(ctx); d == SQLServer {
+		return query + " OFFSET 0 ROWS FETCH NEXT 1 ROWS ONLY"
+	}
+	return query + " LIMIT 1"
+}
+
+// reverseOrder reverses direction of ORDER BY terms.
+func reverseOrder(orderBy string) string {
+	terms := splitTerms(orderBy)
+	for i, term := range terms {
+		words := strings.Fields(term)
+		nulls := ""
+		if n := len(words); n >= 2 && strings.EqualFold(words[n-2], "NULLS") {
+			if strings.EqualFold(words[n-1], "FIRST") {
+				nulls = " NULLS LAST"
+			} else {
+				nulls = " NULLS FIRST"
+			}
+			words = words[:n-2]
+		}
+		dir := " DESC"
+		if n := len(words); n > 1 {
+			switch strings.ToUpper(words[n-1]) {
+			case "DESC":
+				dir = " ASC"
+				words = words[:n-1]
+			case "ASC":
+				words = words[:n-1]
+			}
+		}
+		terms[i] = strings.Join(words, " ") + dir + nulls
+	}
+	return strings.Join(terms, ", ")
+}
+
+// splitTerms splits ORDER BY clause at commas outside of parentheses and
+// literals, so expressions like COALESCE(a, b) stay single term.
+func splitTerms(orderBy string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i := 0; i < len(orderBy); i++ {
+		if j := skipQuoted(orderBy, i); j > i {
+			i = j - 1
+			continue
+		}
+		switch orderBy[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, orderBy[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, orderBy[start:])
+}
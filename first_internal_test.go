@@ -0,0 +1,24 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import "testing"
+
+func TestReverseOrder(t *testing.T) {
+	tests := map[string]string{
+		"at":                        "at DESC",
+		"at DESC, id":               "at ASC, id DESC",
+		"lower(name) asc":           "lower(name) DESC",
+		"score DESC NULLS LAST, id": "score ASC NULLS FIRST, id DESC",
+		"created_at nulls first":    "created_at DESC NULLS LAST",
+		"COALESCE(a, b) DESC, id":   "COALESCE(a, b) ASC, id DESC",
+		"concat(a, ',') asc":        "concat(a, ',') DESC",
+	}
+	for in, want := range tests {
+		if got := reverseOrder(in); got != want {
+			t.Errorf("reverseOrder(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestFirstLast(t *testing.T) {
+	const query = "SELECT id, name, created FROM users WHERE id > ?"
+	rows := usersRecording().Entries[0].Rows
+	cols := []string{"id", "name", "created"}
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{Query: query + " ORDER BY created LIMIT 1", Args: []dbq.RecordedValue{{V: int64(0)}}, Columns: cols, Rows: rows[:1]},
+		{Query: query + " ORDER BY created DESC LIMIT 1", Args: []dbq.RecordedValue{{V: int64(0)}}, Columns: cols, Rows: rows[1:]},
+		{Query: query + " ORDER BY created DESC LIMIT 1", Args: []dbq.RecordedValue{{V: int64(5)}}, Columns: cols},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		first, err := dbq.First(tx, query, "created", userBinder, 0)
+		if err != nil || first.ID != 1 {
+			t.Errorf("bad first %v %v", first, err)
+		}
+		last, err := dbq.Last(tx, query, "created", userBinder, 0)
+		if err != nil || last.ID != 2 {
+			t.Errorf("bad last %v %v", last, err)
+		}
+		if _, err = dbq.Last(tx, query, "created", userBinder, 5); !errors.Is(err, dbq.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		return nil
+	})
+}
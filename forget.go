@@ -0,0 +1,214 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ForgetAction is how personal data of table is removed.
+type ForgetAction int
+
+// Forget actions.
+const (
+	// ForgetDelete deletes rows of subject.
+	ForgetDelete ForgetAction = iota
+	// ForgetNullify sets columns of subject rows to null.
+	ForgetNullify
+	// ForgetHash replaces columns of subject rows with salted hash of
+	// subject key and column name, rows stay joinable by pseudonym.
+	ForgetHash
+)
+
+func (a ForgetAction) String() string {
+	switch a {
+	case ForgetNullify:
+		return "nullify"
+	case ForgetHash:
+		return "hash"
+	case ForgetDelete:
+	}
+	return "delete"
+}
+
+// DefaultForgetAudit is default audit table of Forgetter.
+const DefaultForgetAudit = "dbq_forget_audit"
+
+type forgetRule struct {
+	table   string
+	key     string
+	action  ForgetAction
+	columns []string
+}
+
+// ForgetResult is outcome of Forget.
+type ForgetResult struct {
+	// Subject is salted hash of subject key stored in audit record.
+	Subject string
+	// Affected is number of affected rows per table, in rule order.
+	Affected []TableRows
+}
+
+// TableRows is number of rows affected in table.
+type TableRows struct {
+	Table  string
+	Action ForgetAction
+	Rows   int64
+}
+
+// Forgetter removes personal data of subject (GDPR right to erasure) from
+// related tables in one transaction and writes audit record. Rules run in
+// registration order so child tables should be registered before parents,
+// e.g.
+//
+//	forgetter := dbq.NewForgetter(provider, salt).
+//		Hash("orders", "user_id", "email").
+//		Nullify("comments", "user_id", "author_name").
+//		Delete("users", "id")
+//	res, err := forgetter.Forget(ctx, userID)
+//
+// Audit table needs columns subject, tables and forgotten_at.
+type Forgetter struct {
+	provider  *TxProvider
+	hash      Anonymizer
+	audit     string
+	auditMeta string
+	rules     []forgetRule
+	// err is error of invalid rule reported by Forget.
+	err error
+}
+
+// NewForgetter creates forgetter running in transactions of provider, salt
+// is used for pseudonyms and subject hash in audit record.
+func NewForgetter(provider *TxProvider, salt string) *Forgetter {
+	return &Forgetter{
+		provider: provider,
+		hash:     AnonymizeHash(salt),
+		audit:    DefaultForgetAudit,
+	}
+}
+
+// Audit sets audit table.
+func (f *Forgetter) Audit(table string) *Forgetter {
+	f.audit = table
+	return f
+}
+
+// AuditMeta sets column of audit table storing request metadata of Forget
+// context as JSON, see Meta.
+func (f *Forgetter) AuditMeta(column string) *Forgetter {
+	f.auditMeta = column
+	return f
+}
+
+// Delete registers table whose rows with subject key in column key are
+// deleted.
+func (f *Forgetter) Delete(table, key string) *Forgetter {
+	return f.rule(table, key, ForgetDelete, nil)
+}
+
+// Nullify registers columns of table set to null for subject.
+func (f *Forgetter) Nullify(table, key string, columns ...string) *Forgetter {
+	return f.rule(table, key, ForgetNullify, columns)
+}
+
+// Hash registers columns of table replaced with pseudonym for subject.
+func (f *Forgetter) Hash(table, key string, columns ...string) *Forgetter {
+	return f.rule(table, key, ForgetHash, columns)
+}
+
+func (f *Forgetter) rule(table, key string, action ForgetAction, columns []string) *Forgetter {
+	if action != ForgetDelete && len(columns) == 0 && f.err == nil {
+		f.err = fmt.Errorf("dbq: %s rule of %s has no columns", action, table)
+	}
+	f.rules = append(f.rules, forgetRule{
+		table:   table,
+		key:     key,
+		action:  action,
+		columns: columns,
+	})
+	return f
+}
+
+// Forget removes personal data of subject with all rules and audit record
+// in one transaction, nothing is removed if any statement fails. Error is
+// returned without running any statement when subject is nil or Nullify or
+// Hash rule has no columns.
+func (f *Forgetter) Forget(ctx context.Context, subject any) (ForgetResult, error) {
+	if f.err != nil {
+		return ForgetResult{}, f.err
+	}
+	hashed, ok := f.hash(subject).(string)
+	if !ok {
+		return ForgetResult{}, errors.New("dbq: subject of Forget is nil")
+	}
+	var res ForgetResult
+	err := f.provider.Tx(ctx, func(tx TxContext) error {
+		res = ForgetResult{Subject: hashed}
+		tables := make([]string, 0, len(f.rules))
+		for _, r := range f.rules {
+			query, args := f.statement(r, subject)
+			result, err := tx.Exec(query, args...)
+			if err != nil {
+				return err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			res.Affected = append(res.Affected, TableRows{Table: r.table, Action: r.action, Rows: n})
+			tables = append(tables, r.table+":"+r.action.String()+":"+strconv.FormatInt(n, 10))
+		}
+		if f.auditMeta == "" {
+			_, err := tx.Exec("INSERT INTO "+f.audit+" (subject, tables, forgotten_at) VALUES (?, ?, CURRENT_TIMESTAMP)",
+				res.Subject, strings.Join(tables, ","))
+			return err
+		}
+		m := MetaFromCtx(tx)
+		if m == nil {
+			m = Metadata{}
+		}
+		meta, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO "+f.audit+" (subject, tables, "+f.auditMeta+", forgotten_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)",
+			res.Subject, strings.Join(tables, ","), string(meta))
+		return err
+	})
+	if err != nil {
+		return ForgetResult{}, err
+	}
+	return res, nil
+}
+
+func (f *Forgetter) statement(r forgetRule, subject any) (string, []any) {
+	if r.action == ForgetDelete {
+		return "DELETE FROM " + r.table + " WHERE " + r.key + " = ?", []any{subject}
+	}
+	var (
+		b    strings.Builder
+		args []any
+	)
+	b.WriteString("UPDATE " + r.table + " SET ")
+	for i, col := range r.columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if r.action == ForgetNullify {
+			b.WriteString(col + " = NULL")
+			continue
+		}
+		b.WriteString(col + " = ?")
+		args = append(args, f.hash(exportString(subject)+"/"+col))
+	}
+	b.WriteString(" WHERE " + r.key + " = ?")
+	return b.String(), append(args, subject)
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestForget(t *testing.T) {
+	hash := dbq.AnonymizeHash("salt")
+	subject := hash(int64(7)).(string)
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:        "UPDATE orders SET email = ? WHERE user_id = ?",
+			Args:         []dbq.RecordedValue{{V: hash("7/email")}, {V: int64(7)}},
+			RowsAffected: 3,
+		},
+		{
+			Query:        "UPDATE comments SET author = NULL, ip = NULL WHERE user_id = ?",
+			Args:         []dbq.RecordedValue{{V: int64(7)}},
+			RowsAffected: 2,
+		},
+		{
+			Query:        "DELETE FROM users WHERE id = ?",
+			Args:         []dbq.RecordedValue{{V: int64(7)}},
+			RowsAffected: 1,
+		},
+		{
+			Query: "INSERT INTO audit (subject, tables, forgotten_at) VALUES (?, ?, CURRENT_TIMESTAMP)",
+			Args: []dbq.RecordedValue{
+				{V: subject},
+				{V: "orders:hash:3,comments:nullify:2,users:delete:1"},
+			},
+		},
+	}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	forgetter := dbq.NewForgetter(dbq.NewTxProvider(db), "salt").
+		Audit("audit").
+		Hash("orders", "user_id", "email").
+		Nullify("comments", "user_id", "author", "ip").
+		Delete("users", "id")
+	res, err := forgetter.Forget(context.Background(), int64(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Subject != subject || len(res.Affected) != 3 || res.Affected[0].Rows != 3 ||
+		res.Affected[2].Action != dbq.ForgetDelete {
+		t.Errorf("bad result %+v", res)
+	}
+}
+
+func TestForgetRollback(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query: "DELETE FROM users WHERE id = ?",
+			Args:  []dbq.RecordedValue{{V: int64(7)}},
+			Err:   "locked",
+		},
+	}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	res, err := dbq.NewForgetter(dbq.NewTxProvider(db), "salt").
+		Delete("users", "id").
+		Forget(context.Background(), int64(7))
+	if err == nil || res.Subject != "" {
+		t.Errorf("forget should fail, got %+v %v", res, err)
+	}
+}
+
+func TestForgetInvalid(t *testing.T) {
+	replayer := dbq.NewReplayer(&dbq.Recording{})
+	db := sql.OpenDB(replayer)
+	defer db.Close()
+	provider := dbq.NewTxProvider(db)
+
+	if _, err := dbq.NewForgetter(provider, "salt").Hash("orders", "user_id").
+		Forget(context.Background(), int64(7)); err == nil {
+		t.Error("hash rule without columns should fail")
+	}
+	if _, err := dbq.NewForgetter(provider, "salt").Nullify("comments", "user_id").
+		Forget(context.Background(), int64(7)); err == nil {
+		t.Error("nullify rule without columns should fail")
+	}
+	if _, err := dbq.NewForgetter(provider, "salt").Delete("users", "id").
+		Forget(context.Background(), nil); err == nil {
+		t.Error("nil subject should fail")
+	}
+}
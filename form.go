@@ -0,0 +1,145 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// FormTagName is struct tag naming form field bound by BindForm, TagName
+// tag or snake case field name is used when it is absent.
+const FormTagName = "form"
+
+// FieldError is error of single form field.
+type FieldError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: invalid value %q: %v", e.Field, e.Value, e.Err)
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// BindErrors are errors of all invalid form fields.
+type BindErrors []FieldError
+
+func (e BindErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "dbq: bind form: " + strings.Join(msgs, "; ")
+}
+
+// BindForm populates fields of struct pointed by dst from HTTP form or
+// query values, e.g. filter structs feeding the query builder:
+//
+//	type UserFilter struct {
+//		Name   dbq.Null[string]    `form:"name"`
+//		Since  dbq.Null[time.Time] `form:"since"`
+//		Status []string            `form:"status"`
+//	}
+//	var filter UserFilter
+//	err := dbq.BindForm(&filter, r.URL.Query())
+//
+// Fields without values are left as they are. Empty value sets Null to
+// null and Optional to explicit null, it is ignored for other types.
+// Values are coerced like scanned columns, slices collect all values of
+// field. All invalid fields are reported in BindErrors.
+func BindForm(dst any, values url.Values) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("dbq: bind form destination must be pointer to struct")
+	}
+	var errs BindErrors
+	bindStruct(v.Elem(), values, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func bindStruct(v reflect.Value, values url.Values, errs *BindErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := formName(f)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		if f.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			bindStruct(fv, values, errs)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(f.Name)
+		}
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			elems := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+			failed := false
+			for j, s := range vals {
+				if err := bindValue(elems.Index(j), s); err != nil {
+					*errs = append(*errs, FieldError{Field: name, Value: s, Err: err})
+					failed = true
+				}
+			}
+			if !failed {
+				fv.Set(elems)
+			}
+			continue
+		}
+		if err := bindValue(fv, vals[0]); err != nil {
+			*errs = append(*errs, FieldError{Field: name, Value: vals[0], Err: err})
+		}
+	}
+}
+
+// formName returns form field name of struct field, false for skipped
+// fields.
+func formName(f reflect.StructField) (string, bool) {
+	tag, ok := f.Tag.Lookup(FormTagName)
+	if !ok {
+		tag = f.Tag.Get(TagName)
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, name != "-"
+}
+
+// bindValue sets v from form value s.
+func bindValue(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if s == "" {
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := bindValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	return convertAssign(v.Addr().Interface(), s)
+}
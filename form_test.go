@@ -0,0 +1,91 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type userFilter struct {
+	Name    dbq.Null[string]
+	MinID   dbq.Optional[int64] `form:"min_id"`
+	MaxID   dbq.Optional[int64] `form:"max_id"`
+	Since   dbq.Null[time.Time] `db:"created_after"`
+	Limit   int
+	Active  *bool
+	Status  []string
+	Ignored string `form:"-"`
+}
+
+func TestBindForm(t *testing.T) {
+	values := url.Values{
+		"name":          {"john"},
+		"min_id":        {"10"},
+		"max_id":        {""},
+		"created_after": {"2022-05-01T10:00:00Z"},
+		"limit":         {"20"},
+		"active":        {"true"},
+		"status":        {"new", "open"},
+		"ignored":       {"x"},
+	}
+	var f userFilter
+	maybePanic(dbq.BindForm(&f, values))
+
+	if !f.Name.Equal(dbq.FromValue("john")) {
+		t.Errorf("bad name %v", f.Name)
+	}
+	if !f.MinID.Set || !f.MinID.Valid || f.MinID.Val != 10 {
+		t.Errorf("bad min_id %v", f.MinID)
+	}
+	if !f.MaxID.Set || f.MaxID.Valid {
+		t.Errorf("empty max_id should be explicit null, got %v", f.MaxID)
+	}
+	if !f.Since.Valid || !f.Since.Val.Equal(time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("bad since %v", f.Since)
+	}
+	if f.Limit != 20 || f.Active == nil || !*f.Active {
+		t.Errorf("bad limit %d or active %v", f.Limit, f.Active)
+	}
+	if len(f.Status) != 2 || f.Status[1] != "open" || f.Ignored != "" {
+		t.Errorf("bad status %v or ignored %q", f.Status, f.Ignored)
+	}
+
+	var absent userFilter
+	maybePanic(dbq.BindForm(&absent, url.Values{}))
+	if absent.MinID.Set || absent.Name.Valid {
+		t.Error("absent fields should be left untouched")
+	}
+}
+
+func TestBindFormErrors(t *testing.T) {
+	var f userFilter
+	err := dbq.BindForm(&f, url.Values{
+		"name":   {"john"},
+		"min_id": {"ten"},
+		"limit":  {"many"},
+	})
+	var errs dbq.BindErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected two field errors, got %v", err)
+	}
+	if errs[0].Field != "min_id" || errs[1].Field != "limit" {
+		t.Errorf("bad fields %v", errs)
+	}
+	if errs[1].Value != "many" || errs[1].Err == nil {
+		t.Errorf("bad field error %v", errs[1])
+	}
+	if !f.Name.Valid {
+		t.Error("valid fields should be bound despite errors")
+	}
+
+	if err = dbq.BindForm(f, nil); err == nil {
+		t.Error("expected error for non pointer destination")
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Lookup is result of GetMany for single key.
+type Lookup[K comparable, T any] struct {
+	Key K
+	Val T
+	// Found is false when there is no row with Key.
+	Found bool
+}
+
+// GetMany loads rows of table by keys in keyColumn. Keys found in cache are
+// not queried, misses are loaded with IN queries chunked by parameter limit
+// of dialect and cached after commit of transaction. Results are returned
+// in order of keys with Found marking missing rows, e.g.
+//
+//	res, err := dbq.GetMany[User](ctx, cache, "users", "id", []int64{3, 1, 7})
+//
+// Columns are mapped by TagName tags of T like in QueryStruct, field of
+// keyColumn must be of type K. Cache can be nil. Cache holds unmasked rows,
+// values are masked for role of ctx on every read, also on cache hits.
+func GetMany[T any, K comparable](ctx TxContext, c Cache[K, T], table, keyColumn string, keys []K) ([]Lookup[K, T], error) {
+	m, err := structMapOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	i, ok := m.byColumn[keyColumn]
+	if !ok {
+		return nil, fmt.Errorf("dbq: column %s is not mapped", keyColumn)
+	}
+	keyField := m.fields[i]
+
+	results := make([]Lookup[K, T], len(keys))
+	var misses []K
+	missing := make(map[K]bool)
+	for i, key := range keys {
+		results[i].Key = key
+		if c != nil {
+			if val, ok := c.Get(key); ok {
+				results[i].Val, results[i].Found = val, true
+				continue
+			}
+		}
+		if !missing[key] {
+			missing[key] = true
+			misses = append(misses, key)
+		}
+	}
+	if len(misses) == 0 {
+		return maskLookups(ctx, results), nil
+	}
+
+	columns := make([]string, len(m.fields))
+	for i, f := range m.fields {
+		columns[i] = f.column
+	}
+	prefix := "SELECT " + strings.Join(columns, ", ") + " FROM " + table + " WHERE " + keyColumn + " IN ("
+
+	d, _ := DialectFromCtx(ctx)
+	limit := maxParams[d]
+	if limit == 0 {
+		limit = defaultMaxParams
+	}
+	loaded := make(map[K]T, len(misses))
+	for start := 0; start < len(misses); start += limit {
+		end := start + limit
+		if end > len(misses) {
+			end = len(misses)
+		}
+		args := make([]any, 0, end-start)
+		for _, key := range misses[start:end] {
+			args = append(args, key)
+		}
+		query := prefix + strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ") + ")"
+		rows, err := queryUnmasked[T](ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			fv, ok := fieldValue(reflect.ValueOf(&rows[i]).Elem(), keyField.index)
+			if !ok {
+				continue
+			}
+			key, ok := fv.Interface().(K)
+			if !ok {
+				return nil, fmt.Errorf("dbq: column %s is %v, not %T", keyColumn, fv.Type(), key)
+			}
+			loaded[key] = rows[i]
+			if c != nil {
+				WriteThrough(ctx, c, key, rows[i])
+			}
+		}
+	}
+
+	for i := range results {
+		if results[i].Found {
+			continue
+		}
+		results[i].Val, results[i].Found = loaded[results[i].Key]
+	}
+	return maskLookups(ctx, results), nil
+}
+
+// queryUnmasked runs query like QueryStruct without masking, so rows can be
+// cached for callers of any role.
+func queryUnmasked[T any](ctx TxContext, query string, args []any) ([]T, error) {
+	start := time.Now()
+	rows, err := ctx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	result, err := CollectStructs[T](rows)
+	if err != nil {
+		return nil, wrapError(OpQuery, query, start, err)
+	}
+	localize(ctx, query, result)
+	return result, nil
+}
+
+// maskLookups masks found values for role of ctx. Selected columns are
+// columns of T, so values are masked by their tags.
+func maskLookups[K comparable, T any](ctx TxContext, results []Lookup[K, T]) []Lookup[K, T] {
+	m, ok := ctx.Value(maskerKey{}).(*Masker)
+	if !ok {
+		return results
+	}
+	role, _ := RoleFromCtx(ctx)
+	for i := range results {
+		if results[i].Found {
+			m.Apply(role, &results[i].Val)
+		}
+	}
+	return results
+}
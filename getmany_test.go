@@ -0,0 +1,107 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type member struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestGetMany(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:   "SELECT id, name FROM members WHERE id IN (?, ?)",
+			Args:    []dbq.RecordedValue{{V: int64(3)}, {V: int64(7)}},
+			Columns: []string{"id", "name"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(3)}, {V: "jane"}}},
+		},
+	}}
+	var members dbq.Cache[int64, member] = dbq.NewMemoryCache[int64, member](0)
+	members.Set(1, member{ID: 1, Name: "john"})
+
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		res, err := dbq.GetMany(tx, members, "members", "id", []int64{3, 1, 7, 3})
+		if err != nil {
+			return err
+		}
+		want := []dbq.Lookup[int64, member]{
+			{Key: 3, Val: member{ID: 3, Name: "jane"}, Found: true},
+			{Key: 1, Val: member{ID: 1, Name: "john"}, Found: true},
+			{Key: 7},
+			{Key: 3, Val: member{ID: 3, Name: "jane"}, Found: true},
+		}
+		for i := range want {
+			if res[i] != want[i] {
+				t.Errorf("#%d: got %+v, want %+v", i, res[i], want[i])
+			}
+		}
+		if _, ok := members.Get(3); ok {
+			t.Error("loaded row should not be cached before commit")
+		}
+		return nil
+	})
+	if m, ok := members.Get(3); !ok || m.Name != "jane" {
+		t.Error("loaded row should be cached after commit")
+	}
+
+	// everything is cached, no query is run
+	replayTx(t, &dbq.Recording{}, func(tx dbq.TxContext) error {
+		res, err := dbq.GetMany(tx, members, "members", "id", []int64{1, 3})
+		if err != nil || !res[0].Found || !res[1].Found {
+			t.Errorf("bad cached lookup %+v %v", res, err)
+		}
+		return nil
+	})
+}
+
+func TestGetManyMasked(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:   "SELECT id, name FROM members WHERE id IN (?)",
+			Args:    []dbq.RecordedValue{{V: int64(3)}},
+			Columns: []string{"id", "name"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(3)}, {V: "jane"}}},
+		},
+	}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	var members dbq.Cache[int64, member] = dbq.NewMemoryCache[int64, member](0)
+	members.Set(1, member{ID: 1, Name: "john"})
+	provider := dbq.NewTxProvider(db, dbq.Masking(dbq.NewMasker().Column("name", dbq.MaskRedact, "admin")))
+	for _, tt := range []struct {
+		role string
+		want string
+	}{
+		{"support", "***"},
+		{"admin", "jane"},
+	} {
+		ctx := dbq.WithRole(context.Background(), tt.role)
+		maybePanic(provider.Tx(ctx, func(tx dbq.TxContext) error {
+			res, err := dbq.GetMany(tx, members, "members", "id", []int64{1, 3})
+			if err != nil {
+				return err
+			}
+			if res[1].Val.Name != tt.want || (tt.role == "support") != (res[0].Val.Name == "***") {
+				t.Errorf("%s: bad masked lookups %+v", tt.role, res)
+			}
+			return nil
+		}))
+	}
+	if m, _ := members.Get(1); m.Name != "john" {
+		t.Errorf("cached row should not be masked, got %+v", m)
+	}
+	if m, _ := members.Get(3); m.Name != "jane" {
+		t.Errorf("loaded row should be cached unmasked, got %+v", m)
+	}
+}
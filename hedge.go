@@ -0,0 +1,180 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// isReadOnly returns true only for queries which are certainly read only,
+// every statement of query is SELECT or WITH query without data modifying
+// keywords or locking clauses. Malformed query isn't read only.
+func isReadOnly(query string) bool {
+	stmts, ok := splitStatements(query)
+	if !ok || len(stmts) == 0 {
+		return false
+	}
+	for _, stmt := range stmts {
+		if s := classify(stmt); s.action != ActionSelect || s.writes {
+			return false
+		}
+	}
+	return true
+}
+
+// Hedged is Access which hedges read queries: when query on one replica
+// doesn't finish within delay, the same query is issued on another replica
+// and the first successful result is used while the other query is
+// canceled. Only read only statements are hedged, all other statements
+// and prepared statements go to primary. Read only statement run with
+// ReadFromReplica goes to replica also when there is only one.
+type Hedged struct {
+	Access
+	replicas []Access
+	delay    time.Duration
+	next     uint32
+}
+
+// NewHedged creates hedged access with primary used for writes and
+// replicas used for hedged reads. Hedging needs at least two replicas.
+func NewHedged(primary Access, delay time.Duration, replicas ...Access) *Hedged {
+	return &Hedged{
+		Access:   primary,
+		replicas: replicas,
+		delay:    delay,
+	}
+}
+
+// route returns replicas query of ctx runs on in round-robin order, it
+// returns nil when query runs on primary.
+func (h *Hedged) route(ctx context.Context, query string) []Access {
+	n := len(h.replicas)
+	if n == 0 || (n == 1 && !ReadsFromReplica(ctx)) || !isReadOnly(query) {
+		return nil
+	}
+	return h.candidates()
+}
+
+// candidates returns replicas in round-robin order.
+func (h *Hedged) candidates() []Access {
+	n := len(h.replicas)
+	start := int(atomic.AddUint32(&h.next, 1)-1) % n
+	out := make([]Access, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, h.replicas[(start+i)%n])
+	}
+	return out
+}
+
+// QueryContext runs query, read only queries are hedged across replicas.
+func (h *Hedged) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	candidates := h.route(ctx, query)
+	if candidates == nil {
+		return h.Access.QueryContext(ctx, query, args...)
+	}
+	return hedge(ctx, h.delay, candidates, func(ctx context.Context, db Access) (*sql.Rows, error) {
+		return db.QueryContext(ctx, query, args...)
+	}, func(rows *sql.Rows) {
+		_ = rows.Close()
+	})
+}
+
+// QueryRowContext runs query, read only queries are hedged across replicas.
+func (h *Hedged) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	candidates := h.route(ctx, query)
+	if candidates == nil {
+		return h.Access.QueryRowContext(ctx, query, args...)
+	}
+	row, err := hedge(ctx, h.delay, candidates, func(ctx context.Context, db Access) (*sql.Row, error) {
+		row := db.QueryRowContext(ctx, query, args...)
+		return row, row.Err()
+	}, func(row *sql.Row) {
+		// Scan closes underlying rows.
+		_ = row.Scan()
+	})
+	if err != nil {
+		return errRow(err)
+	}
+	return row
+}
+
+type hedgeResult[R any] struct {
+	i   int
+	r   R
+	err error
+}
+
+// hedge runs call on candidates starting next one after delay or after
+// failure of previous one and returns the first successful result. Results
+// of other calls are canceled and released. Context of winning call is
+// released together with ctx since result may still use it.
+func hedge[R any](
+	ctx context.Context,
+	delay time.Duration,
+	candidates []Access,
+	call func(context.Context, Access) (R, error),
+	release func(R),
+) (R, error) {
+	results := make(chan hedgeResult[R], len(candidates))
+	cancels := make([]context.CancelFunc, 0, len(candidates))
+	launch := func() {
+		i := len(cancels)
+		actx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		go func() {
+			r, err := call(actx, candidates[i])
+			results <- hedgeResult[R]{i: i, r: r, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var (
+		zero    R
+		lastErr error
+	)
+	launch()
+	for pending := 1; pending > 0; {
+		select {
+		case <-timer.C:
+			if len(cancels) < len(candidates) {
+				launch()
+				pending++
+				timer.Reset(delay)
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				for i, cancel := range cancels {
+					if i != res.i {
+						cancel()
+					}
+				}
+				go drainHedge(results, pending, release)
+				return res.r, nil
+			}
+			cancels[res.i]()
+			lastErr = res.err
+			if len(cancels) < len(candidates) {
+				launch()
+				pending++
+			}
+		}
+	}
+	return zero, lastErr
+}
+
+// drainHedge releases results of losing calls.
+func drainHedge[R any](results <-chan hedgeResult[R], pending int, release func(R)) {
+	for ; pending > 0; pending-- {
+		if res := <-results; res.err == nil {
+			release(res.r)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+// slowAccess delays queries.
+type slowAccess struct {
+	dbq.Access
+	delay time.Duration
+}
+
+func (a slowAccess) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(a.delay):
+	}
+	return a.Access.QueryContext(ctx, query, args...)
+}
+
+func TestHedgedQuery(t *testing.T) {
+	slow := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer slow.Close()
+	fast := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer fast.Close()
+
+	primary := &nopAccess{}
+	hedged := dbq.NewHedged(primary, 10*time.Millisecond, slowAccess{slow, time.Second}, fast)
+
+	start := time.Now()
+	rows, err := hedged.QueryContext(context.Background(), "SELECT id, name, created FROM users WHERE id > ?", 0)
+	maybePanic(err)
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	maybePanic(rows.Close())
+
+	if n != 2 {
+		t.Errorf("expected 2 rows, got %d", n)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("hedged query took %v", elapsed)
+	}
+
+	_, _ = hedged.QueryContext(context.Background(), "SELECT id FROM users FOR UPDATE")
+	_, _ = hedged.QueryContext(context.Background(), "DELETE FROM users RETURNING id")
+	_, _ = hedged.QueryContext(context.Background(), "SELECT 1; DELETE FROM users")
+	if primary.calls != 3 {
+		t.Errorf("locking and write queries should go to primary, got %d calls", primary.calls)
+	}
+}
+
+func TestHedgedReadFromReplica(t *testing.T) {
+	replica := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer replica.Close()
+
+	primary := &nopAccess{}
+	access := dbq.Intercept(dbq.NewHedged(primary, 10*time.Millisecond, replica))
+
+	const query = "SELECT id, name, created FROM users WHERE id > ?"
+	_, _ = access.QueryContext(context.Background(), query, 0)
+	if primary.calls != 1 {
+		t.Errorf("query should go to primary with single replica, got %d calls", primary.calls)
+	}
+
+	rows, err := access.QueryContext(context.Background(), query, 0, dbq.ReadFromReplica())
+	maybePanic(err)
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	maybePanic(rows.Close())
+	if n != 2 || primary.calls != 1 {
+		t.Errorf("query should go to replica, got %d rows and %d primary calls", n, primary.calls)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Logger receives log lines, it is satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// QueryEvent describes statement passed to Hooks.
+type QueryEvent struct {
+	Op     Op
+	Query  string
+	Args   []any
+	Label  string
+	Caller string
+	// Meta is request metadata of context, see Meta.
+	Meta Metadata
+	// Duration, RowsAffected and Err are set after statement is executed.
+	// Duration of Query statements does not include reading of rows and
+	// RowsAffected is set only for Exec statements.
+	Duration     time.Duration
+	RowsAffected int64
+	Err          error
+}
+
+// Hooks are called around every statement of transactions and DB created
+// with WithHooks, e.g.
+//
+//	provider := dbq.NewTxProvider(db, dbq.WithHooks(dbq.Hooks{
+//		SlowThreshold: 200 * time.Millisecond,
+//		AfterQuery: func(ctx context.Context, e dbq.QueryEvent) {
+//			metrics.Observe(e.Label, e.Duration)
+//		},
+//	}))
+type Hooks struct {
+	// BeforeQuery is called before statement is executed, returned context
+	// is passed to execution and AfterQuery, nil keeps context.
+	BeforeQuery func(ctx context.Context, e QueryEvent) context.Context
+	// AfterQuery is called after statement is executed.
+	AfterQuery func(ctx context.Context, e QueryEvent)
+	// SlowThreshold logs statements which took at least threshold with
+	// Logger, zero disables logging of slow statements.
+	SlowThreshold time.Duration
+	// Logger logs slow statements, standard logger is used when nil.
+	Logger Logger
+}
+
+// WithHooks sets hooks of transactions.
+func WithHooks(h Hooks) ProviderOption {
+	return func(t *TxProvider) {
+		t.interceptors = append(t.interceptors, h.Interceptor())
+	}
+}
+
+// Interceptor returns interceptor calling hooks, it can be registered with
+// Use or Intercept.
+func (h Hooks) Interceptor() Interceptor {
+	logger := h.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			e := QueryEvent{
+				Op:     stmt.Op,
+				Query:  stmt.Query,
+				Args:   stmt.Args,
+				Label:  stmt.Options.Label,
+				Caller: stmt.Caller,
+				Meta:   MetaFromCtx(ctx),
+			}
+			if h.BeforeQuery != nil {
+				if hctx := h.BeforeQuery(ctx, e); hctx != nil {
+					ctx = hctx
+				}
+			}
+			start := time.Now()
+			out, err := next(ctx, stmt)
+			e.Duration, e.Err = time.Since(start), err
+			if err == nil && out.Result != nil {
+				e.RowsAffected, _ = out.Result.RowsAffected()
+			}
+			if h.AfterQuery != nil {
+				h.AfterQuery(ctx, e)
+			}
+			if h.SlowThreshold > 0 && e.Duration >= h.SlowThreshold {
+				logger.Printf("dbq: slow %s %q took %v (label: %s, caller: %s, meta: %v, err: %v)",
+					e.Op, e.Query, e.Duration, e.Label, e.Caller, e.Meta, e.Err)
+			}
+			return out, err
+		}
+	}
+}
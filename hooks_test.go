@@ -0,0 +1,60 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type hookKey struct{}
+
+type lines []string
+
+func (l *lines) Printf(format string, args ...any) {
+	*l = append(*l, fmt.Sprintf(format, args...))
+}
+
+func TestHooks(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries[1].RowsAffected = 1
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	var (
+		logged lines
+		events []dbq.QueryEvent
+	)
+	hooks := dbq.Hooks{
+		BeforeQuery: func(ctx context.Context, e dbq.QueryEvent) context.Context {
+			return context.WithValue(ctx, hookKey{}, e.Query)
+		},
+		AfterQuery: func(ctx context.Context, e dbq.QueryEvent) {
+			if ctx.Value(hookKey{}) != e.Query {
+				t.Error("context of BeforeQuery should be passed to AfterQuery")
+			}
+			events = append(events, e)
+		},
+		SlowThreshold: 1,
+		Logger:        &logged,
+	}
+	ctx := dbq.NewDB(context.Background(), db, dbq.WithHooks(hooks))
+	_, err := dbq.Query(ctx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+	maybePanic(err)
+	_, err = ctx.Exec("INSERT INTO users (name) VALUES (?)", "jane")
+	maybePanic(err)
+
+	if len(events) != 2 || events[0].Op != dbq.OpQuery || events[1].RowsAffected != 1 || events[1].Args[0] != "jane" {
+		t.Errorf("bad events %+v", events)
+	}
+	if len(logged) != 2 || !strings.Contains(logged[1], `slow exec "INSERT INTO users (name) VALUES (?)"`) {
+		t.Errorf("bad slow log %q", logged)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// IndexSpec describes Postgres index created with CreateIndexConcurrently.
+type IndexSpec struct {
+	Table string
+	Name  string
+	// Columns are indexed columns or expressions, e.g. "lower(email)".
+	Columns []string
+	Unique  bool
+	// Using is index method, e.g. "gin", default is btree.
+	Using string
+	// Where is predicate of partial index.
+	Where string
+
+	// Retries is number of times failed build is started again.
+	Retries int
+	// PollInterval is interval of progress reports, default is 10s.
+	PollInterval time.Duration
+	// OnProgress receives progress of build.
+	OnProgress func(IndexProgress)
+	// Logger logs progress and failed builds, standard logger is used
+	// when nil.
+	Logger Logger
+	// Metrics receives outcome and duration of each build as OpExec.
+	Metrics MetricsCollector
+}
+
+// SQL returns CREATE INDEX CONCURRENTLY statement of spec.
+func (s IndexSpec) SQL() string {
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if s.Unique {
+		b.WriteString("UNIQUE ")
+	}
+	b.WriteString("INDEX CONCURRENTLY ")
+	b.WriteString(s.Name)
+	b.WriteString(" ON ")
+	b.WriteString(s.Table)
+	if s.Using != "" {
+		b.WriteString(" USING ")
+		b.WriteString(s.Using)
+	}
+	b.WriteString(" (")
+	b.WriteString(strings.Join(s.Columns, ", "))
+	b.WriteString(")")
+	if s.Where != "" {
+		b.WriteString(" WHERE ")
+		b.WriteString(s.Where)
+	}
+	return b.String()
+}
+
+// IndexProgress is row of pg_stat_progress_create_index.
+type IndexProgress struct {
+	Index       string
+	Phase       string
+	BlocksDone  int64
+	BlocksTotal int64
+	TuplesDone  int64
+	TuplesTotal int64
+}
+
+// Percent returns progress of current phase, blocks are used while table
+// is scanned and tuples otherwise.
+func (p IndexProgress) Percent() float64 {
+	switch {
+	case p.BlocksTotal > 0:
+		return float64(p.BlocksDone) * 100 / float64(p.BlocksTotal)
+	case p.TuplesTotal > 0:
+		return float64(p.TuplesDone) * 100 / float64(p.TuplesTotal)
+	}
+	return 0
+}
+
+const (
+	// indexStateQuery returns validity of index in current schema and
+	// whether some session is building it.
+	indexStateQuery = `SELECT i.indisvalid,
+	EXISTS (SELECT 1 FROM pg_stat_progress_create_index p WHERE p.index_relid = c.oid)
+FROM pg_index i
+JOIN pg_class c ON c.oid = i.indexrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relname = $1 AND n.nspname = current_schema()`
+	indexProgressQuery = `SELECT phase, blocks_done, blocks_total, tuples_done, tuples_total
+FROM pg_stat_progress_create_index WHERE index_relid = $1::regclass`
+)
+
+// indexState is state of index in current schema.
+type indexState struct {
+	exists   bool
+	valid    bool
+	building bool
+}
+
+func stateOfIndex(ctx context.Context, db Access, name string) (indexState, error) {
+	s := indexState{exists: true}
+	err := db.QueryRowContext(ctx, indexStateQuery, name).Scan(&s.valid, &s.building)
+	if errors.Is(err, sql.ErrNoRows) {
+		return indexState{}, nil
+	}
+	return s, err
+}
+
+// CreateIndexConcurrently creates Postgres index without blocking writes
+// of table. Index of the same name in current schema is left as it is when
+// it is valid or being built by another session, invalid one, left by
+// build which failed or was interrupted, is dropped and built again.
+// Failed build is retried spec.Retries times and its invalid index is
+// dropped, so it doesn't slow down writes. Build of unique index which
+// failed on duplicate values (unique_violation) is not retried. Valid index is never dropped,
+// build which failed because index was created meanwhile succeeds.
+//
+// Statements can't run in transaction, so db should be *sql.DB. Progress
+// is polled on another connection of db every spec.PollInterval.
+func CreateIndexConcurrently(ctx context.Context, db Access, spec IndexSpec) error {
+	logger := spec.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	state, err := stateOfIndex(ctx, db, spec.Name)
+	switch {
+	case err != nil:
+		return fmt.Errorf("dbq: index %s: %w", spec.Name, err)
+	case state.valid:
+		return nil
+	case state.building:
+		return fmt.Errorf("%w: %s", ErrIndexBuilding, spec.Name)
+	case state.exists:
+		logger.Printf("dbq: index %s is invalid, rebuilding", spec.Name)
+		if err = dropIndex(ctx, db, spec.Name); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = buildIndex(ctx, db, spec, logger)
+		if err == nil {
+			return nil
+		}
+		logger.Printf("dbq: index %s: build failed: %v", spec.Name, err)
+		state, serr := stateOfIndex(context.Background(), db, spec.Name)
+		switch {
+		case serr != nil:
+			return fmt.Errorf("dbq: index %s: %w (state of index: %v)", spec.Name, err, serr)
+		case state.valid:
+			// index was created by another session.
+			return nil
+		case state.building:
+			return fmt.Errorf("%w: %s after build failed: %v", ErrIndexBuilding, spec.Name, err)
+		case state.exists:
+			if derr := dropIndex(context.Background(), db, spec.Name); derr != nil {
+				return fmt.Errorf("dbq: index %s: %w (drop of invalid index: %v)", spec.Name, err, derr)
+			}
+		}
+		if attempt >= spec.Retries || ctx.Err() != nil || matchesSentinel(err, ErrConflict) {
+			return fmt.Errorf("dbq: index %s: %w", spec.Name, err)
+		}
+	}
+}
+
+// ErrIndexBuilding is returned by CreateIndexConcurrently when index is
+// being built by another session.
+var ErrIndexBuilding = errors.New("dbq: index is being built by another session")
+
+// buildIndex runs CREATE INDEX CONCURRENTLY and polls its progress.
+func buildIndex(ctx context.Context, db Access, spec IndexSpec, logger Logger) error {
+	interval := spec.PollInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	done := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				pollIndex(ctx, db, spec, logger)
+			}
+		}
+	}()
+
+	start := time.Now()
+	_, err := db.ExecContext(ctx, spec.SQL())
+	close(done)
+	<-polled
+	if spec.Metrics != nil {
+		spec.Metrics.QueryDone(OpExec, queryOutcome(err), time.Since(start))
+	}
+	return err
+}
+
+// pollIndex reports progress of build, build which is not yet listed in
+// pg_stat_progress_create_index is not reported.
+func pollIndex(ctx context.Context, db Access, spec IndexSpec, logger Logger) {
+	p := IndexProgress{Index: spec.Name}
+	err := db.QueryRowContext(ctx, indexProgressQuery, spec.Name).
+		Scan(&p.Phase, &p.BlocksDone, &p.BlocksTotal, &p.TuplesDone, &p.TuplesTotal)
+	if err != nil {
+		return
+	}
+	logger.Printf("dbq: index %s: %s %.1f%%", spec.Name, p.Phase, p.Percent())
+	if spec.OnProgress != nil {
+		spec.OnProgress(p)
+	}
+}
+
+func dropIndex(ctx context.Context, db Access, name string) error {
+	_, err := db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+name)
+	return err
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestIndexSpecSQL(t *testing.T) {
+	spec := dbq.IndexSpec{
+		Table: "users", Name: "users_email_idx", Columns: []string{"lower(email)"},
+		Unique: true, Where: "deleted_at IS NULL",
+	}
+	want := "CREATE UNIQUE INDEX CONCURRENTLY users_email_idx ON users (lower(email)) WHERE deleted_at IS NULL"
+	if got := spec.SQL(); got != want {
+		t.Errorf("got %q", got)
+	}
+}
+
+const indexStateQuery = `SELECT i.indisvalid,
+	EXISTS (SELECT 1 FROM pg_stat_progress_create_index p WHERE p.index_relid = c.oid)
+FROM pg_index i
+JOIN pg_class c ON c.oid = i.indexrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relname = $1 AND n.nspname = current_schema()`
+
+func indexState(valid, building bool) dbq.RecordedEntry {
+	return dbq.RecordedEntry{
+		Query: indexStateQuery, Args: []dbq.RecordedValue{{V: "orders_customer_idx"}},
+		Columns: []string{"indisvalid", "exists"},
+		Rows:    [][]dbq.RecordedValue{{{V: valid}, {V: building}}},
+	}
+}
+
+func TestCreateIndexConcurrently(t *testing.T) {
+	const create = "CREATE INDEX CONCURRENTLY orders_customer_idx ON orders (customer_id)"
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		indexState(false, false),
+		{Query: "DROP INDEX CONCURRENTLY IF EXISTS orders_customer_idx"},
+		{Query: create, Err: "deadlock detected"},
+		indexState(false, false),
+		{Query: "DROP INDEX CONCURRENTLY IF EXISTS orders_customer_idx"},
+		{Query: create},
+	}}
+	replayer := dbq.NewReplayer(rec)
+	db := sql.OpenDB(replayer)
+	defer db.Close()
+
+	var logged lines
+	metrics := &fakeMetrics{}
+	err := dbq.CreateIndexConcurrently(context.Background(), db, dbq.IndexSpec{
+		Table: "orders", Name: "orders_customer_idx", Columns: []string{"customer_id"},
+		Retries: 1, PollInterval: time.Hour, Logger: &logged, Metrics: metrics,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayer.Remaining() != 0 {
+		t.Errorf("%d statements not executed", replayer.Remaining())
+	}
+	if !reflect.DeepEqual(metrics.queries, []string{"exec error", "exec ok"}) {
+		t.Errorf("got metrics %v", metrics.queries)
+	}
+	if len(logged) != 2 {
+		t.Errorf("got log %v", logged)
+	}
+}
+
+func TestCreateIndexConcurrentlyKeepsValid(t *testing.T) {
+	const create = "CREATE INDEX CONCURRENTLY orders_customer_idx ON orders (customer_id)"
+	spec := dbq.IndexSpec{
+		Table: "orders", Name: "orders_customer_idx", Columns: []string{"customer_id"},
+		PollInterval: time.Hour, Logger: &lines{},
+	}
+
+	// index built by another session is neither dropped nor built.
+	replayer := dbq.NewReplayer(&dbq.Recording{Entries: []dbq.RecordedEntry{indexState(false, true)}})
+	db := sql.OpenDB(replayer)
+	err := dbq.CreateIndexConcurrently(context.Background(), db, spec)
+	db.Close()
+	if !errors.Is(err, dbq.ErrIndexBuilding) {
+		t.Errorf("expected ErrIndexBuilding, got %v", err)
+	}
+
+	// index created meanwhile by another session is kept.
+	replayer = dbq.NewReplayer(&dbq.Recording{Entries: []dbq.RecordedEntry{
+		{Query: indexStateQuery, Args: []dbq.RecordedValue{{V: "orders_customer_idx"}}, Columns: []string{"indisvalid", "exists"}},
+		{Query: create, Err: `relation "orders_customer_idx" already exists`},
+		indexState(true, false),
+	}})
+	db = sql.OpenDB(replayer)
+	err = dbq.CreateIndexConcurrently(context.Background(), db, spec)
+	db.Close()
+	if err != nil || replayer.Remaining() != 0 {
+		t.Errorf("valid index should be kept, got %v with %d statements left", err, replayer.Remaining())
+	}
+}
+
+func TestCreateIndexConcurrentlyUniqueViolation(t *testing.T) {
+	const create = "CREATE UNIQUE INDEX CONCURRENTLY orders_number_idx ON orders (number)"
+	state := dbq.RecordedEntry{
+		Query: indexStateQuery, Args: []dbq.RecordedValue{{V: "orders_number_idx"}},
+		Columns: []string{"indisvalid", "exists"},
+		Rows:    [][]dbq.RecordedValue{{{V: false}, {V: false}}},
+	}
+	replayer := dbq.NewReplayer(&dbq.Recording{Entries: []dbq.RecordedEntry{
+		{Query: indexStateQuery, Args: []dbq.RecordedValue{{V: "orders_number_idx"}}, Columns: []string{"indisvalid", "exists"}},
+		{Query: create, Err: `could not create unique index "orders_number_idx" (SQLSTATE 23505)`},
+		state,
+		{Query: "DROP INDEX CONCURRENTLY IF EXISTS orders_number_idx"},
+	}})
+	db := sql.OpenDB(replayer)
+	defer db.Close()
+
+	// duplicate values fail every build, so it is not retried.
+	err := dbq.CreateIndexConcurrently(context.Background(), db, dbq.IndexSpec{
+		Table: "orders", Name: "orders_number_idx", Columns: []string{"number"}, Unique: true,
+		Retries: 2, PollInterval: time.Hour, Logger: &lines{},
+	})
+	if err == nil {
+		t.Error("expected error")
+	}
+	if replayer.Remaining() != 0 {
+		t.Errorf("%d statements not executed", replayer.Remaining())
+	}
+}
+
+func TestIndexProgressPercent(t *testing.T) {
+	p := dbq.IndexProgress{Phase: "building index: scanning table", BlocksDone: 25, BlocksTotal: 100}
+	if p.Percent() != 25 {
+		t.Errorf("got %v", p.Percent())
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Op is kind of database call.
+type Op int
+
+// Database calls passed through interceptors.
+const (
+	OpExec Op = iota + 1
+	OpQuery
+	OpQueryRow
+	OpPrepare
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpExec:
+		return "exec"
+	case OpQuery:
+		return "query"
+	case OpQueryRow:
+		return "query_row"
+	case OpPrepare:
+		return "prepare"
+	}
+	return "unknown"
+}
+
+// Statement is single database call passed through interceptor chain.
+// Interceptors may rewrite Query and Args before calling next handler.
+type Statement struct {
+	Op      Op
+	Query   string
+	Args    []any
+	Options QueryOptions
+	// Caller is file:line of the call site when enabled with CaptureCaller.
+	Caller string
+}
+
+// newStatement creates statement moving query options from args to Options.
+func newStatement(op Op, query string, args []any) *Statement {
+	args, opts := splitOptions(args)
+	if opts.Label == "" {
+		opts.Label = labelFromComment(query)
+	}
+	return &Statement{
+		Op:      op,
+		Query:   query,
+		Args:    args,
+		Options: opts,
+		Caller:  caller(),
+	}
+}
+
+// Outcome is result of executed Statement, only field matching Op is set.
+type Outcome struct {
+	Result sql.Result
+	Rows   *sql.Rows
+	Row    *sql.Row
+	Stmt   *sql.Stmt
+}
+
+// Handler executes statement.
+type Handler func(ctx context.Context, stmt *Statement) (Outcome, error)
+
+// Interceptor wraps next handler. It can rewrite statement, short-circuit
+// by returning without calling next or wrap execution of next.
+type Interceptor func(next Handler) Handler
+
+var (
+	interceptorsMu sync.RWMutex
+	interceptors   []Interceptor
+)
+
+// Use registers interceptors applied to every statement executed through
+// Tx and Intercept wrapped access. The first registered interceptor is the
+// outermost one.
+func Use(interceptor ...Interceptor) {
+	interceptorsMu.Lock()
+	defer interceptorsMu.Unlock()
+	interceptors = append(interceptors, interceptor...)
+}
+
+func registeredInterceptors() []Interceptor {
+	interceptorsMu.RLock()
+	defer interceptorsMu.RUnlock()
+	return interceptors
+}
+
+// accessHandler executes statement directly on db.
+func accessHandler(db Access) Handler {
+	return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+		if err := bindArgs(stmt); err != nil {
+			return Outcome{}, err
+		}
+		db := db
+		if c, ok := db.(*stmtLRU); ok && stmt.Options.NoCache {
+			db = c.db
+		}
+		if stmt.Options.ReadReplica {
+			ctx = context.WithValue(ctx, readReplicaKey{}, true)
+		}
+		if stmt.Options.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, stmt.Options.Timeout)
+			if stmt.Op == OpQuery || stmt.Op == OpQueryRow {
+				// rows are read after return, context is released
+				// when timeout expires.
+				time.AfterFunc(stmt.Options.Timeout, cancel)
+			} else {
+				defer cancel()
+			}
+		}
+		if stmt.Options.Prepare && stmt.Op != OpPrepare {
+			tx, inTx := ctx.Value(currentTxKey{}).(*Tx)
+			_, sqlTx := db.(*sql.Tx)
+			if stmt.Op == OpExec || inTx || !sqlTx {
+				prepared, err := db.PrepareContext(ctx, stmt.Query)
+				if err != nil {
+					return Outcome{}, err
+				}
+				if stmt.Op != OpExec && inTx {
+					// closing statement of transaction closes driver
+					// statement under open rows, it is closed with
+					// transaction.
+					tx.closeOnFinish(prepared)
+				} else {
+					// statement of DB is finalized by database/sql after
+					// its rows are closed.
+					defer prepared.Close()
+				}
+				return execute(ctx, stmtAccess{prepared}, stmt)
+			}
+			// query of bare *sql.Tx runs unprepared, its statement
+			// couldn't be closed after rows.
+		}
+		return execute(ctx, db, stmt)
+	}
+}
+
+// bindArgs converts arguments of statement to driver values.
+func bindArgs(stmt *Statement) error {
+	args, err := convertArgs(stmt.Args)
+	if err != nil {
+		return err
+	}
+	if stmt.Options.ZeroAsNull {
+		args = zeroAsNull(stmt.Query, args)
+	}
+	stmt.Args = args
+	return nil
+}
+
+func execute(ctx context.Context, db Access, stmt *Statement) (Outcome, error) {
+	var (
+		out Outcome
+		err error
+	)
+	switch stmt.Op {
+	case OpExec:
+		out.Result, err = db.ExecContext(ctx, stmt.Query, stmt.Args...)
+	case OpQuery:
+		out.Rows, err = db.QueryContext(ctx, stmt.Query, stmt.Args...)
+	case OpQueryRow:
+		out.Row = db.QueryRowContext(ctx, stmt.Query, stmt.Args...)
+	case OpPrepare:
+		out.Stmt, err = db.PrepareContext(ctx, stmt.Query)
+	default:
+		err = errors.New("dbq: unknown statement operation")
+	}
+	return out, err
+}
+
+// stmtAccess runs statements using prepared statement.
+type stmtAccess struct {
+	stmt *sql.Stmt
+}
+
+func (a stmtAccess) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return a.stmt, nil
+}
+
+func (a stmtAccess) ExecContext(ctx context.Context, _ string, args ...any) (sql.Result, error) {
+	return a.stmt.ExecContext(ctx, args...)
+}
+
+func (a stmtAccess) QueryContext(ctx context.Context, _ string, args ...any) (*sql.Rows, error) {
+	return a.stmt.QueryContext(ctx, args...)
+}
+
+func (a stmtAccess) QueryRowContext(ctx context.Context, _ string, args ...any) *sql.Row {
+	return a.stmt.QueryRowContext(ctx, args...)
+}
+
+// chain builds handler running statements on db through global and local
+// interceptors, named parameters are bound before the first interceptor and
+// errors are wrapped in Error.
+func chain(db Access, local []Interceptor) Handler {
+	return wrapHandler(accessHandler(db), local)
+}
+
+// wrapHandler wraps h with global and local interceptors like chain.
+func wrapHandler(h Handler, local []Interceptor) Handler {
+	h = recordQueries(h)
+	for i := len(local) - 1; i >= 0; i-- {
+		h = local[i](h)
+	}
+	global := registeredInterceptors()
+	for i := len(global) - 1; i >= 0; i-- {
+		h = global[i](h)
+	}
+	return wrapErrors(namedParams(h))
+}
+
+// interceptable is TxContext running statements through interceptors.
+type interceptable interface {
+	// intercept wraps h with interceptors of context.
+	intercept(h Handler) Handler
+}
+
+func (t *Tx) intercept(h Handler) Handler {
+	return wrapHandler(h, t.interceptors)
+}
+
+func (d *DB) intercept(h Handler) Handler {
+	return wrapHandler(h, d.interceptors)
+}
+
+// Intercept wraps db so that its statements pass through interceptors
+// registered with Use followed by given interceptors.
+func Intercept(db Access, interceptor ...Interceptor) Access {
+	return &intercepted{
+		db:           db,
+		interceptors: interceptor,
+	}
+}
+
+type intercepted struct {
+	db           Access
+	interceptors []Interceptor
+}
+
+func (a *intercepted) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	out, err := chain(a.db, a.interceptors)(ctx, newStatement(OpPrepare, query, nil))
+	return out.Stmt, err
+}
+
+func (a *intercepted) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	out, err := chain(a.db, a.interceptors)(ctx, newStatement(OpExec, query, args))
+	return out.Result, err
+}
+
+func (a *intercepted) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	out, err := chain(a.db, a.interceptors)(ctx, newStatement(OpQuery, query, args))
+	return out.Rows, err
+}
+
+func (a *intercepted) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return queryRow(chain(a.db, a.interceptors), ctx, query, args)
+}
+
+//nolint:revive
+func queryRow(h Handler, ctx context.Context, query string, args []any) *sql.Row {
+	out, err := h(ctx, newStatement(OpQueryRow, query, args))
+	if err == nil && out.Row == nil {
+		err = errors.New("dbq: interceptor returned no row")
+	}
+	if err != nil {
+		return errRow(err)
+	}
+	return out.Row
+}
+
+// errRow returns *sql.Row which reports err from Scan and Err, it is used
+// when interceptor short-circuits QueryRow with error.
+func errRow(err error) *sql.Row {
+	db := sql.OpenDB(errConnector{err: err})
+	defer db.Close()
+	return db.QueryRow("")
+}
+
+type errConnector struct {
+	err error
+}
+
+func (c errConnector) Connect(context.Context) (driver.Conn, error) {
+	return errConn(c), nil
+}
+
+func (c errConnector) Driver() driver.Driver {
+	return c
+}
+
+func (c errConnector) Open(string) (driver.Conn, error) {
+	return errConn(c), nil
+}
+
+type errConn struct {
+	err error
+}
+
+func (c errConn) Prepare(string) (driver.Stmt, error) {
+	return nil, c.err
+}
+
+func (c errConn) Close() error {
+	return nil
+}
+
+func (c errConn) Begin() (driver.Tx, error) {
+	return nil, c.err
+}
+
+func (c errConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return nil, c.err
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestInterceptRewrite(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer db.Close()
+
+	rewrite := func(next dbq.Handler) dbq.Handler {
+		return func(ctx context.Context, stmt *dbq.Statement) (dbq.Outcome, error) {
+			stmt.Query = strings.ReplaceAll(stmt.Query, "people", "users")
+			return next(ctx, stmt)
+		}
+	}
+
+	access := dbq.Intercept(db, rewrite)
+	rows, err := access.QueryContext(context.Background(), "SELECT id, name, created FROM people WHERE id > ?", 0)
+	maybePanic(err)
+	maybePanic(rows.Close())
+}
+
+func TestInterceptShortCircuit(t *testing.T) {
+	errDenied := errors.New("denied")
+	deny := func(next dbq.Handler) dbq.Handler {
+		return func(ctx context.Context, stmt *dbq.Statement) (dbq.Outcome, error) {
+			return dbq.Outcome{}, errDenied
+		}
+	}
+
+	inner := &nopAccess{}
+	access := dbq.Intercept(inner, deny)
+
+	var id int
+	if err := access.QueryRowContext(context.Background(), "SELECT 1").Scan(&id); !errors.Is(err, errDenied) {
+		t.Errorf("expected short-circuit error, got %v", err)
+	}
+	if _, err := access.ExecContext(context.Background(), "DELETE FROM t"); !errors.Is(err, errDenied) {
+		t.Errorf("expected short-circuit error, got %v", err)
+	}
+	if inner.calls != 0 {
+		t.Errorf("inner access should not be called, got %d calls", inner.calls)
+	}
+}
+
+func TestUse(t *testing.T) {
+	var inserts int32
+	dbq.Use(func(next dbq.Handler) dbq.Handler {
+		return func(ctx context.Context, stmt *dbq.Statement) (dbq.Outcome, error) {
+			if stmt.Op == dbq.OpExec && strings.HasPrefix(stmt.Query, "INSERT INTO users") {
+				atomic.AddInt32(&inserts, 1)
+			}
+			return next(ctx, stmt)
+		}
+	})
+
+	rec := usersRecording()
+	rec.Entries = rec.Entries[1:]
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	err := dbq.NewTxProvider(db).Tx(context.Background(), func(tx dbq.TxContext) error {
+		_, err := tx.Exec("INSERT INTO users (name) VALUES (?)", "jane")
+		return err
+	})
+	maybePanic(err)
+	if n := atomic.LoadInt32(&inserts); n != 1 {
+		t.Errorf("expected interceptor called once, got %d", n)
+	}
+}
+
+// closingConnector fails rows of statements closed before rows are read.
+type closingConnector struct {
+	*dbq.Replayer
+}
+
+func (c closingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Replayer.Connect(ctx)
+	return closingConn{conn}, err
+}
+
+type closingConn struct {
+	driver.Conn
+}
+
+func (c closingConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	return &closingStmt{Stmt: stmt}, err
+}
+
+type closingStmt struct {
+	driver.Stmt
+	closed bool
+}
+
+func (s *closingStmt) Close() error {
+	s.closed = true
+	return s.Stmt.Close()
+}
+
+func (s *closingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.Stmt.Query(args) //nolint:staticcheck
+	return closingRows{Rows: rows, stmt: s}, err
+}
+
+type closingRows struct {
+	driver.Rows
+	stmt *closingStmt
+}
+
+func (r closingRows) Next(dest []driver.Value) error {
+	if r.stmt.closed {
+		return errors.New("statement closed")
+	}
+	return r.Rows.Next(dest)
+}
+
+func TestPreparedQueryInTx(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = rec.Entries[:1]
+	db := sql.OpenDB(closingConnector{dbq.NewReplayer(rec)})
+	defer db.Close()
+
+	provider := dbq.NewTxProvider(db)
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		users, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0, dbq.Prepared())
+		if len(users) != 2 {
+			t.Errorf("expected 2 users, got %d", len(users))
+		}
+		return err
+	}))
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Join is row of join query mapped into two structs.
+type Join[A, B any] struct {
+	A A
+	B B
+}
+
+// QueryJoin runs join query whose columns are prefixed per table, e.g.
+//
+//	SELECT u.id AS u_id, u.name AS u_name, o.id AS o_id, o.total AS o_total
+//	FROM users u JOIN orders o ON o.user_id = u.id
+//
+// and maps columns with the first prefix into A and with the second prefix
+// into B, prefixes would be [2]string{"u_", "o_"} for query above.
+func QueryJoin[A, B any](ctx TxContext, query string, prefixes [2]string, args ...any) ([]Join[A, B], error) {
+	mapA, err := structMapOf(reflect.TypeOf((*A)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	mapB, err := structMapOf(reflect.TypeOf((*B)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	sqlRows, err := ctx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	rows := maskRows(ctx, query, sqlRows, prefixes[:]...)
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, closeRows(rows, err)
+	}
+
+	type target struct {
+		b     bool // field of B
+		index []int
+	}
+	targets := make([]target, len(cols))
+	for i, col := range cols {
+		var (
+			f  field
+			ok bool
+		)
+		switch {
+		case strings.HasPrefix(col, prefixes[0]):
+			f, ok = mapA.lookup(strings.TrimPrefix(col, prefixes[0]))
+		case strings.HasPrefix(col, prefixes[1]):
+			f, ok = mapB.lookup(strings.TrimPrefix(col, prefixes[1]))
+			targets[i].b = true
+		}
+		if !ok {
+			return nil, closeRows(rows, fmt.Errorf("dbq: no field mapped to column %q", col))
+		}
+		targets[i].index = f.index
+	}
+
+	var results []Join[A, B]
+	dests := make([]any, len(cols))
+	for rows.Next() {
+		var result Join[A, B]
+		va := reflect.ValueOf(&result.A).Elem()
+		vb := reflect.ValueOf(&result.B).Elem()
+		for i, t := range targets {
+			v := va
+			if t.b {
+				v = vb
+			}
+			dests[i] = fieldByIndex(v, t.index).Addr().Interface()
+		}
+		if err = rows.Scan(dests...); err != nil {
+			return nil, closeRows(rows, err)
+		}
+		results = append(results, result)
+	}
+	if err = closeRows(rows, nil); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
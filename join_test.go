@@ -0,0 +1,51 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type joinUser struct {
+	ID   int64
+	Name string
+}
+
+type joinOrder struct {
+	ID    int64   `db:"id"`
+	Total float64 `db:"total"`
+}
+
+func TestQueryJoin(t *testing.T) {
+	const query = "SELECT u.id u_id, u.name u_name, o.id o_id, o.total o_total FROM users u JOIN orders o ON o.user_id = u.id"
+	rec := &dbq.Recording{
+		Entries: []dbq.RecordedEntry{
+			{
+				Query:   query,
+				Columns: []string{"u_id", "u_name", "o_id", "o_total"},
+				Rows: [][]dbq.RecordedValue{
+					{{V: int64(1)}, {V: "john"}, {V: int64(10)}, {V: 9.5}},
+					{{V: int64(1)}, {V: "john"}, {V: int64(11)}, {V: 20.0}},
+				},
+			},
+		},
+	}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		rows, err := dbq.QueryJoin[joinUser, joinOrder](tx, query, [2]string{"u_", "o_"})
+		if err != nil {
+			return err
+		}
+		want := []dbq.Join[joinUser, joinOrder]{
+			{A: joinUser{ID: 1, Name: "john"}, B: joinOrder{ID: 10, Total: 9.5}},
+			{A: joinUser{ID: 1, Name: "john"}, B: joinOrder{ID: 11, Total: 20}},
+		}
+		if len(rows) != len(want) || rows[0] != want[0] || rows[1] != want[1] {
+			t.Errorf("bad join rows %v", rows)
+		}
+		return nil
+	})
+}
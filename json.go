@@ -0,0 +1,183 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON is JSON document column, e.g. Postgres json or jsonb, decoded into
+// typed value:
+//
+//	type Order struct {
+//		ID    int64                 `db:"id"`
+//		Items dbq.JSON[[]Item]      `db:"items"`
+//		Meta  dbq.NullJSON[Details] `db:"meta"`
+//	}
+//
+// Document is scanned from text, json, jsonb or bytea column and written
+// as text, which is accepted by all of them. NULL can't be scanned into
+// JSON, use NullJSON for nullable columns.
+type JSON[T any] struct {
+	Val T
+}
+
+// JSONOf creates JSON document of v.
+func JSONOf[T any](v T) JSON[T] {
+	return JSON[T]{Val: v}
+}
+
+// Scan implements the Scanner interface.
+func (j *JSON[T]) Scan(value any) error {
+	if value == nil {
+		return fmt.Errorf("converting NULL to %T is unsupported", j)
+	}
+	var zero T
+	j.Val = zero
+	return unmarshalDocument(value, &j.Val)
+}
+
+// Value implements the driver Valuer interface.
+func (j JSON[T]) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.Val)
+	if err != nil {
+		return nil, fmt.Errorf("json: couldn't marshal value: %w", err)
+	}
+	return string(data), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *JSON[T]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &j.Val)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (j JSON[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Val)
+}
+
+// NullJSON is nullable JSON document column. Both NULL and JSON null
+// document are scanned as invalid value, invalid value is written as NULL.
+type NullJSON[T any] struct {
+	Val   T
+	Valid bool // Valid is true if Val is not NULL
+}
+
+// NewNullJSON creates a new NullJSON[T].
+func NewNullJSON[T any](val T, valid bool) NullJSON[T] {
+	return NullJSON[T]{
+		Val:   val,
+		Valid: valid,
+	}
+}
+
+// Scan implements the Scanner interface.
+func (n *NullJSON[T]) Scan(value any) error {
+	var zero T
+	n.Val, n.Valid = zero, false
+	if value == nil {
+		return nil
+	}
+	if isJSONNull(value) {
+		return nil
+	}
+	if err := unmarshalDocument(value, &n.Val); err != nil {
+		n.Val = zero
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (n NullJSON[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return JSON[T]{Val: n.Val}.Value()
+}
+
+// ValueOrZero returns the inner value if valid, otherwise zero value.
+func (n NullJSON[T]) ValueOrZero() T {
+	var zero T
+	if !n.Valid {
+		return zero
+	}
+	return n.Val
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *NullJSON[T]) UnmarshalJSON(data []byte) error {
+	var zero T
+	if bytes.Equal(data, nullBytes) {
+		n.Val, n.Valid = zero, false
+		return nil
+	}
+	if err := json.Unmarshal(data, &n.Val); err != nil {
+		return fmt.Errorf("null: couldn't unmarshal JSON: %w", err)
+	}
+	n.Valid = true
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (n NullJSON[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Val)
+}
+
+// Ptr returns a pointer to this T value, or a nil pointer if Val is null.
+func (n NullJSON[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Val
+}
+
+// IsZero returns true for invalid value
+func (n NullJSON[T]) IsZero() bool {
+	return !n.Valid
+}
+
+func (n NullJSON[T]) patchValue() (any, bool) {
+	if !n.Valid {
+		return nil, false
+	}
+	return JSON[T]{Val: n.Val}, true
+}
+
+// documentBytes returns JSON document of scanned value.
+func documentBytes(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("unsupported Scan, storing driver.Value type %T into JSON document", value)
+}
+
+// unmarshalDocument decodes JSON document of scanned value into dest.
+func unmarshalDocument(value, dest any) error {
+	data, err := documentBytes(value)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("json: couldn't unmarshal document: %w", err)
+	}
+	return nil
+}
+
+// isJSONNull returns true for JSON null document.
+func isJSONNull(value any) bool {
+	data, err := documentBytes(value)
+	return err == nil && bytes.Equal(bytes.TrimSpace(data), nullBytes)
+}
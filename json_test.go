@@ -0,0 +1,69 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type item struct {
+	SKU string `json:"sku"`
+	Qty int    `json:"qty"`
+}
+
+func TestJSON(t *testing.T) {
+	var items dbq.JSON[[]item]
+	maybePanic(items.Scan([]byte(`[{"sku":"a","qty":2}]`)))
+	if !reflect.DeepEqual(items.Val, []item{{SKU: "a", Qty: 2}}) {
+		t.Errorf("bad items %v", items.Val)
+	}
+	v, err := items.Value()
+	maybePanic(err)
+	if v != `[{"sku":"a","qty":2}]` {
+		t.Errorf("bad value %v", v)
+	}
+	if err = items.Scan(nil); err == nil {
+		t.Error("NULL should not scan into JSON")
+	}
+	if err = items.Scan(`{"sku":`); err == nil {
+		t.Error("expected error for invalid document")
+	}
+
+	data, err := json.Marshal(dbq.JSONOf(map[string]int{"a": 1}))
+	maybePanic(err)
+	assertJSONEquals(t, data, `{"a":1}`, "json document")
+}
+
+func TestNullJSON(t *testing.T) {
+	var meta dbq.NullJSON[item]
+	maybePanic(meta.Scan(`{"sku":"b","qty":1}`))
+	if !meta.Valid || meta.Val.SKU != "b" {
+		t.Errorf("bad meta %v", meta)
+	}
+	for _, null := range []any{nil, "null", []byte(" null ")} {
+		maybePanic(meta.Scan(null))
+		if meta.Valid || meta.Val.SKU != "" {
+			t.Errorf("%v should scan as null, got %v", null, meta)
+		}
+	}
+	if v, err := meta.Value(); v != nil || err != nil {
+		t.Errorf("null should be written as NULL, got %v %v", v, err)
+	}
+	if v, _ := dbq.NewNullJSON(item{SKU: "c"}, true).Value(); v != `{"sku":"c","qty":0}` {
+		t.Errorf("bad value %v", v)
+	}
+
+	data, err := json.Marshal(meta)
+	maybePanic(err)
+	assertJSONEquals(t, data, "null", "null json document")
+	maybePanic(json.Unmarshal([]byte(`{"sku":"d","qty":3}`), &meta))
+	if !meta.Valid || meta.Val.Qty != 3 {
+		t.Errorf("bad unmarshaled meta %v", meta)
+	}
+}
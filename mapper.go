@@ -0,0 +1,236 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// TagName is struct tag used for column mapping, e.g.
+//
+//	type User struct {
+//		ID   int64  `db:"id"`
+//		Name string `db:"name"`
+//		Note string `db:"-"` // not mapped
+//	}
+//
+// Fields without tag are mapped to snake case of field name and fields of
+// embedded structs are mapped as fields of outer struct. Embedded pointers
+// to unexported struct types are not mapped.
+//
+// Columns filled by database, identity and generated columns, are marked
+// with tag option so they are left out of INSERT and UPDATE statements and
+// returned with RETURNING clause:
+//
+//	type Order struct {
+//		ID    int64   `db:"id,identity"`
+//		Price float64 `db:"price"`
+//		Total float64 `db:"total,generated"`
+//	}
+//
+// Columns marked with zeronull option are written as NULL by Insert and
+// Update when field has zero value, e.g. `db:"email,zeronull"`.
+//
+// Columns marked with notnull option are checked by ValidateForInsert,
+// e.g. `db:"name,notnull"`.
+const TagName = "db"
+
+// field is struct field mapped to column.
+type field struct {
+	column string
+	index  []int
+	// generated is true for identity and generated columns.
+	generated bool
+	// zeroNull is true when zero value is written as NULL.
+	zeroNull bool
+	// notNull is true for NOT NULL columns.
+	notNull bool
+}
+
+// structMap is column mapping of struct type.
+type structMap struct {
+	fields   []field
+	byColumn map[string]int
+}
+
+var structMaps sync.Map // map[reflect.Type]*structMap
+
+// structMapOf returns cached column mapping of struct type t.
+func structMapOf(t reflect.Type) (*structMap, error) {
+	if m, ok := structMaps.Load(t); ok {
+		return m.(*structMap), nil //nolint:forcetypeassert
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dbq: %v is not a struct", t)
+	}
+	m := &structMap{
+		byColumn: make(map[string]int),
+	}
+	var all []field
+	collectFields(t, nil, &all)
+	for _, f := range all {
+		if i, ok := m.byColumn[f.column]; ok {
+			// shallower fields shadow fields of embedded structs
+			if len(f.index) < len(m.fields[i].index) {
+				m.fields[i] = f
+			}
+			continue
+		}
+		m.byColumn[f.column] = len(m.fields)
+		m.fields = append(m.fields, f)
+	}
+	structMaps.Store(t, m)
+	return m, nil
+}
+
+// collectFields appends mapped fields of struct t in declaration order.
+func collectFields(t reflect.Type, index []int, fields *[]field) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get(TagName)
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			// pointer to unexported struct can't be allocated.
+			if f.Anonymous && !f.IsExported() {
+				continue
+			}
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			collectFields(ft, idx, fields)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(f.Name)
+		}
+		*fields = append(*fields, field{
+			column:    name,
+			index:     idx,
+			generated: hasTagOption(opts, "identity") || hasTagOption(opts, "generated"),
+			zeroNull:  hasTagOption(opts, "zeronull"),
+			notNull:   hasTagOption(opts, "notnull"),
+		})
+	}
+}
+
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if strings.TrimSpace(opt) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns field mapped to column.
+func (m *structMap) lookup(column string) (field, bool) {
+	i, ok := m.byColumn[column]
+	if !ok {
+		i, ok = m.byColumn[strings.ToLower(column)]
+	}
+	if !ok {
+		return field{}, false
+	}
+	return m.fields[i], true
+}
+
+// fieldByIndex returns struct field allocating nil embedded pointers.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// snakeCase converts Go field name to snake case, e.g. UserID to user_id.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// indexes returns field indexes of columns.
+func (m *structMap) indexes(cols []string) ([][]int, error) {
+	indexes := make([][]int, len(cols))
+	for i, col := range cols {
+		f, ok := m.lookup(col)
+		if !ok {
+			return nil, fmt.Errorf("dbq: no field mapped to column %q", col)
+		}
+		indexes[i] = f.index
+	}
+	return indexes, nil
+}
+
+// CollectStructs scans all rows into slice of structs mapping columns to
+// fields by TagName tags and closes rows. Every column must be mapped to
+// field.
+func CollectStructs[T any](rows Rows) ([]T, error) {
+	m, err := structMapOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, closeRows(rows, err)
+	}
+	indexes, err := m.indexes(cols)
+	if err != nil {
+		return nil, closeRows(rows, err)
+	}
+
+	var results []T
+	dests := make([]any, len(cols))
+	for rows.Next() {
+		var result T
+		v := reflect.ValueOf(&result).Elem()
+		for i, index := range indexes {
+			dests[i] = fieldByIndex(v, index).Addr().Interface()
+		}
+		if err = rows.Scan(dests...); err != nil {
+			return nil, closeRows(rows, err)
+		}
+		results = append(results, result)
+	}
+	if err = closeRows(rows, nil); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
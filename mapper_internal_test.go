@@ -0,0 +1,80 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"ID":         "id",
+		"UserID":     "user_id",
+		"Name":       "name",
+		"HTTPServer": "http_server",
+		"Address2":   "address2",
+		"CreatedAt":  "created_at",
+	}
+	for in, want := range tests {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+type mappedBase struct {
+	ID      int64
+	Created string `db:"created_at"`
+}
+
+type mappedUser struct {
+	mappedBase
+	Name    string
+	Ignored string `db:"-"`
+	Created string `db:"created_at"`
+	secret  string //nolint:unused
+}
+
+func TestStructMap(t *testing.T) {
+	m, err := structMapOf(reflect.TypeOf(mappedUser{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cols []string
+	for _, f := range m.fields {
+		cols = append(cols, f.column)
+	}
+	if !reflect.DeepEqual(cols, []string{"id", "created_at", "name"}) {
+		t.Errorf("bad columns %v", cols)
+	}
+	if f, _ := m.lookup("created_at"); !reflect.DeepEqual(f.index, []int{3}) {
+		t.Errorf("bad created_at index %v", f.index)
+	}
+	if _, ok := m.lookup("ignored"); ok {
+		t.Error("ignored field should not be mapped")
+	}
+
+	if _, err = structMapOf(reflect.TypeOf(1)); err == nil {
+		t.Error("err should be present for non struct type")
+	}
+}
+
+func TestStructMapIndexes(t *testing.T) {
+	m, err := structMapOf(reflect.TypeOf(mappedUser{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexes, err := m.indexes([]string{"ID", "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(indexes, [][]int{{0, 0}, {1}}) {
+		t.Errorf("bad indexes %v", indexes)
+	}
+	if _, err = m.indexes([]string{"ignored"}); err == nil {
+		t.Error("expected error for unmapped column")
+	}
+}
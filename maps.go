@@ -0,0 +1,65 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import "time"
+
+// QueryMaps runs query and returns each row as map of column names to
+// values, for admin tools, dynamic reports and debugging where no struct
+// exists, e.g.
+//
+//	rows, err := dbq.QueryMaps(ctx, "SELECT * FROM users WHERE id = ?", id)
+//	name := rows[0]["name"]
+//
+// Values are returned as driver provides them, except []byte which is
+// converted to string. NULL is nil. Masking and time zone policy of
+// transaction apply to map values like to struct fields.
+func QueryMaps(ctx TxContext, query string, args ...any) ([]map[string]any, error) {
+	start := time.Now()
+	rows, err := ctx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	result, err := CollectMaps(maskRows(ctx, query, rows))
+	if err != nil {
+		return nil, wrapError(OpQuery, query, start, err)
+	}
+	localize(ctx, query, result)
+	return result, nil
+}
+
+// CollectMaps scans all rows into maps of column names to values and
+// closes rows. When query returns duplicate column names the last one
+// wins.
+func CollectMaps(rows Rows) ([]map[string]any, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, closeRows(rows, err)
+	}
+	values := make([]any, len(cols))
+	dests := make([]any, len(cols))
+	for i := range dests {
+		dests[i] = &values[i]
+	}
+	var results []map[string]any
+	for rows.Next() {
+		if err = rows.Scan(dests...); err != nil {
+			return nil, closeRows(rows, err)
+		}
+		m := make(map[string]any, len(cols))
+		for i, col := range cols {
+			v := values[i]
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			m[col] = v
+		}
+		results = append(results, m)
+	}
+	if err = closeRows(rows, nil); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
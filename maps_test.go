@@ -0,0 +1,55 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestQueryMaps(t *testing.T) {
+	replayTx(t, usersRecording(), func(tx dbq.TxContext) error {
+		rows, err := dbq.QueryMaps(tx, "SELECT id, name, created FROM users WHERE id > ?", 0)
+		if err != nil {
+			return err
+		}
+		if len(rows) != 2 {
+			t.Fatalf("expected 2 rows, got %d", len(rows))
+		}
+		if rows[0]["id"] != int64(1) || rows[0]["name"] != "john" || rows[1]["name"] != nil {
+			t.Errorf("bad rows %v", rows)
+		}
+		if created, ok := rows[1]["created"].(time.Time); !ok || !created.Equal(time.Unix(200, 0)) {
+			t.Errorf("bad created %v", rows[1]["created"])
+		}
+		return nil
+	})
+}
+
+func TestQueryMapsMaskedAndLocalized(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer db.Close()
+
+	loc := time.FixedZone("CET", 3600)
+	masker := dbq.NewMasker().Column("name", dbq.MaskRedact, "admin").Column("id", dbq.MaskRedact)
+	provider := dbq.NewTxProvider(db, dbq.Masking(masker), dbq.TimeZone(dbq.TimePolicy{Location: loc}))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		rows, err := dbq.QueryMaps(tx, "SELECT id, name, created FROM users WHERE id > ?", 0)
+		if err != nil {
+			return err
+		}
+		if rows[0]["name"] == "john" || rows[0]["id"] != int64(0) || rows[1]["name"] != nil {
+			t.Errorf("bad masked rows %v", rows)
+		}
+		if created := rows[0]["created"].(time.Time); created.Location() != loc {
+			t.Errorf("created should be in %v, got %v", loc, created.Location())
+		}
+		return nil
+	}))
+}
@@ -0,0 +1,471 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Mask replaces string column value.
+type Mask func(s string) string
+
+// MaskRedact replaces value with fixed "***".
+func MaskRedact(string) string {
+	return "***"
+}
+
+// MaskPartial returns mask keeping first keepStart and last keepEnd
+// characters, the rest is replaced with '*', e.g. MaskPartial(1, 4) masks
+// "4111111111111111" as "4***********1111". Too short values are redacted
+// entirely.
+func MaskPartial(keepStart, keepEnd int) Mask {
+	return func(s string) string {
+		r := []rune(s)
+		if len(r) <= keepStart+keepEnd {
+			return strings.Repeat("*", len(r))
+		}
+		return string(r[:keepStart]) + strings.Repeat("*", len(r)-keepStart-keepEnd) + string(r[len(r)-keepEnd:])
+	}
+}
+
+// MaskEmail keeps first character of local part and domain of email.
+func MaskEmail(s string) string {
+	at := strings.LastIndexByte(s, '@')
+	if at < 0 {
+		return MaskRedact(s)
+	}
+	return MaskPartial(1, 0)(s[:at]) + s[at:]
+}
+
+type columnMask struct {
+	mask     Mask
+	unmasked map[string]bool
+}
+
+// Masker masks configured columns of query results unless role of caller
+// from context (see WithRole) is allowed to see full values, e.g. support
+// staff using admin endpoints:
+//
+//	masker := dbq.NewMasker().
+//		Column("email", dbq.MaskEmail, "admin").
+//		Column("card", dbq.MaskPartial(0, 4))
+//	provider := dbq.NewTxProvider(db, dbq.Masking(masker))
+//
+// Result columns are matched by name, case insensitively, in every query
+// helper: Query, QueryStruct, QueryRow, QueryOne, QueryScalar, QueryColumn,
+// QueryJoin (with prefix trimmed), QueryMaps, QueryIter and ExecReturning.
+// Aliases of select list expressions reading masked columns are masked
+// too, e.g. email in "SELECT email AS contact". Values are masked as they
+// are scanned, whatever they are scanned into: mask is applied to strings,
+// []byte, *string, Null[string] and sql.NullString, values of other types
+// are set to zero value.
+type Masker struct {
+	mu      sync.RWMutex
+	columns map[string]columnMask
+}
+
+// NewMasker creates masker without masked columns.
+func NewMasker() *Masker {
+	return &Masker{
+		columns: make(map[string]columnMask),
+	}
+}
+
+// Column registers mask of column, values are not masked for given roles.
+// Column names are matched case-insensitively.
+func (m *Masker) Column(name string, mask Mask, unmasked ...string) *Masker {
+	roles := make(map[string]bool, len(unmasked))
+	for _, role := range unmasked {
+		roles[role] = true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.columns[strings.ToLower(name)] = columnMask{mask: mask, unmasked: roles}
+	return m
+}
+
+type maskerKey struct{}
+
+// Masking sets masker of transactions.
+func Masking(m *Masker) ProviderOption {
+	return func(t *TxProvider) {
+		t.masker = m
+	}
+}
+
+// Apply masks columns of dest for role, dest is pointer to struct, map
+// keyed by column or slice of them.
+func (m *Masker) Apply(role string, dest any) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.apply(role, reflect.ValueOf(dest))
+}
+
+// maskRows wraps rows of query so that values of masked columns are masked
+// as they are scanned, whatever they are scanned into. Result columns are
+// matched by name, with prefixes trimmed, and by aliases of select list
+// expressions reading masked columns. Rows are returned as they are when
+// ctx has no masker.
+func maskRows(ctx context.Context, query string, rows Rows, prefixes ...string) Rows {
+	m, ok := ctx.Value(maskerKey{}).(*Masker)
+	if !ok {
+		return rows
+	}
+	role, _ := RoleFromCtx(ctx)
+	return &maskedRows{Rows: rows, masker: m, role: role, query: query, prefixes: prefixes}
+}
+
+type maskedRows struct {
+	Rows
+	masker   *Masker
+	role     string
+	query    string
+	prefixes []string
+	// masks of result columns, nil for columns which are not masked.
+	masks    []Mask
+	resolved bool
+}
+
+func (r *maskedRows) Scan(dest ...any) error {
+	if !r.resolved {
+		cols, err := r.Rows.Columns()
+		if err != nil {
+			// columns which should be masked are not known.
+			return err
+		}
+		r.masks = r.masker.resultMasks(r.role, r.query, cols, r.prefixes)
+		r.resolved = true
+	}
+	if err := r.Rows.Scan(dest...); err != nil {
+		return err
+	}
+	for i, m := range r.masks {
+		if m != nil && i < len(dest) {
+			if v := reflect.ValueOf(dest[i]); v.Kind() == reflect.Pointer && !v.IsNil() {
+				maskValue(v.Elem(), m)
+			}
+		}
+	}
+	return nil
+}
+
+// ErrUnmaskableRow is returned when rows of pipelined statement should be
+// masked, but PipelineExecutor passes rows which don't report columns.
+var ErrUnmaskableRow = errors.New("dbq: masked pipeline rows must report columns")
+
+// maskQueued masks rows of pipelined statement q of query when ctx has
+// masker. Executor passes rows to Each, which masks them, so Dest is
+// scanned in Each too and returned function, called after statement is
+// executed, sets sql.ErrNoRows when no row was scanned.
+func maskQueued(ctx context.Context, query string, q *Queued) func() {
+	if _, ok := ctx.Value(maskerKey{}).(*Masker); !ok || (q.Each == nil && len(q.Dest) == 0) {
+		return func() {}
+	}
+	current := &queuedRow{}
+	rows := maskRows(ctx, query, current)
+	each, dest, scanned := q.Each, q.Dest, false
+	q.Dest = nil
+	q.Each = func(row Row) error {
+		r, ok := row.(columnsRow)
+		if !ok {
+			return ErrUnmaskableRow
+		}
+		current.row = r
+		if each != nil {
+			return each(rows)
+		}
+		if scanned {
+			return nil
+		}
+		scanned = true
+		return rows.Scan(dest...)
+	}
+	return func() {
+		if each == nil && !scanned && q.Err == nil {
+			q.Err = sql.ErrNoRows
+		}
+	}
+}
+
+// columnsRow is Row which reports its columns, like *sql.Rows.
+type columnsRow interface {
+	Row
+	Columns() ([]string, error)
+}
+
+// queuedRow is Rows over the current row passed to Each by executor.
+type queuedRow struct {
+	row columnsRow
+}
+
+func (r *queuedRow) Scan(dest ...any) error {
+	return r.row.Scan(dest...)
+}
+
+func (r *queuedRow) Columns() ([]string, error) {
+	return r.row.Columns()
+}
+
+func (r *queuedRow) Next() bool {
+	return false
+}
+
+func (r *queuedRow) Err() error {
+	return nil
+}
+
+func (r *queuedRow) Close() error {
+	return nil
+}
+
+// maskedRow returns row of query for single row helpers. *sql.Row doesn't
+// report columns, so when ctx has masker row is read with Query.
+func maskedRow(ctx TxContext, query string, args []any) Row {
+	if _, ok := ctx.Value(maskerKey{}).(*Masker); !ok {
+		return ctx.QueryRow(query, args...)
+	}
+	rows, err := ctx.Query(query, args...)
+	if err != nil {
+		return firstRow{err: err}
+	}
+	return firstRow{rows: maskRows(ctx, query, rows)}
+}
+
+// firstRow scans the first row of rows like *sql.Row.
+type firstRow struct {
+	rows Rows
+	err  error
+}
+
+func (r firstRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if !r.rows.Next() {
+		if err := closeRows(r.rows, nil); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	return closeRows(r.rows, r.rows.Scan(dest...))
+}
+
+// resultMasks returns masks of result columns cols of query for role.
+func (m *Masker) resultMasks(role, query string, cols []string, prefixes []string) []Mask {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	masked := make(map[string]columnMask, len(m.columns))
+	for name, cm := range m.columns {
+		if !cm.unmasked[role] {
+			masked[name] = cm
+		}
+	}
+	if len(masked) == 0 {
+		return nil
+	}
+	// aliases of expressions reading masked columns are masked too, also
+	// when they are read again by outer query.
+	items := selectItems(query)
+	for changed := true; changed; {
+		changed = false
+		for _, it := range items {
+			if _, ok := masked[it.alias]; ok {
+				continue
+			}
+			for _, src := range it.sources {
+				if cm, ok := masked[src]; ok {
+					masked[it.alias] = cm
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var masks []Mask
+	for i, col := range cols {
+		name := strings.ToLower(col)
+		for _, prefix := range prefixes {
+			if prefix != "" && strings.HasPrefix(name, strings.ToLower(prefix)) {
+				name = name[len(prefix):]
+				break
+			}
+		}
+		if cm, ok := masked[name]; ok {
+			if masks == nil {
+				masks = make([]Mask, len(cols))
+			}
+			masks[i] = cm.mask
+		}
+	}
+	return masks
+}
+
+// selectItem is aliased expression of select list.
+type selectItem struct {
+	alias string
+	// sources are lower case unqualified names read by expression.
+	sources []string
+}
+
+// selectItems returns aliased expressions of select lists of query and its
+// subqueries.
+func selectItems(query string) []selectItem {
+	tokens := tokenize(query)
+	var items []selectItem
+	for i, t := range tokens {
+		if t.keyword() != "select" {
+			continue
+		}
+		// select list ends with FROM or end of subquery at its depth.
+		var item []sqlToken
+		depth := 0
+		for _, t := range tokens[i+1:] {
+			kw := t.keyword()
+			if depth == 0 && (kw == "from" || kw == "into" || t.text == ")" || t.text == ";" || t.text == ",") {
+				if it, ok := newSelectItem(item); ok {
+					items = append(items, it)
+				}
+				item = item[:0]
+				if t.text == "," {
+					continue
+				}
+				break
+			}
+			switch t.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			item = append(item, t)
+		}
+	}
+	return items
+}
+
+// newSelectItem returns alias and sources of select list item, item
+// without alias is not returned, its result column is matched by name.
+func newSelectItem(tokens []sqlToken) (selectItem, bool) {
+	n := len(tokens)
+	if n < 2 || !tokens[n-1].word {
+		return selectItem{}, false
+	}
+	exprEnd := n - 1
+	if tokens[n-2].keyword() == "as" {
+		exprEnd = n - 2
+	} else if !tokens[n-2].word && tokens[n-2].text != ")" {
+		return selectItem{}, false
+	}
+	it := selectItem{alias: strings.ToLower(tokens[n-1].text)}
+	for _, t := range tokens[:exprEnd] {
+		if t.word {
+			name := strings.ToLower(t.text)
+			it.sources = append(it.sources, name[strings.LastIndexByte(name, '.')+1:])
+		}
+	}
+	return it, true
+}
+
+var (
+	nullStringType    = reflect.TypeOf(Null[string]{})
+	nullStringSQLType = reflect.TypeOf(sql.NullString{})
+)
+
+//nolint:exhaustive
+func (m *Masker) apply(role string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			m.apply(role, v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			m.apply(role, v.Index(i))
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			cm, ok := m.columns[strings.ToLower(key.String())]
+			if !ok || cm.unmasked[role] {
+				continue
+			}
+			val := v.MapIndex(key)
+			// map values are not addressable, masked copy is stored back.
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(val)
+			if elem.Kind() == reflect.Interface && !elem.IsNil() {
+				inner := reflect.New(elem.Elem().Type()).Elem()
+				inner.Set(elem.Elem())
+				maskValue(inner, cm.mask)
+				elem.Set(inner)
+			} else {
+				maskValue(elem, cm.mask)
+			}
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Struct:
+		if v.Type() == timeType || v.Type() == nullStringType {
+			return
+		}
+		sm, err := structMapOf(v.Type())
+		if err != nil {
+			return
+		}
+		for _, f := range sm.fields {
+			cm, ok := m.columns[strings.ToLower(f.column)]
+			if !ok || cm.unmasked[role] {
+				continue
+			}
+			if fv, ok := fieldValue(v, f.index); ok && fv.CanSet() {
+				maskValue(fv, cm.mask)
+			}
+		}
+	}
+}
+
+// maskValue applies mask to string values and zeroes others.
+func maskValue(v reflect.Value, mask Mask) {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(mask(v.String()))
+	case v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.String:
+		if !v.IsNil() {
+			s := reflect.New(v.Type().Elem())
+			s.Elem().SetString(mask(v.Elem().String()))
+			v.Set(s)
+		}
+	case v.Type() == nullStringType:
+		if n := v.Addr().Interface().(*Null[string]); n.Valid { //nolint:forcetypeassert
+			n.Val = mask(n.Val)
+		}
+	case v.Type() == nullStringSQLType:
+		if n := v.Addr().Interface().(*sql.NullString); n.Valid { //nolint:forcetypeassert
+			n.String = mask(n.String)
+		}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		if !v.IsNil() {
+			v.SetBytes([]byte(mask(string(v.Bytes()))))
+		}
+	case v.Kind() == reflect.Interface && !v.IsNil():
+		switch s := v.Elem().Interface().(type) {
+		case string:
+			v.Set(reflect.ValueOf(mask(s)))
+		case []byte:
+			v.Set(reflect.ValueOf([]byte(mask(string(s)))))
+		default:
+			v.Set(reflect.Zero(v.Elem().Type()))
+		}
+	default:
+		v.Set(reflect.Zero(v.Type()))
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestMasks(t *testing.T) {
+	tests := []struct {
+		mask dbq.Mask
+		in   string
+		want string
+	}{
+		{dbq.MaskRedact, "secret", "***"},
+		{dbq.MaskPartial(1, 4), "4111111111111111", "4***********1111"},
+		{dbq.MaskPartial(2, 2), "abc", "***"},
+		{dbq.MaskEmail, "john@example.com", "j***@example.com"},
+		{dbq.MaskEmail, "john", "***"},
+	}
+	for i, tt := range tests {
+		if got := tt.mask(tt.in); got != tt.want {
+			t.Errorf("#%d: mask(%q) = %q, want %q", i, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaskerApply(t *testing.T) {
+	type account struct {
+		ID    int64   `db:"id"`
+		Email string  `db:"email"`
+		Phone *string `db:"phone"`
+		Note  string  `db:"note"`
+	}
+	phone := "555123"
+	accounts := []account{{ID: 1, Email: "john@example.com", Phone: &phone, Note: "vip"}}
+
+	masker := dbq.NewMasker().
+		Column("id", dbq.MaskRedact).
+		Column("email", dbq.MaskEmail, "admin").
+		Column("phone", dbq.MaskPartial(0, 2))
+	masker.Apply("support", &accounts)
+
+	a := accounts[0]
+	if a.ID != 0 || a.Email != "j***@example.com" || *a.Phone != "****23" || a.Note != "vip" {
+		t.Errorf("bad masked account %+v %s", a, *a.Phone)
+	}
+	if phone != "555123" {
+		t.Error("masked pointer should not modify original value")
+	}
+
+	accounts = []account{{Email: "john@example.com"}}
+	masker.Apply("admin", accounts)
+	if accounts[0].Email != "john@example.com" {
+		t.Errorf("email should not be masked for admin, got %s", accounts[0].Email)
+	}
+}
+
+func TestMaskerApplyCase(t *testing.T) {
+	type account struct {
+		Email string `db:"Email"`
+	}
+	masker := dbq.NewMasker().Column("EMAIL", dbq.MaskRedact)
+
+	a := account{Email: "john@example.com"}
+	masker.Apply("support", &a)
+	row := map[string]any{"email": "john@example.com"}
+	masker.Apply("support", row)
+	if a.Email != "***" || row["email"] != "***" {
+		t.Errorf("columns should match case-insensitively, got %q %q", a.Email, row["email"])
+	}
+}
+
+func TestMasking(t *testing.T) {
+	masker := dbq.NewMasker().Column("name", dbq.MaskPartial(1, 0), "admin")
+	for _, tt := range []struct {
+		role string
+		want string
+	}{
+		{"support", "j***"},
+		{"admin", "john"},
+	} {
+		rec := usersRecording()
+		rec.Entries = rec.Entries[:1]
+		db := sql.OpenDB(dbq.NewReplayer(rec))
+		provider := dbq.NewTxProvider(db, dbq.Masking(masker))
+		ctx := dbq.WithRole(context.Background(), tt.role)
+		maybePanic(provider.Tx(ctx, func(tx dbq.TxContext) error {
+			users, err := dbq.QueryStruct[taggedUser](tx, "SELECT id, name, created FROM users WHERE id > ?", 0)
+			if err != nil {
+				return err
+			}
+			if users[0].Name.Val != tt.want || users[1].Name.Valid {
+				t.Errorf("%s: bad masked users %+v", tt.role, users)
+			}
+			return nil
+		}))
+		db.Close()
+	}
+}
+
+func TestMaskingResultColumns(t *testing.T) {
+	const (
+		aliased = "SELECT id, name AS contact FROM users"
+		scalar  = "SELECT upper(name) n FROM users WHERE id = ?"
+		column  = "SELECT contact FROM (SELECT name AS contact FROM users) u"
+		joined  = "SELECT u.id AS u_id, u.name AS u_name, o.id AS o_id FROM users u JOIN orders o ON o.user_id = u.id"
+	)
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:   aliased,
+			Columns: []string{"id", "contact"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(1)}, {V: "john"}}},
+		},
+		{
+			Query:   scalar,
+			Args:    []dbq.RecordedValue{{V: int64(1)}},
+			Columns: []string{"n"},
+			Rows:    [][]dbq.RecordedValue{{{V: "JOHN"}}},
+		},
+		{
+			Query:   column,
+			Columns: []string{"contact"},
+			Rows:    [][]dbq.RecordedValue{{{V: "john"}}},
+		},
+		{
+			Query:   joined,
+			Columns: []string{"u_id", "u_name", "o_id"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(1)}, {V: "john"}, {V: int64(7)}}},
+		},
+	}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	type named struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	type order struct {
+		ID int64 `db:"id"`
+	}
+	masker := dbq.NewMasker().Column("NAME", dbq.MaskRedact)
+	provider := dbq.NewTxProvider(db, dbq.Masking(masker))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		users, err := dbq.Query(tx, aliased, func(u *named) []any {
+			return []any{&u.ID, &u.Name}
+		})
+		if err != nil {
+			return err
+		}
+		if users[0].ID != 1 || users[0].Name != "***" {
+			t.Errorf("aliased column should be masked, got %+v", users[0])
+		}
+
+		s, err := dbq.QueryScalar[string](tx, scalar, 1)
+		if err != nil {
+			return err
+		}
+		if s != "***" {
+			t.Errorf("scalar should be masked, got %s", s)
+		}
+
+		contacts, err := dbq.QueryColumn[string](tx, column)
+		if err != nil {
+			return err
+		}
+		if contacts[0] != "***" {
+			t.Errorf("column should be masked, got %v", contacts)
+		}
+
+		joins, err := dbq.QueryJoin[named, order](tx, joined, [2]string{"u_", "o_"})
+		if err != nil {
+			return err
+		}
+		if joins[0].A.ID != 1 || joins[0].A.Name != "***" || joins[0].B.ID != 7 {
+			t.Errorf("joined column should be masked, got %+v", joins[0])
+		}
+		return nil
+	}))
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Metadata is request metadata accumulated with Meta, e.g. user id,
+// endpoint or feature flag.
+type Metadata map[string]string
+
+type metaKey struct{}
+
+// Meta returns ctx carrying metadata of ctx with key set to value. Metadata
+// is attached to QueryEvent of Hooks, spans of WithTracer, SQL comments of
+// MetaComments and audit records of Forgetter, e.g. in HTTP middleware:
+//
+//	ctx = dbq.Meta(ctx, "user", userID)
+//	ctx = dbq.Meta(ctx, "route", "/orders/{id}")
+func Meta(ctx context.Context, key, value string) context.Context {
+	prev := MetaFromCtx(ctx)
+	m := make(Metadata, len(prev)+1)
+	for k, v := range prev {
+		m[k] = v
+	}
+	m[key] = value
+	return context.WithValue(ctx, metaKey{}, m)
+}
+
+// MetaFromCtx returns metadata stored in context, it must not be modified.
+func MetaFromCtx(ctx context.Context) Metadata {
+	m, _ := ctx.Value(metaKey{}).(Metadata)
+	return m
+}
+
+// Keys returns sorted keys of metadata.
+func (m Metadata) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Comment returns metadata as SQL comment in sqlcommenter format, e.g.
+// /*route='%2Forders',user='42'*/, empty metadata returns empty string.
+func (m Metadata) Comment() string {
+	if len(m) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("/*")
+	for i, k := range m.Keys() {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(url.QueryEscape(k))
+		b.WriteString("='")
+		// QueryEscape escapes quotes and comment terminators.
+		b.WriteString(strings.ReplaceAll(url.QueryEscape(m[k]), "+", "%20"))
+		b.WriteByte('\'')
+	}
+	b.WriteString("*/")
+	return b.String()
+}
+
+// MetaComments appends metadata of context to statements as SQL comment,
+// so it shows up in database logs and statement statistics. Statements
+// differ by metadata, so they should not be combined with prepared
+// statement cache.
+func MetaComments() ProviderOption {
+	return func(t *TxProvider) {
+		t.interceptors = append(t.interceptors, metaComments)
+	}
+}
+
+// metaComments is interceptor appending metadata comment to statements.
+func metaComments(next Handler) Handler {
+	return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+		if comment := MetaFromCtx(ctx).Comment(); comment != "" {
+			query := strings.TrimRight(stmt.Query, " \t\r\n")
+			if strings.HasSuffix(query, ";") {
+				stmt.Query = strings.TrimSuffix(query, ";") + " " + comment + ";"
+			} else {
+				stmt.Query = query + " " + comment
+			}
+		}
+		return next(ctx, stmt)
+	}
+}
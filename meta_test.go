@@ -0,0 +1,86 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestMeta(t *testing.T) {
+	ctx := dbq.Meta(context.Background(), "user", "42")
+	child := dbq.Meta(ctx, "route", "/orders/{id}")
+	if m := dbq.MetaFromCtx(ctx); len(m) != 1 || m["user"] != "42" {
+		t.Errorf("parent metadata should not change, got %v", m)
+	}
+	m := dbq.MetaFromCtx(child)
+	if len(m) != 2 || m["route"] != "/orders/{id}" {
+		t.Errorf("bad metadata %v", m)
+	}
+	if c := m.Comment(); c != "/*route='%2Forders%2F%7Bid%7D',user='42'*/" {
+		t.Errorf("bad comment %s", c)
+	}
+	escaped := dbq.MetaFromCtx(dbq.Meta(ctx, "note", "it's */ done")).Comment()
+	if escaped != "/*note='it%27s%20%2A%2F%20done',user='42'*/" {
+		t.Errorf("comment should be escaped, got %s", escaped)
+	}
+	if dbq.MetaFromCtx(context.Background()).Comment() != "" {
+		t.Error("empty metadata should have no comment")
+	}
+}
+
+func TestMetaPropagation(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries[0].Query = "SELECT id, name, created FROM users WHERE id > ? /*user='42'*/"
+	rec.Entries = rec.Entries[:1]
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	var events []dbq.QueryEvent
+	provider := dbq.NewTxProvider(db, dbq.MetaComments(), dbq.WithHooks(dbq.Hooks{
+		AfterQuery: func(_ context.Context, e dbq.QueryEvent) {
+			events = append(events, e)
+		},
+	}))
+	ctx := dbq.Meta(context.Background(), "user", "42")
+	maybePanic(provider.Tx(ctx, func(tx dbq.TxContext) error {
+		_, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+		return err
+	}))
+	if len(events) != 1 || events[0].Meta["user"] != "42" {
+		t.Errorf("bad events %+v", events)
+	}
+}
+
+func TestForgetAuditMeta(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:        "DELETE FROM users WHERE id = ?",
+			Args:         []dbq.RecordedValue{{V: int64(7)}},
+			RowsAffected: 1,
+		},
+		{
+			Query: "INSERT INTO audit (subject, tables, meta, forgotten_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)",
+			Args: []dbq.RecordedValue{
+				{V: dbq.AnonymizeHash("salt")(int64(7))},
+				{V: "users:delete:1"},
+				{V: `{"user":"admin"}`},
+			},
+		},
+	}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	ctx := dbq.Meta(context.Background(), "user", "admin")
+	_, err := dbq.NewForgetter(dbq.NewTxProvider(db), "salt").
+		Audit("audit").
+		AuditMeta("meta").
+		Delete("users", "id").
+		Forget(ctx, int64(7))
+	maybePanic(err)
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Outcomes of statements reported to MetricsCollector.
+const (
+	QueryOK       = "ok"
+	QueryFailed   = "error"
+	QueryCanceled = "canceled"
+)
+
+// MetricsCollector receives statistics of statements and transactions, it
+// is implemented by adapters of metrics libraries, see package dbqprom for
+// Prometheus.
+type MetricsCollector interface {
+	// QueryDone is called after statement is executed with one of
+	// QueryOK, QueryFailed or QueryCanceled outcomes. Duration of Query
+	// statements does not include reading of rows.
+	QueryDone(op Op, outcome string, d time.Duration)
+	// TxStarted is called when transaction is acquired.
+	TxStarted()
+	// TxDone is called once when transaction is committed or rolled back.
+	TxDone(committed bool, d time.Duration)
+}
+
+// WithMetrics reports statements and transactions to collector.
+func WithMetrics(collector MetricsCollector) ProviderOption {
+	return func(t *TxProvider) {
+		t.metrics = collector
+		t.interceptors = append(t.interceptors, metering(collector))
+	}
+}
+
+// queryOutcome returns outcome of statement error.
+func queryOutcome(err error) string {
+	switch {
+	case err == nil:
+		return QueryOK
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return QueryCanceled
+	}
+	return QueryFailed
+}
+
+// metering returns interceptor reporting statements to collector.
+func metering(collector MetricsCollector) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			start := time.Now()
+			out, err := next(ctx, stmt)
+			collector.QueryDone(stmt.Op, queryOutcome(err), time.Since(start))
+			return out, err
+		}
+	}
+}
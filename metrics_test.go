@@ -0,0 +1,70 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type fakeMetrics struct {
+	queries []string
+	open    int
+	done    []bool
+}
+
+func (m *fakeMetrics) QueryDone(op dbq.Op, outcome string, _ time.Duration) {
+	m.queries = append(m.queries, op.String()+" "+outcome)
+}
+
+func (m *fakeMetrics) TxStarted() { m.open++ }
+
+func (m *fakeMetrics) TxDone(committed bool, _ time.Duration) {
+	m.open--
+	m.done = append(m.done, committed)
+}
+
+func TestMetrics(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries[1].Err = "locked"
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	metrics := &fakeMetrics{}
+	provider := dbq.NewTxProvider(db, dbq.WithMetrics(metrics))
+	err := provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		if metrics.open != 1 {
+			t.Errorf("expected one open transaction, got %d", metrics.open)
+		}
+		if _, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO users (name) VALUES (?)", "jane")
+		return err
+	})
+	if err == nil {
+		t.Fatal("insert should fail")
+	}
+
+	if len(metrics.queries) != 2 || metrics.queries[0] != "query ok" || metrics.queries[1] != "exec error" {
+		t.Errorf("bad queries %v", metrics.queries)
+	}
+	if metrics.open != 0 || len(metrics.done) != 1 || metrics.done[0] {
+		t.Errorf("expected one rolled back transaction, got %d open and %v", metrics.open, metrics.done)
+	}
+
+	metrics = &fakeMetrics{}
+	tx, err := dbq.NewTxProvider(db, dbq.WithMetrics(metrics)).Acquire(context.Background())
+	maybePanic(err)
+	maybePanic(tx.Commit())
+	_ = tx.Rollback()
+	if metrics.open != 0 || len(metrics.done) != 1 || !metrics.done[0] {
+		t.Errorf("expected one committed transaction, got %d open and %v", metrics.open, metrics.done)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NamedParams are values of named parameters in query, see Params.
+type NamedParams struct {
+	v any
+}
+
+// Params passes values of named parameters, :name or @name, written in
+// query. v is map with string keys or struct mapped by TagName tags, e.g.
+//
+//	dbq.Query(ctx, "SELECT id, name FROM users WHERE id = :id", binder,
+//		dbq.Params(map[string]any{"id": id}))
+//
+// Params must be the only query argument besides query options. Named
+// parameters are rewritten to positional ? placeholders before statement
+// reaches interceptors.
+func Params(v any) NamedParams {
+	return NamedParams{v: v}
+}
+
+// lookup returns value of parameter name.
+func (p NamedParams) lookup() (func(name string) (any, bool), error) {
+	rv := reflect.ValueOf(p.v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		return func(name string) (any, bool) {
+			v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if !v.IsValid() {
+				return nil, false
+			}
+			return v.Interface(), true
+		}, nil
+	case reflect.Struct:
+		m, err := structMapOf(rv.Type())
+		if err != nil {
+			return nil, err
+		}
+		return func(name string) (any, bool) {
+			f, ok := m.lookup(name)
+			if !ok {
+				return nil, false
+			}
+			v, ok := fieldValue(rv, f.index)
+			if !ok {
+				return nil, true
+			}
+			return f.arg(v.Interface()), true
+		}, nil
+	}
+	return nil, fmt.Errorf("dbq: named parameters must be map or struct, got %T", p.v)
+}
+
+// namedParams rewrites statements with NamedParams argument.
+func namedParams(next Handler) Handler {
+	return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+		if len(stmt.Args) != 1 {
+			return next(ctx, stmt)
+		}
+		params, ok := stmt.Args[0].(NamedParams)
+		if !ok {
+			return next(ctx, stmt)
+		}
+		lookup, err := params.lookup()
+		if err != nil {
+			return Outcome{}, err
+		}
+		query, args, err := bindNamed(stmt.Query, lookup)
+		if err != nil {
+			return Outcome{}, err
+		}
+		stmt.Query, stmt.Args = query, args
+		return next(ctx, stmt)
+	}
+}
+
+// bindNamed replaces :name and @name parameters outside of literals and
+// comments with ? placeholders and returns their values in order. Casts
+// (::), assignments (:=) and @@ variables are left as they are.
+//
+//nolint:gocognit,cyclop
+func bindNamed(query string, lookup func(name string) (any, bool)) (string, []any, error) {
+	var (
+		b    strings.Builder
+		args []any
+	)
+	b.Grow(len(query))
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '-' || c == '/':
+			if j := skipQuoted(query, i); j > i {
+				b.WriteString(query[i:j])
+				i = j - 1
+				continue
+			}
+		case (c == ':' || c == '@') && i+1 < len(query):
+			next := query[i+1]
+			if next == c {
+				b.WriteString(query[i : i+2])
+				i++
+				continue
+			}
+			if !isNameStart(next) {
+				break
+			}
+			j := i + 1
+			for j < len(query) && isNameChar(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			v, ok := lookup(name)
+			if !ok {
+				return "", nil, fmt.Errorf("dbq: missing value of named parameter %q", name)
+			}
+			b.WriteByte('?')
+			args = append(args, v)
+			i = j - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), args, nil
+}
+
+// skipQuoted returns index after string literal, quoted identifier or
+// comment starting at i, or i when there is none.
+func skipQuoted(query string, i int) int {
+	switch c := query[i]; {
+	case c == '\'' || c == '"' || c == '`':
+		if end := strings.IndexByte(query[i+1:], c); end >= 0 {
+			return i + end + 2
+		}
+		return len(query)
+	case strings.HasPrefix(query[i:], "--"):
+		if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+			return i + end
+		}
+		return len(query)
+	case strings.HasPrefix(query[i:], "/*"):
+		if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+			return i + end + 4
+		}
+		return len(query)
+	case c == '$':
+		if tag := dollarTag(query, i); tag != "" {
+			if end := strings.Index(query[i+len(tag):], tag); end >= 0 {
+				return i + end + 2*len(tag)
+			}
+			return len(query)
+		}
+	}
+	return i
+}
+
+// dollarTag returns tag opening Postgres dollar quoted literal at i, e.g. $$
+// or $body$, or empty string when there is none.
+func dollarTag(query string, i int) string {
+	if query[i] != '$' || (i > 0 && (isNameChar(query[i-1]) || query[i-1] == '$')) {
+		return ""
+	}
+	j := i + 1
+	if j < len(query) && isNameStart(query[j]) {
+		for j < len(query) && isNameChar(query[j]) {
+			j++
+		}
+	}
+	if j < len(query) && query[j] == '$' {
+		return query[i : j+1]
+	}
+	return ""
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
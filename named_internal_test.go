@@ -0,0 +1,40 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBindNamed(t *testing.T) {
+	params := map[string]any{"id": 1, "name": "john"}
+	lookup := func(name string) (any, bool) {
+		v, ok := params[name]
+		return v, ok
+	}
+	tests := []struct {
+		query, want string
+		args        []any
+	}{
+		{"SELECT * FROM users WHERE id = :id", "SELECT * FROM users WHERE id = ?", []any{1}},
+		{"UPDATE users SET name = @name WHERE id = @id", "UPDATE users SET name = ? WHERE id = ?", []any{"john", 1}},
+		{"SELECT created::date, ':id', \"@id\" FROM users -- :id\nWHERE id=:id /* @name */", "SELECT created::date, ':id', \"@id\" FROM users -- :id\nWHERE id=? /* @name */", []any{1}},
+		{"SELECT @@version, 'unterminated :id", "SELECT @@version, 'unterminated :id", nil},
+	}
+	for _, tt := range tests {
+		got, args, err := bindNamed(tt.query, lookup)
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+			continue
+		}
+		if got != tt.want || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("bindNamed(%q) = %q %v, want %q %v", tt.query, got, args, tt.want, tt.args)
+		}
+	}
+	if _, _, err := bindNamed("SELECT :missing", lookup); err == nil {
+		t.Error("expected error for missing parameter")
+	}
+}
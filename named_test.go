@@ -0,0 +1,29 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestNamedParams(t *testing.T) {
+	replayTx(t, usersRecording(), func(tx dbq.TxContext) error {
+		users, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > :id", userBinder,
+			dbq.Params(map[string]any{"id": 0}))
+		if err != nil {
+			return err
+		}
+		if len(users) != 2 {
+			t.Errorf("bad users %v", users)
+		}
+		params := struct {
+			Name string `db:"name"`
+		}{"jane"}
+		_, err = dbq.Exec(tx, "INSERT INTO users (name) VALUES (:name)", dbq.Params(&params))
+		return err
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+)
+
+// NativeConnector is extension hook for Connector of database/sql driver
+// with features beyond database/sql, e.g. adapter of pgx stdlib driver.
+// Transactions of native connector are begun on connection reserved with
+// Conn and released when they are finished, so access can reach driver
+// connection of transaction with Raw:
+//
+//	func (c *pgxConnector) NativeAccess(conn *sql.Conn, tx *sql.Tx) dbq.Access {
+//		return &pgxAccess{Tx: tx, conn: conn} // implements dbq.CopyFromer
+//	}
+//
+//	func (a *pgxAccess) CopyFrom(ctx context.Context, table string, columns []string, rows [][]any) (n int64, err error) {
+//		err = a.conn.Raw(func(dc any) error {
+//			n, err = dc.(*stdlib.Conn).Conn().CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+//			return err
+//		})
+//		return n, err
+//	}
+//
+// Access returned for transaction is available in its context, statements
+// of Tx still run on *sql.Tx. Only Pipeline, Batch and CopyFrom use
+// PipelineExecutor or CopyFromer implemented by access and fall back to
+// plain statements for other drivers, so the same code works with both.
+//
+// dbq runs on database/sql only, pgx.Conn or pgxpool.Pool can't be used
+// without it yet.
+type NativeConnector interface {
+	Connector
+	// Conn reserves connection transaction is begun on, *sql.DB implements
+	// it.
+	Conn(ctx context.Context) (*sql.Conn, error)
+	// NativeAccess returns access of transaction tx begun on conn, its
+	// native features should run on conn.
+	NativeAccess(conn *sql.Conn, tx *sql.Tx) Access
+}
+
+// beginNative begins transaction of n on reserved connection, which is
+// closed when transaction is finished.
+func beginNative(ctx context.Context, n NativeConnector, opts *sql.TxOptions) (*sql.Tx, *sql.Conn, error) {
+	conn, err := n.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	return tx, conn, nil
+}
+
+// CopyFromer is implemented by access of drivers supporting bulk load
+// protocol, e.g. COPY FROM STDIN of Postgres.
+type CopyFromer interface {
+	CopyFrom(ctx context.Context, table string, columns []string, rows [][]any) (int64, error)
+}
+
+// CopyFrom loads rows of columns into table and returns number of loaded
+// rows. When access of ctx implements CopyFromer rows are loaded with bulk
+// load protocol, otherwise they are inserted with InsertBatch. Bulk load
+// passes through interceptors of ctx as single INSERT statement without
+// arguments, so Policy and Quota apply to it as to inserted rows.
+func CopyFrom(ctx TxContext, table string, columns []string, rows [][]any) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	c, ok := ctx.Value(txKeyType{}).(CopyFromer)
+	if !ok {
+		return InsertBatch(ctx, table, rows, BatchBinder(columns, func(row *[]any) []any {
+			return *row
+		}))
+	}
+
+	var n int64
+	h := func(ctx context.Context, _ *Statement) (Outcome, error) {
+		var err error
+		n, err = c.CopyFrom(ctx, table, columns, rows)
+		return Outcome{Result: driver.RowsAffected(n)}, err
+	}
+	query := "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" +
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	stmt := newStatement(OpExec, query, nil)
+	var err error
+	if i, ok := ctx.(interceptable); ok {
+		_, err = i.intercept(h)(ctx, stmt)
+	} else {
+		_, err = wrapHandler(h, nil)(ctx, stmt)
+	}
+	return n, err
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+// nativeConnector provides access with bulk load, as pgx adapter would.
+type nativeConnector struct {
+	*sql.DB
+	copied [][]any
+}
+
+type nativeAccess struct {
+	*sql.Tx
+	conn      *sql.Conn
+	connector *nativeConnector
+}
+
+func (c *nativeConnector) NativeAccess(conn *sql.Conn, tx *sql.Tx) dbq.Access {
+	return &nativeAccess{Tx: tx, conn: conn, connector: c}
+}
+
+func (a *nativeAccess) CopyFrom(ctx context.Context, _ string, _ []string, rows [][]any) (int64, error) {
+	if err := a.conn.Raw(func(any) error { return nil }); err != nil {
+		return 0, err
+	}
+	a.connector.copied = append(a.connector.copied, rows...)
+	return int64(len(rows)), nil
+}
+
+func TestCopyFromNative(t *testing.T) {
+	conn := &nativeConnector{DB: sql.OpenDB(dbq.NewReplayer(&dbq.Recording{}))}
+	defer conn.Close()
+
+	rows := [][]any{{"john"}, {"jane"}}
+	maybePanic(dbq.NewTxProvider(conn).Tx(context.Background(), func(tx dbq.TxContext) error {
+		n, err := dbq.CopyFrom(tx, "users", []string{"name"}, rows)
+		if n != 2 {
+			t.Errorf("expected 2 copied rows, got %d", n)
+		}
+		return err
+	}))
+	if !reflect.DeepEqual(conn.copied, rows) {
+		t.Errorf("got copied %v", conn.copied)
+	}
+}
+
+func TestCopyFromNativePolicy(t *testing.T) {
+	conn := &nativeConnector{DB: sql.OpenDB(dbq.NewReplayer(&dbq.Recording{}))}
+	defer conn.Close()
+
+	policy := dbq.NewPolicy().Allow("support", "users", dbq.ActionSelect)
+	provider := dbq.NewTxProvider(conn, dbq.Interceptors(policy.Interceptor()))
+	ctx := dbq.WithRole(context.Background(), "support")
+	err := provider.Tx(ctx, func(tx dbq.TxContext) error {
+		_, err := dbq.CopyFrom(tx, "users", []string{"name"}, [][]any{{"john"}})
+		return err
+	})
+	if !errors.Is(err, dbq.ErrNoAccess) || len(conn.copied) != 0 {
+		t.Errorf("bulk load should be rejected, got %v %v", err, conn.copied)
+	}
+}
+
+func TestCopyFromFallback(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{{
+		Query:        "INSERT INTO users (name, age) VALUES (?, ?), (?, ?)",
+		Args:         []dbq.RecordedValue{{V: "john"}, {V: int64(30)}, {V: "jane"}, {V: nil}},
+		RowsAffected: 2,
+	}}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		n, err := dbq.CopyFrom(tx, "users", []string{"name", "age"}, [][]any{{"john", 30}, {"jane", nil}})
+		if n != 2 {
+			t.Errorf("expected 2 inserted rows, got %d", n)
+		}
+		return err
+	})
+}
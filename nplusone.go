@@ -0,0 +1,162 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// NPlusOne reports statement executed repeatedly with different single
+// argument, typically query of child rows run in loop over parent rows
+// which should be single IN query or join.
+type NPlusOne struct {
+	// Query is fingerprint of statement, see Fingerprint.
+	Query string
+	// Count is number of executions with distinct argument.
+	Count int
+	// Callers are distinct call sites of executions, they are captured
+	// only when CaptureCaller is enabled.
+	Callers []string
+}
+
+// NPlusOneDetector detects N+1 queries during development, e.g.
+//
+//	dbq.CaptureCaller(true)
+//	provider := dbq.NewTxProvider(db, dbq.DetectNPlusOne(dbq.NPlusOneDetector{}))
+//
+// Statements are tracked within scope started with NPlusOneScope, e.g. in
+// HTTP middleware, or within transaction when there is no scope.
+type NPlusOneDetector struct {
+	// Threshold is number of executions with distinct argument after
+	// which statement is reported, default 5.
+	Threshold int
+	// OnDetect is called once per statement and scope, nil logs report
+	// with Logger.
+	OnDetect func(ctx context.Context, r NPlusOne)
+	// Logger logs reports, standard logger is used when nil.
+	Logger Logger
+}
+
+// DetectNPlusOne detects N+1 queries of transactions.
+func DetectNPlusOne(d NPlusOneDetector) ProviderOption {
+	return func(t *TxProvider) {
+		t.interceptors = append(t.interceptors, d.Interceptor())
+	}
+}
+
+type nplusoneKey struct{}
+
+// nplusoneScope tracks statements of single request or transaction.
+type nplusoneScope struct {
+	mu      sync.Mutex
+	queries map[string]*repeated
+}
+
+// repeated are executions of single statement fingerprint.
+type repeated struct {
+	args     map[string]bool
+	callers  []string
+	reported bool
+}
+
+func newNPlusOneScope() *nplusoneScope {
+	return &nplusoneScope{
+		queries: make(map[string]*repeated),
+	}
+}
+
+// NPlusOneScope returns ctx in which statements of all transactions are
+// tracked together by NPlusOneDetector, e.g. for single HTTP request.
+func NPlusOneScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nplusoneKey{}, newNPlusOneScope())
+}
+
+// nplusoneScopeOf returns scope of ctx or of its transaction.
+func nplusoneScopeOf(ctx context.Context) (*nplusoneScope, bool) {
+	if s, ok := ctx.Value(nplusoneKey{}).(*nplusoneScope); ok {
+		return s, true
+	}
+	tx, ok := ctx.Value(currentTxKey{}).(*Tx)
+	if !ok || tx.stash == nil {
+		return nil, false
+	}
+	tx.stash.mu.Lock()
+	defer tx.stash.mu.Unlock()
+	s, ok := tx.stash.values[nplusoneKey{}].(*nplusoneScope)
+	if !ok {
+		s = newNPlusOneScope()
+		if tx.stash.values == nil {
+			tx.stash.values = make(map[any]any)
+		}
+		tx.stash.values[nplusoneKey{}] = s
+	}
+	return s, true
+}
+
+// track records execution of statement and returns report when statement
+// reached threshold.
+func (s *nplusoneScope) track(stmt *Statement, threshold int) (NPlusOne, bool) {
+	query := Fingerprint(stmt.Query)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.queries[query]
+	if !ok {
+		r = &repeated{args: make(map[string]bool)}
+		s.queries[query] = r
+	}
+	r.args[fmt.Sprintf("%T:%v", stmt.Args[0], stmt.Args[0])] = true
+	if stmt.Caller != "" {
+		known := false
+		for _, c := range r.callers {
+			known = known || c == stmt.Caller
+		}
+		if !known {
+			r.callers = append(r.callers, stmt.Caller)
+		}
+	}
+	if r.reported || len(r.args) < threshold {
+		return NPlusOne{}, false
+	}
+	r.reported = true
+	return NPlusOne{
+		Query:   query,
+		Count:   len(r.args),
+		Callers: append([]string(nil), r.callers...),
+	}, true
+}
+
+// Interceptor returns interceptor detecting N+1 queries, it can be
+// registered with Use or Intercept.
+func (d NPlusOneDetector) Interceptor() Interceptor {
+	threshold := d.Threshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+	logger := d.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			if stmt.Op == OpPrepare || len(stmt.Args) != 1 {
+				return next(ctx, stmt)
+			}
+			if s, ok := nplusoneScopeOf(ctx); ok {
+				if r, ok := s.track(stmt, threshold); ok {
+					if d.OnDetect != nil {
+						d.OnDetect(ctx, r)
+					} else {
+						logger.Printf("dbq: N+1 query %q executed %d times with different argument (callers: %v)",
+							r.Query, r.Count, r.Callers)
+					}
+				}
+			}
+			return next(ctx, stmt)
+		}
+	}
+}
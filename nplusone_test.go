@@ -0,0 +1,80 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func ordersRecording(userIDs ...int64) *dbq.Recording {
+	rec := &dbq.Recording{}
+	for _, id := range userIDs {
+		rec.Entries = append(rec.Entries, dbq.RecordedEntry{
+			Query:   "SELECT id FROM orders WHERE user_id = ?",
+			Args:    []dbq.RecordedValue{{V: id}},
+			Columns: []string{"id"},
+		})
+	}
+	return rec
+}
+
+func TestNPlusOneDetector(t *testing.T) {
+	dbq.CaptureCaller(true)
+	defer dbq.CaptureCaller(false)
+
+	db := sql.OpenDB(dbq.NewReplayer(ordersRecording(1, 1, 2, 3, 4)))
+	defer db.Close()
+
+	var reports []dbq.NPlusOne
+	provider := dbq.NewTxProvider(db, dbq.DetectNPlusOne(dbq.NPlusOneDetector{
+		Threshold: 3,
+		OnDetect: func(_ context.Context, r dbq.NPlusOne) {
+			reports = append(reports, r)
+		},
+	}))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		for _, id := range []int64{1, 1, 2, 3, 4} {
+			if _, err := dbq.QueryColumn[int64](tx, "SELECT id FROM orders WHERE user_id = ?", id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	if len(reports) != 1 {
+		t.Fatalf("expected single report, got %v", reports)
+	}
+	r := reports[0]
+	if r.Query != "SELECT id FROM orders WHERE user_id = ?" || r.Count != 3 {
+		t.Errorf("bad report %+v", r)
+	}
+	if len(r.Callers) != 1 || !strings.Contains(r.Callers[0], "nplusone_test.go") {
+		t.Errorf("bad callers %v", r.Callers)
+	}
+}
+
+func TestNPlusOneScope(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(ordersRecording(1, 2)))
+	defer db.Close()
+
+	out := &lines{}
+	provider := dbq.NewTxProvider(db, dbq.DetectNPlusOne(dbq.NPlusOneDetector{Threshold: 2, Logger: out}))
+	// transactions of single request share scope.
+	ctx := dbq.NPlusOneScope(context.Background())
+	for _, id := range []int64{1, 2} {
+		maybePanic(provider.Tx(ctx, func(tx dbq.TxContext) error {
+			_, err := dbq.QueryColumn[int64](tx, "SELECT id FROM orders WHERE user_id = ?", id)
+			return err
+		}))
+	}
+	if len(*out) != 1 || !strings.Contains((*out)[0], "N+1 query") {
+		t.Errorf("expected N+1 report, got %v", *out)
+	}
+}
@@ -7,8 +7,10 @@ package dbq
 import (
 	"bytes"
 	"database/sql/driver"
+	"encoding"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"time"
 )
 
@@ -50,6 +52,11 @@ func (n *Null[T]) Scan(value any) error {
 		return nil
 	}
 
+	if ok, err := scanConverted(&n.Val, value); ok {
+		n.Valid = err == nil
+		return err
+	}
+
 	n.Val, ok = value.(T)
 	if ok {
 		n.Valid = true
@@ -61,12 +68,43 @@ func (n *Null[T]) Scan(value any) error {
 	return err
 }
 
-// Value implements the driver Valuer interface.
+// Value implements the driver Valuer interface. Value is converted with
+// registered converter or driver.Valuer of T, otherwise values of named
+// types are converted to their underlying driver type, so they can be
+// written with any driver.
 func (n Null[T]) Value() (driver.Value, error) {
 	if !n.Valid {
 		return nil, nil
 	}
-	return n.Val, nil
+	if v, ok, err := valueConverted(n.Val); ok {
+		return v, err
+	}
+	if v, ok := any(n.Val).(driver.Valuer); ok {
+		return v.Value()
+	}
+	return canonicalValue(n.Val), nil
+}
+
+// canonicalValue converts v of named type, e.g. type Status string, to its
+// underlying driver type: int64, float64, bool, string or time.Time, which
+// all drivers accept.
+//
+//nolint:exhaustive
+func canonicalValue[T Type](v T) driver.Value {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint8:
+		return int64(rv.Uint())
+	case reflect.Float64:
+		return rv.Float()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		return rv.String()
+	}
+	return v
 }
 
 // FromValue creates a new T that will always be valid.
@@ -129,12 +167,107 @@ func (n Null[T]) Ptr() *T {
 	return &n.Val
 }
 
-// IsZero returns true for invalid value
+// IsZero returns true for invalid value, valid zero value like 0 or "" is
+// not zero, use IsZeroOrNull for omitempty-like checks.
 func (n Null[T]) IsZero() bool {
 	return !n.Valid
 }
 
+// IsNull returns true for invalid value.
+func (n Null[T]) IsNull() bool {
+	return !n.Valid
+}
+
+// IsZeroOrNull returns true for invalid value and for valid zero value.
+func (n Null[T]) IsZeroOrNull() bool {
+	var zero T
+	return !n.Valid || n.Val == zero
+}
+
 // Equal returns true if have the same value or are both null.
 func (n Null[T]) Equal(other Null[T]) bool {
 	return n.Valid == other.Valid && (!n.Valid || n.Val == other.Val)
 }
+
+// EqualValue returns true if values returned by ValueOrZero are equal, so
+// null is equal to valid zero value.
+func (n Null[T]) EqualValue(other Null[T]) bool {
+	return n.ValueOrZero() == other.ValueOrZero()
+}
+
+// MarshalText implements encoding.TextMarshaler, null is marshaled to
+// empty text.
+func (n Null[T]) MarshalText() ([]byte, error) {
+	if !n.Valid {
+		return []byte{}, nil
+	}
+	if m, ok := any(n.Val).(encoding.TextMarshaler); ok {
+		return m.MarshalText()
+	}
+	return []byte(asString(n.Val)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, empty text is parsed
+// as null.
+func (n *Null[T]) UnmarshalText(text []byte) error {
+	var zero T
+	if len(text) == 0 {
+		n.Val, n.Valid = zero, false
+		return nil
+	}
+	var err error
+	if u, ok := any(&n.Val).(encoding.TextUnmarshaler); ok {
+		err = u.UnmarshalText(text)
+	} else {
+		err = convertAssign(&n.Val, string(text))
+	}
+	if err != nil {
+		n.Val, n.Valid = zero, false
+		return fmt.Errorf("null: couldn't unmarshal text: %w", err)
+	}
+	n.Valid = true
+	return nil
+}
+
+// Or returns the inner value if valid, otherwise fallback.
+func (n Null[T]) Or(fallback T) T {
+	if !n.Valid {
+		return fallback
+	}
+	return n.Val
+}
+
+// Map returns null or fn applied to valid value.
+func (n Null[T]) Map(fn func(T) T) Null[T] {
+	if !n.Valid {
+		return n
+	}
+	return FromValue(fn(n.Val))
+}
+
+// Then returns null or result of fn applied to valid value, fn can return
+// null itself.
+func (n Null[T]) Then(fn func(T) Null[T]) Null[T] {
+	if !n.Valid {
+		return n
+	}
+	return fn(n.Val)
+}
+
+// MapNull returns null or fn applied to valid value of n, e.g.
+//
+//	name := dbq.MapNull(id, func(id int64) string { return names[id] })
+func MapNull[T, U Type](n Null[T], fn func(T) U) Null[U] {
+	if !n.Valid {
+		return Null[U]{}
+	}
+	return FromValue(fn(n.Val))
+}
+
+// ThenNull returns null or result of fn applied to valid value of n.
+func ThenNull[T, U Type](n Null[T], fn func(T) Null[U]) Null[U] {
+	if !n.Valid {
+		return Null[U]{}
+	}
+	return fn(n.Val)
+}
@@ -5,11 +5,14 @@
 package dbq_test
 
 import (
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/enverbisevac/dbq"
 )
@@ -102,7 +105,7 @@ func TestUnmarshalInt64Overflow(t *testing.T) {
 	int64Overflow := uint64(math.MaxInt64)
 
 	// Max int64 should decode successfully
-	var i dbq.Null[int]
+	var i dbq.Null[int64]
 	err := json.Unmarshal([]byte(strconv.FormatUint(int64Overflow, 10)), &i)
 	maybePanic(err)
 
@@ -263,3 +266,138 @@ func assertJSONEquals(t *testing.T, data []byte, cmp string, from string) {
 		t.Errorf("bad %s data: %s ≠ %s\n", from, data, cmp)
 	}
 }
+
+func TestNullText(t *testing.T) {
+	data, err := json.Marshal(map[dbq.Null[int64]]string{
+		dbq.FromValue[int64](7): "seven",
+	})
+	maybePanic(err)
+	assertJSONEquals(t, data, `{"7":"seven"}`, "null map key")
+
+	var keys map[dbq.Null[int64]]string
+	maybePanic(json.Unmarshal(data, &keys))
+	if keys[dbq.FromValue[int64](7)] != "seven" {
+		t.Errorf("bad unmarshaled map %v", keys)
+	}
+
+	text, err := dbq.NewNull("", false).MarshalText()
+	if err != nil || len(text) != 0 {
+		t.Errorf("null should marshal to empty text, got %q", text)
+	}
+
+	var b dbq.Null[bool]
+	maybePanic(b.UnmarshalText([]byte("true")))
+	if !b.Valid || !b.Val {
+		t.Errorf("bad bool %v", b)
+	}
+	maybePanic(b.UnmarshalText(nil))
+	if b.Valid {
+		t.Error("empty text should be null")
+	}
+	if err = b.UnmarshalText([]byte("maybe")); err == nil {
+		t.Error("expected error for invalid bool")
+	}
+
+	var tm dbq.Null[time.Time]
+	maybePanic(tm.UnmarshalText([]byte("2022-05-01T10:00:00Z")))
+	if text, _ = tm.MarshalText(); string(text) != "2022-05-01T10:00:00Z" {
+		t.Errorf("bad time text %q", text)
+	}
+}
+
+func TestNullCombinators(t *testing.T) {
+	valid, null := dbq.FromValue(2), dbq.Null[int]{}
+	if valid.Or(5) != 2 || null.Or(5) != 5 {
+		t.Error("bad Or")
+	}
+	double := func(i int) int { return i * 2 }
+	if !valid.Map(double).Equal(dbq.FromValue(4)) || null.Map(double).Valid {
+		t.Error("bad Map")
+	}
+	positive := func(i int) dbq.Null[int] { return dbq.NewNull(i, i > 0) }
+	if !valid.Then(positive).Valid || dbq.FromValue(-1).Then(positive).Valid || null.Then(positive).Valid {
+		t.Error("bad Then")
+	}
+	if s := dbq.MapNull(valid, strconv.Itoa); !s.Equal(dbq.FromValue("2")) || dbq.MapNull(null, strconv.Itoa).Valid {
+		t.Error("bad MapNull")
+	}
+	parse := func(s string) dbq.Null[int] {
+		i, err := strconv.Atoi(s)
+		return dbq.NewNull(i, err == nil)
+	}
+	if !dbq.ThenNull(dbq.FromValue("7"), parse).Equal(dbq.FromValue(7)) || dbq.ThenNull(dbq.FromValue("x"), parse).Valid {
+		t.Error("bad ThenNull")
+	}
+}
+
+func TestZeroSemantics(t *testing.T) {
+	null := dbq.Null[int]{}
+	zero := dbq.FromValue(0)
+	ten := dbq.FromValue(10)
+
+	if !null.IsNull() || zero.IsNull() {
+		t.Error("only invalid value should be null")
+	}
+	if !null.IsZeroOrNull() || !zero.IsZeroOrNull() || ten.IsZeroOrNull() {
+		t.Error("null and valid zero should be zero or null")
+	}
+	if zero.IsZero() {
+		t.Error("valid zero should not be zero")
+	}
+	if !null.EqualValue(zero) || null.Equal(zero) {
+		t.Error("null should equal valid zero only by value")
+	}
+	if zero.EqualValue(ten) {
+		t.Error("different values should not be equal")
+	}
+}
+
+type (
+	status   string
+	priority int16
+	ratio    float64
+	flag     bool
+	// level is written by its own Valuer.
+	level int
+)
+
+func (l level) Value() (driver.Value, error) {
+	return fmt.Sprintf("L%d", int(l)), nil
+}
+
+func TestValueNamedTypes(t *testing.T) {
+	tests := []struct {
+		valuer driver.Valuer
+		want   driver.Value
+	}{
+		{dbq.FromValue(status("active")), "active"},
+		{dbq.FromValue(priority(3)), int64(3)},
+		{dbq.FromValue(ratio(0.5)), 0.5},
+		{dbq.FromValue(flag(true)), true},
+		{dbq.FromValue(byte(7)), int64(7)},
+		{dbq.FromValue(level(2)), "L2"},
+		{dbq.FromValue(time.Unix(100, 0).UTC()), time.Unix(100, 0).UTC()},
+		{dbq.Null[status]{}, nil},
+	}
+	for _, tt := range tests {
+		v, err := tt.valuer.Value()
+		maybePanic(err)
+		if v != tt.want {
+			t.Errorf("%#v: expected %#v, got %#v", tt.valuer, tt.want, v)
+		}
+		if v != nil && !driver.IsValue(v) {
+			t.Errorf("%#v: %T is not driver value", tt.valuer, v)
+		}
+	}
+
+	var s dbq.Null[status]
+	maybePanic(s.Scan("active"))
+	if !s.Equal(dbq.FromValue(status("active"))) {
+		t.Errorf("bad scanned status %v", s)
+	}
+	var p dbq.Null[priority]
+	maybePanic(p.Scan(int64(3)))
+	if !p.Equal(dbq.FromValue(priority(3))) {
+		t.Errorf("bad scanned priority %v", p)
+	}
+}
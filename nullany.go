@@ -0,0 +1,133 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Comparer is implemented by types with own equality, e.g. time.Time.
+type Comparer[T any] interface {
+	Equal(other T) bool
+}
+
+// NullAny is nullable value of any type, e.g. uint64, uuid.UUID or domain
+// struct. Value is scanned with sql.Scanner when *T implements it, with
+// converter registered with RegisterConverter or with database/sql
+// conversion rules otherwise. It has the same API as Null.
+type NullAny[T any] struct {
+	Val   T
+	Valid bool // Valid is true if T is not NULL
+}
+
+// NewNullAny creates a new NullAny[T].
+func NewNullAny[T any](val T, valid bool) NullAny[T] {
+	return NullAny[T]{
+		Val:   val,
+		Valid: valid,
+	}
+}
+
+// Scan implements the Scanner interface.
+func (n *NullAny[T]) Scan(value any) error {
+	var zero T
+	if value == nil {
+		n.Val, n.Valid = zero, false
+		return nil
+	}
+	if s, ok := any(&n.Val).(sql.Scanner); ok {
+		err := s.Scan(value)
+		n.Valid = err == nil
+		return err
+	}
+	err := convertAssign(&n.Val, value)
+	n.Valid = err == nil
+	return err
+}
+
+// Value implements the driver Valuer interface.
+func (n NullAny[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	if v, ok, err := valueConverted(n.Val); ok {
+		return v, err
+	}
+	return driver.DefaultParameterConverter.ConvertValue(n.Val)
+}
+
+// ValueOrZero returns the inner value if valid, otherwise zero value.
+func (n NullAny[T]) ValueOrZero() T {
+	var zero T
+	if !n.Valid {
+		return zero
+	}
+	return n.Val
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *NullAny[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, nullBytes) {
+		n.Valid = false
+		return nil
+	}
+	if err := json.Unmarshal(data, &n.Val); err != nil {
+		return fmt.Errorf("null: couldn't unmarshal JSON: %w", err)
+	}
+	n.Valid = true
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (n NullAny[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Val)
+}
+
+// SetValid changes this T value and also sets it to be non-null.
+func (n *NullAny[T]) SetValid(v T) {
+	n.Val = v
+	n.Valid = true
+}
+
+// Ptr returns a pointer to this T value, or a nil pointer if Val is null.
+func (n NullAny[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Val
+}
+
+// IsZero returns true for invalid value
+func (n NullAny[T]) IsZero() bool {
+	return !n.Valid
+}
+
+// Equal returns true if have the same value or are both null. Values are
+// compared with Comparer when T implements it and with reflect.DeepEqual
+// otherwise.
+func (n NullAny[T]) Equal(other NullAny[T]) bool {
+	if n.Valid != other.Valid {
+		return false
+	}
+	if !n.Valid {
+		return true
+	}
+	if c, ok := any(n.Val).(Comparer[T]); ok {
+		return c.Equal(other.Val)
+	}
+	return reflect.DeepEqual(n.Val, other.Val)
+}
+
+func (n NullAny[T]) patchValue() (any, bool) {
+	return n.Val, n.Valid
+}
@@ -0,0 +1,62 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type point struct {
+	X, Y int
+}
+
+func TestNullAny(t *testing.T) {
+	var u dbq.NullAny[uint64]
+	maybePanic(u.Scan(int64(42)))
+	if !u.Valid || u.Val != 42 {
+		t.Errorf("bad scanned uint64 %v", u)
+	}
+	v, err := u.Value()
+	if err != nil || v != int64(42) {
+		t.Errorf("bad value %v %v", v, err)
+	}
+	maybePanic(u.Scan(nil))
+	if u.Valid {
+		t.Error("expected null")
+	}
+	if v, _ = u.Value(); v != driver.Value(nil) {
+		t.Errorf("expected nil value, got %v", v)
+	}
+
+	var h dbq.NullAny[dbq.HugeInt]
+	maybePanic(h.Scan("170141183460469231731687303715884105727"))
+	if !h.Valid || h.Val.String() != "170141183460469231731687303715884105727" {
+		t.Errorf("bad scanned scanner %v", h.Val.String())
+	}
+
+	a := dbq.NewNullAny(point{1, 2}, true)
+	if !a.Equal(dbq.NewNullAny(point{1, 2}, true)) || a.Equal(dbq.NullAny[point]{}) {
+		t.Error("bad struct equality")
+	}
+	berlin := time.FixedZone("CET", 3600)
+	t1 := dbq.NewNullAny(time.Unix(100, 0).UTC(), true)
+	if !t1.Equal(dbq.NewNullAny(time.Unix(100, 0).In(berlin), true)) {
+		t.Error("Comparer not used")
+	}
+
+	data, err := json.Marshal(a)
+	maybePanic(err)
+	assertJSONEquals(t, data, `{"X":1,"Y":2}`, "marshal NullAny")
+	var b dbq.NullAny[point]
+	maybePanic(json.Unmarshal([]byte(`null`), &b))
+	if b.Valid {
+		t.Error("expected null after unmarshal")
+	}
+}
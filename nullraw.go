@@ -0,0 +1,80 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var errInvalidJSON = errors.New("null: invalid JSON")
+
+// NullRaw is nullable raw JSON document. It is scanned from text, json or
+// bytea column and marshaled as is, without decoding and re-encoding, so
+// stored documents can be proxied untouched.
+type NullRaw struct {
+	Raw   json.RawMessage
+	Valid bool // Valid is true if Raw is not NULL
+}
+
+// NewNullRaw creates a new NullRaw.
+func NewNullRaw(raw json.RawMessage, valid bool) NullRaw {
+	return NullRaw{
+		Raw:   raw,
+		Valid: valid,
+	}
+}
+
+// Scan implements the Scanner interface.
+func (n *NullRaw) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		n.Raw, n.Valid = nil, false
+		return nil
+	case []byte:
+		raw = cloneBytes(v)
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", value, n)
+	}
+	if !json.Valid(raw) {
+		n.Raw, n.Valid = nil, false
+		return errInvalidJSON
+	}
+	n.Raw, n.Valid = raw, true
+	return nil
+}
+
+// Value implements the driver Valuer interface. Document is written as text
+// which is accepted by text, json and jsonb columns.
+func (n NullRaw) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return string(n.Raw), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *NullRaw) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, nullBytes) {
+		n.Raw, n.Valid = nil, false
+		return nil
+	}
+	n.Raw, n.Valid = cloneBytes(data), true
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (n NullRaw) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return n.Raw, nil
+}
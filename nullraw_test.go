@@ -0,0 +1,55 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestNullRawScan(t *testing.T) {
+	var n dbq.NullRaw
+	maybePanic(n.Scan([]byte(`{"b": 1,  "a": [1,2]}`)))
+	if !n.Valid || string(n.Raw) != `{"b": 1,  "a": [1,2]}` {
+		t.Errorf("bad raw %s", n.Raw)
+	}
+
+	data, err := json.Marshal(struct {
+		Doc dbq.NullRaw `json:"doc"`
+	}{n})
+	maybePanic(err)
+	assertJSONEquals(t, data, `{"doc":{"b":1,"a":[1,2]}}`, "raw json marshal")
+
+	maybePanic(n.Scan(nil))
+	if n.Valid {
+		t.Error("raw should be invalid after NULL")
+	}
+	if err = n.Scan("{"); err == nil {
+		t.Error("err should be present for invalid JSON")
+	}
+
+	v, err := dbq.NewNullRaw(json.RawMessage(`[1]`), true).Value()
+	maybePanic(err)
+	if v != "[1]" {
+		t.Errorf("bad raw value %v", v)
+	}
+}
+
+func TestNullRawUnmarshal(t *testing.T) {
+	var doc struct {
+		Doc dbq.NullRaw `json:"doc"`
+	}
+	maybePanic(json.Unmarshal([]byte(`{"doc": {"x": "y"}}`), &doc))
+	if !doc.Doc.Valid || string(doc.Doc.Raw) != `{"x": "y"}` {
+		t.Errorf("bad raw %s", doc.Doc.Raw)
+	}
+
+	maybePanic(json.Unmarshal([]byte(`{"doc": null}`), &doc))
+	if doc.Doc.Valid {
+		t.Error("raw should be invalid after null")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Optional is tri-state value for PATCH semantics, it distinguishes field
+// absent from JSON (Set is false), explicit null (Set is true, Valid is
+// false) and value (Set and Valid are true).
+type Optional[T Type] struct {
+	Val   T
+	Valid bool // Valid is true if T is not NULL
+	Set   bool // Set is true if value was present
+}
+
+// OptionalOf creates Optional set to value.
+func OptionalOf[T Type](val T) Optional[T] {
+	return Optional[T]{
+		Val:   val,
+		Valid: true,
+		Set:   true,
+	}
+}
+
+// Default creates absent Optional which stands for column DEFAULT in Insert
+// statement builder, e.g.
+//
+//	type User struct {
+//		Name    string
+//		Created dbq.Optional[time.Time]
+//	}
+//	query, args, err := dbq.Insert("users", User{Name: "john", Created: dbq.Default[time.Time]()})
+func Default[T Type]() Optional[T] {
+	return Optional[T]{}
+}
+
+// OptionalNull creates Optional set to explicit null.
+func OptionalNull[T Type]() Optional[T] {
+	return Optional[T]{
+		Set: true,
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It is called only for fields
+// present in JSON so it marks optional as set.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	var zero T
+	o.Set = true
+	if bytes.Equal(data, nullBytes) {
+		o.Val, o.Valid = zero, false
+		return nil
+	}
+
+	if err := json.Unmarshal(data, &o.Val); err != nil {
+		return fmt.Errorf("optional: couldn't unmarshal JSON: %w", err)
+	}
+
+	o.Valid = true
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Like UnmarshalJSON it
+// marks optional as set, empty text is explicit null.
+func (o *Optional[T]) UnmarshalText(text []byte) error {
+	var n Null[T]
+	err := n.UnmarshalText(text)
+	o.Val, o.Valid, o.Set = n.Val, n.Valid, err == nil
+	return err
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Val)
+}
+
+// Scan implements the Scanner interface.
+func (o *Optional[T]) Scan(value any) error {
+	var n Null[T]
+	err := n.Scan(value)
+	o.Val, o.Valid, o.Set = n.Val, n.Valid, err == nil
+	return err
+}
+
+// Value implements the driver Valuer interface. Absent value stands for
+// column DEFAULT, which only statement builders and binders omitting
+// columns, e.g. Insert and InsertBatch, can write, so it is rejected with
+// ErrDefaultValue as plain query argument instead of writing NULL.
+func (o Optional[T]) Value() (driver.Value, error) {
+	if !o.Set {
+		return nil, ErrDefaultValue
+	}
+	return o.Null().Value()
+}
+
+// Null converts optional to Null, absent value is NULL.
+func (o Optional[T]) Null() Null[T] {
+	return NewNull(o.Val, o.Valid)
+}
+
+// IsZero returns true if value is absent.
+func (o Optional[T]) IsZero() bool {
+	return !o.Set
+}
+
+func (o Optional[T]) isDefault() bool {
+	return !o.Set
+}
+
+func (o Optional[T]) patchValue() (any, bool) {
+	if !o.Valid {
+		return nil, o.Set
+	}
+	return o.Val, true
+}
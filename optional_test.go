@@ -0,0 +1,108 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type userPatch struct {
+	Name  dbq.Optional[string] `json:"name"`
+	Email dbq.Optional[string] `json:"email"`
+	Age   dbq.Optional[int]    `json:"age"`
+}
+
+func TestOptionalUnmarshal(t *testing.T) {
+	var p userPatch
+	maybePanic(json.Unmarshal([]byte(`{"name":"jane","email":null}`), &p))
+
+	if !p.Name.Set || !p.Name.Valid || p.Name.Val != "jane" {
+		t.Errorf("bad name %+v", p.Name)
+	}
+	if !p.Email.Set || p.Email.Valid {
+		t.Errorf("email should be explicit null %+v", p.Email)
+	}
+	if p.Age.Set || !p.Age.IsZero() {
+		t.Errorf("age should be absent %+v", p.Age)
+	}
+
+	if err := json.Unmarshal([]byte(`{"age":"x"}`), &p); err == nil {
+		t.Error("err should be present for invalid value")
+	}
+}
+
+func TestOptionalApplyPatch(t *testing.T) {
+	acc := account{Name: "john", Email: dbq.FromValue("john@example.com")}
+	maybePanic(dbq.ApplyPatch(&acc, userPatch{
+		Email: dbq.OptionalNull[string](),
+		Age:   dbq.OptionalOf(30),
+	}))
+	if acc.Name != "john" || acc.Email.Valid || acc.Age == nil || *acc.Age != 30 {
+		t.Errorf("bad patched account %+v", acc)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	query, args, err := dbq.Update("users", userPatch{
+		Name:  dbq.OptionalOf("jane"),
+		Email: dbq.OptionalNull[string](),
+	}, "id = ?", 1)
+	maybePanic(err)
+	if query != "UPDATE users SET name = ?, email = ? WHERE id = ?" {
+		t.Errorf("bad query %q", query)
+	}
+	if !reflect.DeepEqual(args, []any{"jane", nil, 1}) {
+		t.Errorf("bad args %v", args)
+	}
+
+	if _, _, err = dbq.Update("users", userPatch{}, "id = ?", 1); !errors.Is(err, dbq.ErrNothingToUpdate) {
+		t.Errorf("expected ErrNothingToUpdate, got %v", err)
+	}
+}
+
+type newUser struct {
+	ID      dbq.Optional[int64] `db:"id"`
+	Name    string
+	Email   dbq.Null[string]
+	Created dbq.Optional[string] `db:"created_at"`
+}
+
+func TestInsertDefault(t *testing.T) {
+	query, args, err := dbq.Insert("users", newUser{
+		Name:    "john",
+		Created: dbq.Default[string](),
+	})
+	maybePanic(err)
+	if query != "INSERT INTO users (name, email) VALUES (?, ?)" {
+		t.Errorf("bad query %q", query)
+	}
+	if len(args) != 2 || args[0] != "john" || args[1] != dbq.NewNull("", false) {
+		t.Errorf("bad args %v", args)
+	}
+
+	query, _, err = dbq.Insert("users", &newUser{ID: dbq.OptionalOf[int64](7), Created: dbq.OptionalNull[string]()})
+	maybePanic(err)
+	if query != "INSERT INTO users (id, name, email, created_at) VALUES (?, ?, ?, ?)" {
+		t.Errorf("bad query %q", query)
+	}
+
+	if _, err = dbq.Default[int]().Value(); !errors.Is(err, dbq.ErrDefaultValue) {
+		t.Errorf("expected ErrDefaultValue, got %v", err)
+	}
+
+	db := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{}))
+	defer db.Close()
+	_, err = db.ExecContext(context.Background(), "UPDATE users SET age = ?", dbq.Default[int]())
+	if !errors.Is(err, dbq.ErrDefaultValue) {
+		t.Errorf("absent argument should be rejected, got %v", err)
+	}
+}
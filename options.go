@@ -0,0 +1,148 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+// Interceptors adds interceptors to every transaction acquired from
+// provider, they run after interceptors registered with Use.
+func Interceptors(interceptor ...Interceptor) ProviderOption {
+	return func(t *TxProvider) {
+		t.interceptors = append(t.interceptors, interceptor...)
+	}
+}
+
+// QueryOptions are per-call settings of single statement.
+type QueryOptions struct {
+	// Timeout bounds statement execution including reading of rows.
+	Timeout time.Duration
+	// NoCache bypasses statement cache and caching interceptors.
+	NoCache bool
+	// Prepare executes statement as prepared statement.
+	Prepare bool
+	// ReadReplica asks routing access, e.g. Hedged, to run statement on
+	// replica.
+	ReadReplica bool
+	// Label is name of statement used by interceptors for grouping, when
+	// empty it is taken from "name:" comment in query, e.g.
+	// "-- name: FindActiveUsers".
+	Label string
+	// ZeroAsNull writes zero arguments as NULL.
+	ZeroAsNull bool
+	// Priority is used by Shedder to reject low priority statements when
+	// pool is under pressure.
+	Priority Priority
+}
+
+// QueryOption configures single Query, QueryRow or Exec call. Options are
+// passed together with query arguments and removed from them before
+// statement is executed, e.g.
+//
+//	dbq.Query(ctx, query, binder, id, dbq.Timeout(time.Second))
+type QueryOption func(*QueryOptions)
+
+// Timeout sets statement timeout.
+func Timeout(d time.Duration) QueryOption {
+	return func(o *QueryOptions) {
+		o.Timeout = d
+	}
+}
+
+// NoCache bypasses caches of statement: it runs unprepared instead of with
+// statement cache of StatementCache, caching interceptors should check
+// Options.NoCache.
+func NoCache() QueryOption {
+	return func(o *QueryOptions) {
+		o.NoCache = true
+	}
+}
+
+// Prepared executes statement as prepared statement.
+func Prepared() QueryOption {
+	return func(o *QueryOptions) {
+		o.Prepare = true
+	}
+}
+
+// ReadFromReplica routes read statement to replica. Access executing
+// statement sees it with ReadsFromReplica, Hedged sends read only queries
+// to its replica also when it has only one.
+func ReadFromReplica() QueryOption {
+	return func(o *QueryOptions) {
+		o.ReadReplica = true
+	}
+}
+
+type readReplicaKey struct{}
+
+// ReadsFromReplica returns true when statement executed with ctx was run
+// with ReadFromReplica option, it is used by routing Access.
+func ReadsFromReplica(ctx context.Context) bool {
+	v, _ := ctx.Value(readReplicaKey{}).(bool)
+	return v
+}
+
+// Label names statement for observability.
+func Label(name string) QueryOption {
+	return func(o *QueryOptions) {
+		o.Label = name
+	}
+}
+
+// Priority of statement, zero value is PriorityNormal.
+type Priority int
+
+// Statement priorities.
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// WithPriority sets statement priority, e.g. PriorityLow for reports and
+// background jobs, PriorityHigh for user facing queries.
+func WithPriority(p Priority) QueryOption {
+	return func(o *QueryOptions) {
+		o.Priority = p
+	}
+}
+
+var labelComment = regexp.MustCompile(`(?:--|/\*)\s*name:\s*([\w.-]+)`)
+
+// labelFromComment returns label from "name:" comment in query.
+func labelFromComment(query string) string {
+	m := labelComment.FindStringSubmatch(query)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// splitOptions separates query options from query arguments.
+func splitOptions(args []any) ([]any, QueryOptions) {
+	var opts QueryOptions
+	n := 0
+	for _, arg := range args {
+		if opt, ok := arg.(QueryOption); ok {
+			opt(&opts)
+			continue
+		}
+		n++
+	}
+	if n == len(args) {
+		return args, opts
+	}
+	clean := make([]any, 0, n)
+	for _, arg := range args {
+		if _, ok := arg.(QueryOption); !ok {
+			clean = append(clean, arg)
+		}
+	}
+	return clean, opts
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestQueryOptions(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer db.Close()
+
+	var opts dbq.QueryOptions
+	capture := func(next dbq.Handler) dbq.Handler {
+		return func(ctx context.Context, stmt *dbq.Statement) (dbq.Outcome, error) {
+			opts = stmt.Options
+			return next(ctx, stmt)
+		}
+	}
+	access := dbq.Intercept(db, capture)
+
+	rows, err := access.QueryContext(context.Background(), "SELECT id, name, created FROM users WHERE id > ?",
+		0, dbq.Timeout(time.Second), dbq.Prepared(), dbq.NoCache(), dbq.Label("users"))
+	maybePanic(err)
+	maybePanic(rows.Close())
+
+	want := dbq.QueryOptions{Timeout: time.Second, Prepare: true, NoCache: true, Label: "users"}
+	if opts != want {
+		t.Errorf("bad options %+v, want %+v", opts, want)
+	}
+
+	_, err = access.ExecContext(context.Background(), "INSERT INTO users (name) VALUES (?)", dbq.WithPriority(dbq.PriorityLow), dbq.ReadFromReplica(), "jane")
+	maybePanic(err)
+	if opts.Priority != dbq.PriorityLow || !opts.ReadReplica {
+		t.Errorf("Priority and ReadReplica options should be set, got %+v", opts)
+	}
+}
+
+func TestQueryLabel(t *testing.T) {
+	var label string
+	capture := func(next dbq.Handler) dbq.Handler {
+		return func(ctx context.Context, stmt *dbq.Statement) (dbq.Outcome, error) {
+			label = stmt.Options.Label
+			return dbq.Outcome{}, nil
+		}
+	}
+	access := dbq.Intercept(&nopAccess{}, capture)
+
+	_, _ = access.ExecContext(context.Background(), "UPDATE users SET active = ?", true, dbq.Label("ActivateUsers"))
+	if label != "ActivateUsers" {
+		t.Errorf("bad label %q", label)
+	}
+
+	_, _ = access.QueryContext(context.Background(), "-- name: FindActiveUsers :many\nSELECT id FROM users")
+	if label != "FindActiveUsers" {
+		t.Errorf("bad comment label %q", label)
+	}
+
+	_, _ = access.QueryContext(context.Background(), "SELECT id FROM users /* name: users.find */")
+	if label != "users.find" {
+		t.Errorf("bad comment label %q", label)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// patchField is implemented by nullable field types of patch structs.
+type patchField interface {
+	// patchValue returns value and true if field should be applied.
+	patchValue() (any, bool)
+}
+
+func (n Null[T]) patchValue() (any, bool) {
+	return n.Val, n.Valid
+}
+
+// ApplyPatch copies fields of patch into dst, fields are matched by column
+// mapping. Valid Null fields overwrite destination and invalid ones are
+// skipped, nil pointer fields are skipped as well and other fields are
+// always copied. It is in memory counterpart of partial UPDATE.
+func ApplyPatch[T, P any](dst *T, patch P) error {
+	dstMap, err := structMapOf(reflect.TypeOf(dst).Elem())
+	if err != nil {
+		return err
+	}
+	patchMap, err := structMapOf(reflect.TypeOf(patch))
+	if err != nil {
+		return err
+	}
+
+	dv := reflect.ValueOf(dst).Elem()
+	pv := reflect.ValueOf(patch)
+	for _, pf := range patchMap.fields {
+		value, ok := patchFieldValue(pv, pf.index)
+		if !ok {
+			continue
+		}
+		df, found := dstMap.lookup(pf.column)
+		if !found {
+			return fmt.Errorf("dbq: patch column %q not found in %T", pf.column, dst)
+		}
+		if err = convertAssign(fieldByIndex(dv, df.index).Addr().Interface(), value); err != nil {
+			return fmt.Errorf("dbq: patch column %q: %w", pf.column, err)
+		}
+	}
+	return nil
+}
+
+// patchFieldValue returns value of patch field and true if it should be
+// applied.
+func patchFieldValue(v reflect.Value, index []int) (any, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	if f, ok := v.Interface().(patchField); ok {
+		return f.patchValue()
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, false
+		}
+		return v.Elem().Interface(), true
+	}
+	return v.Interface(), true
+}
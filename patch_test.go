@@ -0,0 +1,51 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type account struct {
+	ID    int64
+	Name  string
+	Email dbq.Null[string]
+	Age   *int
+	Admin bool
+}
+
+type accountPatch struct {
+	Name  dbq.Null[string]
+	Email dbq.Null[string]
+	Age   dbq.Null[int]
+	Admin *bool
+}
+
+func TestApplyPatch(t *testing.T) {
+	acc := account{ID: 1, Name: "john", Email: dbq.FromValue("john@example.com")}
+
+	maybePanic(dbq.ApplyPatch(&acc, accountPatch{
+		Name: dbq.FromValue("jane"),
+		Age:  dbq.FromValue(30),
+	}))
+	if acc.ID != 1 || acc.Name != "jane" || acc.Email.Val != "john@example.com" || acc.Age == nil || *acc.Age != 30 || acc.Admin {
+		t.Errorf("bad patched account %+v", acc)
+	}
+
+	admin := true
+	maybePanic(dbq.ApplyPatch(&acc, accountPatch{Email: dbq.FromValue("jane@example.com"), Admin: &admin}))
+	if acc.Email.Val != "jane@example.com" || !acc.Email.Valid || !acc.Admin || acc.Name != "jane" {
+		t.Errorf("bad patched account %+v", acc)
+	}
+
+	type unknownPatch struct {
+		Phone dbq.Null[string]
+	}
+	if err := dbq.ApplyPatch(&acc, unknownPatch{Phone: dbq.FromValue("555")}); err == nil {
+		t.Error("err should be present for unknown column")
+	}
+}
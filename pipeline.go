@@ -0,0 +1,264 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Queued is statement queued in pipeline, its result is available after
+// Pipeline returns.
+type Queued struct {
+	Query string
+	Args  []any
+	// Dest receives columns of single returned row, statement is executed
+	// as exec when empty.
+	Dest []any
+	// Each is called for every returned row, statement is executed as
+	// query when set.
+	Each func(row Row) error
+	// Result is set for exec statements.
+	Result sql.Result
+	// Err is error of statement.
+	Err error
+}
+
+// Scan marks statement as returning single row scanned into dest.
+func (q *Queued) Scan(dest ...any) *Queued {
+	q.Dest = dest
+	return q
+}
+
+// ForEach marks statement as returning rows passed to fn one by one.
+func (q *Queued) ForEach(fn func(row Row) error) *Queued {
+	q.Each = fn
+	return q
+}
+
+// PipelineQueue collects statements sent together.
+type PipelineQueue struct {
+	queued []*Queued
+}
+
+// Exec queues statement.
+func (p *PipelineQueue) Exec(query string, args ...any) *Queued {
+	q := &Queued{Query: query, Args: args}
+	p.queued = append(p.queued, q)
+	return q
+}
+
+// PipelineExecutor is implemented by access of drivers supporting pipeline
+// mode, e.g. pgx adapter sending statements as batch without waiting for
+// each round trip. It sets Result, scans Dest or calls Each for returned
+// rows and sets Err of every statement and returns the first error.
+type PipelineExecutor interface {
+	ExecPipeline(ctx context.Context, queued []*Queued) error
+}
+
+// Pipeline queues statements with fn and executes them in order, results
+// are collected at the end, e.g.
+//
+//	var id int64
+//	err := dbq.Pipeline(ctx, func(p *dbq.PipelineQueue) {
+//		p.Exec("INSERT INTO users (name) VALUES (?) RETURNING id", name).Scan(&id)
+//		p.Exec("UPDATE stats SET users = users + 1")
+//	})
+//
+// When access of ctx implements PipelineExecutor statements pass through
+// interceptors of ctx, e.g. Policy and Rebinding, and are sent in one
+// round trip, otherwise they run one by one through ctx. Execution stops
+// at the first error, statements which didn't run have Err set to it.
+func Pipeline(ctx TxContext, fn func(p *PipelineQueue)) error {
+	var p PipelineQueue
+	fn(&p)
+	return execQueued(ctx, p.queued)
+}
+
+// execQueued executes queued statements with PipelineExecutor of ctx or
+// one by one.
+func execQueued(ctx TxContext, queued []*Queued) error {
+	if len(queued) == 0 {
+		return nil
+	}
+	if e, ok := ctx.Value(txKeyType{}).(PipelineExecutor); ok {
+		return execPipeline(ctx, e, queued)
+	}
+
+	for i, q := range queued {
+		switch {
+		case q.Each != nil:
+			q.Err = queryEach(ctx, q)
+		case len(q.Dest) > 0:
+			q.Err = maskedRow(ctx, q.Query, q.Args).Scan(q.Dest...)
+		default:
+			q.Result, q.Err = ctx.Exec(q.Query, q.Args...)
+		}
+		if q.Err != nil {
+			for _, skipped := range queued[i+1:] {
+				skipped.Err = q.Err
+			}
+			return q.Err
+		}
+	}
+	return nil
+}
+
+// execPipeline passes queued statements through interceptors of ctx, so
+// they are checked, rewritten and bound like other statements, and sends
+// them with executor. Chains of statements are nested: handler of each
+// statement runs chains of the following statements and the innermost
+// sends the pipeline, so interceptors observe the real execution. Statement
+// which interceptor doesn't pass on, e.g. served from cache, is not sent
+// and gets outcome returned by interceptor.
+func execPipeline(ctx TxContext, e PipelineExecutor, queued []*Queued) error {
+	var (
+		sent    []*Queued
+		sendErr error
+	)
+	var run func(i int) error
+	run = func(i int) error {
+		if i == len(queued) {
+			if len(sent) > 0 {
+				sendErr = e.ExecPipeline(ctx, sent)
+			}
+			return sendErr
+		}
+		q := queued[i]
+		op := OpExec
+		if q.Each != nil || len(q.Dest) > 0 {
+			op = OpQuery
+		}
+		var forwarded *Queued
+		h := func(_ context.Context, stmt *Statement) (Outcome, error) {
+			if err := bindArgs(stmt); err != nil {
+				return Outcome{}, err
+			}
+			forwarded = &Queued{Query: stmt.Query, Args: stmt.Args, Dest: q.Dest, Each: q.Each}
+			done := maskQueued(ctx, q.Query, forwarded)
+			sent = append(sent, forwarded)
+			if err := run(i + 1); err != nil && forwarded.Err == nil && sendErr == nil {
+				// pipeline stopped by the following statement before send.
+				forwarded.Err = err
+			}
+			done()
+			return Outcome{Result: forwarded.Result}, forwarded.Err
+		}
+		var (
+			out Outcome
+			err error
+		)
+		if c, ok := ctx.(interceptable); ok {
+			out, err = c.intercept(h)(ctx, newStatement(op, q.Query, q.Args))
+		} else {
+			out, err = wrapHandler(h, nil)(ctx, newStatement(op, q.Query, q.Args))
+		}
+		q.Result, q.Err = out.Result, err
+		if forwarded != nil {
+			return err
+		}
+		if err != nil {
+			for _, skipped := range queued[i+1:] {
+				skipped.Err = err
+			}
+			return err
+		}
+		if out.Rows != nil {
+			if q.Err = readQueued(q, out.Rows); q.Err != nil {
+				return q.Err
+			}
+		}
+		return run(i + 1)
+	}
+	err := run(0)
+	for _, q := range queued {
+		if q.Err != nil {
+			return q.Err
+		}
+	}
+	if err == nil {
+		err = sendErr
+	}
+	return err
+}
+
+// readQueued reads rows returned for statement q by interceptor.
+func readQueued(q *Queued, rows Rows) error {
+	if q.Each == nil {
+		return firstRow{rows: rows}.Scan(q.Dest...)
+	}
+	for rows.Next() {
+		if err := q.Each(rows); err != nil {
+			return closeRows(rows, err)
+		}
+	}
+	return closeRows(rows, nil)
+}
+
+// queryEach passes rows of statement q to q.Each.
+func queryEach(ctx TxContext, q *Queued) error {
+	sqlRows, err := ctx.Query(q.Query, q.Args...)
+	if err != nil {
+		return err
+	}
+	rows := maskRows(ctx, q.Query, sqlRows)
+	for rows.Next() {
+		if err = q.Each(rows); err != nil {
+			return closeRows(rows, err)
+		}
+	}
+	return closeRows(rows, nil)
+}
+
+// ErrTxRequired is returned outside of transaction by helpers which need
+// one, e.g. Batch.Send and Restore.
+var ErrTxRequired = errors.New("dbq: transaction required")
+
+// Batch collects statements submitted together with Send, e.g.
+//
+//	var b dbq.Batch
+//	b.Exec("UPDATE accounts SET balance = balance - ? WHERE id = ?", amount, from)
+//	b.Exec("UPDATE accounts SET balance = balance + ? WHERE id = ?", amount, to)
+//	b.Exec("SELECT id, balance FROM accounts WHERE id IN (?, ?)", from, to).
+//		ForEach(func(row dbq.Row) error { ... })
+//	err := b.Send(ctx)
+//
+// Batch is reusable builder of Pipeline: statements are sent in one round
+// trip when access of transaction implements PipelineExecutor, e.g. with
+// pgx native batching, and run one by one in the transaction otherwise.
+type Batch struct {
+	queued []*Queued
+}
+
+// Exec queues statement, its result is available after Send. Rows of
+// statement are read with Scan or ForEach of returned Queued.
+func (b *Batch) Exec(query string, args ...any) *Queued {
+	q := &Queued{Query: query, Args: args}
+	b.queued = append(b.queued, q)
+	return q
+}
+
+// Len returns number of queued statements.
+func (b *Batch) Len() int {
+	return len(b.queued)
+}
+
+// Statements returns queued statements in order, each with its result and
+// error after Send.
+func (b *Batch) Statements() []*Queued {
+	return b.queued
+}
+
+// Send executes queued statements in order in transaction of ctx and
+// returns the first error, statements which didn't run have Err set to
+// it. Outside of transaction ErrTxRequired is returned, so statements are
+// never applied partially.
+func (b *Batch) Send(ctx TxContext) error {
+	if _, ok := ctx.(*Tx); !ok {
+		return ErrTxRequired
+	}
+	return execQueued(ctx, b.queued)
+}
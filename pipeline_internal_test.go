@@ -0,0 +1,168 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+type fakePipeline struct {
+	sent [][]*Queued
+}
+
+func (f *fakePipeline) ExecPipeline(_ context.Context, queued []*Queued) error {
+	f.sent = append(f.sent, queued)
+	queued[1].Err = errors.New("boom")
+	return queued[1].Err
+}
+
+func TestPipelineExecutor(t *testing.T) {
+	exec := &fakePipeline{}
+	ctx := &DB{Context: context.WithValue(context.Background(), txKeyType{}, exec)}
+	err := Pipeline(ctx, func(p *PipelineQueue) {
+		p.Exec("UPDATE a SET n = n + 1")
+		p.Exec("UPDATE b SET n = n + 1")
+	})
+	var e *Error
+	if !errors.As(err, &e) || e.Query != "UPDATE b SET n = n + ?" {
+		t.Errorf("expected error of the second statement, got %v", err)
+	}
+	if len(exec.sent) != 1 || len(exec.sent[0]) != 2 {
+		t.Errorf("statements should be sent together, got %v", exec.sent)
+	}
+}
+
+type recordingPipeline struct {
+	sent []*Queued
+}
+
+func (f *recordingPipeline) ExecPipeline(_ context.Context, queued []*Queued) error {
+	f.sent = queued
+	return nil
+}
+
+func TestPipelineExecutorInterceptors(t *testing.T) {
+	exec := &recordingPipeline{}
+	policy := NewPolicy().Allow("support", "users", ActionSelect, ActionUpdate)
+	ctx := &Tx{
+		Context:      WithRole(context.WithValue(context.Background(), txKeyType{}, exec), "support"),
+		interceptors: []Interceptor{policy.Interceptor(), Rebinding(Postgres)},
+	}
+	var b Batch
+	update := b.Exec("UPDATE users SET name = ? WHERE id = ?", "jane", 1)
+	if err := b.Send(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.sent) != 1 || exec.sent[0].Query != "UPDATE users SET name = $1 WHERE id = $2" ||
+		len(exec.sent[0].Args) != 2 {
+		t.Errorf("statement should be rebound and bound, got %q %#v", exec.sent[0].Query, exec.sent[0].Args)
+	}
+	if update.Query != "UPDATE users SET name = ? WHERE id = ?" {
+		t.Errorf("queued statement should not change, got %q", update.Query)
+	}
+
+	b = Batch{}
+	b.Exec("DELETE FROM users")
+	if err := b.Send(ctx); !errors.Is(err, ErrNoAccess) {
+		t.Errorf("pipelined statement should be checked by policy, got %v", err)
+	}
+	if err := b.Send(&DB{Context: context.Background()}); !errors.Is(err, ErrTxRequired) {
+		t.Errorf("batch should require transaction, got %v", err)
+	}
+}
+
+func TestPipelineExecutorObservesSend(t *testing.T) {
+	exec := &recordingPipeline{}
+	var sentDuringNext []bool
+	observe := func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			out, err := next(ctx, stmt)
+			sentDuringNext = append(sentDuringNext, exec.sent != nil)
+			return out, err
+		}
+	}
+	skip := func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			if stmt.Query == "UPDATE cached SET n = 1" {
+				return Outcome{Result: driver.RowsAffected(1)}, nil
+			}
+			return next(ctx, stmt)
+		}
+	}
+	ctx := &Tx{
+		Context:      context.WithValue(context.Background(), txKeyType{}, exec),
+		interceptors: []Interceptor{observe, skip},
+	}
+	var b Batch
+	b.Exec("UPDATE a SET n = 1")
+	cached := b.Exec("UPDATE cached SET n = 1")
+	b.Exec("UPDATE b SET n = 1")
+	if err := b.Send(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.sent) != 2 || exec.sent[0].Query != "UPDATE a SET n = 1" || exec.sent[1].Query != "UPDATE b SET n = 1" {
+		t.Errorf("statement skipped by interceptor should not be sent, got %v", exec.sent)
+	}
+	if n, _ := cached.Result.RowsAffected(); n != 1 {
+		t.Errorf("skipped statement should get outcome of interceptor, got %v", cached.Result)
+	}
+	// skipped statement completes first, the others complete after send.
+	if len(sentDuringNext) != 3 || sentDuringNext[0] || !sentDuringNext[1] || !sentDuringNext[2] {
+		t.Errorf("interceptors should wrap the send, got %v", sentDuringNext)
+	}
+}
+
+type nameRow struct{}
+
+func (r nameRow) Scan(dest ...any) error {
+	*dest[0].(*string) = "jane" //nolint:forcetypeassert
+	return nil
+}
+
+func (r nameRow) Columns() ([]string, error) {
+	return []string{"name"}, nil
+}
+
+type rowPipeline struct {
+	row Row
+}
+
+func (f *rowPipeline) ExecPipeline(_ context.Context, queued []*Queued) error {
+	for _, q := range queued {
+		if q.Err = q.Each(f.row); q.Err != nil {
+			return q.Err
+		}
+	}
+	return nil
+}
+
+type bareRow struct{}
+
+func (bareRow) Scan(...any) error {
+	return nil
+}
+
+func TestPipelineExecutorMasked(t *testing.T) {
+	exec := &rowPipeline{row: nameRow{}}
+	ctx := &Tx{Context: WithRole(context.WithValue(context.Background(), txKeyType{}, exec), "support")}
+	ctx.Context = context.WithValue(ctx.Context, maskerKey{}, NewMasker().Column("name", MaskRedact))
+
+	var (
+		b    Batch
+		name string
+	)
+	b.Exec("SELECT name FROM users WHERE id = ?", 1).Scan(&name)
+	if err := b.Send(ctx); err != nil || name != "***" {
+		t.Errorf("pipelined row should be masked, got %q %v", name, err)
+	}
+
+	exec.row = bareRow{}
+	if err := b.Send(ctx); !errors.Is(err, ErrUnmaskableRow) {
+		t.Errorf("expected ErrUnmaskableRow, got %v", err)
+	}
+}
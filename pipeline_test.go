@@ -0,0 +1,136 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestPipeline(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:   "INSERT INTO users (name) VALUES (?) RETURNING id",
+			Args:    []dbq.RecordedValue{{V: "jane"}},
+			Columns: []string{"id"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(3)}}},
+		},
+		{
+			Query:        "UPDATE stats SET users = users + 1",
+			RowsAffected: 1,
+		},
+		{
+			Query: "UPDATE stats SET active = active + 1",
+			Err:   "locked",
+		},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		var (
+			id                     int64
+			insert, update, active *dbq.Queued
+			last                   *dbq.Queued
+		)
+		err := dbq.Pipeline(tx, func(p *dbq.PipelineQueue) {
+			insert = p.Exec("INSERT INTO users (name) VALUES (?) RETURNING id", "jane").Scan(&id)
+			update = p.Exec("UPDATE stats SET users = users + 1")
+			active = p.Exec("UPDATE stats SET active = active + 1")
+			last = p.Exec("UPDATE stats SET total = total + 1")
+		})
+		if err == nil || active.Err == nil || last.Err == nil {
+			t.Error("pipeline should fail at the third statement")
+		}
+		if insert.Err != nil || id != 3 {
+			t.Errorf("bad insert %d %v", id, insert.Err)
+		}
+		if n, _ := update.Result.RowsAffected(); update.Err != nil || n != 1 {
+			t.Errorf("bad update %d %v", n, update.Err)
+		}
+		return nil
+	})
+}
+
+func TestBatch(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:        "UPDATE accounts SET balance = balance - ? WHERE id = ?",
+			Args:         []dbq.RecordedValue{{V: int64(10)}, {V: int64(1)}},
+			RowsAffected: 1,
+		},
+		{
+			Query:   "SELECT id, balance FROM accounts WHERE id IN (?, ?)",
+			Args:    []dbq.RecordedValue{{V: int64(1)}, {V: int64(2)}},
+			Columns: []string{"id", "balance"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(1)}, {V: int64(90)}}, {{V: int64(2)}, {V: int64(10)}}},
+		},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		var b dbq.Batch
+		update := b.Exec("UPDATE accounts SET balance = balance - ? WHERE id = ?", 10, 1)
+		balances := make(map[int64]int64)
+		b.Exec("SELECT id, balance FROM accounts WHERE id IN (?, ?)", 1, 2).ForEach(func(row dbq.Row) error {
+			var id, balance int64
+			if err := row.Scan(&id, &balance); err != nil {
+				return err
+			}
+			balances[id] = balance
+			return nil
+		})
+		if b.Len() != 2 {
+			t.Errorf("expected 2 queued statements, got %d", b.Len())
+		}
+		if err := b.Send(tx); err != nil {
+			return err
+		}
+		if n, _ := update.Result.RowsAffected(); n != 1 {
+			t.Errorf("bad update %d", n)
+		}
+		if balances[1] != 90 || balances[2] != 10 {
+			t.Errorf("bad balances %v", balances)
+		}
+		for _, q := range b.Statements() {
+			if q.Err != nil {
+				t.Errorf("%s failed: %v", q.Query, q.Err)
+			}
+		}
+		return nil
+	})
+}
+
+func TestBatchMasked(t *testing.T) {
+	const query = "SELECT id, name, created FROM users WHERE id > ?"
+	rec := usersRecording()
+	rec.Entries = []dbq.RecordedEntry{rec.Entries[0], rec.Entries[0]}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	provider := dbq.NewTxProvider(db, dbq.Masking(dbq.NewMasker().Column("name", dbq.MaskRedact, "admin")))
+	ctx := dbq.WithRole(context.Background(), "support")
+	maybePanic(provider.Tx(ctx, func(tx dbq.TxContext) error {
+		var (
+			b     dbq.Batch
+			first user
+			names []string
+		)
+		b.Exec(query, 0).Scan(userBinder(&first)...)
+		b.Exec(query, 0).ForEach(func(row dbq.Row) error {
+			var u user
+			if err := row.Scan(userBinder(&u)...); err != nil {
+				return err
+			}
+			names = append(names, u.Name.Val)
+			return nil
+		})
+		if err := b.Send(tx); err != nil {
+			return err
+		}
+		if first.Name.Val != "***" || len(names) != 2 || names[0] != "***" {
+			t.Errorf("batch results should be masked, got %+v %q", first, names)
+		}
+		return nil
+	}))
+}
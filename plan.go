@@ -0,0 +1,198 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PlanClass is coarse classification of query plan access path.
+type PlanClass int
+
+// Plan classes ordered from the cheapest to the most expensive access path.
+const (
+	PlanUnknown PlanClass = iota
+	PlanIndexOnlyScan
+	PlanIndexScan
+	PlanBitmapScan
+	PlanSeqScan
+)
+
+func (c PlanClass) String() string {
+	switch c {
+	case PlanIndexOnlyScan:
+		return "index only scan"
+	case PlanIndexScan:
+		return "index scan"
+	case PlanBitmapScan:
+		return "bitmap scan"
+	case PlanSeqScan:
+		return "seq scan"
+	case PlanUnknown:
+	}
+	return "unknown"
+}
+
+// planMarkers maps EXPLAIN output fragments (lower case) of Postgres,
+// MySQL and SQLite to plan class, the first matching marker classifies
+// line. Lines are wrapped in tabs, so MySQL access type column, e.g.
+// "\tref\t", and SQLite detail column, e.g. "\tscan ", are matched as
+// whole fields.
+var planMarkers = []struct {
+	marker string
+	class  PlanClass
+}{
+	{"index only scan", PlanIndexOnlyScan},
+	{"using covering index", PlanIndexOnlyScan},
+	{"using index", PlanIndexScan},
+	{"index scan", PlanIndexScan},
+	{"\tsearch ", PlanIndexScan},
+	{"bitmap heap scan", PlanBitmapScan},
+	{"bitmap index scan", PlanBitmapScan},
+	{"seq scan", PlanSeqScan},
+	{"full scan", PlanSeqScan},
+	{"\tscan constant row", PlanUnknown},
+	{"\tscan ", PlanSeqScan},
+	{"\tall\t", PlanSeqScan},
+	{"\tindex\t", PlanSeqScan},
+	{"\tsystem\t", PlanIndexScan},
+	{"\tconst\t", PlanIndexScan},
+	{"\teq_ref\t", PlanIndexScan},
+	{"\tref\t", PlanIndexScan},
+	{"\tref_or_null\t", PlanIndexScan},
+	{"\tfulltext\t", PlanIndexScan},
+	{"\tindex_merge\t", PlanIndexScan},
+	{"\tunique_subquery\t", PlanIndexScan},
+	{"\tindex_subquery\t", PlanIndexScan},
+	{"\trange\t", PlanIndexScan},
+}
+
+// ClassifyPlan returns the most expensive access path found in EXPLAIN
+// output.
+func ClassifyPlan(plan string) PlanClass {
+	class := PlanUnknown
+	for _, line := range strings.Split(strings.ToLower(plan), "\n") {
+		line = "\t" + line + "\t"
+		for _, m := range planMarkers {
+			if strings.Contains(line, m.marker) {
+				if m.class > class {
+					class = m.class
+				}
+				break
+			}
+		}
+	}
+	return class
+}
+
+// Explain runs EXPLAIN for query and returns its output, one row per line
+// with columns separated by tab.
+func Explain(ctx context.Context, db Access, query string, args ...any) (string, error) {
+	rows, err := db.QueryContext(ctx, "EXPLAIN "+query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for rows.Next() {
+		values := make([]any, len(cols))
+		for i := range values {
+			values[i] = new(Null[string])
+		}
+		if err = rows.Scan(values...); err != nil {
+			return "", err
+		}
+		fields := make([]string, len(values))
+		for i, v := range values {
+			fields[i] = v.(*Null[string]).Val //nolint:forcetypeassert
+		}
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+// TestingT is subset of testing.TB used by test helpers.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+type criticalQuery struct {
+	class PlanClass
+	query string
+	args  []any
+}
+
+// PlanGuard guards registered critical queries against plan regressions,
+// e.g. edit of query which turns index scan into seq scan.
+type PlanGuard struct {
+	db      Access
+	mu      sync.Mutex
+	queries map[string]criticalQuery
+}
+
+// NewPlanGuard creates plan guard running EXPLAIN on db.
+func NewPlanGuard(db Access) *PlanGuard {
+	return &PlanGuard{
+		db:      db,
+		queries: make(map[string]criticalQuery),
+	}
+}
+
+// Register critical query under name with expected plan class.
+func (g *PlanGuard) Register(name string, class PlanClass, query string, args ...any) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.queries[name] = criticalQuery{
+		class: class,
+		query: query,
+		args:  args,
+	}
+}
+
+// Check captures plans of all registered queries and reports error for each
+// query whose plan class differs from expected one.
+func (g *PlanGuard) Check(ctx context.Context, t TestingT) {
+	t.Helper()
+
+	g.mu.Lock()
+	names := make([]string, 0, len(g.queries))
+	for name := range g.queries {
+		names = append(names, name)
+	}
+	g.mu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := g.check(ctx, name); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+}
+
+func (g *PlanGuard) check(ctx context.Context, name string) error {
+	g.mu.Lock()
+	q := g.queries[name]
+	g.mu.Unlock()
+
+	plan, err := Explain(ctx, g.db, q.query, q.args...)
+	if err != nil {
+		return fmt.Errorf("plan guard %s: %w", name, err)
+	}
+	if class := ClassifyPlan(plan); class != q.class {
+		return fmt.Errorf("plan guard %s: plan changed from %v to %v:\n%s", name, q.class, class, plan)
+	}
+	return nil
+}
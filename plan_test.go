@@ -0,0 +1,41 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestClassifyPlan(t *testing.T) {
+	tests := []struct {
+		plan string
+		want dbq.PlanClass
+	}{
+		{"Index Scan using users_pkey on users  (cost=0.29..8.30 rows=1 width=4)", dbq.PlanIndexScan},
+		{"Index Only Scan using users_email_idx on users", dbq.PlanIndexOnlyScan},
+		{"Seq Scan on users  (cost=0.00..18.50 rows=850 width=4)", dbq.PlanSeqScan},
+		{"Bitmap Heap Scan on users\n  ->  Bitmap Index Scan on users_email_idx", dbq.PlanBitmapScan},
+		{"Nested Loop\n  ->  Index Scan using a_pkey on a\n  ->  Seq Scan on b", dbq.PlanSeqScan},
+		{"2\t0\t0\tSEARCH users USING INDEX users_email_idx (email=?)", dbq.PlanIndexScan},
+		{"2\t0\t0\tSCAN users", dbq.PlanSeqScan},
+		{"1\tSIMPLE\tusers\tALL\tNULL", dbq.PlanSeqScan},
+		{"Result", dbq.PlanUnknown},
+		{"CTE Scan on recent\n  ->  Function Scan on generate_series g", dbq.PlanUnknown},
+		{"Subquery Scan on s\n  ->  Values Scan on \"*VALUES*\"", dbq.PlanUnknown},
+		{"Parallel Seq Scan on events", dbq.PlanSeqScan},
+		{"1\tSIMPLE\tusers\tNULL\tref\tusers_email_idx\tusers_email_idx\t1022\tconst\t1\t100.00\tNULL", dbq.PlanIndexScan},
+		{"1\tSIMPLE\tusers\tNULL\teq_ref\tPRIMARY\tPRIMARY\t8\tt.user_id\t1\t100.00\tNULL", dbq.PlanIndexScan},
+		{"1\tSIMPLE\tusers\tNULL\trange\tPRIMARY\tPRIMARY\t8\tNULL\t10\t100.00\tUsing where", dbq.PlanIndexScan},
+		{"1\tSIMPLE\tusers\tNULL\tALL\tNULL\tNULL\tNULL\tNULL\t100\t10.00\tUsing where", dbq.PlanSeqScan},
+		{"2\t0\t0\tSCAN CONSTANT ROW", dbq.PlanUnknown},
+	}
+	for _, tt := range tests {
+		if got := dbq.ClassifyPlan(tt.plan); got != tt.want {
+			t.Errorf("ClassifyPlan(%q) = %v, want %v", tt.plan, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// AnyTable matches every table in Policy rules.
+const AnyTable = "*"
+
+type policyRule struct {
+	table   string
+	actions map[Action]bool
+}
+
+// Policy rejects statements which role of caller from context (see
+// WithRole) is not allowed to run, as defense in depth for shared internal
+// tooling, e.g.
+//
+//	policy := dbq.NewPolicy().
+//		Allow("support", dbq.AnyTable, dbq.ActionSelect).
+//		Allow("support", "tickets", dbq.ActionInsert, dbq.ActionUpdate)
+//	provider := dbq.NewTxProvider(db, dbq.Interceptors(policy.Interceptor()))
+//
+// Statement is allowed when every table it references is allowed for its
+// action, rejected statements fail with error matching ErrNoAccess.
+// Statements without tables, like SET, are checked against AnyTable rules.
+// Statements which are not recognized (ActionOther), like DO or CALL, may
+// run anything, so they are denied unless ActionOther is allowed on
+// AnyTable. Savepoints of nested transactions are not checked. Callers
+// without role are rejected unless DefaultRole is set.
+type Policy struct {
+	// DefaultRole is used when context carries no role.
+	DefaultRole string
+
+	mu    sync.RWMutex
+	rules map[string][]policyRule
+}
+
+// NewPolicy creates policy which denies everything.
+func NewPolicy() *Policy {
+	return &Policy{
+		rules: make(map[string][]policyRule),
+	}
+}
+
+// Allow allows role to run actions on table or AnyTable. Unqualified table
+// matches table in any schema, table qualified with schema matches only
+// qualified references.
+func (p *Policy) Allow(role, table string, actions ...Action) *Policy {
+	allowed := make(map[Action]bool, len(actions))
+	for _, a := range actions {
+		allowed[a] = true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rules[role] = append(p.rules[role], policyRule{
+		table:   strings.ToLower(table),
+		actions: allowed,
+	})
+	return p
+}
+
+// Check returns error matching ErrNoAccess when role is not allowed to run
+// query. Every statement of query with several statements must be
+// allowed, query which can't be split into statements, e.g. with
+// unterminated literal, is rejected.
+func (p *Policy) Check(role, query string) error {
+	stmts, ok := splitStatements(query)
+	if !ok || len(stmts) == 0 {
+		return fmt.Errorf("%w: role %q can't run malformed statement", ErrNoAccess, role)
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, stmt := range stmts {
+		action, tables := ClassifyStatement(stmt)
+		if len(tables) == 0 || action == ActionOther {
+			tables = Tables{AnyTable}
+		}
+		for _, table := range tables {
+			if !p.allowed(role, action, strings.ToLower(table)) {
+				return fmt.Errorf("%w: role %q can't %s %s", ErrNoAccess, role, action, table)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Policy) allowed(role string, action Action, table string) bool {
+	unqualified := table[strings.LastIndexByte(table, '.')+1:]
+	for _, r := range p.rules[role] {
+		if !r.actions[action] {
+			continue
+		}
+		if r.table == table || (action != ActionOther && (r.table == AnyTable || r.table == unqualified)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Interceptor rejects disallowed statements before they are executed.
+func (p *Policy) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			if internalSavepoint(ctx) {
+				return next(ctx, stmt)
+			}
+			role, ok := RoleFromCtx(ctx)
+			if !ok {
+				role = p.DefaultRole
+			}
+			if err := p.Check(role, stmt.Query); err != nil {
+				return Outcome{}, err
+			}
+			return next(ctx, stmt)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := dbq.NewPolicy().
+		Allow("support", dbq.AnyTable, dbq.ActionSelect).
+		Allow("support", "tickets", dbq.ActionInsert, dbq.ActionUpdate).
+		Allow("ops", "public.logs", dbq.ActionDelete).
+		Allow("ops", "users", dbq.ActionOther).
+		Allow("dba", dbq.AnyTable, dbq.ActionSet, dbq.ActionShow)
+
+	tests := []struct {
+		role    string
+		query   string
+		allowed bool
+	}{
+		{"support", "SELECT * FROM users", true},
+		{"support", "UPDATE tickets SET state = ?", true},
+		{"support", "UPDATE users SET name = ?", false},
+		{"support", "INSERT INTO tickets SELECT * FROM users", false},
+		{"support", "DELETE FROM tickets", false},
+		{"ops", "DELETE FROM logs", false},
+		{"ops", "DELETE FROM public.logs", true},
+		{"ops", "SELECT 1", false},
+		{"", "SELECT * FROM users", false},
+		{"support", "SELECT * FROM users;", true},
+		{"support", "SELECT 1; DELETE FROM users", false},
+		{"support", "SELECT ';' FROM users; SELECT 2", true},
+		{"support", "SELECT * INTO users_copy FROM users", false},
+		{"support", "SELECT * FROM users WHERE name = 'x", false},
+		{"support", "SELECT * FROM users WHERE id IN (1", false},
+		{"support", "", false},
+		{"support", "EXPLAIN SELECT * FROM users", true},
+		{"support", "EXPLAIN ANALYZE DELETE FROM users", false},
+		{"support", "SELECT 1 /*! ; DELETE FROM users */", false},
+		{"dba", "SET search_path = x", true},
+		{"dba", "SHOW search_path", true},
+		{"dba", "SET ROLE admin", false},
+		{"dba", "DO $$ BEGIN DELETE FROM users; END $$", false},
+		{"dba", "CALL purge()", false},
+		{"ops", "COPY users FROM stdin", false},
+	}
+	for i, tt := range tests {
+		err := policy.Check(tt.role, tt.query)
+		if tt.allowed && err != nil {
+			t.Errorf("#%d: %s should be allowed to run %q: %v", i, tt.role, tt.query, err)
+		}
+		if !tt.allowed && !errors.Is(err, dbq.ErrNoAccess) {
+			t.Errorf("#%d: %s should not be allowed to run %q", i, tt.role, tt.query)
+		}
+	}
+}
+
+func TestPolicyInterceptor(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = rec.Entries[:1]
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	policy := dbq.NewPolicy().Allow("support", "users", dbq.ActionSelect)
+	provider := dbq.NewTxProvider(db, dbq.Interceptors(policy.Interceptor()))
+	ctx := dbq.WithRole(context.Background(), "support")
+	err := provider.Tx(ctx, func(tx dbq.TxContext) error {
+		if _, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO users (name) VALUES (?)", "jane")
+		return err
+	})
+	if !errors.Is(err, dbq.ErrNoAccess) {
+		t.Errorf("insert should be rejected, got %v", err)
+	}
+}
+
+func TestPolicyNestedTx(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = []dbq.RecordedEntry{
+		{Query: "SAVEPOINT dbq_sp_1"},
+		rec.Entries[0],
+		{Query: "RELEASE SAVEPOINT dbq_sp_1"},
+	}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	policy := dbq.NewPolicy().Allow("support", "users", dbq.ActionSelect)
+	provider := dbq.NewTxProvider(db, dbq.Interceptors(policy.Interceptor()))
+	ctx := dbq.WithRole(context.Background(), "support")
+	maybePanic(provider.Tx(ctx, func(tx dbq.TxContext) error {
+		return provider.Tx(tx, func(tx dbq.TxContext) error {
+			_, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+			return err
+		})
+	}))
+	if err := policy.Check("support", "SAVEPOINT x"); !errors.Is(err, dbq.ErrNoAccess) {
+		t.Errorf("savepoint of caller should be checked, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FoldDialect is implemented by dialects with native case-insensitive
+// comparison, other dialects use LOWER() on both sides. Returned fragments
+// use ? placeholder for compared value.
+type FoldDialect interface {
+	// EqualFold returns case-insensitive equality predicate for column.
+	EqualFold(column string) string
+	// LikeFold returns case-insensitive LIKE predicate for column, pattern
+	// is escaped with '!', see EscapeLike.
+	LikeFold(column string) string
+}
+
+func (duckDB) EqualFold(column string) string {
+	return "lower(" + column + ") = lower(?)"
+}
+
+func (duckDB) LikeFold(column string) string {
+	return column + ` ILIKE ? ESCAPE '!'`
+}
+
+// likeEscaper escapes LIKE wildcards. Escape character is '!', backslash
+// is escape character of MySQL string literals and ESCAPE '\' is syntax
+// error there.
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+// EscapeLike escapes LIKE wildcards in s with '!', predicate should use
+// ESCAPE '!' clause.
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// EqualFold returns predicate and its argument comparing column with value
+// ignoring case, e.g.
+//
+//	where, args := dbq.EqualFold(dialect, "email", email)
+//	users, err := dbq.Query(ctx, "SELECT id, email FROM users WHERE "+where, binder, args...)
+func EqualFold(d Dialect, column, value string) (string, []any) {
+	if f, ok := d.(FoldDialect); ok {
+		return f.EqualFold(column), []any{value}
+	}
+	return "LOWER(" + column + ") = LOWER(?)", []any{value}
+}
+
+// HasPrefixFold returns predicate and its argument matching column values
+// starting with prefix ignoring case.
+func HasPrefixFold(d Dialect, column, prefix string) (string, []any) {
+	return likeFold(d, column, EscapeLike(prefix)+"%")
+}
+
+// ContainsFold returns predicate and its argument matching column values
+// containing substr ignoring case.
+func ContainsFold(d Dialect, column, substr string) (string, []any) {
+	return likeFold(d, column, "%"+EscapeLike(substr)+"%")
+}
+
+func likeFold(d Dialect, column, pattern string) (string, []any) {
+	if f, ok := d.(FoldDialect); ok {
+		return f.LikeFold(column), []any{pattern}
+	}
+	return "LOWER(" + column + `) LIKE LOWER(?) ESCAPE '!'`, []any{pattern}
+}
+
+var collationName = regexp.MustCompile(`^"?[A-Za-z0-9_.-]+"?$`)
+
+// Collate returns column expression compared with given collation, e.g.
+// Collate("name", "NOCASE") for SQLite or Collate("name", `"und-x-icu"`)
+// for Postgres. Invalid collation names are ignored.
+func Collate(column, collation string) string {
+	if !collationName.MatchString(collation) {
+		return column
+	}
+	return column + " COLLATE " + collation
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type plainDialect struct{}
+
+func (plainDialect) Name() string {
+	return "plain"
+}
+
+func (plainDialect) Placeholder(int) string {
+	return "?"
+}
+
+func TestFoldPredicates(t *testing.T) {
+	tests := []struct {
+		where, wantWhere string
+		args, wantArgs   []any
+	}{}
+	add := func(where string, args []any, wantWhere string, wantArgs ...any) {
+		tests = append(tests, struct {
+			where, wantWhere string
+			args, wantArgs   []any
+		}{where, wantWhere, args, wantArgs})
+	}
+
+	w, a := dbq.EqualFold(plainDialect{}, "email", "John@Example.com")
+	add(w, a, "LOWER(email) = LOWER(?)", "John@Example.com")
+	w, a = dbq.ContainsFold(plainDialect{}, "name", "50%_off!")
+	add(w, a, `LOWER(name) LIKE LOWER(?) ESCAPE '!'`, `%50!%!_off!!%`)
+	w, a = dbq.HasPrefixFold(dbq.DuckDB, "name", "jo")
+	add(w, a, `name ILIKE ? ESCAPE '!'`, "jo%")
+
+	for _, tt := range tests {
+		if tt.where != tt.wantWhere || !reflect.DeepEqual(tt.args, tt.wantArgs) {
+			t.Errorf("got %q %v, want %q %v", tt.where, tt.args, tt.wantWhere, tt.wantArgs)
+		}
+	}
+}
+
+func TestCollate(t *testing.T) {
+	if c := dbq.Collate("name", "NOCASE"); c != "name COLLATE NOCASE" {
+		t.Errorf("bad collate %q", c)
+	}
+	if c := dbq.Collate("name", "x; DROP TABLE t"); c != "name" {
+		t.Errorf("invalid collation should be ignored, got %q", c)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var inList = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`)
+
+// Fingerprint normalizes query so executions differing only in literals
+// and whitespace share fingerprint: comments are removed, string and
+// numeric literals are replaced with ?, lists of placeholders are
+// collapsed to (...) and whitespace runs to single space.
+func Fingerprint(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if j := skipQuoted(query, i); j > i {
+			switch c {
+			case '\'':
+				b.WriteByte('?')
+			case '"', '`':
+				b.WriteString(query[i:j])
+			default:
+				b.WriteByte(' ')
+			}
+			i = j - 1
+			continue
+		}
+		if c >= '0' && c <= '9' && (i == 0 || !isNameChar(query[i-1]) && query[i-1] != '$') {
+			j := i
+			for j < len(query) && (query[j] >= '0' && query[j] <= '9' || query[j] == '.') {
+				j++
+			}
+			b.WriteByte('?')
+			i = j - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return inList.ReplaceAllString(squashSpaces(b.String()), "(...)")
+}
+
+// QueryStats are cumulative stats of queries sharing fingerprint.
+type QueryStats struct {
+	Fingerprint string        `json:"fingerprint"`
+	Count       int64         `json:"count"`
+	Errors      int64         `json:"errors"`
+	Total       time.Duration `json:"total"`
+	Mean        time.Duration `json:"mean"`
+	StdDev      time.Duration `json:"stddev"`
+	Max         time.Duration `json:"max"`
+	// Rows is total of rows affected by Exec statements.
+	Rows int64 `json:"rows"`
+	// Action and Tables classify statement, see ClassifyStatement.
+	Action string `json:"action"`
+	Tables Tables `json:"tables,omitempty"`
+}
+
+type profileEntry struct {
+	stats QueryStats
+	// mean and m2 of latency in seconds, Welford's algorithm.
+	mean, m2 float64
+}
+
+// Profiler accumulates per-fingerprint stats of statements, which can be
+// dumped for offline analysis of misbehaving instance. Latency of Query
+// statements does not include reading of rows.
+type Profiler struct {
+	mu      sync.Mutex
+	entries map[string]*profileEntry
+	since   time.Time
+}
+
+// NewProfiler creates empty profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		entries: make(map[string]*profileEntry),
+		since:   time.Now(),
+	}
+}
+
+// Interceptor returns interceptor profiling statements.
+func (p *Profiler) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			start := time.Now()
+			out, err := next(ctx, stmt)
+			var rows int64
+			if err == nil && out.Result != nil {
+				rows, _ = out.Result.RowsAffected()
+			}
+			p.add(Fingerprint(stmt.Query), time.Since(start), rows, err)
+			return out, err
+		}
+	}
+}
+
+func (p *Profiler) add(fp string, d time.Duration, rows int64, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.entries[fp]
+	if !ok {
+		action, tables := ClassifyStatement(fp)
+		e = &profileEntry{stats: QueryStats{Fingerprint: fp, Action: action.String(), Tables: tables}}
+		p.entries[fp] = e
+	}
+	e.stats.Count++
+	if err != nil {
+		e.stats.Errors++
+	}
+	e.stats.Total += d
+	e.stats.Rows += rows
+	if d > e.stats.Max {
+		e.stats.Max = d
+	}
+	x := d.Seconds()
+	delta := x - e.mean
+	e.mean += delta / float64(e.stats.Count)
+	e.m2 += delta * (x - e.mean)
+}
+
+// Snapshot returns stats ordered by total latency, the highest first.
+func (p *Profiler) Snapshot() []QueryStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]QueryStats, 0, len(p.entries))
+	for _, e := range p.entries {
+		s := e.stats
+		s.Mean = time.Duration(e.mean * float64(time.Second))
+		if s.Count > 1 {
+			s.StdDev = time.Duration(math.Sqrt(e.m2/float64(s.Count-1)) * float64(time.Second))
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Total != stats[j].Total {
+			return stats[i].Total > stats[j].Total
+		}
+		return stats[i].Fingerprint < stats[j].Fingerprint
+	})
+	return stats
+}
+
+// Reset clears accumulated stats.
+func (p *Profiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries = make(map[string]*profileEntry)
+	p.since = time.Now()
+}
+
+// WriteJSON writes snapshot as JSON.
+func (p *Profiler) WriteJSON(w io.Writer) error {
+	p.mu.Lock()
+	since := p.since
+	p.mu.Unlock()
+	return json.NewEncoder(w).Encode(struct {
+		Since   time.Time    `json:"since"`
+		Queries []QueryStats `json:"queries"`
+	}{since, p.Snapshot()})
+}
+
+// WriteText writes snapshot in format similar to pprof top listing.
+func (p *Profiler) WriteText(w io.Writer) error {
+	stats := p.Snapshot()
+	var total time.Duration
+	for _, s := range stats {
+		total += s.Total
+	}
+	if _, err := fmt.Fprintf(w, "Total: %v\n%12s %7s %8s %12s %12s %8s  %s\n",
+		total, "total", "total%", "count", "mean", "stddev", "rows", "fingerprint"); err != nil {
+		return err
+	}
+	for _, s := range stats {
+		pct := 0.0
+		if total > 0 {
+			pct = 100 * float64(s.Total) / float64(total)
+		}
+		if _, err := fmt.Fprintf(w, "%12v %6.2f%% %8d %12v %12v %8d  %s\n",
+			s.Total, pct, s.Count, s.Mean, s.StdDev, s.Rows, s.Fingerprint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
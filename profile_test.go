@@ -0,0 +1,71 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM users  WHERE id = 42 -- find\n":          "SELECT * FROM users WHERE id = ?",
+		"SELECT * FROM t2 WHERE name = 'john' AND x = $1":       "SELECT * FROM t2 WHERE name = ? AND x = $1",
+		"SELECT * FROM users WHERE id IN (?, ?, ?) /* batch */": "SELECT * FROM users WHERE id IN (...)",
+		`SELECT "col1" FROM t WHERE v > 1.5`:                    `SELECT "col1" FROM t WHERE v > ?`,
+	}
+	for in, want := range tests {
+		if got := dbq.Fingerprint(in); got != want {
+			t.Errorf("Fingerprint(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	p := dbq.NewProfiler()
+	db := dbq.Intercept(&nopAccess{}, p.Interceptor())
+	ctx := context.Background()
+	for _, q := range []string{"DELETE FROM logs WHERE id = 1", "DELETE FROM logs WHERE id = 2", "DELETE FROM users"} {
+		_, _ = db.ExecContext(ctx, q)
+	}
+
+	stats := p.Snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("bad stats %v", stats)
+	}
+	counts := map[string]int64{}
+	for _, s := range stats {
+		counts[s.Fingerprint] = s.Count
+	}
+	if counts["DELETE FROM logs WHERE id = ?"] != 2 || counts["DELETE FROM users"] != 1 {
+		t.Errorf("bad counts %v", counts)
+	}
+
+	var buf bytes.Buffer
+	maybePanic(p.WriteJSON(&buf))
+	var dump struct {
+		Queries []dbq.QueryStats `json:"queries"`
+	}
+	maybePanic(json.Unmarshal(buf.Bytes(), &dump))
+	if len(dump.Queries) != 2 {
+		t.Errorf("bad dump %s", buf.String())
+	}
+
+	buf.Reset()
+	maybePanic(p.WriteText(&buf))
+	if !strings.Contains(buf.String(), "DELETE FROM logs WHERE id = ?") {
+		t.Errorf("bad text dump %s", buf.String())
+	}
+
+	p.Reset()
+	if len(p.Snapshot()) != 0 {
+		t.Error("stats not reset")
+	}
+}
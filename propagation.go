@@ -0,0 +1,53 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import "errors"
+
+// ErrTxExists is returned by TxPropagation with PropagationNever when ctx
+// already belongs to transaction.
+var ErrTxExists = errors.New("dbq: transaction already in progress")
+
+// Propagation declares how function run with TxPropagation relates to
+// transaction of its context. It formalizes FromCtxOr pattern: functions
+// can be called both inside and outside of transaction, e.g.
+//
+//	func (s *Store) Transfer(ctx context.Context, from, to int64, amount int64) error {
+//		return s.provider.TxPropagation(ctx, dbq.PropagationRequired, func(tx dbq.TxContext) error {
+//			...
+//		}, &dbq.DefaultTxOpts)
+//	}
+type Propagation int
+
+const (
+	// PropagationNested runs function in savepoint of existing
+	// transaction or in new transaction. It is used by Tx and TxWithOpts.
+	PropagationNested Propagation = iota
+	// PropagationRequired joins existing transaction or starts new one.
+	// Joined transaction is not rolled back by error of function, it is
+	// rolled back when the error is returned from function of existing
+	// transaction too.
+	PropagationRequired
+	// PropagationRequiresNew always starts new independent transaction,
+	// it commits even if existing transaction rolls back later.
+	PropagationRequiresNew
+	// PropagationNever fails with ErrTxExists inside transaction and runs
+	// function in new transaction otherwise.
+	PropagationNever
+)
+
+func (p Propagation) String() string {
+	switch p {
+	case PropagationNested:
+		return "nested"
+	case PropagationRequired:
+		return "required"
+	case PropagationRequiresNew:
+		return "requires_new"
+	case PropagationNever:
+		return "never"
+	}
+	return "unknown"
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestTxPropagation(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{Query: "DELETE FROM users"},
+		{Query: "DELETE FROM orders"},
+		{Query: "DELETE FROM logs"},
+	}}
+	replayer := dbq.NewReplayer(rec)
+	db := sql.OpenDB(replayer)
+	defer db.Close()
+	provider := dbq.NewTxProvider(db)
+	exec := func(query string) func(dbq.TxContext) error {
+		return func(tx dbq.TxContext) error {
+			_, err := tx.Exec(query)
+			return err
+		}
+	}
+
+	maybePanic(provider.Tx(context.Background(), func(outer dbq.TxContext) error {
+		if err := exec("DELETE FROM users")(outer); err != nil {
+			return err
+		}
+		// joins outer transaction without savepoint.
+		if err := provider.TxPropagation(outer, dbq.PropagationRequired, exec("DELETE FROM orders"), &dbq.DefaultTxOpts); err != nil {
+			return err
+		}
+		// new transaction on another connection.
+		if err := provider.TxPropagation(outer, dbq.PropagationRequiresNew, exec("DELETE FROM logs"), &dbq.DefaultTxOpts); err != nil {
+			return err
+		}
+		err := provider.TxPropagation(outer, dbq.PropagationNever, exec("DELETE FROM users"), &dbq.DefaultTxOpts)
+		if !errors.Is(err, dbq.ErrTxExists) {
+			t.Errorf("expected ErrTxExists, got %v", err)
+		}
+		if err = provider.TxPropagation(outer, dbq.Propagation(42), exec("DELETE FROM users"), &dbq.DefaultTxOpts); err == nil {
+			t.Error("expected error for unknown propagation")
+		}
+		return nil
+	}))
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("expected all statements executed, %d remaining", n)
+	}
+}
@@ -2,43 +2,212 @@ package dbq
 
 import (
 	"database/sql"
+	"errors"
+	"time"
 )
 
+// Query loads all rows into slice of T. Rows are scanned with RowScanner
+// when *T implements it, with binder when it is not nil, otherwise single
+// column is scanned into T.
 func Query[T any](ctx TxContext, query string, binder func(*T) []any, args ...any) ([]T, error) {
+	start := time.Now()
 	rows, err := ctx.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	result, err := CollectRows(maskRows(ctx, query, rows), binder)
+	if err != nil {
+		return nil, wrapError(OpQuery, query, start, err)
+	}
+	localize(ctx, query, result)
+	return result, nil
+}
 
-	var results []T
+// QueryStruct runs query and maps columns to fields of T by TagName tags,
+// so no binder is needed, e.g.
+//
+//	users, err := dbq.QueryStruct[User](ctx, "SELECT id, name FROM users")
+func QueryStruct[T any](ctx TxContext, query string, args ...any) ([]T, error) {
+	start := time.Now()
+	rows, err := ctx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	result, err := CollectStructs[T](maskRows(ctx, query, rows))
+	if err != nil {
+		return nil, wrapError(OpQuery, query, start, err)
+	}
+	localize(ctx, query, result)
+	return result, nil
+}
 
-	for rows.Next() {
-		var result T
-		cols := binder(&result)
-		err = rows.Scan(cols...)
-		if err != nil {
-			return nil, err
+// QueryRow loads single row into T. Row is scanned with RowScanner when *T
+// implements it, with binder when it is not nil, otherwise single column is
+// scanned into T.
+func QueryRow[T any](ctx TxContext, query string, binder func(*T) []any, args ...any) (T, error) {
+	var result T
+	start := time.Now()
+	row := maskedRow(ctx, query, args)
+	var err error
+	if binder != nil {
+		err = scanRow[T](row, &result, binder)
+	} else if s, ok := any(&result).(RowScanner); ok {
+		err = s.ScanRow(row)
+	} else {
+		err = row.Scan(&result)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, notFound(ctx, query, args)
 		}
-		results = append(results, result)
+		return result, wrapError(OpQueryRow, query, start, err)
 	}
-	return results, nil
+	localize(ctx, query, &result)
+	return result, nil
 }
 
-func QueryRow[T any](ctx TxContext, query string, binder func(*T) []any, args ...any) (T, error) {
+// QueryOne loads single row into T like QueryRow, but returns
+// ErrTooManyRows when query returns more than one row instead of silently
+// taking the first one.
+func QueryOne[T any](ctx TxContext, query string, binder func(*T) []any, args ...any) (T, error) {
+	var result T
+	start := time.Now()
+	sqlRows, err := ctx.Query(query, args...)
+	if err != nil {
+		return result, err
+	}
+	rows := maskRows(ctx, query, sqlRows)
+
+	if !rows.Next() {
+		if err = closeRows(rows, nil); err != nil {
+			return result, wrapError(OpQuery, query, start, err)
+		}
+		return result, notFound(ctx, query, args)
+	}
+	if binder != nil {
+		err = scanRow[T](rows, &result, binder)
+	} else if s, ok := any(&result).(RowScanner); ok {
+		err = s.ScanRow(rows)
+	} else {
+		err = rows.Scan(&result)
+	}
+	if err != nil {
+		return result, wrapError(OpQuery, query, start, closeRows(rows, err))
+	}
+	if rows.Next() {
+		var zero T
+		_ = rows.Close()
+		return zero, ErrTooManyRows
+	}
+	if err = closeRows(rows, nil); err != nil {
+		return result, wrapError(OpQuery, query, start, err)
+	}
+	localize(ctx, query, &result)
+	return result, nil
+}
+
+// QueryScalar loads single value, e.g. of COUNT, EXISTS or MAX, without
+// binder:
+//
+//	n, err := dbq.QueryScalar[int64](ctx, "SELECT COUNT(*) FROM users")
+//
+// Use Null[T] for values which may be NULL, e.g. MAX of empty table.
+// NotFoundError is returned when query returns no row.
+func QueryScalar[T any](ctx TxContext, query string, args ...any) (T, error) {
 	var result T
-	if err := ctx.QueryRow(query, args...).Scan(&result); err != nil {
-		if err == sql.ErrNoRows {
-			return result, &NotFoundError{
-				DataSource: ctx.Value(CtxDataSourceKey{}).(string),
-			}
+	start := time.Now()
+	err := maskedRow(ctx, query, args).Scan(&result)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, notFound(ctx, query, args)
 		}
+		return result, wrapError(OpQueryRow, query, start, err)
+	}
+	localize(ctx, query, &result)
+	return result, nil
+}
+
+// QueryColumn loads values of single column result set without binder,
+// e.g.
+//
+//	ids, err := dbq.QueryColumn[int64](ctx, "SELECT id FROM users WHERE active")
+func QueryColumn[T any](ctx TxContext, query string, args ...any) ([]T, error) {
+	start := time.Now()
+	rows, err := ctx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	result, err := CollectRows(maskRows(ctx, query, rows), func(v *T) []any {
+		return []any{v}
+	})
+	if err != nil {
+		return nil, wrapError(OpQuery, query, start, err)
+	}
+	localize(ctx, query, result)
+	return result, nil
+}
+
+// ExecReturning runs INSERT, UPDATE or DELETE statement with RETURNING
+// clause (Postgres, SQLite) and scans the first returned row into T like
+// QueryRow, so generated ids and defaulted columns come back without second
+// query, e.g.
+//
+//	u, err := dbq.ExecReturning(ctx, "INSERT INTO users (name) VALUES (?) RETURNING id, created", func(u *User) []any {
+//		return []any{&u.ID, &u.Created}
+//	}, name)
+//
+// NotFoundError is returned when statement returns no row, e.g. INSERT with
+// ON CONFLICT DO NOTHING.
+func ExecReturning[T any](ctx TxContext, query string, binder func(*T) []any, args ...any) (T, error) {
+	return execReturning(ctx, query, binder, args, true)
+}
+
+// execReturning runs ExecReturning, returned row is masked when masked is
+// true.
+func execReturning[T any](ctx TxContext, query string, binder func(*T) []any, args []any, masked bool) (T, error) {
+	var result T
+	start := time.Now()
+	sqlRows, err := ctx.Query(query, args...)
+	if err != nil {
 		return result, err
 	}
+	var rows Rows = sqlRows
+	if masked {
+		rows = maskRows(ctx, query, sqlRows)
+	}
+
+	if !rows.Next() {
+		if err = closeRows(rows, nil); err != nil {
+			return result, wrapError(OpQuery, query, start, err)
+		}
+		return result, notFound(ctx, query, args)
+	}
+	if binder != nil {
+		err = scanRow[T](rows, &result, binder)
+	} else if s, ok := any(&result).(RowScanner); ok {
+		err = s.ScanRow(rows)
+	} else {
+		err = rows.Scan(&result)
+	}
+	if err != nil {
+		return result, wrapError(OpQuery, query, start, closeRows(rows, err))
+	}
+	// remaining rows are drained so that statement is fully executed.
+	for rows.Next() {
+	}
+	if err = closeRows(rows, nil); err != nil {
+		var zero T
+		return zero, wrapError(OpQuery, query, start, err)
+	}
+	localize(ctx, query, &result)
 	return result, nil
 }
 
+// Exec runs query and returns last insert id. Drivers not supporting last
+// insert id, like Postgres, return error, use ExecAffected or ExecResult
+// instead.
 func Exec(ctx TxContext, query string, args ...any) (int64, error) {
+	start := time.Now()
 	result, err := ctx.Exec(query, args...)
 	if err != nil {
 		return 0, err
@@ -46,8 +215,51 @@ func Exec(ctx TxContext, query string, args ...any) (int64, error) {
 
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, wrapError(OpExec, query, start, err)
 	}
 
 	return id, nil
 }
+
+// ExecAffected runs query and returns number of affected rows, e.g. for
+// UPDATE and DELETE.
+func ExecAffected(ctx TxContext, query string, args ...any) (int64, error) {
+	start := time.Now()
+	result, err := ctx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, wrapError(OpExec, query, start, err)
+	}
+
+	return n, nil
+}
+
+// Result of ExecResult.
+type Result struct {
+	// LastInsertID is zero when driver doesn't support it.
+	LastInsertID int64
+	RowsAffected int64
+}
+
+// ExecResult runs query and returns both last insert id and number of
+// affected rows. Only error of rows affected is reported since last insert
+// id is not supported by every driver.
+func ExecResult(ctx TxContext, query string, args ...any) (Result, error) {
+	start := time.Now()
+	result, err := ctx.Exec(query, args...)
+	if err != nil {
+		return Result{}, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return Result{}, wrapError(OpExec, query, start, err)
+	}
+	id, _ := result.LastInsertId()
+
+	return Result{LastInsertID: id, RowsAffected: n}, nil
+}
@@ -0,0 +1,269 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type scannedUser struct {
+	user
+}
+
+func (u *scannedUser) ScanRow(row dbq.Row) error {
+	return row.Scan(userBinder(&u.user)...)
+}
+
+func replayTx(t *testing.T, rec *dbq.Recording, fn func(tx dbq.TxContext) error) {
+	t.Helper()
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+	maybePanic(dbq.NewTxProvider(db).Tx(context.Background(), fn))
+}
+
+func TestQueryRowScanner(t *testing.T) {
+	replayTx(t, usersRecording(), func(tx dbq.TxContext) error {
+		users, err := dbq.Query[scannedUser](tx, "SELECT id, name, created FROM users WHERE id > ?", nil, 0)
+		if err != nil {
+			return err
+		}
+		if len(users) != 2 || users[0].ID != 1 || users[0].Name.Val != "john" || users[1].Name.Valid {
+			t.Errorf("bad users %v", users)
+		}
+		return nil
+	})
+}
+
+func TestQuerySingleColumn(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{{
+		Query: "SELECT id FROM users", Columns: []string{"id"},
+		Rows: [][]dbq.RecordedValue{{{V: int64(1)}}, {{V: int64(2)}}},
+	}}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		ids, err := dbq.Query[int64](tx, "SELECT id FROM users", nil)
+		if err != nil {
+			return err
+		}
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Errorf("bad ids %v", ids)
+		}
+		return nil
+	})
+}
+
+func TestQueryRowRowScanner(t *testing.T) {
+	replayTx(t, usersRecording(), func(tx dbq.TxContext) error {
+		u, err := dbq.QueryRow[scannedUser](tx, "SELECT id, name, created FROM users WHERE id > ?", nil, 0)
+		if err != nil {
+			return err
+		}
+		if u.ID != 1 || u.Name.Val != "john" {
+			t.Errorf("bad user %v", u)
+		}
+		return nil
+	})
+}
+
+func TestQueryRowBinder(t *testing.T) {
+	replayTx(t, usersRecording(), func(tx dbq.TxContext) error {
+		u, err := dbq.QueryRow(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+		if err != nil {
+			return err
+		}
+		if u.ID != 1 || u.Name.Val != "john" || !u.Created.Equal(time.Unix(100, 0)) {
+			t.Errorf("bad user %v", u)
+		}
+		return nil
+	})
+}
+
+// sliceRows is Rows adapter over in memory values.
+type sliceRows struct {
+	values [][]any
+	pos    int
+	closed bool
+}
+
+func (r *sliceRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.values)
+}
+
+func (r *sliceRows) Scan(dest ...any) error {
+	for i, d := range dest {
+		*(d.(*any)) = r.values[r.pos-1][i]
+	}
+	return nil
+}
+
+func (r *sliceRows) Columns() ([]string, error) {
+	return []string{"v"}, nil
+}
+
+func (r *sliceRows) Err() error {
+	return nil
+}
+
+func (r *sliceRows) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestCollectRows(t *testing.T) {
+	rows := &sliceRows{values: [][]any{{1}, {"a"}}}
+	values, err := dbq.CollectRows(rows, func(v *any) []any { return []any{v} })
+	maybePanic(err)
+	if len(values) != 2 || values[0] != 1 || values[1] != "a" {
+		t.Errorf("bad values %v", values)
+	}
+	if !rows.closed {
+		t.Error("rows should be closed")
+	}
+}
+
+func TestQueryOne(t *testing.T) {
+	const byName = "SELECT id, name, created FROM users WHERE name = ?"
+	rec := usersRecording()
+	rec.Entries = []dbq.RecordedEntry{
+		rec.Entries[0],
+		{
+			Query:   byName,
+			Args:    []dbq.RecordedValue{{V: "john"}},
+			Columns: []string{"id", "name", "created"},
+			Rows:    rec.Entries[0].Rows[:1],
+		},
+		{Query: byName, Args: []dbq.RecordedValue{{V: "nobody"}}, Columns: []string{"id", "name", "created"}},
+	}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		if _, err := dbq.QueryOne(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0); !errors.Is(err, dbq.ErrTooManyRows) {
+			t.Errorf("expected ErrTooManyRows, got %v", err)
+		}
+		u, err := dbq.QueryOne(tx, byName, userBinder, "john")
+		if err != nil || u.ID != 1 {
+			t.Errorf("bad user %v %v", u, err)
+		}
+		if _, err = dbq.QueryOne(tx, byName, userBinder, "nobody"); !errors.Is(err, dbq.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		return nil
+	})
+}
+
+func TestExecResult(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:        "DELETE FROM users WHERE id > ?",
+			Args:         []dbq.RecordedValue{{V: int64(1)}},
+			RowsAffected: 4,
+		},
+		{
+			Query:        "INSERT INTO users (name) VALUES (?)",
+			Args:         []dbq.RecordedValue{{V: "jane"}},
+			LastInsertID: 9,
+			RowsAffected: 1,
+		},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		n, err := dbq.ExecAffected(tx, "DELETE FROM users WHERE id > ?", 1)
+		if err != nil || n != 4 {
+			t.Errorf("bad rows affected %d %v", n, err)
+		}
+		res, err := dbq.ExecResult(tx, "INSERT INTO users (name) VALUES (?)", "jane")
+		if err != nil || res != (dbq.Result{LastInsertID: 9, RowsAffected: 1}) {
+			t.Errorf("bad result %+v %v", res, err)
+		}
+		return nil
+	})
+}
+
+func TestExecReturning(t *testing.T) {
+	created := time.Unix(300, 0).UTC()
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:   "INSERT INTO users (name) VALUES (?) RETURNING id, created",
+			Args:    []dbq.RecordedValue{{V: "jane"}},
+			Columns: []string{"id", "created"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(3)}, {V: created}}},
+		},
+		{
+			Query: "INSERT INTO users (name) VALUES (?) ON CONFLICT DO NOTHING RETURNING id",
+			Args:  []dbq.RecordedValue{{V: "jane"}},
+		},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		u, err := dbq.ExecReturning(tx, "INSERT INTO users (name) VALUES (?) RETURNING id, created", func(u *user) []any {
+			return []any{&u.ID, &u.Created}
+		}, "jane")
+		if err != nil || u.ID != 3 || !u.Created.Equal(created) {
+			t.Errorf("bad returned user %+v %v", u, err)
+		}
+		_, err = dbq.ExecReturning[int64](tx, "INSERT INTO users (name) VALUES (?) ON CONFLICT DO NOTHING RETURNING id", nil, "jane")
+		if !errors.Is(err, dbq.ErrNotFound) {
+			t.Errorf("expected not found, got %v", err)
+		}
+		return nil
+	})
+}
+
+func TestQueryScalarAndColumn(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:   "SELECT COUNT(*) FROM users",
+			Columns: []string{"count"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(2)}}},
+		},
+		{
+			Query:   "SELECT MAX(name) FROM users WHERE id > ?",
+			Args:    []dbq.RecordedValue{{V: int64(5)}},
+			Columns: []string{"max"},
+			Rows:    [][]dbq.RecordedValue{{{V: nil}}},
+		},
+		{
+			Query:   "SELECT id FROM users",
+			Columns: []string{"id"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(1)}}, {{V: int64(2)}}},
+		},
+		{
+			Query:   "SELECT id FROM users WHERE id < ?",
+			Args:    []dbq.RecordedValue{{V: int64(0)}},
+			Columns: []string{"id"},
+		},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		n, err := dbq.QueryScalar[int64](tx, "SELECT COUNT(*) FROM users")
+		if err != nil {
+			return err
+		}
+		if n != 2 {
+			t.Errorf("expected count 2, got %d", n)
+		}
+		last, err := dbq.QueryScalar[dbq.Null[string]](tx, "SELECT MAX(name) FROM users WHERE id > ?", 5)
+		if err != nil {
+			return err
+		}
+		if last.Valid {
+			t.Errorf("expected null max, got %v", last)
+		}
+
+		ids, err := dbq.QueryColumn[int64](tx, "SELECT id FROM users")
+		if err != nil {
+			return err
+		}
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Errorf("bad ids %v", ids)
+		}
+		ids, err = dbq.QueryColumn[int64](tx, "SELECT id FROM users WHERE id < ?", 0)
+		if err != nil || len(ids) != 0 {
+			t.Errorf("expected no ids, got %v %v", ids, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// QueryLog records statements sent to database, tests use it to assert
+// order and number of round trips within TxContext, e.g.
+//
+//	log := dbq.NewQueryLog()
+//	err := provider.Tx(ctx, func(tx dbq.TxContext) error {
+//		return svc.Rename(log.Record(tx), id, name)
+//	})
+//	log.AssertSequence(t, "name: FindUser", "UPDATE users")
+type QueryLog struct {
+	mu    sync.Mutex
+	stmts []Statement
+}
+
+// NewQueryLog creates empty query log.
+func NewQueryLog() *QueryLog {
+	return &QueryLog{}
+}
+
+type queryLogKey struct{}
+
+// Record returns ctx whose statements, and statements of contexts derived
+// from it, are recorded to log. Statements are recorded after all
+// interceptors, so statements short-circuited by caching interceptors are
+// not counted as round trips. Statements of other contexts sharing the
+// same transaction or provider are not recorded.
+func (l *QueryLog) Record(ctx TxContext) TxContext {
+	return ctx.WithValue(queryLogKey{}, l)
+}
+
+// recordQueries wraps h recording statements to query log of context.
+func recordQueries(h Handler) Handler {
+	return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+		if l, ok := ctx.Value(queryLogKey{}).(*QueryLog); ok {
+			l.add(stmt)
+		}
+		return h(ctx, stmt)
+	}
+}
+
+// Interceptor returns interceptor recording all statements of provider or
+// access it is added to, see Record to record statements of single
+// TxContext. Statements short-circuited by inner interceptors are recorded
+// too, place it after caching interceptors to count only database round
+// trips.
+func (l *QueryLog) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			l.add(stmt)
+			return next(ctx, stmt)
+		}
+	}
+}
+
+func (l *QueryLog) add(stmt *Statement) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stmts = append(l.stmts, *stmt)
+}
+
+// Statements returns recorded statements in order of execution.
+func (l *QueryLog) Statements() []Statement {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Statement(nil), l.stmts...)
+}
+
+// Reset clears log.
+func (l *QueryLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stmts = nil
+}
+
+// AssertCount reports error when number of recorded statements is not n.
+func (l *QueryLog) AssertCount(t TestingT, n int) bool {
+	t.Helper()
+	if stmts := l.Statements(); len(stmts) != n {
+		t.Errorf("dbq: expected %d statements, got %d:\n%s", n, len(stmts), formatStatements(stmts))
+		return false
+	}
+	return true
+}
+
+// AssertAtMost reports error when more than n statements are recorded.
+func (l *QueryLog) AssertAtMost(t TestingT, n int) bool {
+	t.Helper()
+	if stmts := l.Statements(); len(stmts) > n {
+		t.Errorf("dbq: expected at most %d statements, got %d:\n%s", n, len(stmts), formatStatements(stmts))
+		return false
+	}
+	return true
+}
+
+// AssertSequence reports error unless recorded statements match patterns
+// one by one. Pattern matches statement with equal label, pattern with
+// "name: " prefix matches only label, or query containing pattern where
+// whitespace runs are compared as single space.
+func (l *QueryLog) AssertSequence(t TestingT, patterns ...string) bool {
+	t.Helper()
+	stmts := l.Statements()
+	ok := len(stmts) == len(patterns)
+	for i := 0; ok && i < len(stmts); i++ {
+		ok = matchStatement(&stmts[i], patterns[i])
+	}
+	if !ok {
+		t.Errorf("dbq: expected statements:\n%s\ngot:\n%s",
+			"  "+strings.Join(patterns, "\n  "), formatStatements(stmts))
+	}
+	return ok
+}
+
+func matchStatement(stmt *Statement, pattern string) bool {
+	if label := strings.TrimPrefix(pattern, "name: "); label != pattern {
+		return stmt.Options.Label == label
+	}
+	if stmt.Options.Label != "" && stmt.Options.Label == pattern {
+		return true
+	}
+	return strings.Contains(squashSpaces(stmt.Query), squashSpaces(pattern))
+}
+
+func squashSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func formatStatements(stmts []Statement) string {
+	var b strings.Builder
+	for i := range stmts {
+		b.WriteString("  ")
+		b.WriteString(stmts[i].Op.String())
+		b.WriteString(": ")
+		b.WriteString(squashSpaces(stmts[i].Query))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
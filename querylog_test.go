@@ -0,0 +1,71 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type recordingT struct {
+	errors []string
+}
+
+func (*recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestQueryLog(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer db.Close()
+
+	log := dbq.NewQueryLog()
+	provider := dbq.NewTxProvider(db, dbq.Interceptors(log.Interceptor()))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		if _, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO users (name) VALUES (?)", "jane", dbq.Label("InsertUser"))
+		return err
+	}))
+
+	if !log.AssertCount(t, 2) || !log.AssertAtMost(t, 2) ||
+		!log.AssertSequence(t, "SELECT  id, name", "name: InsertUser") {
+		return
+	}
+
+	rt := &recordingT{}
+	if log.AssertSequence(rt, "INSERT", "SELECT") || log.AssertAtMost(rt, 1) || len(rt.errors) != 2 {
+		t.Errorf("mismatch not reported: %v", rt.errors)
+	}
+
+	log.Reset()
+	log.AssertCount(t, 0)
+}
+
+func TestQueryLogRecord(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer db.Close()
+
+	log := dbq.NewQueryLog()
+	maybePanic(dbq.NewTxProvider(db).Tx(context.Background(), func(tx dbq.TxContext) error {
+		if _, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0); err != nil {
+			return err
+		}
+		// only statements of recorded context and contexts derived from
+		// it are logged.
+		type key struct{}
+		recorded := log.Record(tx).WithValue(key{}, "v")
+		_, err := recorded.Exec("INSERT INTO users (name) VALUES (?)", "jane")
+		return err
+	}))
+	log.AssertSequence(t, "INSERT INTO users")
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StoredQuery is single version of named query.
+type StoredQuery struct {
+	Name       string
+	Version    int
+	SQL        string
+	Deprecated bool
+}
+
+// Label is statement label of query version, e.g. FindUser.v2.
+func (q StoredQuery) Label() string {
+	return q.Name + ".v" + strconv.Itoa(q.Version)
+}
+
+// QueryStore is library of versioned named queries. Queries returned by
+// store are labeled with their version, so store interceptor observes
+// executions of deprecated versions while call sites migrate, e.g.
+//
+//	store := dbq.NewQueryStore()
+//	store.Register("FindUser", 1, "SELECT * FROM users WHERE id = ?")
+//	store.Register("FindUser", 2, "SELECT id, name FROM users WHERE id = ?")
+//	store.Deprecate("FindUser", 1)
+//	dbq.Use(store.Interceptor())
+//	...
+//	query, err := store.Get("FindUser", 1) // executions are reported
+type QueryStore struct {
+	// OnDeprecated is called when deprecated query version is executed,
+	// when nil it is logged.
+	OnDeprecated func(ctx context.Context, q StoredQuery, stmt *Statement)
+
+	mu      sync.RWMutex
+	queries map[string]map[int]*storedQuery
+}
+
+type storedQuery struct {
+	StoredQuery
+	executions int64
+}
+
+// NewQueryStore creates empty query store.
+func NewQueryStore() *QueryStore {
+	return &QueryStore{
+		queries: make(map[string]map[int]*storedQuery),
+	}
+}
+
+// Register adds version of named query replacing previously registered SQL
+// of the same version.
+func (s *QueryStore) Register(name string, version int, sql string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions, ok := s.queries[name]
+	if !ok {
+		versions = make(map[int]*storedQuery)
+		s.queries[name] = versions
+	}
+	q := StoredQuery{Name: name, Version: version}
+	q.SQL = "-- name: " + q.Label() + "\n" + sql
+	if old, ok := versions[version]; ok {
+		q.Deprecated = old.Deprecated
+	}
+	versions[version] = &storedQuery{StoredQuery: q}
+}
+
+// Deprecate marks version of query deprecated.
+func (s *QueryStore) Deprecate(name string, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queries[name][version]
+	if !ok {
+		return fmt.Errorf("dbq: query %s version %d is not registered", name, version)
+	}
+	q.Deprecated = true
+	return nil
+}
+
+// Get returns SQL of query version.
+func (s *QueryStore) Get(name string, version int) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q, ok := s.queries[name][version]
+	if !ok {
+		return "", fmt.Errorf("dbq: query %s version %d is not registered", name, version)
+	}
+	return q.SQL, nil
+}
+
+// Latest returns SQL of the highest registered version of query.
+func (s *QueryStore) Latest(name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var latest *storedQuery
+	for _, q := range s.queries[name] {
+		if latest == nil || q.Version > latest.Version {
+			latest = q
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("dbq: query %s is not registered", name)
+	}
+	return latest.SQL, nil
+}
+
+// Executions returns number of executions per version of query observed
+// by store interceptor.
+func (s *QueryStore) Executions(name string) map[int]int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[int]int64, len(s.queries[name]))
+	for v, q := range s.queries[name] {
+		out[v] = q.executions
+	}
+	return out
+}
+
+// Interceptor returns interceptor counting executions of stored queries
+// and reporting deprecated ones.
+func (s *QueryStore) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			if q, ok := s.executed(stmt.Options.Label); ok && q.Deprecated {
+				if s.OnDeprecated != nil {
+					s.OnDeprecated(ctx, q, stmt)
+				} else {
+					log.Printf("dbq: deprecated query %s executed at %s", q.Label(), stmt.Caller)
+				}
+			}
+			return next(ctx, stmt)
+		}
+	}
+}
+
+// executed counts execution of query with label.
+func (s *QueryStore) executed(label string) (StoredQuery, bool) {
+	i := strings.LastIndex(label, ".v")
+	if i < 0 {
+		return StoredQuery{}, false
+	}
+	version, err := strconv.Atoi(label[i+2:])
+	if err != nil {
+		return StoredQuery{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queries[label[:i]][version]
+	if !ok {
+		return StoredQuery{}, false
+	}
+	q.executions++
+	return q.StoredQuery, true
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestQueryStore(t *testing.T) {
+	store := dbq.NewQueryStore()
+	store.Register("DeleteLogs", 1, "DELETE FROM logs")
+	store.Register("DeleteLogs", 2, "DELETE FROM logs WHERE created < ?")
+	if err := store.Deprecate("DeleteLogs", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Deprecate("DeleteLogs", 3); err == nil {
+		t.Error("expected error for unknown version")
+	}
+	var deprecated []string
+	store.OnDeprecated = func(_ context.Context, q dbq.StoredQuery, _ *dbq.Statement) {
+		deprecated = append(deprecated, q.Label())
+	}
+
+	db := dbq.Intercept(&nopAccess{}, store.Interceptor())
+	ctx := context.Background()
+	v1, err := store.Get("DeleteLogs", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := store.Latest("DeleteLogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{v1, latest, latest} {
+		if _, err = db.ExecContext(ctx, q, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(deprecated) != 1 || deprecated[0] != "DeleteLogs.v1" {
+		t.Errorf("bad deprecated executions %v", deprecated)
+	}
+	if e := store.Executions("DeleteLogs"); e[1] != 1 || e[2] != 2 {
+		t.Errorf("bad executions %v", e)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// QuotaConfig configures soft per-tenant limits.
+type QuotaConfig struct {
+	// Window is accounting period after which usage is reset, default
+	// one minute.
+	Window time.Duration
+	// MaxQueries is number of statements per window, zero is unlimited.
+	MaxQueries int64
+	// MaxTime is total execution time of statements per window, zero is
+	// unlimited. Time of reading rows after Query returns is not counted.
+	MaxTime time.Duration
+	// OnExceeded is called before every statement of tenant which exceeded
+	// limit in current window. Returned error rejects statement, nil lets
+	// it run, so callback can alert, delay or throttle tenant.
+	OnExceeded func(ctx context.Context, tenant string, usage TenantUsage) error
+}
+
+// TenantUsage is usage of tenant in current window.
+type TenantUsage struct {
+	Queries int64
+	Time    time.Duration
+	Since   time.Time
+}
+
+// Quota accounts statements and their execution time per tenant taken from
+// context with WithTenant, statements without tenant are accounted to
+// empty tenant. Limits are soft: statements are only counted and reported
+// to OnExceeded callback.
+type Quota struct {
+	cfg QuotaConfig
+	now func() time.Time
+
+	mu     sync.Mutex
+	usage  map[string]*TenantUsage
+	pruned time.Time
+}
+
+// NewQuota creates per-tenant quota.
+func NewQuota(cfg QuotaConfig) *Quota {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	return &Quota{
+		cfg:   cfg,
+		now:   time.Now,
+		usage: make(map[string]*TenantUsage),
+	}
+}
+
+// Usage returns usage of tenant in current window.
+func (q *Quota) Usage(tenant string) TenantUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return *q.current(tenant)
+}
+
+// Interceptor returns interceptor accounting statements.
+func (q *Quota) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			tenant, _ := TenantFromCtx(ctx)
+			if usage, exceeded := q.check(tenant); exceeded && q.cfg.OnExceeded != nil {
+				if err := q.cfg.OnExceeded(ctx, tenant, usage); err != nil {
+					return Outcome{}, err
+				}
+			}
+			start := q.now()
+			out, err := next(ctx, stmt)
+			q.add(tenant, q.now().Sub(start))
+			return out, err
+		}
+	}
+}
+
+func (q *Quota) check(tenant string) (TenantUsage, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.current(tenant)
+	exceeded := (q.cfg.MaxQueries > 0 && u.Queries >= q.cfg.MaxQueries) ||
+		(q.cfg.MaxTime > 0 && u.Time >= q.cfg.MaxTime)
+	return *u, exceeded
+}
+
+func (q *Quota) add(tenant string, d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.current(tenant)
+	u.Queries++
+	u.Time += d
+}
+
+// current returns usage of tenant in current window, q.mu must be held.
+// Once per window usage of tenants whose window expired is removed, so
+// tenants which stopped sending statements are not kept forever.
+func (q *Quota) current(tenant string) *TenantUsage {
+	now := q.now()
+	if now.Sub(q.pruned) >= q.cfg.Window {
+		for t, u := range q.usage {
+			if now.Sub(u.Since) >= q.cfg.Window {
+				delete(q.usage, t)
+			}
+		}
+		q.pruned = now
+	}
+	u, ok := q.usage[tenant]
+	if !ok || now.Sub(u.Since) >= q.cfg.Window {
+		u = &TenantUsage{Since: now}
+		q.usage[tenant] = u
+	}
+	return u
+}
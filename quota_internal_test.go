@@ -0,0 +1,28 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotaPrunesExpiredUsage(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	quota := NewQuota(QuotaConfig{Window: time.Minute})
+	quota.now = func() time.Time { return now }
+
+	quota.add("a", time.Millisecond)
+	quota.add("b", time.Millisecond)
+	now = now.Add(time.Minute)
+	quota.add("b", time.Millisecond)
+
+	if _, ok := quota.usage["a"]; ok || len(quota.usage) != 1 {
+		t.Errorf("expired usage should be pruned, got %v", quota.usage)
+	}
+	if u := quota.Usage("b"); u.Queries != 1 {
+		t.Errorf("usage should be reset in new window, got %+v", u)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestQuota(t *testing.T) {
+	errThrottled := errors.New("throttled")
+	var alerts []string
+	quota := dbq.NewQuota(dbq.QuotaConfig{
+		MaxQueries: 2,
+		OnExceeded: func(ctx context.Context, tenant string, usage dbq.TenantUsage) error {
+			alerts = append(alerts, tenant)
+			if usage.Queries >= 3 {
+				return errThrottled
+			}
+			return nil
+		},
+	})
+	db := dbq.Intercept(&nopAccess{}, quota.Interceptor())
+	noisy := dbq.WithTenant(context.Background(), "noisy")
+	quiet := dbq.WithTenant(context.Background(), "quiet")
+
+	for i := 0; i < 3; i++ {
+		if _, err := db.ExecContext(noisy, "DELETE FROM logs"); err != nil {
+			t.Errorf("soft limit rejected statement: %v", err)
+		}
+	}
+	if _, err := db.ExecContext(noisy, "DELETE FROM logs"); !errors.Is(err, errThrottled) {
+		t.Errorf("expected throttled, got %v", err)
+	}
+	if _, err := db.ExecContext(quiet, "DELETE FROM logs"); err != nil {
+		t.Error(err)
+	}
+
+	if u := quota.Usage("noisy"); u.Queries != 3 {
+		t.Errorf("bad usage %+v", u)
+	}
+	if u := quota.Usage("quiet"); u.Queries != 1 {
+		t.Errorf("bad usage %+v", u)
+	}
+	if len(alerts) != 2 || alerts[0] != "noisy" {
+		t.Errorf("bad alerts %v", alerts)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"sync"
+)
+
+type converter struct {
+	scan  func(src any) (any, error)
+	value func(v any) (driver.Value, error)
+}
+
+var (
+	convertersMu sync.RWMutex
+	converters   = map[reflect.Type]converter{}
+)
+
+// RegisterConverter registers scan and value functions for type T, so third
+// party types can be scanned and written without wrapping every field.
+// Scan function is used by Null, Optional and other scanners of this
+// package when destination is T, value function is used when T is written
+// through Null, Optional or passed as argument of statements executed by Tx
+// and Intercept wrapped access. Either function can be nil.
+func RegisterConverter[T any](scan func(src any) (T, error), value func(T) (driver.Value, error)) {
+	var c converter
+	if scan != nil {
+		c.scan = func(src any) (any, error) {
+			return scan(src)
+		}
+	}
+	if value != nil {
+		c.value = func(v any) (driver.Value, error) {
+			return value(v.(T)) //nolint:forcetypeassert
+		}
+	}
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	converters[reflect.TypeOf((*T)(nil)).Elem()] = c
+}
+
+func lookupConverter(t reflect.Type) (converter, bool) {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+	if len(converters) == 0 {
+		return converter{}, false
+	}
+	c, ok := converters[t]
+	return c, ok
+}
+
+// scanConverted scans src into dest with registered converter, false is
+// returned when dest type has no scan converter.
+func scanConverted(dest, src any) (bool, error) {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() {
+		return false, nil
+	}
+	c, ok := lookupConverter(dv.Type().Elem())
+	if !ok || c.scan == nil {
+		return false, nil
+	}
+	v, err := c.scan(src)
+	if err != nil {
+		return true, err
+	}
+	dv.Elem().Set(reflect.ValueOf(v))
+	return true, nil
+}
+
+// valueConverted returns driver value of v with registered converter, false
+// is returned when v type has no value converter.
+func valueConverted(v any) (driver.Value, bool, error) {
+	if v == nil {
+		return nil, false, nil
+	}
+	c, ok := lookupConverter(reflect.TypeOf(v))
+	if !ok || c.value == nil {
+		return nil, false, nil
+	}
+	dv, err := c.value(v)
+	return dv, true, err
+}
+
+// convertArgs replaces arguments of registered types with driver values.
+func convertArgs(args []any) ([]any, error) {
+	var out []any
+	for i, arg := range args {
+		v, ok, err := valueConverted(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = append([]any(nil), args...)
+		}
+		out[i] = v
+	}
+	if out == nil {
+		return args, nil
+	}
+	return out, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+// shout is stored lower case and used upper case.
+type shout string
+
+func init() {
+	dbq.RegisterConverter(func(src any) (shout, error) {
+		s, ok := src.(string)
+		if !ok {
+			return "", fmt.Errorf("can't scan %T into shout", src)
+		}
+		return shout(strings.ToUpper(s)), nil
+	}, func(v shout) (driver.Value, error) {
+		return strings.ToLower(string(v)), nil
+	})
+}
+
+type argsAccess struct {
+	nopAccess
+	args []any
+}
+
+func (a *argsAccess) ExecContext(_ context.Context, _ string, args ...any) (sql.Result, error) {
+	a.args = args
+	return nil, nil
+}
+
+func TestRegisterConverter(t *testing.T) {
+	var n dbq.Null[shout]
+	maybePanic(n.Scan("hello"))
+	if !n.Valid || n.Val != "HELLO" {
+		t.Errorf("bad scanned value %+v", n)
+	}
+	if err := n.Scan(1); err == nil || n.Valid {
+		t.Error("err should be present for invalid source")
+	}
+
+	v, err := dbq.FromValue[shout]("HI").Value()
+	maybePanic(err)
+	if v != "hi" {
+		t.Errorf("bad driver value %v", v)
+	}
+
+	access := &argsAccess{}
+	_, err = dbq.Intercept(access).ExecContext(context.Background(), "UPDATE t SET s = ?", shout("BYE"), 1)
+	maybePanic(err)
+	if len(access.args) != 2 || access.args[0] != "bye" || access.args[1] != 1 {
+		t.Errorf("bad converted args %v", access.args)
+	}
+}
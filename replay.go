@@ -0,0 +1,589 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrReplayMismatch is returned when replayed statement doesn't match
+// the next recorded one.
+var ErrReplayMismatch = errors.New("dbq: statement doesn't match recording")
+
+// Recording is ordered list of statements with their results.
+type Recording struct {
+	Entries []RecordedEntry `json:"entries"`
+}
+
+// RecordedEntry is single recorded statement.
+type RecordedEntry struct {
+	Query        string            `json:"query"`
+	Args         []RecordedValue   `json:"args,omitempty"`
+	Columns      []string          `json:"columns,omitempty"`
+	Rows         [][]RecordedValue `json:"rows,omitempty"`
+	LastInsertID int64             `json:"last_insert_id,omitempty"`
+	RowsAffected int64             `json:"rows_affected,omitempty"`
+	Err          string            `json:"err,omitempty"`
+}
+
+// RecordedValue is driver.Value which keeps its type through JSON encoding.
+type RecordedValue struct {
+	V driver.Value
+}
+
+type recordedJSON struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r RecordedValue) MarshalJSON() ([]byte, error) {
+	var typ string
+	switch r.V.(type) {
+	case nil:
+		return json.Marshal(recordedJSON{Type: "null"})
+	case int64:
+		typ = "int64"
+	case float64:
+		typ = "float64"
+	case bool:
+		typ = "bool"
+	case []byte:
+		typ = "bytes"
+	case string:
+		typ = "string"
+	case time.Time:
+		typ = "time"
+	default:
+		return nil, fmt.Errorf("dbq: can't record value of type %T", r.V)
+	}
+	value, err := json.Marshal(r.V)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(recordedJSON{Type: typ, Value: value})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *RecordedValue) UnmarshalJSON(data []byte) error {
+	var raw recordedJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var err error
+	switch raw.Type {
+	case "null":
+		r.V = nil
+	case "int64":
+		r.V, err = unmarshalAs[int64](raw.Value)
+	case "float64":
+		r.V, err = unmarshalAs[float64](raw.Value)
+	case "bool":
+		r.V, err = unmarshalAs[bool](raw.Value)
+	case "bytes":
+		r.V, err = unmarshalAs[[]byte](raw.Value)
+	case "string":
+		r.V, err = unmarshalAs[string](raw.Value)
+	case "time":
+		r.V, err = unmarshalAs[time.Time](raw.Value)
+	default:
+		err = fmt.Errorf("dbq: unknown recorded value type %q", raw.Type)
+	}
+	return err
+}
+
+func unmarshalAs[T any](data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+func recordValues(values []driver.NamedValue) []RecordedValue {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]RecordedValue, len(values))
+	for i, v := range values {
+		out[i] = RecordedValue{V: v.Value}
+	}
+	return out
+}
+
+// ReadRecording decodes recording from r.
+func ReadRecording(r io.Reader) (*Recording, error) {
+	rec := &Recording{}
+	if err := json.NewDecoder(r).Decode(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// ReadRecordingFile decodes recording from file.
+func ReadRecordingFile(name string) (*Recording, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadRecording(f)
+}
+
+// Write encodes recording to w.
+func (r *Recording) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteFile encodes recording to file.
+func (r *Recording) WriteFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = r.Write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Recorder is driver.Connector which records every statement executed over
+// connections of wrapped connector. Use it with sql.OpenDB.
+type Recorder struct {
+	connector driver.Connector
+	mu        sync.Mutex
+	rec       Recording
+}
+
+// NewRecorder wraps connector in record mode.
+func NewRecorder(connector driver.Connector) *Recorder {
+	return &Recorder{
+		connector: connector,
+	}
+}
+
+// Connect implements driver.Connector.
+func (r *Recorder) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := r.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &recorderConn{conn: conn, recorder: r}, nil
+}
+
+// Driver implements driver.Connector.
+func (r *Recorder) Driver() driver.Driver {
+	return r.connector.Driver()
+}
+
+// Recording returns copy of statements recorded so far.
+func (r *Recorder) Recording() *Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Recording{
+		Entries: append([]RecordedEntry(nil), r.rec.Entries...),
+	}
+}
+
+// WriteFile writes statements recorded so far to file.
+func (r *Recorder) WriteFile(name string) error {
+	return r.Recording().WriteFile(name)
+}
+
+func (r *Recorder) add(entry RecordedEntry, err error) {
+	if err != nil {
+		entry.Err = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.Entries = append(r.rec.Entries, entry)
+}
+
+type recorderConn struct {
+	conn     driver.Conn
+	recorder *Recorder
+}
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *recorderConn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return &recorderStmt{conn: c, query: query}, nil
+}
+
+func (c *recorderConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *recorderConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recorderConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if conn, ok := c.conn.(driver.ConnBeginTx); ok {
+		return conn.BeginTx(ctx, opts)
+	}
+	return c.conn.Begin() //nolint:staticcheck
+}
+
+func (c *recorderConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	entry := RecordedEntry{Query: query, Args: recordValues(args)}
+	res, err := c.exec(ctx, query, args)
+	if err == nil {
+		entry.LastInsertID, _ = res.LastInsertId()
+		entry.RowsAffected, _ = res.RowsAffected()
+	}
+	c.recorder.add(entry, err)
+	if err != nil {
+		return nil, err
+	}
+	return replayResult(entry), nil
+}
+
+func (c *recorderConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	entry := RecordedEntry{Query: query, Args: recordValues(args)}
+	err := c.query(ctx, query, args, &entry)
+	c.recorder.add(entry, err)
+	if err != nil {
+		return nil, err
+	}
+	return &replayRows{entry: entry}, nil
+}
+
+func (c *recorderConn) prepare(ctx context.Context, query string) (driver.Stmt, error) {
+	if conn, ok := c.conn.(driver.ConnPrepareContext); ok {
+		return conn.PrepareContext(ctx, query)
+	}
+	return c.conn.Prepare(query)
+}
+
+func (c *recorderConn) exec(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if conn, ok := c.conn.(driver.ExecerContext); ok {
+		res, err := conn.ExecContext(ctx, query, args)
+		if !errors.Is(err, driver.ErrSkip) {
+			return res, err
+		}
+	}
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	if s, ok := stmt.(driver.StmtExecContext); ok {
+		return s.ExecContext(ctx, args)
+	}
+	return stmt.Exec(namedToValues(args)) //nolint:staticcheck
+}
+
+func (c *recorderConn) query(ctx context.Context, query string, args []driver.NamedValue, entry *RecordedEntry) error {
+	var (
+		rows driver.Rows
+		err  = driver.ErrSkip
+	)
+	if conn, ok := c.conn.(driver.QueryerContext); ok {
+		rows, err = conn.QueryContext(ctx, query, args)
+	}
+	if errors.Is(err, driver.ErrSkip) {
+		var stmt driver.Stmt
+		stmt, err = c.prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		if s, ok := stmt.(driver.StmtQueryContext); ok {
+			rows, err = s.QueryContext(ctx, args)
+		} else {
+			rows, err = stmt.Query(namedToValues(args)) //nolint:staticcheck
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	entry.Columns = rows.Columns()
+	for {
+		dest := make([]driver.Value, len(entry.Columns))
+		if err = rows.Next(dest); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		row := make([]RecordedValue, len(dest))
+		for i, v := range dest {
+			if b, ok := v.([]byte); ok {
+				v = cloneBytes(b)
+			}
+			row[i] = RecordedValue{V: v}
+		}
+		entry.Rows = append(entry.Rows, row)
+	}
+}
+
+func namedToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+type recorderStmt struct {
+	conn  *recorderConn
+	query string
+}
+
+func (s *recorderStmt) Close() error {
+	return nil
+}
+
+func (s *recorderStmt) NumInput() int {
+	return -1
+}
+
+func (s *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *recorderStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *recorderStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func valuesToNamed(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+// Replayer is driver.Connector which serves recorded statements back in
+// recorded order without real database.
+type Replayer struct {
+	mu   sync.Mutex
+	rec  *Recording
+	next int
+}
+
+// NewReplayer creates connector in replay mode.
+func NewReplayer(rec *Recording) *Replayer {
+	return &Replayer{
+		rec: rec,
+	}
+}
+
+// OpenReplay opens database which replays recording from file.
+func OpenReplay(name string) (*sql.DB, error) {
+	rec, err := ReadRecordingFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(NewReplayer(rec)), nil
+}
+
+// Connect implements driver.Connector.
+func (r *Replayer) Connect(context.Context) (driver.Conn, error) {
+	return &replayConn{replayer: r}, nil
+}
+
+// Driver implements driver.Connector.
+func (r *Replayer) Driver() driver.Driver {
+	return r
+}
+
+// Open implements driver.Driver.
+func (r *Replayer) Open(string) (driver.Conn, error) {
+	return &replayConn{replayer: r}, nil
+}
+
+// Remaining returns number of recorded statements not yet replayed.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.rec.Entries) - r.next
+}
+
+func (r *Replayer) take(query string, args []driver.NamedValue) (RecordedEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next >= len(r.rec.Entries) {
+		return RecordedEntry{}, fmt.Errorf("%w: unexpected %q, recording exhausted", ErrReplayMismatch, query)
+	}
+	entry := r.rec.Entries[r.next]
+	if entry.Query != query {
+		return RecordedEntry{}, fmt.Errorf("%w: got %q, want %q", ErrReplayMismatch, query, entry.Query)
+	}
+	got, err := json.Marshal(recordValues(args))
+	if err != nil {
+		return RecordedEntry{}, err
+	}
+	want, err := json.Marshal(entry.Args)
+	if err != nil {
+		return RecordedEntry{}, err
+	}
+	if string(got) != string(want) {
+		return RecordedEntry{}, fmt.Errorf("%w: %q args %s, want %s", ErrReplayMismatch, query, got, want)
+	}
+	r.next++
+	if entry.Err != "" {
+		return entry, replayedError(entry.Err)
+	}
+	return entry, nil
+}
+
+// replayedSentinels are errors recognized by callers with errors.Is, they
+// are restored from recorded messages.
+var replayedSentinels = []error{
+	driver.ErrBadConn,
+	sql.ErrNoRows,
+	sql.ErrTxDone,
+	sql.ErrConnDone,
+	context.DeadlineExceeded,
+	context.Canceled,
+}
+
+// replayedError returns recorded error msg, sentinel error or error
+// wrapping it when msg ends with message of sentinel.
+func replayedError(msg string) error {
+	for _, sentinel := range replayedSentinels {
+		s := sentinel.Error()
+		if msg == s {
+			return sentinel
+		}
+		if prefix := strings.TrimSuffix(msg, ": "+s); prefix != msg {
+			return fmt.Errorf("%s: %w", prefix, sentinel)
+		}
+	}
+	return errors.New(msg)
+}
+
+type replayConn struct {
+	replayer *Replayer
+}
+
+func (c *replayConn) Prepare(query string) (driver.Stmt, error) {
+	return &replayStmt{conn: c, query: query}, nil
+}
+
+func (c *replayConn) Close() error {
+	return nil
+}
+
+func (c *replayConn) Begin() (driver.Tx, error) {
+	return replayTx{}, nil
+}
+
+func (c *replayConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	entry, err := c.replayer.take(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return replayResult(entry), nil
+}
+
+func (c *replayConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	entry, err := c.replayer.take(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &replayRows{entry: entry}, nil
+}
+
+type replayStmt struct {
+	conn  *replayConn
+	query string
+}
+
+func (s *replayStmt) Close() error {
+	return nil
+}
+
+func (s *replayStmt) NumInput() int {
+	return -1
+}
+
+func (s *replayStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, valuesToNamed(args))
+}
+
+func (s *replayStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, valuesToNamed(args))
+}
+
+type replayTx struct{}
+
+func (replayTx) Commit() error {
+	return nil
+}
+
+func (replayTx) Rollback() error {
+	return nil
+}
+
+type replayResultValue struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func replayResult(entry RecordedEntry) driver.Result {
+	return replayResultValue{
+		lastInsertID: entry.LastInsertID,
+		rowsAffected: entry.RowsAffected,
+	}
+}
+
+func (r replayResultValue) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r replayResultValue) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+type replayRows struct {
+	entry RecordedEntry
+	pos   int
+}
+
+func (r *replayRows) Columns() []string {
+	return r.entry.Columns
+}
+
+func (r *replayRows) Close() error {
+	return nil
+}
+
+func (r *replayRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.entry.Rows) {
+		return io.EOF
+	}
+	for i, v := range r.entry.Rows[r.pos] {
+		dest[i] = v.V
+	}
+	r.pos++
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func usersRecording() *dbq.Recording {
+	return &dbq.Recording{
+		Entries: []dbq.RecordedEntry{
+			{
+				Query:   "SELECT id, name, created FROM users WHERE id > ?",
+				Args:    []dbq.RecordedValue{{V: int64(0)}},
+				Columns: []string{"id", "name", "created"},
+				Rows: [][]dbq.RecordedValue{
+					{{V: int64(1)}, {V: "john"}, {V: time.Unix(100, 0).UTC()}},
+					{{V: int64(2)}, {V: nil}, {V: time.Unix(200, 0).UTC()}},
+				},
+			},
+			{
+				Query:        "INSERT INTO users (name) VALUES (?)",
+				Args:         []dbq.RecordedValue{{V: "jane"}},
+				LastInsertID: 3,
+				RowsAffected: 1,
+			},
+		},
+	}
+}
+
+type user struct {
+	ID      int64
+	Name    dbq.Null[string]
+	Created time.Time
+}
+
+func userBinder(u *user) []any {
+	return []any{&u.ID, &u.Name, &u.Created}
+}
+
+func replayUsers(t *testing.T, db *sql.DB) {
+	t.Helper()
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, "SELECT id, name, created FROM users WHERE id > ?", 0)
+	maybePanic(err)
+	defer rows.Close()
+
+	var users []user
+	for rows.Next() {
+		var u user
+		maybePanic(rows.Scan(userBinder(&u)...))
+		users = append(users, u)
+	}
+	maybePanic(rows.Err())
+	want := []user{
+		{ID: 1, Name: dbq.FromValue("john"), Created: time.Unix(100, 0).UTC()},
+		{ID: 2, Created: time.Unix(200, 0).UTC()},
+	}
+	if !reflect.DeepEqual(users, want) {
+		t.Errorf("bad replayed users: %v", users)
+	}
+
+	res, err := db.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "jane")
+	maybePanic(err)
+	if id, _ := res.LastInsertId(); id != 3 {
+		t.Errorf("bad last insert id: %d", id)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	replayer := dbq.NewReplayer(usersRecording())
+	db := sql.OpenDB(replayer)
+	defer db.Close()
+
+	replayUsers(t, db)
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("expected all statements replayed, %d remaining", n)
+	}
+
+	_, err := db.ExecContext(context.Background(), "DELETE FROM users")
+	if !errors.Is(err, dbq.ErrReplayMismatch) {
+		t.Errorf("expected ErrReplayMismatch, got %v", err)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(usersRecording()))
+	defer db.Close()
+
+	_, err := db.QueryContext(context.Background(), "SELECT id, name, created FROM users WHERE id > ?", 1)
+	if !errors.Is(err, dbq.ErrReplayMismatch) {
+		t.Errorf("expected ErrReplayMismatch, got %v", err)
+	}
+}
+
+func TestReplaySentinelErrors(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{Entries: []dbq.RecordedEntry{
+		{Query: "UPDATE users SET name = 'a'", Err: "context deadline exceeded"},
+		{Query: "UPDATE users SET name = 'b'", Err: "lookup: sql: no rows in result set"},
+		{Query: "UPDATE users SET name = 'c'", Err: "locked"},
+	}}))
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "UPDATE users SET name = 'a'"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	_, err := db.ExecContext(ctx, "UPDATE users SET name = 'b'")
+	if !errors.Is(err, sql.ErrNoRows) || err.Error() != "lookup: sql: no rows in result set" {
+		t.Errorf("expected wrapped sql.ErrNoRows, got %v", err)
+	}
+	if _, err = db.ExecContext(ctx, "UPDATE users SET name = 'c'"); err == nil || err.Error() != "locked" {
+		t.Errorf("expected recorded error, got %v", err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	recorder := dbq.NewRecorder(dbq.NewReplayer(usersRecording()))
+	db := sql.OpenDB(recorder)
+	replayUsers(t, db)
+	maybePanic(db.Close())
+
+	name := filepath.Join(t.TempDir(), "users.json")
+	maybePanic(recorder.WriteFile(name))
+
+	db, err := dbq.OpenReplay(name)
+	maybePanic(err)
+	defer db.Close()
+	replayUsers(t, db)
+}
+
+func TestRecordingEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	maybePanic(usersRecording().Write(&buf))
+
+	rec, err := dbq.ReadRecording(&buf)
+	maybePanic(err)
+	if !reflect.DeepEqual(rec, usersRecording()) {
+		t.Errorf("recording changed after encoding: %+v", rec)
+	}
+}
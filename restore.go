@@ -0,0 +1,194 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// DefaultRestoreAudit is default audit table of History.
+const DefaultRestoreAudit = "dbq_restore_audit"
+
+// History describes history tables holding prior versions of rows, e.g.
+// written by triggers on every insert and update. History table of table
+// has the same columns plus column with time version was written and
+// optional extra columns, e.g. operation or changed_by:
+//
+//	CREATE TABLE documents_history (LIKE documents, changed_at timestamptz NOT NULL);
+//
+// Zero value uses defaults.
+type History struct {
+	// Suffix of history table name, default "_history".
+	Suffix string
+	// Key is primary key column, default "id".
+	Key string
+	// ChangedAt is column with time version was written, default
+	// "changed_at".
+	ChangedAt string
+	// Extra are columns of history table which are not restored.
+	Extra []string
+	// Audit is table recording restores with columns table_name, row_key,
+	// as_of, meta and restored_at, default DefaultRestoreAudit. "-"
+	// disables audit.
+	Audit string
+}
+
+// DefaultHistory is History with defaults used by Restore and Versions.
+var DefaultHistory = History{}
+
+// Restore writes back version of row of table with key pk which was
+// current at asOf, see History.Restore.
+func Restore(ctx TxContext, table string, pk any, asOf time.Time) error {
+	return DefaultHistory.Restore(ctx, table, pk, asOf)
+}
+
+// Versions returns versions of row of table with key pk, see
+// History.Versions.
+func Versions(ctx TxContext, table string, pk any) ([]map[string]any, error) {
+	return DefaultHistory.Versions(ctx, table, pk)
+}
+
+func (h History) withDefaults() History {
+	if h.Suffix == "" {
+		h.Suffix = "_history"
+	}
+	if h.Key == "" {
+		h.Key = "id"
+	}
+	if h.ChangedAt == "" {
+		h.ChangedAt = "changed_at"
+	}
+	if h.Audit == "" {
+		h.Audit = DefaultRestoreAudit
+	}
+	return h
+}
+
+// Versions returns versions of row of table with key pk from history
+// table, the newest first.
+func (h History) Versions(ctx TxContext, table string, pk any) ([]map[string]any, error) {
+	h = h.withDefaults()
+	return QueryMaps(ctx, "SELECT * FROM "+table+h.Suffix+" WHERE "+h.Key+" = ? ORDER BY "+h.ChangedAt+" DESC", pk)
+}
+
+// Restore writes back version of row of table with key pk which was
+// current at asOf, e.g. for undo feature of product. Row is updated, or
+// inserted again when it was deleted since, and restore is recorded in
+// audit table together with request metadata of ctx, see Meta. Restore
+// runs in transaction of ctx so that row and audit record are written
+// together, ErrTxRequired is returned outside of transaction.
+// NotFoundError is returned when row has no version at asOf.
+func (h History) Restore(ctx TxContext, table string, pk any, asOf time.Time) error {
+	if _, ok := ctx.(*Tx); !ok {
+		return ErrTxRequired
+	}
+	h = h.withDefaults()
+	query := limitOne(ctx, "SELECT * FROM "+table+h.Suffix+" WHERE "+h.Key+" = ? AND "+h.ChangedAt+" <= ?",
+		h.ChangedAt+" DESC")
+	cols, values, err := h.version(ctx, query, pk, asOf)
+	if err != nil {
+		return err
+	}
+
+	var (
+		sets []string
+		args []any
+	)
+	for i, col := range cols {
+		if col != h.Key {
+			sets = append(sets, col+" = ?")
+			args = append(args, values[i])
+		}
+	}
+	// affected rows can't tell whether row exists, MySQL doesn't count
+	// rows updated to the same values.
+	exists, err := rowExists(ctx, "SELECT 1 FROM "+table+" WHERE "+h.Key+" = ?", pk)
+	if err != nil {
+		return err
+	}
+	if exists {
+		_, err = ctx.Exec("UPDATE "+table+" SET "+strings.Join(sets, ", ")+" WHERE "+h.Key+" = ?",
+			append(args, pk)...)
+	} else {
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")
+		_, err = ctx.Exec("INSERT INTO "+table+" ("+strings.Join(cols, ", ")+") VALUES ("+marks+")",
+			values...)
+	}
+	if err != nil {
+		return err
+	}
+
+	if h.Audit == "-" {
+		return nil
+	}
+	m := MetaFromCtx(ctx)
+	if m == nil {
+		m = Metadata{}
+	}
+	meta, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = ctx.Exec("INSERT INTO "+h.Audit+" (table_name, row_key, as_of, meta, restored_at) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)",
+		table, exportString(pk), asOf, string(meta))
+	return err
+}
+
+// version returns restored columns and values of the first row of query.
+func (h History) version(ctx TxContext, query string, args ...any) ([]string, []any, error) {
+	rows, err := ctx.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	all, err := rows.Columns()
+	if err != nil {
+		return nil, nil, closeRows(rows, err)
+	}
+	if !rows.Next() {
+		if err = closeRows(rows, nil); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, notFound(ctx, query, args)
+	}
+	values := make([]any, len(all))
+	dests := make([]any, len(all))
+	for i := range dests {
+		dests[i] = &values[i]
+	}
+	if err = rows.Scan(dests...); err != nil {
+		return nil, nil, closeRows(rows, err)
+	}
+	if err = closeRows(rows, nil); err != nil {
+		return nil, nil, err
+	}
+
+	skip := map[string]bool{h.ChangedAt: true}
+	for _, col := range h.Extra {
+		skip[col] = true
+	}
+	var (
+		cols   []string
+		result []any
+	)
+	for i, col := range all {
+		if !skip[col] {
+			cols = append(cols, col)
+			result = append(result, values[i])
+		}
+	}
+	return cols, result, nil
+}
+
+// rowExists returns true when query returns a row.
+func rowExists(ctx TxContext, query string, args ...any) (bool, error) {
+	rows, err := ctx.Query(query, args...)
+	if err != nil {
+		return false, err
+	}
+	exists := rows.Next()
+	return exists, closeRows(rows, nil)
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestRestore(t *testing.T) {
+	asOf := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query:   "SELECT * FROM documents_history WHERE id = ? AND changed_at <= ? ORDER BY changed_at DESC LIMIT 1",
+			Args:    []dbq.RecordedValue{{V: int64(7)}, {V: asOf}},
+			Columns: []string{"id", "title", "body", "changed_at", "changed_by"},
+			Rows: [][]dbq.RecordedValue{
+				{{V: int64(7)}, {V: "v1"}, {V: nil}, {V: asOf.Add(-time.Hour)}, {V: "john"}},
+			},
+		},
+		{
+			Query:   "SELECT 1 FROM documents WHERE id = ?",
+			Args:    []dbq.RecordedValue{{V: int64(7)}},
+			Columns: []string{"1"},
+		},
+		{
+			Query:        "INSERT INTO documents (id, title, body) VALUES (?, ?, ?)",
+			Args:         []dbq.RecordedValue{{V: int64(7)}, {V: "v1"}, {V: nil}},
+			RowsAffected: 1,
+		},
+		{
+			Query: "INSERT INTO dbq_restore_audit (table_name, row_key, as_of, meta, restored_at) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)",
+			Args:  []dbq.RecordedValue{{V: "documents"}, {V: "7"}, {V: asOf}, {V: `{"user":"42"}`}},
+		},
+		{
+			Query:   "SELECT * FROM documents_history WHERE id = ? AND changed_at <= ? ORDER BY changed_at DESC LIMIT 1",
+			Args:    []dbq.RecordedValue{{V: int64(9)}, {V: asOf}},
+			Columns: []string{"id", "title", "body", "changed_at", "changed_by"},
+			Rows: [][]dbq.RecordedValue{
+				{{V: int64(9)}, {V: "v1"}, {V: "text"}, {V: asOf.Add(-time.Hour)}, {V: "john"}},
+			},
+		},
+		{
+			Query:   "SELECT 1 FROM documents WHERE id = ?",
+			Args:    []dbq.RecordedValue{{V: int64(9)}},
+			Columns: []string{"1"},
+			Rows:    [][]dbq.RecordedValue{{{V: int64(1)}}},
+		},
+		{
+			// unchanged row is reported as not affected by MySQL.
+			Query: "UPDATE documents SET title = ?, body = ? WHERE id = ?",
+			Args:  []dbq.RecordedValue{{V: "v1"}, {V: "text"}, {V: int64(9)}},
+		},
+		{
+			Query: "INSERT INTO dbq_restore_audit (table_name, row_key, as_of, meta, restored_at) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)",
+			Args:  []dbq.RecordedValue{{V: "documents"}, {V: "9"}, {V: asOf}, {V: `{"user":"42"}`}},
+		},
+		{
+			Query:   "SELECT * FROM documents_history WHERE id = ? AND changed_at <= ? ORDER BY changed_at DESC LIMIT 1",
+			Args:    []dbq.RecordedValue{{V: int64(8)}, {V: asOf}},
+			Columns: []string{"id", "title", "body", "changed_at", "changed_by"},
+		},
+	}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	history := dbq.History{Extra: []string{"changed_by"}}
+	ctx := dbq.Meta(context.Background(), "user", "42")
+	maybePanic(dbq.NewTxProvider(db).Tx(ctx, func(tx dbq.TxContext) error {
+		// deleted row is inserted again.
+		if err := history.Restore(tx, "documents", int64(7), asOf); err != nil {
+			return err
+		}
+		// existing row is updated also when nothing changes.
+		if err := history.Restore(tx, "documents", int64(9), asOf); err != nil {
+			return err
+		}
+		if err := history.Restore(tx, "documents", int64(8), asOf); !errors.Is(err, dbq.ErrNotFound) {
+			t.Errorf("expected not found for row without version, got %v", err)
+		}
+		return nil
+	}))
+
+	if err := history.Restore(dbq.NewDB(ctx, db), "documents", int64(7), asOf); !errors.Is(err, dbq.ErrTxRequired) {
+		t.Errorf("expected ErrTxRequired outside of transaction, got %v", err)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{{
+		Query:   "SELECT * FROM documents_history WHERE id = ? ORDER BY changed_at DESC",
+		Args:    []dbq.RecordedValue{{V: int64(7)}},
+		Columns: []string{"id", "title"},
+		Rows:    [][]dbq.RecordedValue{{{V: int64(7)}, {V: "v2"}}, {{V: int64(7)}, {V: "v1"}}},
+	}}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		versions, err := dbq.Versions(tx, "documents", int64(7))
+		if err != nil {
+			return err
+		}
+		if len(versions) != 2 || versions[0]["title"] != "v2" {
+			t.Errorf("bad versions %v", versions)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"errors"
+	"strings"
+)
+
+// CommitError is returned when transaction function succeeded but COMMIT
+// failed.
+type CommitError struct {
+	Err error
+}
+
+func (e *CommitError) Error() string {
+	return "commit failed: " + e.Err.Error()
+}
+
+// Unwrap returns error returned by COMMIT.
+func (e *CommitError) Unwrap() error {
+	return e.Err
+}
+
+// sqlStater is implemented by driver errors exposing SQLSTATE code, e.g.
+// pgconn.PgError.
+type sqlStater interface {
+	SQLState() string
+}
+
+// retryableStates are SQLSTATE codes of serialization failure and deadlock.
+var retryableStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+}
+
+// retryableMessages are fragments of error messages of drivers which don't
+// expose SQLSTATE code.
+var retryableMessages = []string{
+	"could not serialize access",
+	"restart transaction",
+	"deadlock detected",
+	"deadlock found",
+	"(sqlstate 40001)",
+	"(sqlstate 40p01)",
+}
+
+// IsRetryable returns true if err is serialization failure or deadlock after
+// which whole transaction can be safely retried. It is common at commit time
+// on CockroachDB and Postgres with serializable isolation.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var s sqlStater
+	if errors.As(err, &s) {
+		return retryableStates[s.SQLState()]
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range retryableMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommitRetries enables retry of whole transaction function, up to n times,
+// when COMMIT fails with retryable error. Transaction function must be safe
+// to run more than once.
+func CommitRetries(n int) ProviderOption {
+	return func(t *TxProvider) {
+		t.commitRetries = n
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type pgError struct {
+	code string
+}
+
+func (e pgError) Error() string {
+	return "pg error " + e.code
+}
+
+func (e pgError) SQLState() string {
+	return e.code
+}
+
+// commitFailer fails first commits with err.
+type commitFailer struct {
+	driver.Connector
+	fails int
+	err   error
+}
+
+func (c *commitFailer) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	return &commitFailerConn{Conn: conn, failer: c}, err
+}
+
+type commitFailerConn struct {
+	driver.Conn
+	failer *commitFailer
+}
+
+func (c *commitFailerConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *commitFailerConn) Commit() error {
+	if c.failer.fails > 0 {
+		c.failer.fails--
+		return c.failer.err
+	}
+	return nil
+}
+
+func (c *commitFailerConn) Rollback() error {
+	return nil
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{pgError{"40001"}, true},
+		{fmt.Errorf("wrapped: %w", pgError{"40P01"}), true},
+		{pgError{"23505"}, false},
+		{errors.New("ERROR: restart transaction: TransactionRetryWithProtoRefreshError"), true},
+		{errors.New("Error 1213: Deadlock found when trying to get lock"), true},
+		{errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := dbq.IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCommitRetries(t *testing.T) {
+	const query = "UPDATE counters SET n = n + 1"
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{{Query: query}, {Query: query}, {Query: query}}}
+
+	run := func(retries int) (int, error) {
+		failer := &commitFailer{Connector: dbq.NewReplayer(rec), fails: 1, err: pgError{"40001"}}
+		db := sql.OpenDB(failer)
+		defer db.Close()
+
+		attempts := 0
+		err := dbq.NewTxProvider(db, dbq.CommitRetries(retries)).Tx(context.Background(), func(tx dbq.TxContext) error {
+			attempts++
+			_, err := tx.Exec(query)
+			return err
+		})
+		return attempts, err
+	}
+
+	attempts, err := run(2)
+	maybePanic(err)
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+
+	attempts, err = run(0)
+	var cerr *dbq.CommitError
+	if !errors.As(err, &cerr) || attempts != 1 {
+		t.Errorf("expected CommitError after single attempt, got %v after %d", err, attempts)
+	}
+}
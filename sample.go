@@ -0,0 +1,77 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MaxSample is the largest sample size accepted by Sample and SampleTable.
+var MaxSample = 10000
+
+// Sample loads n random rows of query mapped to T by TagName tags. Rows are
+// ordered by random function of dialect set with WithDialect, so whole
+// result of query is sorted, use SampleTable for large tables, e.g.
+//
+//	users, err := dbq.Sample[User](ctx, "SELECT id, name FROM users WHERE active", 100)
+func Sample[T any](ctx TxContext, query string, n int, args ...any) ([]T, error) {
+	if err := checkSample(n); err != nil {
+		return nil, err
+	}
+	d, _ := DialectFromCtx(ctx)
+	query = "SELECT * FROM (" + query + ") dbq_sample ORDER BY " + randomFunc(d) + limitN(d, n)
+	return QueryStruct[T](ctx, query, args...)
+}
+
+// SampleTable loads up to n random rows of table mapped to T, reading only
+// about percent of table pages with TABLESAMPLE on Postgres, SQL Server and
+// DuckDB. Other dialects fall back to ordering by random function.
+func SampleTable[T any](ctx TxContext, table string, percent float64, n int) ([]T, error) {
+	if err := checkSample(n); err != nil {
+		return nil, err
+	}
+	if percent <= 0 || percent > 100 {
+		return nil, fmt.Errorf("dbq: sample percent %v out of range (0, 100]", percent)
+	}
+	p := strconv.FormatFloat(percent, 'f', -1, 64)
+	d, _ := DialectFromCtx(ctx)
+	var query string
+	switch d {
+	case Postgres:
+		query = "SELECT * FROM " + table + " TABLESAMPLE SYSTEM (" + p + ")" + limitN(d, n)
+	case SQLServer:
+		query = "SELECT TOP " + strconv.Itoa(n) + " * FROM " + table + " TABLESAMPLE (" + p + " PERCENT)"
+	case DuckDB:
+		query = "SELECT * FROM " + table + " USING SAMPLE " + p + "% (system)" + limitN(d, n)
+	default:
+		query = "SELECT * FROM " + table + " ORDER BY " + randomFunc(d) + limitN(d, n)
+	}
+	return QueryStruct[T](ctx, query)
+}
+
+func checkSample(n int) error {
+	if n <= 0 || n > MaxSample {
+		return fmt.Errorf("dbq: sample size %d out of range [1, %d]", n, MaxSample)
+	}
+	return nil
+}
+
+func randomFunc(d Dialect) string {
+	switch d {
+	case MySQL:
+		return "RAND()"
+	case SQLServer:
+		return "NEWID()"
+	}
+	return "random()"
+}
+
+func limitN(d Dialect, n int) string {
+	if d == SQLServer {
+		return " OFFSET 0 ROWS FETCH NEXT " + strconv.Itoa(n) + " ROWS ONLY"
+	}
+	return " LIMIT " + strconv.Itoa(n)
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestSample(t *testing.T) {
+	users := usersRecording().Entries[0]
+	sampled := users
+	sampled.Query = "SELECT * FROM (SELECT id, name, created FROM users WHERE id > $1) dbq_sample ORDER BY random() LIMIT 2"
+	table := users
+	table.Query = "SELECT * FROM users TABLESAMPLE SYSTEM (0.5) LIMIT 2"
+	table.Args = nil
+
+	db := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{Entries: []dbq.RecordedEntry{sampled, table}}))
+	defer db.Close()
+	provider := dbq.NewTxProvider(db, dbq.WithDialect(dbq.Postgres))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		if _, err := dbq.Sample[taggedUser](tx, users.Query, 0, 0); err == nil {
+			t.Error("expected error for empty sample")
+		}
+		rows, err := dbq.Sample[taggedUser](tx, users.Query, 2, 0)
+		if err != nil || len(rows) != 2 {
+			t.Errorf("bad sample %v %v", rows, err)
+		}
+		rows, err = dbq.SampleTable[taggedUser](tx, "users", 0.5, 2)
+		if err != nil || len(rows) != 2 {
+			t.Errorf("bad table sample %v %v", rows, err)
+		}
+		return nil
+	}))
+}
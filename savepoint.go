@@ -0,0 +1,120 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+var savepointName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// WithSavepoint runs fn inside named savepoint. Savepoint is released when
+// fn succeeds and rolled back when fn returns error or panics, so the rest of
+// transaction can continue after failed sub-operation. Functions registered
+// by fn with Defer, OnCommit and OnRollback and updates queued with
+// CoalesceAdd are discarded with rolled back savepoint.
+func (t *Tx) WithSavepoint(name string, fn func(TxContext) error) (err error) {
+	if !savepointName.MatchString(name) {
+		return fmt.Errorf("dbq: invalid savepoint name %q", name)
+	}
+	sp := t.WithValue(savepointKey{}, true)
+	if _, err = sp.Exec("SAVEPOINT " + name); err != nil {
+		return err
+	}
+	m := t.mark()
+
+	defer func() {
+		if r := recover(); r != nil {
+			_, _ = sp.Exec("ROLLBACK TO SAVEPOINT " + name)
+			t.rewind(m)
+			panic(r)
+		}
+	}()
+
+	if err = fn(t); err != nil {
+		t.rewind(m)
+		if _, rerr := sp.Exec("ROLLBACK TO SAVEPOINT " + name); rerr != nil {
+			return fmt.Errorf("%w (rollback to savepoint: %v)", err, rerr) //nolint:errorlint
+		}
+		return err
+	}
+	_, err = sp.Exec("RELEASE SAVEPOINT " + name)
+	return err
+}
+
+// savepointKey marks context of savepoint statements run by WithSavepoint.
+type savepointKey struct{}
+
+// internalSavepoint returns true for context of savepoint statements run by
+// WithSavepoint, which interceptors like Policy don't check.
+func internalSavepoint(ctx context.Context) bool {
+	v, _ := ctx.Value(savepointKey{}).(bool)
+	return v
+}
+
+// rewinder is implemented by values stored with Set which are rolled back
+// with savepoint, e.g. pending updates of CoalesceAdd.
+type rewinder interface {
+	// snapshot returns function restoring current state of value.
+	snapshot() func()
+}
+
+// savepointMark is state of transaction registrations when savepoint is
+// entered.
+type savepointMark struct {
+	deferred   int
+	onCommit   int
+	onRollback int
+	restore    map[any]func()
+}
+
+// mark returns current state of registrations.
+func (t *Tx) mark() savepointMark {
+	if t.stash == nil {
+		t.stash = &stash{}
+	}
+	t.stash.mu.Lock()
+	defer t.stash.mu.Unlock()
+	m := savepointMark{
+		deferred:   len(t.stash.deferred),
+		onCommit:   len(t.stash.onCommit),
+		onRollback: len(t.stash.onRollback),
+		restore:    make(map[any]func()),
+	}
+	for k, v := range t.stash.values {
+		if r, ok := v.(rewinder); ok {
+			m.restore[k] = r.snapshot()
+		}
+	}
+	return m
+}
+
+// rewind discards registrations made after m, rewinders stored after m are
+// removed.
+func (t *Tx) rewind(m savepointMark) {
+	t.stash.mu.Lock()
+	defer t.stash.mu.Unlock()
+	if len(t.stash.deferred) > m.deferred {
+		t.stash.deferred = t.stash.deferred[:m.deferred]
+	}
+	if len(t.stash.onCommit) > m.onCommit {
+		t.stash.onCommit = t.stash.onCommit[:m.onCommit]
+	}
+	if len(t.stash.onRollback) > m.onRollback {
+		t.stash.onRollback = t.stash.onRollback[:m.onRollback]
+	}
+	for k, v := range t.stash.values {
+		if _, ok := v.(rewinder); !ok {
+			continue
+		}
+		if restore, ok := m.restore[k]; ok {
+			restore()
+		} else {
+			delete(t.stash.values, k)
+		}
+	}
+}
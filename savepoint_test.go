@@ -0,0 +1,155 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestWithSavepoint(t *testing.T) {
+	rec := &dbq.Recording{
+		Entries: []dbq.RecordedEntry{
+			{Query: "SAVEPOINT a"},
+			{Query: "DELETE FROM users"},
+			{Query: "RELEASE SAVEPOINT a"},
+			{Query: "SAVEPOINT b"},
+			{Query: "ROLLBACK TO SAVEPOINT b"},
+		},
+	}
+	replayer := dbq.NewReplayer(rec)
+	db := sql.OpenDB(replayer)
+	defer db.Close()
+
+	tx, err := dbq.NewTxProvider(db).Acquire(context.Background())
+	maybePanic(err)
+	defer func() { _ = tx.Rollback() }()
+
+	maybePanic(tx.WithSavepoint("a", func(tx dbq.TxContext) error {
+		_, err := tx.Exec("DELETE FROM users")
+		return err
+	}))
+
+	errFailed := errors.New("failed")
+	err = tx.WithSavepoint("b", func(dbq.TxContext) error {
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("expected all statements executed, %d remaining", n)
+	}
+
+	if err = tx.WithSavepoint("x; DROP TABLE users", func(dbq.TxContext) error { return nil }); err == nil {
+		t.Error("err should be present for invalid savepoint name")
+	}
+}
+
+func TestNestedTx(t *testing.T) {
+	rec := &dbq.Recording{
+		Entries: []dbq.RecordedEntry{
+			{Query: "DELETE FROM users"},
+			{Query: "SAVEPOINT dbq_sp_1"},
+			{Query: "DELETE FROM orders"},
+			{Query: "ROLLBACK TO SAVEPOINT dbq_sp_1"},
+			{Query: "SAVEPOINT dbq_sp_2"},
+			{Query: "DELETE FROM logs"},
+			{Query: "RELEASE SAVEPOINT dbq_sp_2"},
+		},
+	}
+	replayer := dbq.NewReplayer(rec)
+	db := sql.OpenDB(replayer)
+	defer db.Close()
+
+	provider := dbq.NewTxProvider(db)
+	errFailed := errors.New("failed")
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		if _, err := tx.Exec("DELETE FROM users"); err != nil {
+			return err
+		}
+		err := provider.Tx(tx, func(tx dbq.TxContext) error {
+			if _, err := tx.Exec("DELETE FROM orders"); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) {
+			t.Errorf("expected inner error, got %v", err)
+		}
+		return provider.Tx(tx.WithValue("k", "v"), func(tx dbq.TxContext) error {
+			_, err := tx.Exec("DELETE FROM logs")
+			return err
+		})
+	}))
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("expected all statements executed, %d remaining", n)
+	}
+}
+
+func TestNestedTxRewind(t *testing.T) {
+	rec := &dbq.Recording{
+		Entries: []dbq.RecordedEntry{
+			{Query: "SAVEPOINT dbq_sp_1"},
+			{Query: "ROLLBACK TO SAVEPOINT dbq_sp_1"},
+			{Query: "UPDATE counters SET n = n + ? WHERE id = ?", Args: []dbq.RecordedValue{{V: int64(1)}, {V: int64(7)}}},
+		},
+	}
+	replayer := dbq.NewReplayer(rec)
+	db := sql.OpenDB(replayer)
+	defer db.Close()
+
+	var hooks []string
+	provider := dbq.NewTxProvider(db)
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		outer := tx.(*dbq.Tx)
+		maybePanic(dbq.CoalesceAdd(tx, "UPDATE counters SET n = n + ? WHERE id = ?", 1, 7))
+		_ = provider.Tx(tx, func(tx dbq.TxContext) error {
+			inner := tx.(*dbq.Tx)
+			inner.OnCommit(func() { hooks = append(hooks, "inner commit") })
+			inner.OnRollback(func() { hooks = append(hooks, "inner rollback") })
+			inner.Defer(func(dbq.TxContext) error {
+				hooks = append(hooks, "inner defer")
+				return nil
+			})
+			maybePanic(dbq.CoalesceAdd(tx, "UPDATE counters SET n = n + ? WHERE id = ?", 5, 7))
+			return errors.New("failed")
+		})
+		outer.OnCommit(func() { hooks = append(hooks, "outer commit") })
+		return nil
+	}))
+	if len(hooks) != 1 || hooks[0] != "outer commit" {
+		t.Errorf("hooks of rolled back savepoint should be discarded, got %v", hooks)
+	}
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("expected all statements executed, %d remaining", n)
+	}
+}
+
+func TestNestedTxOtherProvider(t *testing.T) {
+	dbA := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{}))
+	defer dbA.Close()
+	replayerB := dbq.NewReplayer(&dbq.Recording{
+		Entries: []dbq.RecordedEntry{{Query: "DELETE FROM logs"}},
+	})
+	dbB := sql.OpenDB(replayerB)
+	defer dbB.Close()
+
+	providerA, providerB := dbq.NewTxProvider(dbA), dbq.NewTxProvider(dbB)
+	maybePanic(providerA.Tx(context.Background(), func(tx dbq.TxContext) error {
+		// runs in transaction of B, not in savepoint of A.
+		return providerB.Tx(tx, func(tx dbq.TxContext) error {
+			_, err := tx.Exec("DELETE FROM logs")
+			return err
+		})
+	}))
+	if n := replayerB.Remaining(); n != 0 {
+		t.Errorf("expected statement executed on B, %d remaining", n)
+	}
+}
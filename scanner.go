@@ -0,0 +1,100 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Row is single result row which can be scanned, it is satisfied by both
+// *sql.Row and *sql.Rows.
+type Row interface {
+	Scan(dest ...any) error
+}
+
+// Rows is result set iterated row by row. It is satisfied by *sql.Rows and
+// implemented by adapters of other drivers, so Query helpers, RowScanner and
+// adapters share single scanning contract.
+type Rows interface {
+	Row
+	Next() bool
+	Columns() ([]string, error)
+	Err() error
+	Close() error
+}
+
+// RowScanner is implemented by types which scan themselves from row. Query
+// and QueryRow use it instead of binder when *T implements it.
+type RowScanner interface {
+	ScanRow(row Row) error
+}
+
+// scanRow scans row into dest using RowScanner when implemented or binder,
+// single column is scanned into dest when binder is nil.
+func scanRow[T any](row Row, dest *T, binder func(*T) []any) error {
+	if s, ok := any(dest).(RowScanner); ok {
+		return s.ScanRow(row)
+	}
+	if binder == nil {
+		return row.Scan(dest)
+	}
+	return row.Scan(binder(dest)...)
+}
+
+// CollectRows scans all rows into slice of T and closes rows. Each row is
+// scanned with RowScanner when *T implements it, with binder otherwise and
+// single column is scanned into T when binder is nil.
+// Error of rows and of closing them is returned, so result set broken
+// mid-stream is not silently truncated.
+func CollectRows[T any](rows Rows, binder func(*T) []any) ([]T, error) {
+	var results []T
+	for rows.Next() {
+		var result T
+		if err := scanRow(rows, &result, binder); err != nil {
+			return nil, closeRows(rows, err)
+		}
+		results = append(results, result)
+	}
+	if err := closeRows(rows, nil); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// closeRows closes rows read until err and returns err joined with error
+// of rows, when err is nil, and with error of Close.
+func closeRows(rows Rows, err error) error {
+	if err == nil {
+		err = rows.Err()
+	}
+	cerr := rows.Close()
+	switch {
+	case cerr == nil:
+		return err
+	case err == nil:
+		return cerr
+	}
+	return &closeError{err: err, close: cerr}
+}
+
+// closeError is error of reading rows which also failed to close.
+type closeError struct {
+	err   error
+	close error
+}
+
+func (e *closeError) Error() string {
+	return fmt.Sprintf("%v (close: %v)", e.err, e.close)
+}
+
+func (e *closeError) Unwrap() error {
+	return e.err
+}
+
+// Is matches error of Close, error of reading rows is matched by Unwrap.
+func (e *closeError) Is(target error) bool {
+	return errors.Is(e.close, target)
+}
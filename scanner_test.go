@@ -0,0 +1,143 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+var (
+	errBroken = errors.New("connection reset")
+	errClose  = errors.New("close failed")
+)
+
+// brokenRows returns ids and then fails with err, Close fails with
+// closeErr.
+type brokenRows struct {
+	ids      []int64
+	err      error
+	closeErr error
+	i        int
+	closed   bool
+}
+
+func (r *brokenRows) Next() bool {
+	if r.i >= len(r.ids) {
+		return false
+	}
+	r.i++
+	return true
+}
+
+func (r *brokenRows) Scan(dest ...any) error {
+	switch d := dest[0].(type) {
+	case *int64:
+		*d = r.ids[r.i-1]
+	case *any:
+		*d = r.ids[r.i-1]
+	}
+	return nil
+}
+
+func (r *brokenRows) Columns() ([]string, error) { return []string{"id"}, nil }
+func (r *brokenRows) Err() error                 { return r.err }
+
+func (r *brokenRows) Close() error {
+	r.closed = true
+	return r.closeErr
+}
+
+type idRow struct {
+	ID int64 `db:"id"`
+}
+
+func TestCollectRowsErrors(t *testing.T) {
+	binder := func(id *int64) []any { return []any{id} }
+
+	rows := &brokenRows{ids: []int64{1, 2}, err: errBroken}
+	ids, err := dbq.CollectRows(rows, binder)
+	if !errors.Is(err, errBroken) || ids != nil || !rows.closed {
+		t.Errorf("expected rows error, got %v %v", ids, err)
+	}
+
+	rows = &brokenRows{ids: []int64{1}, closeErr: errClose}
+	if _, err = dbq.CollectStructs[idRow](rows); !errors.Is(err, errClose) {
+		t.Errorf("expected close error, got %v", err)
+	}
+
+	rows = &brokenRows{ids: []int64{1}, err: errBroken, closeErr: errClose}
+	_, err = dbq.CollectMaps(rows)
+	if !errors.Is(err, errBroken) || !errors.Is(err, errClose) {
+		t.Errorf("expected both rows and close errors, got %v", err)
+	}
+}
+
+// brokenDriver returns one row of every query and fails reading the next.
+type brokenDriver struct{}
+
+func (brokenDriver) Open(string) (driver.Conn, error) { return brokenConn{}, nil }
+
+type brokenConn struct{}
+
+func (brokenConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (brokenConn) Close() error                        { return nil }
+func (brokenConn) Begin() (driver.Tx, error)           { return brokenConn{}, nil }
+func (brokenConn) Commit() error                       { return nil }
+func (brokenConn) Rollback() error                     { return nil }
+
+func (brokenConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &brokenDriverRows{}, nil
+}
+
+type brokenDriverRows struct {
+	n int
+}
+
+func (r *brokenDriverRows) Columns() []string { return []string{"id"} }
+func (r *brokenDriverRows) Close() error      { return nil }
+
+func (r *brokenDriverRows) Next(dest []driver.Value) error {
+	r.n++
+	if r.n > 1 {
+		return errBroken
+	}
+	dest[0] = int64(r.n)
+	return nil
+}
+
+func TestQueryBrokenMidStream(t *testing.T) {
+	sql.Register("dbq-broken", brokenDriver{})
+	db, err := sql.Open("dbq-broken", "")
+	maybePanic(err)
+	defer db.Close()
+
+	err = dbq.NewTxProvider(db).Tx(context.Background(), func(tx dbq.TxContext) error {
+		ids, err := dbq.QueryColumn[int64](tx, "SELECT id FROM users")
+		if !errors.Is(err, errBroken) || ids != nil {
+			t.Errorf("QueryColumn: expected error, got %v %v", ids, err)
+		}
+		_, err = dbq.QueryOne[int64](tx, "SELECT id FROM users WHERE id = 1", nil)
+		if !errors.Is(err, errBroken) {
+			t.Errorf("QueryOne: expected error, got %v", err)
+		}
+		cur, err := dbq.QueryIter[int64](tx, "SELECT id FROM users", nil)
+		if err != nil {
+			return err
+		}
+		for cur.Next() {
+		}
+		if !errors.Is(cur.Err(), errBroken) {
+			t.Errorf("QueryIter: expected error, got %v", cur.Err())
+		}
+		return nil
+	})
+	maybePanic(err)
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// Sentinel errors matched with errors.Is against errors returned by dbq,
+// e.g. errors.Is(err, dbq.ErrConflict) for unique constraint violation.
+var (
+	// ErrNotFound matches NotFoundError and sql.ErrNoRows.
+	ErrNotFound = errors.New("dbq: not found")
+	// ErrTxDone is returned for statements of finished transaction.
+	ErrTxDone = sql.ErrTxDone
+	// ErrConflict matches unique and exclusion constraint violations.
+	ErrConflict = errors.New("dbq: conflict")
+	// ErrTooManyRows is returned when single row is expected but query
+	// returned more.
+	ErrTooManyRows = errors.New("dbq: too many rows")
+	// ErrNoAccess matches permission errors.
+	ErrNoAccess = errors.New("dbq: no access")
+	// ErrForeignKey matches foreign key constraint violations.
+	ErrForeignKey = errors.New("dbq: foreign key violation")
+	// ErrCheckViolation matches check constraint violations.
+	ErrCheckViolation = errors.New("dbq: check violation")
+	// ErrSerialization matches serialization failures and deadlocks.
+	ErrSerialization = errors.New("dbq: serialization failure")
+	// ErrNotNull matches NOT NULL constraint violations and
+	// ValidationErrors.
+	ErrNotNull = errors.New("dbq: not null violation")
+)
+
+// sentinelStates maps SQLSTATE codes to sentinel errors.
+var sentinelStates = map[string]error{
+	"23505": ErrConflict,       // unique_violation
+	"23P01": ErrConflict,       // exclusion_violation
+	"23502": ErrNotNull,        // not_null_violation
+	"23503": ErrForeignKey,     // foreign_key_violation
+	"23514": ErrCheckViolation, // check_violation
+	"40001": ErrSerialization,  // serialization_failure
+	"40P01": ErrSerialization,  // deadlock_detected
+	"42501": ErrNoAccess,       // insufficient_privilege
+}
+
+// sentinelMessages maps fragments of error messages of drivers which don't
+// expose SQLSTATE code to sentinel errors.
+var sentinelMessages = []struct {
+	fragment string
+	err      error
+}{
+	{"duplicate key value", ErrConflict},
+	{"duplicate entry", ErrConflict},
+	{"unique constraint failed", ErrConflict},
+	{"(sqlstate 23505)", ErrConflict},
+	{"violates not-null constraint", ErrNotNull},
+	{"not null constraint failed", ErrNotNull},
+	{"cannot be null", ErrNotNull},
+	{"(sqlstate 23502)", ErrNotNull},
+	{"violates foreign key constraint", ErrForeignKey},
+	{"foreign key constraint fails", ErrForeignKey},
+	{"foreign key constraint failed", ErrForeignKey},
+	{"(sqlstate 23503)", ErrForeignKey},
+	{"violates check constraint", ErrCheckViolation},
+	{"check constraint failed", ErrCheckViolation},
+	{"(sqlstate 23514)", ErrCheckViolation},
+	{"could not serialize access", ErrSerialization},
+	{"deadlock detected", ErrSerialization},
+	{"deadlock found", ErrSerialization},
+	{"(sqlstate 40001)", ErrSerialization},
+	{"permission denied", ErrNoAccess},
+	{"access denied", ErrNoAccess},
+	{"(sqlstate 42501)", ErrNoAccess},
+}
+
+// matchesSentinel reports whether driver error err is classified as
+// sentinel target.
+func matchesSentinel(err, target error) bool {
+	if err == nil {
+		return false
+	}
+	if target == ErrNotFound {
+		return errors.Is(err, sql.ErrNoRows)
+	}
+	if errors.Is(err, target) {
+		return true
+	}
+	var s sqlStater
+	if errors.As(err, &s) {
+		if sentinel, ok := sentinelStates[s.SQLState()]; ok {
+			return sentinel == target
+		}
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range sentinelMessages {
+		if m.err == target && strings.Contains(msg, m.fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// Is matches ErrNotFound and sql.ErrNoRows.
+func (e NotFoundError) Is(target error) bool {
+	return target == ErrNotFound || target == sql.ErrNoRows //nolint:errorlint
+}
+
+// Is matches sentinel errors classifying underlying error.
+func (e *Error) Is(target error) bool {
+	return matchesSentinel(e.Err, target)
+}
+
+// Is matches sentinel errors classifying underlying error.
+func (e *CommitError) Is(target error) bool {
+	return matchesSentinel(e.Err, target)
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		target error
+	}{
+		{pgError{code: "23505"}, dbq.ErrConflict},
+		{errors.New(`UNIQUE constraint failed: users.email`), dbq.ErrConflict},
+		{errors.New(`Error 1062: Duplicate entry 'a' for key 'email'`), dbq.ErrConflict},
+		{pgError{code: "42501"}, dbq.ErrNoAccess},
+		{pgError{code: "23502"}, dbq.ErrNotNull},
+		{errors.New(`Error 1048: Column 'name' cannot be null`), dbq.ErrNotNull},
+		{sql.ErrNoRows, dbq.ErrNotFound},
+	}
+	for _, tt := range tests {
+		db := dbq.Intercept(&nopAccess{}, func(dbq.Handler) dbq.Handler {
+			return func(context.Context, *dbq.Statement) (dbq.Outcome, error) {
+				return dbq.Outcome{}, tt.err
+			}
+		})
+		_, err := db.ExecContext(context.Background(), "INSERT INTO users (email) VALUES (?)", "a")
+		if !errors.Is(err, tt.target) {
+			t.Errorf("%v should match %v", tt.err, tt.target)
+		}
+		if errors.Is(err, dbq.ErrTooManyRows) {
+			t.Errorf("%v should not match ErrTooManyRows", tt.err)
+		}
+	}
+
+	if err := error(&dbq.NotFoundError{}); !errors.Is(err, dbq.ErrNotFound) || !errors.Is(err, sql.ErrNoRows) {
+		t.Error("NotFoundError should match ErrNotFound")
+	}
+	if err := error(&dbq.CommitError{Err: pgError{code: "23505"}}); !errors.Is(err, dbq.ErrConflict) {
+		t.Error("CommitError should match ErrConflict")
+	}
+}
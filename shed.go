@@ -0,0 +1,182 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrShed is returned for statement rejected by Shedder.
+var ErrShed = errors.New("dbq: statement shed under load")
+
+// ShedConfig configures load shedding.
+type ShedConfig struct {
+	// Threshold is fraction of max open connections in use above which
+	// pool is under pressure, default 0.8. Pool without connection limit
+	// is under pressure when callers wait for connections.
+	Threshold float64
+	// MinPriority is lowest priority which is never shed, default
+	// PriorityNormal.
+	MinPriority Priority
+	// Delay is time shed statement waits for pressure to drop before it is
+	// rejected, zero rejects it immediately.
+	Delay time.Duration
+	// Metrics receives shed statements and transactions when it
+	// implements ShedCollector.
+	Metrics MetricsCollector
+}
+
+// ShedCollector is optionally implemented by MetricsCollector of
+// ShedConfig to count shed statements and transactions.
+type ShedCollector interface {
+	// Shed is called for every delayed or rejected statement or
+	// transaction, rejected is false when it ran after pressure dropped.
+	Shed(rejected bool)
+}
+
+// ShedStats are counters of Shedder.
+type ShedStats struct {
+	// Rejected is number of statements rejected with ErrShed.
+	Rejected int64
+	// Delayed is number of statements which waited for pressure to drop.
+	Delayed int64
+}
+
+// Shedder rejects or delays statements and transactions with priority
+// lower than MinPriority while pool is under pressure, so user facing
+// statements get connections first, e.g.
+//
+//	shedder := dbq.NewShedder(db, dbq.ShedConfig{Delay: 100 * time.Millisecond})
+//	provider := dbq.NewTxProvider(db, dbq.Shedding(shedder))
+//	...
+//	err := provider.Tx(dbq.WithTxPriority(ctx, dbq.PriorityLow), report)
+//
+// Transactions are shed when they begin, statements of begun transaction
+// are never shed, so work done in it isn't wasted. Interceptor sheds
+// statements run outside of transactions.
+type Shedder struct {
+	// counters are first so they are 64-bit aligned on 32-bit platforms.
+	waitCount int64
+	rejected  int64
+	delayed   int64
+
+	pool Pool
+	cfg  ShedConfig
+}
+
+// NewShedder creates shedder observing pool.
+func NewShedder(pool Pool, cfg ShedConfig) *Shedder {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 0.8
+	}
+	s := &Shedder{
+		pool: pool,
+		cfg:  cfg,
+	}
+	s.waitCount = pool.Stats().WaitCount
+	return s
+}
+
+// Stats returns shed counters.
+func (s *Shedder) Stats() ShedStats {
+	return ShedStats{
+		Rejected: atomic.LoadInt64(&s.rejected),
+		Delayed:  atomic.LoadInt64(&s.delayed),
+	}
+}
+
+// UnderPressure reports whether pool is under pressure.
+func (s *Shedder) UnderPressure() bool {
+	stats := s.pool.Stats()
+	if stats.MaxOpenConnections > 0 {
+		return float64(stats.InUse) >= s.cfg.Threshold*float64(stats.MaxOpenConnections)
+	}
+	// all callers observing growth of wait count are under pressure, the
+	// last observed count only moves forward.
+	prev := atomic.LoadInt64(&s.waitCount)
+	if stats.WaitCount <= prev {
+		return false
+	}
+	atomic.CompareAndSwapInt64(&s.waitCount, prev, stats.WaitCount)
+	return true
+}
+
+// Interceptor returns interceptor shedding statements run outside of
+// transactions.
+func (s *Shedder) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			if _, inTx := ctx.Value(currentTxKey{}).(*Tx); !inTx {
+				if err := s.admit(ctx, stmt.Options.Priority); err != nil {
+					return Outcome{}, err
+				}
+			}
+			return next(ctx, stmt)
+		}
+	}
+}
+
+// Shedding sheds transactions of provider with shedder when they begin,
+// priority of transaction is set with WithTxPriority.
+func Shedding(s *Shedder) ProviderOption {
+	return func(t *TxProvider) {
+		t.shedder = s
+	}
+}
+
+type txPriorityKey struct{}
+
+// WithTxPriority sets priority of transactions started with ctx.
+func WithTxPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, txPriorityKey{}, p)
+}
+
+// txPriority returns priority set with WithTxPriority.
+func txPriority(ctx context.Context) Priority {
+	p, _ := ctx.Value(txPriorityKey{}).(Priority)
+	return p
+}
+
+// admit returns nil when statement or transaction of priority p may run,
+// under pressure it waits for pressure to drop or is rejected.
+func (s *Shedder) admit(ctx context.Context, p Priority) error {
+	if p >= s.cfg.MinPriority || !s.UnderPressure() {
+		return nil
+	}
+	err := s.wait(ctx)
+	if c, ok := s.cfg.Metrics.(ShedCollector); ok && (err == nil || errors.Is(err, ErrShed)) {
+		c.Shed(err != nil)
+	}
+	return err
+}
+
+// wait waits up to Delay for pressure to drop.
+func (s *Shedder) wait(ctx context.Context) error {
+	if s.cfg.Delay > 0 {
+		atomic.AddInt64(&s.delayed, 1)
+		deadline := time.NewTimer(s.cfg.Delay)
+		defer deadline.Stop()
+		tick := time.NewTicker(s.cfg.Delay/10 + time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-deadline.C:
+				atomic.AddInt64(&s.rejected, 1)
+				return ErrShed
+			case <-tick.C:
+				if !s.UnderPressure() {
+					return nil
+				}
+			}
+		}
+	}
+	atomic.AddInt64(&s.rejected, 1)
+	return ErrShed
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestShedder(t *testing.T) {
+	pool := &fakePool{}
+	pool.stats.MaxOpenConnections = 10
+	pool.stats.InUse = 9
+	shedder := dbq.NewShedder(pool, dbq.ShedConfig{})
+	db := dbq.Intercept(&nopAccess{}, shedder.Interceptor())
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM logs", dbq.WithPriority(dbq.PriorityLow)); !errors.Is(err, dbq.ErrShed) {
+		t.Errorf("expected ErrShed, got %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE users SET name = ?", "john"); err != nil {
+		t.Errorf("normal priority shed: %v", err)
+	}
+	pool.stats.InUse = 2
+	if _, err := db.ExecContext(ctx, "DELETE FROM logs", dbq.WithPriority(dbq.PriorityLow)); err != nil {
+		t.Errorf("shed without pressure: %v", err)
+	}
+	if s := shedder.Stats(); s.Rejected != 1 || s.Delayed != 0 {
+		t.Errorf("bad stats %+v", s)
+	}
+}
+
+func TestShedderDelay(t *testing.T) {
+	pool := &fakePool{}
+	pool.stats.WaitCount = 1
+	shedder := dbq.NewShedder(pool, dbq.ShedConfig{Delay: 50 * time.Millisecond})
+	db := dbq.Intercept(&nopAccess{}, shedder.Interceptor())
+
+	pool.stats.WaitCount = 2
+	// wait count stops growing, so pressure drops on first check.
+	if _, err := db.ExecContext(context.Background(), "DELETE FROM logs", dbq.WithPriority(dbq.PriorityLow)); err != nil {
+		t.Errorf("delayed statement failed: %v", err)
+	}
+	if s := shedder.Stats(); s.Rejected != 0 || s.Delayed != 1 {
+		t.Errorf("bad stats %+v", s)
+	}
+}
+
+type shedMetrics struct {
+	fakeMetrics
+	rejected, delayed int
+}
+
+func (m *shedMetrics) Shed(rejected bool) {
+	if rejected {
+		m.rejected++
+	} else {
+		m.delayed++
+	}
+}
+
+func TestShedderTx(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = rec.Entries[1:]
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	pool := &fakePool{}
+	pool.stats.MaxOpenConnections = 10
+	metrics := &shedMetrics{}
+	shedder := dbq.NewShedder(pool, dbq.ShedConfig{Metrics: metrics})
+	provider := dbq.NewTxProvider(db, dbq.Shedding(shedder), dbq.Interceptors(shedder.Interceptor()))
+
+	// statements of begun transaction are not shed.
+	maybePanic(provider.Tx(dbq.WithTxPriority(context.Background(), dbq.PriorityLow), func(tx dbq.TxContext) error {
+		pool.stats.InUse = 9
+		_, err := tx.Exec("INSERT INTO users (name) VALUES (?)", "jane", dbq.WithPriority(dbq.PriorityLow))
+		return err
+	}))
+
+	err := provider.Tx(dbq.WithTxPriority(context.Background(), dbq.PriorityLow), func(tx dbq.TxContext) error {
+		t.Error("low priority transaction should be shed at begin")
+		return nil
+	})
+	if !errors.Is(err, dbq.ErrShed) {
+		t.Errorf("expected ErrShed, got %v", err)
+	}
+	if metrics.rejected != 1 || metrics.delayed != 0 {
+		t.Errorf("bad shed metrics %+v", metrics)
+	}
+}
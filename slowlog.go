@@ -0,0 +1,93 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SlowQuery is statement which took longer than SlowLog threshold.
+type SlowQuery struct {
+	Query    string        `json:"query"`
+	Label    string        `json:"label,omitempty"`
+	Caller   string        `json:"caller,omitempty"`
+	Duration time.Duration `json:"duration"`
+	At       time.Time     `json:"at"`
+	Err      string        `json:"err,omitempty"`
+}
+
+// SlowLog keeps last slow statements in ring buffer. Duration of Query
+// statements does not include reading of rows.
+type SlowLog struct {
+	threshold time.Duration
+
+	mu      sync.Mutex
+	entries []SlowQuery
+	next    int
+	full    bool
+}
+
+// NewSlowLog creates slow log keeping last size statements slower than
+// threshold.
+func NewSlowLog(threshold time.Duration, size int) *SlowLog {
+	if size <= 0 {
+		size = 100
+	}
+	return &SlowLog{
+		threshold: threshold,
+		entries:   make([]SlowQuery, size),
+	}
+}
+
+// Interceptor returns interceptor recording slow statements.
+func (l *SlowLog) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			start := time.Now()
+			out, err := next(ctx, stmt)
+			if d := time.Since(start); d >= l.threshold {
+				q := SlowQuery{
+					Query:    stmt.Query,
+					Label:    stmt.Options.Label,
+					Caller:   stmt.Caller,
+					Duration: d,
+					At:       start,
+				}
+				if err != nil {
+					q.Err = err.Error()
+				}
+				l.add(q)
+			}
+			return out, err
+		}
+	}
+}
+
+func (l *SlowLog) add(q SlowQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = q
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Tail returns up to n last slow statements, the most recent last.
+func (l *SlowLog) Tail(n int) []SlowQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var all []SlowQuery
+	if l.full {
+		all = append(all, l.entries[l.next:]...)
+	}
+	all = append(all, l.entries[:l.next]...)
+	if n > 0 && len(all) > n {
+		all = all[len(all)-n:]
+	}
+	return all
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IsUnreachable returns true if err means database can't be reached, e.g.
+// bad or closed connection, network error or refused connection.
+func IsUnreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "connection refused") || strings.Contains(msg, "no such host")
+}
+
+// Standby keeps last successful results of designated read queries to serve
+// them when database is unreachable. It is meant for non-critical data,
+// like dashboards, where stale data is better than error.
+type Standby struct {
+	maxAge  time.Duration
+	mu      sync.RWMutex
+	entries map[string]standbyEntry
+}
+
+type standbyEntry struct {
+	val     any
+	updated time.Time
+}
+
+// NewStandby creates standby cache, results older than maxAge are not
+// served, zero maxAge serves results of any age.
+func NewStandby(maxAge time.Duration) *Standby {
+	return &Standby{
+		maxAge:  maxAge,
+		entries: make(map[string]standbyEntry),
+	}
+}
+
+// Cached is result which may be served from standby cache.
+type Cached[T any] struct {
+	Val T
+	// Stale is true when Val is served from standby cache.
+	Stale bool
+	// Updated is time when Val was loaded from database.
+	Updated time.Time
+}
+
+// WithStandby runs fn and stores its result under key. When fn fails because
+// database is unreachable, the last stored result is returned marked as
+// stale, e.g.
+//
+//	res, err := dbq.WithStandby(standby, "daily-stats", func() ([]Stat, error) {
+//		return dbq.Query(ctx, statsQuery, statBinder)
+//	})
+func WithStandby[T any](s *Standby, key string, fn func() (T, error)) (Cached[T], error) {
+	val, err := fn()
+	now := time.Now()
+	if err == nil {
+		s.mu.Lock()
+		s.entries[key] = standbyEntry{val: val, updated: now}
+		s.mu.Unlock()
+		return Cached[T]{Val: val, Updated: now}, nil
+	}
+	if !IsUnreachable(err) {
+		return Cached[T]{}, err
+	}
+
+	s.mu.RLock()
+	entry, ok := s.entries[key]
+	s.mu.RUnlock()
+	if !ok || (s.maxAge > 0 && now.Sub(entry.updated) > s.maxAge) {
+		return Cached[T]{}, err
+	}
+	cached, ok := entry.val.(T)
+	if !ok {
+		return Cached[T]{}, err
+	}
+	return Cached[T]{Val: cached, Stale: true, Updated: entry.updated}, nil
+}
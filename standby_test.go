@@ -0,0 +1,42 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestWithStandby(t *testing.T) {
+	standby := dbq.NewStandby(time.Hour)
+
+	res, err := dbq.WithStandby(standby, "stats", func() (int, error) { return 42, nil })
+	maybePanic(err)
+	if res.Val != 42 || res.Stale {
+		t.Errorf("bad fresh result %+v", res)
+	}
+
+	res, err = dbq.WithStandby(standby, "stats", func() (int, error) {
+		return 0, fmt.Errorf("query: %w", driver.ErrBadConn)
+	})
+	maybePanic(err)
+	if res.Val != 42 || !res.Stale {
+		t.Errorf("bad stale result %+v", res)
+	}
+
+	errSyntax := errors.New("syntax error")
+	if _, err = dbq.WithStandby(standby, "stats", func() (int, error) { return 0, errSyntax }); !errors.Is(err, errSyntax) {
+		t.Errorf("expected query error, got %v", err)
+	}
+
+	if _, err = dbq.WithStandby(standby, "other", func() (int, error) { return 0, driver.ErrBadConn }); err == nil {
+		t.Error("err should be present without cached result")
+	}
+}
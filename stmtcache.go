@@ -0,0 +1,176 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+)
+
+// StmtCacheStats are counters of statement caches.
+type StmtCacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+}
+
+// StmtCache configures caching of prepared statements, repeated query
+// strings are prepared once per transaction or DB and reused. Statements
+// are kept in LRU cache of capacity and closed when transaction ends or DB
+// is closed, e.g.
+//
+//	stmts := dbq.NewStmtCache(64)
+//	provider := dbq.NewTxProvider(db, dbq.StatementCache(stmts))
+//
+// Stats are accumulated over all caches created with the same StmtCache.
+type StmtCache struct {
+	// counters are first so they are 64-bit aligned on 32-bit platforms.
+	hits      int64
+	misses    int64
+	evictions int64
+	capacity  int
+}
+
+// NewStmtCache creates statement cache configuration.
+func NewStmtCache(capacity int) *StmtCache {
+	return &StmtCache{capacity: capacity}
+}
+
+// Stats returns hit, miss and eviction counters.
+func (c *StmtCache) Stats() StmtCacheStats {
+	return StmtCacheStats{
+		Hits:      atomic.LoadInt64(&c.hits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Evictions: atomic.LoadInt64(&c.evictions),
+	}
+}
+
+// StatementCache enables caching of prepared statements in transactions
+// and DB.
+func StatementCache(c *StmtCache) ProviderOption {
+	return func(t *TxProvider) {
+		t.stmtCache = c
+	}
+}
+
+type cachedStmt struct {
+	query string
+	stmt  *sql.Stmt
+}
+
+// stmtLRU is statement cache of single transaction or DB.
+type stmtLRU struct {
+	cfg     *StmtCache
+	db      Access
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	// retired are evicted statements of transaction, they are closed with
+	// cache because rows of cursors may still read from them.
+	retired []*sql.Stmt
+	// inTx is true for cache of transaction.
+	inTx bool
+}
+
+func newStmtLRU(cfg *StmtCache, db Access) *stmtLRU {
+	_, inTx := db.(*sql.Tx)
+	return &stmtLRU{
+		cfg:     cfg,
+		db:      db,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		inTx:    inTx,
+	}
+}
+
+func (c *stmtLRU) get(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[query]; ok {
+		atomic.AddInt64(&c.cfg.hits, 1)
+		c.order.MoveToFront(e)
+		return e.Value.(*cachedStmt).stmt, nil //nolint:forcetypeassert
+	}
+	atomic.AddInt64(&c.cfg.misses, 1)
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.entries[query] = c.order.PushFront(&cachedStmt{query: query, stmt: stmt})
+	for c.cfg.capacity > 0 && c.order.Len() > c.cfg.capacity {
+		oldest := c.order.Back()
+		evicted := c.order.Remove(oldest).(*cachedStmt) //nolint:forcetypeassert
+		delete(c.entries, evicted.query)
+		if c.inTx {
+			// closing statement of transaction closes driver statement
+			// at once, also under open rows.
+			c.retired = append(c.retired, evicted.stmt)
+		} else {
+			// statement of DB is finalized by database/sql after its
+			// open rows are closed.
+			evicted.stmt.Close()
+		}
+		atomic.AddInt64(&c.cfg.evictions, 1)
+	}
+	return stmt, nil
+}
+
+// close closes all cached statements.
+func (c *stmtLRU) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var first error
+	for e := c.order.Front(); e != nil; e = e.Next() {
+		if err := e.Value.(*cachedStmt).stmt.Close(); err != nil && first == nil { //nolint:forcetypeassert
+			first = err
+		}
+	}
+	for _, stmt := range c.retired {
+		if err := stmt.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	c.retired = nil
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	return first
+}
+
+// ExecContext runs query with cached statement.
+func (c *stmtLRU) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	stmt, err := c.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+// QueryContext runs query with cached statement.
+func (c *stmtLRU) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	stmt, err := c.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+// QueryRowContext runs query with cached statement.
+func (c *stmtLRU) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	stmt, err := c.get(ctx, query)
+	if err != nil {
+		// sql.Row can't be created with error, query runs unprepared and
+		// reports the same error.
+		return c.db.QueryRowContext(ctx, query, args...)
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+// PrepareContext prepares uncached statement, caller owns it.
+func (c *stmtLRU) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(ctx, query)
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestStatementCache(t *testing.T) {
+	const (
+		insert = "INSERT INTO users (name) VALUES (?)"
+		update = "UPDATE users SET name = ? WHERE id = ?"
+	)
+	rec := &dbq.Recording{}
+	for _, name := range []string{"a", "b"} {
+		rec.Entries = append(rec.Entries, dbq.RecordedEntry{Query: insert, Args: []dbq.RecordedValue{{V: name}}, RowsAffected: 1})
+	}
+	rec.Entries = append(rec.Entries,
+		dbq.RecordedEntry{Query: update, Args: []dbq.RecordedValue{{V: "c"}, {V: int64(1)}}, RowsAffected: 1},
+		dbq.RecordedEntry{Query: insert, Args: []dbq.RecordedValue{{V: "d"}}, RowsAffected: 1},
+	)
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	stmts := dbq.NewStmtCache(1)
+	provider := dbq.NewTxProvider(db, dbq.StatementCache(stmts))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		for _, name := range []string{"a", "b"} {
+			if _, err := dbq.ExecAffected(tx, insert, name); err != nil {
+				return err
+			}
+		}
+		if _, err := dbq.ExecAffected(tx, update, "c", 1); err != nil {
+			return err
+		}
+		_, err := dbq.ExecAffected(tx, insert, "d")
+		return err
+	}))
+	if s := stmts.Stats(); s != (dbq.StmtCacheStats{Hits: 1, Misses: 3, Evictions: 2}) {
+		t.Errorf("bad stats %+v", s)
+	}
+}
+
+func TestStatementCacheDB(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = append(rec.Entries[:1], rec.Entries[0])
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	stmts := dbq.NewStmtCache(8)
+	ctx := dbq.NewDB(context.Background(), db, dbq.StatementCache(stmts))
+	defer ctx.Close()
+	for i := 0; i < 2; i++ {
+		users, err := dbq.Query(ctx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+		if err != nil || len(users) != 2 {
+			t.Fatalf("bad users %v %v", users, err)
+		}
+	}
+	if s := stmts.Stats(); s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("bad stats %+v", s)
+	}
+}
+
+func TestStatementCacheNoCache(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = append(rec.Entries[:1], rec.Entries[0])
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	stmts := dbq.NewStmtCache(8)
+	ctx := dbq.NewDB(context.Background(), db, dbq.StatementCache(stmts))
+	defer ctx.Close()
+	for i := 0; i < 2; i++ {
+		users, err := dbq.Query(ctx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0, dbq.NoCache())
+		if err != nil || len(users) != 2 {
+			t.Fatalf("bad users %v %v", users, err)
+		}
+	}
+	if s := stmts.Stats(); s != (dbq.StmtCacheStats{}) {
+		t.Errorf("statements with NoCache should bypass cache, got %+v", s)
+	}
+}
+
+func TestStatementCacheEvictOpenRows(t *testing.T) {
+	rec := usersRecording()
+	db := sql.OpenDB(closingConnector{dbq.NewReplayer(rec)})
+	defer db.Close()
+
+	provider := dbq.NewTxProvider(db, dbq.StatementCache(dbq.NewStmtCache(1)))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		rows, err := tx.Query("SELECT id, name, created FROM users WHERE id > ?", 0)
+		if err != nil {
+			return err
+		}
+		// evicts statement of open rows.
+		if _, err = tx.Exec("INSERT INTO users (name) VALUES (?)", "jane"); err != nil {
+			return err
+		}
+		users, err := dbq.CollectRows(rows, userBinder)
+		if len(users) != 2 {
+			t.Errorf("expected 2 users, got %d", len(users))
+		}
+		return err
+	}))
+}
@@ -0,0 +1,36 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type AuditFields struct {
+	Created time.Time `db:"created"`
+}
+
+type taggedUser struct {
+	ID   int64            `db:"id"`
+	Name dbq.Null[string] `db:"name"`
+	*AuditFields
+}
+
+func TestQueryStruct(t *testing.T) {
+	replayTx(t, usersRecording(), func(tx dbq.TxContext) error {
+		users, err := dbq.QueryStruct[taggedUser](tx, "SELECT id, name, created FROM users WHERE id > ?", 0)
+		if err != nil {
+			return err
+		}
+		if len(users) != 2 || users[0].ID != 1 || users[0].Name.Val != "john" || users[1].Name.Valid ||
+			!users[1].Created.Equal(time.Unix(200, 0)) {
+			t.Errorf("bad users %v", users)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"time"
+)
+
+// TimePolicy controls time zone of time.Time values crossing database
+// boundary.
+type TimePolicy struct {
+	// Location is time zone scanned time values are converted to, nil
+	// keeps values as returned by driver.
+	Location *time.Location
+	// OnLocal is called when time in time.Local zone is written as argument
+	// or returned by driver, such times depend on zone of the host. When nil
+	// it is logged.
+	OnLocal func(query string, t time.Time)
+}
+
+type timePolicyKey struct{}
+
+// TimeZone sets time zone policy of transactions: time.Time arguments, also
+// in Null and Optional, are normalized to UTC before they are written and
+// scanned values are converted to policy Location by Query and QueryRow.
+func TimeZone(policy TimePolicy) ProviderOption {
+	return func(t *TxProvider) {
+		t.timePolicy = &policy
+		t.interceptors = append(t.interceptors, policy.interceptor())
+	}
+}
+
+func (p *TimePolicy) local(query string, t time.Time) {
+	if t.Location() != time.Local || time.Local == time.UTC {
+		return
+	}
+	if p.OnLocal != nil {
+		p.OnLocal(query, t)
+		return
+	}
+	log.Printf("dbq: time %s in local zone used with query %q", t, query)
+}
+
+// interceptor normalizes time arguments to UTC.
+func (p TimePolicy) interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			// args slice belongs to caller, normalized args are copied.
+			args := make([]any, len(stmt.Args))
+			for i, arg := range stmt.Args {
+				args[i] = arg
+				switch v := arg.(type) {
+				case time.Time:
+					p.local(stmt.Query, v)
+					args[i] = v.UTC()
+				case *time.Time:
+					if v != nil {
+						p.local(stmt.Query, *v)
+						args[i] = v.UTC()
+					}
+				case Null[time.Time]:
+					if v.Valid {
+						p.local(stmt.Query, v.Val)
+						args[i] = Null[time.Time]{Val: v.Val.UTC(), Valid: true}
+					}
+				case Optional[time.Time]:
+					if v.Set && v.Valid {
+						p.local(stmt.Query, v.Val)
+						args[i] = OptionalOf(v.Val.UTC())
+					}
+				}
+			}
+			stmt.Args = args
+			return next(ctx, stmt)
+		}
+	}
+}
+
+// localize converts scanned time values in dest to policy location,
+// dest is pointer to scanned value.
+func localize(ctx context.Context, query string, dest any) {
+	p, ok := ctx.Value(timePolicyKey{}).(*TimePolicy)
+	if !ok {
+		return
+	}
+	p.localize(query, reflect.ValueOf(dest))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+//nolint:exhaustive
+func (p *TimePolicy) localize(query string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			p.localize(query, v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			p.localize(query, v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			t := v.Interface().(time.Time) //nolint:forcetypeassert
+			if t.IsZero() {
+				return
+			}
+			p.local(query, t)
+			if p.Location != nil && v.CanSet() {
+				v.Set(reflect.ValueOf(t.In(p.Location)))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				p.localize(query, v.Field(i))
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			t, ok := iter.Value().Interface().(time.Time)
+			if !ok || t.IsZero() {
+				continue
+			}
+			p.local(query, t)
+			if p.Location != nil {
+				v.SetMapIndex(iter.Key(), reflect.ValueOf(t.In(p.Location)))
+			}
+		}
+	}
+}
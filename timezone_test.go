@@ -0,0 +1,79 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enverbisevac/dbq"
+)
+
+func TestTimeZonePolicy(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries = append(rec.Entries[:1], dbq.RecordedEntry{
+		Query:        "UPDATE users SET created = ? WHERE id = ?",
+		Args:         []dbq.RecordedValue{{V: time.Unix(300, 0).UTC()}, {V: int64(1)}},
+		RowsAffected: 1,
+	})
+	berlin := time.FixedZone("CET", 3600)
+
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+	provider := dbq.NewTxProvider(db, dbq.TimeZone(dbq.TimePolicy{Location: berlin}))
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		users, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			if u.Created.Location() != berlin {
+				t.Errorf("scanned time not localized: %v", u.Created)
+			}
+		}
+		_, err = tx.Exec("UPDATE users SET created = ? WHERE id = ?", time.Unix(300, 0).In(berlin), 1)
+		return err
+	}))
+}
+
+func TestTimeZonePolicyNullArgs(t *testing.T) {
+	const query = "UPDATE users SET created = ?, updated = ?, deleted = ? WHERE id = ?"
+	berlin := time.FixedZone("CET", 3600)
+	created := time.Unix(300, 0).In(berlin)
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{{
+		Query: query,
+		Args: []dbq.RecordedValue{
+			{V: created.UTC()}, {V: created.UTC()}, {V: nil}, {V: int64(1)},
+		},
+		RowsAffected: 1,
+	}}}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	var sent []any
+	provider := dbq.NewTxProvider(db, dbq.TimeZone(dbq.TimePolicy{Location: berlin}),
+		dbq.Interceptors(func(next dbq.Handler) dbq.Handler {
+			return func(ctx context.Context, stmt *dbq.Statement) (dbq.Outcome, error) {
+				sent = append([]any(nil), stmt.Args...)
+				return next(ctx, stmt)
+			}
+		}))
+	args := []any{dbq.NewNull(created, true), dbq.OptionalOf(created), dbq.Null[time.Time]{}, 1}
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		_, err := tx.Exec(query, args...)
+		return err
+	}))
+	if n, ok := sent[0].(dbq.Null[time.Time]); !ok || n.Val.Location() != time.UTC {
+		t.Errorf("null time should be normalized to UTC, got %v", sent[0])
+	}
+	if o, ok := sent[1].(dbq.Optional[time.Time]); !ok || o.Val.Location() != time.UTC {
+		t.Errorf("optional time should be normalized to UTC, got %v", sent[1])
+	}
+	if n := args[0].(dbq.Null[time.Time]); n.Val.Location() != berlin {
+		t.Errorf("args of caller should not be modified, got %v", args[0])
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+)
+
+// Span is unit of traced work, it is implemented by thin adapters of
+// tracing libraries, e.g. OpenTelemetry trace.Span.
+type Span interface {
+	SetAttribute(key string, value any)
+	// RecordError records err of failed statement or transaction and marks
+	// span as failed, e.g. with error status of OpenTelemetry span.
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans, it is implemented by thin adapters of tracing
+// libraries, e.g. dbqotel.New of separate module
+// github.com/enverbisevac/dbq/dbqotel for OpenTelemetry:
+//
+//	provider := dbq.NewTxProvider(db, dbq.WithTracer(dbqotel.New(otel.Tracer("app"))))
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// WithTracer traces transactions and their statements, statement spans are
+// children of transaction span and carry db.system, db.statement,
+// db.operation, db.sql.table, db.rows_affected, error and request metadata
+// as dbq.meta.<key>.
+func WithTracer(tracer Tracer) ProviderOption {
+	return func(t *TxProvider) {
+		t.tracer = tracer
+		t.interceptors = append(t.interceptors, tracing(tracer))
+	}
+}
+
+// dbSystem returns OpenTelemetry db.system of dialect.
+func dbSystem(d Dialect) string {
+	switch d.Name() {
+	case "postgres":
+		return "postgresql"
+	case "sqlserver":
+		return "mssql"
+	}
+	return d.Name()
+}
+
+// startTxSpan starts span of transaction.
+func startTxSpan(ctx context.Context, tracer Tracer, d Dialect) (context.Context, Span) {
+	ctx, span := tracer.Start(ctx, "dbq.tx")
+	if d != nil {
+		span.SetAttribute("db.system", dbSystem(d))
+	}
+	return ctx, span
+}
+
+// tracing returns interceptor tracing statements.
+func tracing(tracer Tracer) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) (Outcome, error) {
+			action, tables := ClassifyStatement(stmt.Query)
+			name := action.String()
+			if action == ActionOther {
+				name = stmt.Op.String()
+			}
+			if len(tables) > 0 {
+				name += " " + tables[0]
+			}
+			ctx, span := tracer.Start(ctx, name)
+			defer span.End()
+
+			if d, ok := DialectFromCtx(ctx); ok {
+				span.SetAttribute("db.system", dbSystem(d))
+			}
+			span.SetAttribute("db.statement", stmt.Query)
+			span.SetAttribute("db.operation", action.String())
+			if len(tables) > 0 {
+				span.SetAttribute("db.sql.table", tables[0])
+			}
+			if stmt.Options.Label != "" {
+				span.SetAttribute("dbq.label", stmt.Options.Label)
+			}
+			if stmt.Caller != "" {
+				span.SetAttribute("code.caller", stmt.Caller)
+			}
+			for k, v := range MetaFromCtx(ctx) {
+				span.SetAttribute("dbq.meta."+k, v)
+			}
+
+			out, err := next(ctx, stmt)
+			if err != nil {
+				span.RecordError(err)
+			} else if out.Result != nil {
+				if n, rerr := out.Result.RowsAffected(); rerr == nil {
+					span.SetAttribute("db.rows_affected", n)
+				}
+			}
+			return out, err
+		}
+	}
+}
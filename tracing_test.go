@@ -0,0 +1,75 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type spanKey struct{}
+
+type fakeSpan struct {
+	name   string
+	parent *fakeSpan
+	attrs  map[string]any
+	errs   []error
+	ended  int
+}
+
+func (s *fakeSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *fakeSpan) RecordError(err error)              { s.errs = append(s.errs, err) }
+func (s *fakeSpan) End()                               { s.ended++ }
+
+type fakeTracer struct {
+	spans []*fakeSpan
+}
+
+func (f *fakeTracer) Start(ctx context.Context, name string) (context.Context, dbq.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*fakeSpan)
+	span := &fakeSpan{name: name, parent: parent, attrs: make(map[string]any)}
+	f.spans = append(f.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func TestTracer(t *testing.T) {
+	rec := usersRecording()
+	rec.Entries[0].Query = "SELECT id, name, created FROM users WHERE id > $1"
+	rec.Entries[1] = dbq.RecordedEntry{Query: "INSERT INTO users (name) VALUES ($1)", Args: rec.Entries[1].Args, Err: "locked"}
+	db := sql.OpenDB(dbq.NewReplayer(rec))
+	defer db.Close()
+
+	tracer := &fakeTracer{}
+	provider := dbq.NewTxProvider(db, dbq.WithDialect(dbq.Postgres), dbq.WithTracer(tracer))
+	err := provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		if _, err := dbq.Query(tx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO users (name) VALUES (?)", "jane")
+		return err
+	})
+	if err == nil {
+		t.Fatal("insert should fail")
+	}
+
+	if len(tracer.spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(tracer.spans))
+	}
+	txSpan, query, insert := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	if txSpan.name != "dbq.tx" || txSpan.ended != 1 || len(txSpan.errs) != 1 || txSpan.attrs["db.system"] != "postgresql" {
+		t.Errorf("bad tx span %+v", txSpan)
+	}
+	if query.name != "select users" || query.parent != txSpan || query.ended != 1 ||
+		query.attrs["db.statement"] != "SELECT id, name, created FROM users WHERE id > ?" ||
+		query.attrs["db.sql.table"] != "users" || query.attrs["db.system"] != "postgresql" {
+		t.Errorf("bad query span %+v", query)
+	}
+	if insert.name != "insert users" || len(insert.errs) != 1 || insert.ended != 1 {
+		t.Errorf("bad insert span %+v", insert)
+	}
+}
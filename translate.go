@@ -0,0 +1,227 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ConflictError is unique or exclusion constraint violation, it matches
+// ErrConflict.
+type ConflictError struct {
+	// Constraint is name of violated constraint when driver reports it.
+	Constraint string
+	Err        error
+}
+
+func (e *ConflictError) Error() string {
+	return constraintMessage("unique", e.Constraint, e.Err)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict //nolint:errorlint
+}
+
+// ForeignKeyError is foreign key constraint violation, it matches
+// ErrForeignKey.
+type ForeignKeyError struct {
+	// Constraint is name of violated constraint when driver reports it.
+	Constraint string
+	Err        error
+}
+
+func (e *ForeignKeyError) Error() string {
+	return constraintMessage("foreign key", e.Constraint, e.Err)
+}
+
+func (e *ForeignKeyError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrForeignKey.
+func (e *ForeignKeyError) Is(target error) bool {
+	return target == ErrForeignKey //nolint:errorlint
+}
+
+// CheckViolationError is check constraint violation, it matches
+// ErrCheckViolation.
+type CheckViolationError struct {
+	// Constraint is name of violated constraint when driver reports it.
+	Constraint string
+	Err        error
+}
+
+func (e *CheckViolationError) Error() string {
+	return constraintMessage("check", e.Constraint, e.Err)
+}
+
+func (e *CheckViolationError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrCheckViolation.
+func (e *CheckViolationError) Is(target error) bool {
+	return target == ErrCheckViolation //nolint:errorlint
+}
+
+// SerializationError is serialization failure or deadlock after which
+// transaction can be retried, it matches ErrSerialization.
+type SerializationError struct {
+	Err error
+}
+
+func (e *SerializationError) Error() string {
+	return fmt.Sprintf("dbq: serialization failure: %v", e.Err)
+}
+
+func (e *SerializationError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrSerialization.
+func (e *SerializationError) Is(target error) bool {
+	return target == ErrSerialization //nolint:errorlint
+}
+
+func constraintMessage(kind, constraint string, err error) string {
+	if constraint != "" {
+		return fmt.Sprintf("dbq: %s constraint %s violated: %v", kind, constraint, err)
+	}
+	return fmt.Sprintf("dbq: %s constraint violated: %v", kind, err)
+}
+
+// ErrorTranslator converts driver error to dbq error type, it returns nil
+// for errors it doesn't recognize.
+type ErrorTranslator func(err error) error
+
+var (
+	translatorsMu sync.RWMutex
+	translators   []ErrorTranslator
+)
+
+// RegisterTranslator registers translators of driver errors tried before
+// the default one, which recognizes SQLSTATE codes (lib/pq, pgx), MySQL
+// error numbers and SQLite messages.
+func RegisterTranslator(translator ...ErrorTranslator) {
+	translatorsMu.Lock()
+	defer translatorsMu.Unlock()
+	translators = append(translators, translator...)
+}
+
+// TranslateError converts driver error to ConflictError, ForeignKeyError,
+// CheckViolationError or SerializationError, other errors are returned as
+// they are. Errors of Query, Exec and Tx helpers are translated
+// automatically.
+func TranslateError(err error) error {
+	if err == nil || translated(err) {
+		return err
+	}
+	translatorsMu.RLock()
+	registered := translators
+	translatorsMu.RUnlock()
+	for _, t := range registered {
+		if terr := t(err); terr != nil {
+			return terr
+		}
+	}
+	if terr := defaultTranslator(err); terr != nil {
+		return terr
+	}
+	return err
+}
+
+func translated(err error) bool {
+	var (
+		conflict      *ConflictError
+		foreignKey    *ForeignKeyError
+		check         *CheckViolationError
+		serialization *SerializationError
+	)
+	return errors.As(err, &conflict) || errors.As(err, &foreignKey) ||
+		errors.As(err, &check) || errors.As(err, &serialization)
+}
+
+// mysqlNumbers maps MySQL error numbers to sentinel errors.
+var mysqlNumbers = map[uint64]error{
+	1062: ErrConflict,       // ER_DUP_ENTRY
+	1586: ErrConflict,       // ER_DUP_ENTRY_WITH_KEY_NAME
+	1451: ErrForeignKey,     // ER_ROW_IS_REFERENCED_2
+	1452: ErrForeignKey,     // ER_NO_REFERENCED_ROW_2
+	3819: ErrCheckViolation, // ER_CHECK_CONSTRAINT_VIOLATED
+	1213: ErrSerialization,  // ER_LOCK_DEADLOCK
+}
+
+func defaultTranslator(err error) error {
+	var kind error
+	var s sqlStater
+	if errors.As(err, &s) {
+		kind = sentinelStates[s.SQLState()]
+	} else if n, ok := driverField(err, "Number").(uint64); ok {
+		kind = mysqlNumbers[n]
+	} else {
+		msg := strings.ToLower(err.Error())
+		for _, m := range sentinelMessages {
+			if strings.Contains(msg, m.fragment) {
+				kind = m.err
+				break
+			}
+		}
+	}
+
+	constraint, _ := driverField(err, "ConstraintName", "Constraint").(string)
+	switch kind {
+	case ErrConflict:
+		return &ConflictError{Constraint: constraint, Err: err}
+	case ErrForeignKey:
+		return &ForeignKeyError{Constraint: constraint, Err: err}
+	case ErrCheckViolation:
+		return &CheckViolationError{Constraint: constraint, Err: err}
+	case ErrSerialization:
+		return &SerializationError{Err: err}
+	}
+	return nil
+}
+
+// driverField returns value of the first exported struct field with one of
+// names found in err chain, e.g. Number of mysql.MySQLError or
+// ConstraintName of pgconn.PgError. Integers are returned as uint64.
+func driverField(err error, names ...string) any {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.ValueOf(err)
+		for v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		for _, name := range names {
+			f := v.FieldByName(name)
+			if !f.IsValid() || !f.CanInterface() {
+				continue
+			}
+			//nolint:exhaustive
+			switch f.Kind() {
+			case reflect.String:
+				return f.String()
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				return f.Uint()
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if f.Int() >= 0 {
+					return uint64(f.Int())
+				}
+			}
+		}
+	}
+	return nil
+}
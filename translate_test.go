@@ -0,0 +1,81 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type constraintPgError struct {
+	pgError
+	ConstraintName string
+}
+
+type mysqlError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *mysqlError) Error() string {
+	return e.Message
+}
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		err        error
+		target     error
+		constraint string
+	}{
+		{constraintPgError{pgError{"23505"}, "users_name_key"}, dbq.ErrConflict, "users_name_key"},
+		{pgError{"23503"}, dbq.ErrForeignKey, ""},
+		{pgError{"23514"}, dbq.ErrCheckViolation, ""},
+		{pgError{"40001"}, dbq.ErrSerialization, ""},
+		{&mysqlError{Number: 1452, Message: "Cannot add or update a child row"}, dbq.ErrForeignKey, ""},
+		{&mysqlError{Number: 1213, Message: "Deadlock"}, dbq.ErrSerialization, ""},
+		{errors.New("UNIQUE constraint failed: users.name"), dbq.ErrConflict, ""},
+		{errors.New("CHECK constraint failed: age"), dbq.ErrCheckViolation, ""},
+	}
+	for i, tt := range tests {
+		err := dbq.TranslateError(tt.err)
+		if !errors.Is(err, tt.target) || !errors.Is(err, tt.err) {
+			t.Errorf("#%d: %v should translate to %v", i, err, tt.target)
+		}
+		var conflict *dbq.ConflictError
+		if errors.As(err, &conflict) && conflict.Constraint != tt.constraint {
+			t.Errorf("#%d: bad constraint %q", i, conflict.Constraint)
+		}
+	}
+
+	other := errors.New("syntax error")
+	if err := dbq.TranslateError(other); err != other { //nolint:errorlint
+		t.Errorf("unknown error should not be translated: %v", err)
+	}
+	var serialization *dbq.SerializationError
+	if !errors.As(dbq.TranslateError(pgError{"40P01"}), &serialization) ||
+		!dbq.IsRetryable(dbq.TranslateError(pgError{"40P01"})) {
+		t.Error("deadlock should be retryable SerializationError")
+	}
+}
+
+func TestTranslateExecError(t *testing.T) {
+	rec := &dbq.Recording{Entries: []dbq.RecordedEntry{
+		{
+			Query: "INSERT INTO users (name) VALUES (?)",
+			Args:  []dbq.RecordedValue{{V: "jane"}},
+			Err:   "UNIQUE constraint failed: users.name",
+		},
+	}}
+	replayTx(t, rec, func(tx dbq.TxContext) error {
+		_, err := tx.Exec("INSERT INTO users (name) VALUES (?)", "jane")
+		var conflict *dbq.ConflictError
+		if !errors.As(err, &conflict) || !errors.Is(err, dbq.ErrConflict) {
+			t.Errorf("expected ConflictError, got %v", err)
+		}
+		return nil
+	})
+}
@@ -8,7 +8,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"sync"
+	"time"
 )
 
 type txKeyType struct{}
@@ -33,45 +37,301 @@ type TxContext interface {
 type Tx struct {
 	context.Context //nolint:containedctx
 	Tx              *sql.Tx
+
+	interceptors []Interceptor
+	stash        *stash
+}
+
+// stash is storage shared by all contexts of transaction.
+type stash struct {
+	mu         sync.Mutex
+	values     map[any]any
+	deferred   []func(TxContext) error
+	savepoints int
+	statements int
+	onCommit   []func()
+	onRollback []func()
+	stmts      *stmtLRU
+	// prepared are statements of queries closed with transaction.
+	prepared  []*sql.Stmt
+	span      Span
+	metrics   MetricsCollector
+	started   time.Time
+	committed bool
+	// finished is true after transaction is committed or rolled back.
+	finished bool
+	// provider is provider transaction was acquired from.
+	provider *TxProvider
+	// cancel releases context transaction was started with.
+	cancel context.CancelFunc
+	// conn is connection reserved for transaction of NativeConnector.
+	conn *sql.Conn
 }
 
+type currentTxKey struct{}
+
 func (t *Tx) WithValue(key, value any) TxContext {
 	return &Tx{
-		Context: context.WithValue(t.Context, key, value),
-		Tx:      t.Tx,
+		Context:      context.WithValue(t.Context, key, value),
+		Tx:           t.Tx,
+		interceptors: t.interceptors,
+		stash:        t.stash,
 	}
 }
 
+// Set stores val under key for the lifetime of transaction, value is
+// visible to all contexts derived with WithValue. Layers cooperating in
+// one transaction, e.g. audit or outbox, use it to accumulate data written
+// before commit.
+func (t *Tx) Set(key, val any) {
+	if t.stash == nil {
+		t.stash = &stash{}
+	}
+	t.stash.mu.Lock()
+	defer t.stash.mu.Unlock()
+	if t.stash.values == nil {
+		t.stash.values = make(map[any]any)
+	}
+	t.stash.values[key] = val
+}
+
+// Get returns value stored with Set.
+func (t *Tx) Get(key any) (any, bool) {
+	if t.stash == nil {
+		return nil, false
+	}
+	t.stash.mu.Lock()
+	defer t.stash.mu.Unlock()
+	val, ok := t.stash.values[key]
+	return val, ok
+}
+
 // Prepare query.
 func (t *Tx) Prepare(query string) (*sql.Stmt, error) {
-	return t.Tx.PrepareContext(t.Context, query)
+	out, err := chain(t.access(), t.interceptors)(t.Context, newStatement(OpPrepare, query, nil))
+	return out.Stmt, err
 }
 
 // Exec executes query with args.
 func (t *Tx) Exec(query string, args ...any) (sql.Result, error) {
-	return t.Tx.ExecContext(t.Context, query, args...)
+	out, err := chain(t.access(), t.interceptors)(t.Context, newStatement(OpExec, query, args))
+	return out.Result, err
 }
 
 // Query loads data from db.
 func (t *Tx) Query(query string, args ...any) (*sql.Rows, error) {
-	return t.Tx.QueryContext(t.Context, query, args...)
+	out, err := chain(t.access(), t.interceptors)(t.Context, newStatement(OpQuery, query, args))
+	return out.Rows, err
 }
 
 // QueryRow loads single row from db.
 func (t *Tx) QueryRow(query string, args ...any) *sql.Row {
-	return t.Tx.QueryRowContext(t.Context, query, args...)
+	return queryRow(chain(t.access(), t.interceptors), t.Context, query, args)
+}
+
+// Defer registers fn run just before COMMIT, functions run in
+// registration order and may register further functions. Error of fn
+// rolls back transaction. Bulk counters and derived tables can be updated
+// once per transaction instead of once per statement.
+func (t *Tx) Defer(fn func(TxContext) error) {
+	if t.stash == nil {
+		t.stash = &stash{}
+	}
+	t.stash.mu.Lock()
+	defer t.stash.mu.Unlock()
+	t.stash.deferred = append(t.stash.deferred, fn)
 }
 
-// Commit this transaction.
+// runDeferred runs and removes functions registered with Defer.
+func (t *Tx) runDeferred() error {
+	if t.stash == nil {
+		return nil
+	}
+	for {
+		t.stash.mu.Lock()
+		if len(t.stash.deferred) == 0 {
+			t.stash.mu.Unlock()
+			return nil
+		}
+		fn := t.stash.deferred[0]
+		t.stash.deferred = t.stash.deferred[1:]
+		t.stash.mu.Unlock()
+
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+}
+
+// Commit this transaction, functions registered with Defer are run first
+// and their error rolls back transaction.
 func (t *Tx) Commit() error {
-	return t.Tx.Commit()
+	defer t.finish()
+	if err := t.runDeferred(); err != nil {
+		_ = t.Tx.Rollback()
+		t.runHooks(false)
+		t.recordError(err)
+		return err
+	}
+	if err := t.Tx.Commit(); err != nil {
+		t.runHooks(false)
+		t.recordError(err)
+		return err
+	}
+	if t.stash != nil {
+		t.stash.mu.Lock()
+		t.stash.committed = true
+		t.stash.mu.Unlock()
+	}
+	t.runHooks(true)
+	return nil
 }
 
 // Rollback cancel this transaction.
 func (t *Tx) Rollback() error {
-	return t.Tx.Rollback()
+	defer t.finish()
+	err := t.Tx.Rollback()
+	if !errors.Is(err, sql.ErrTxDone) {
+		t.runHooks(false)
+		t.recordError(errRolledBack)
+	}
+	return err
+}
+
+// OnCommit registers fn run after transaction is committed, e.g. cache
+// invalidation or event publishing. Hooks registered in nested
+// transactions run after outer transaction is committed.
+func (t *Tx) OnCommit(fn func()) {
+	if t.stash == nil {
+		t.stash = &stash{}
+	}
+	t.stash.mu.Lock()
+	defer t.stash.mu.Unlock()
+	t.stash.onCommit = append(t.stash.onCommit, fn)
+}
+
+// OnRollback registers fn run after transaction is rolled back or its
+// commit fails.
+func (t *Tx) OnRollback(fn func()) {
+	if t.stash == nil {
+		t.stash = &stash{}
+	}
+	t.stash.mu.Lock()
+	defer t.stash.mu.Unlock()
+	t.stash.onRollback = append(t.stash.onRollback, fn)
+}
+
+// runHooks runs commit or rollback hooks in registration order, hooks run
+// at most once.
+func (t *Tx) runHooks(committed bool) {
+	if t.stash == nil {
+		return
+	}
+	t.stash.mu.Lock()
+	hooks := t.stash.onRollback
+	if committed {
+		hooks = t.stash.onCommit
+	}
+	t.stash.onCommit, t.stash.onRollback = nil, nil
+	t.stash.finished = true
+	t.stash.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// access returns statement cache of transaction or transaction itself.
+func (t *Tx) access() Access {
+	if t.stash != nil && t.stash.stmts != nil {
+		return t.stash.stmts
+	}
+	return t.Tx
+}
+
+// errRolledBack is recorded in span of rolled back transaction.
+var errRolledBack = errors.New("dbq: transaction rolled back")
+
+// recordError records err in span of transaction.
+func (t *Tx) recordError(err error) {
+	if t.stash == nil {
+		return
+	}
+	t.stash.mu.Lock()
+	span := t.stash.span
+	t.stash.mu.Unlock()
+	if span != nil {
+		span.RecordError(err)
+	}
+}
+
+// closeOnFinish closes stmt when transaction is finished.
+func (t *Tx) closeOnFinish(stmt *sql.Stmt) {
+	if t.stash == nil {
+		t.stash = &stash{}
+	}
+	t.stash.mu.Lock()
+	defer t.stash.mu.Unlock()
+	t.stash.prepared = append(t.stash.prepared, stmt)
 }
 
+// isFinished returns true after transaction is committed or rolled back.
+func (t *Tx) isFinished() bool {
+	if t.stash == nil {
+		return false
+	}
+	t.stash.mu.Lock()
+	defer t.stash.mu.Unlock()
+	return t.stash.finished
+}
+
+// finish removes transaction from open transactions, closes its cached
+// statements, ends its span and reports it to metrics collector.
+func (t *Tx) finish() {
+	if t.stash == nil {
+		return
+	}
+	openTxs.Delete(t.stash)
+	if t.stash.stmts != nil {
+		_ = t.stash.stmts.close()
+	}
+	t.stash.mu.Lock()
+	for _, stmt := range t.stash.prepared {
+		_ = stmt.Close()
+	}
+	t.stash.prepared = nil
+	span := t.stash.span
+	t.stash.span = nil
+	metrics, committed := t.stash.metrics, t.stash.committed
+	t.stash.metrics = nil
+	cancel, conn := t.stash.cancel, t.stash.conn
+	t.stash.cancel, t.stash.conn = nil, nil
+	t.stash.finished = true
+	t.stash.mu.Unlock()
+	if conn != nil {
+		_ = conn.Close()
+	}
+	if cancel != nil {
+		cancel()
+	}
+	if span != nil {
+		span.End()
+	}
+	if metrics != nil {
+		metrics.TxDone(committed, time.Since(t.stash.started))
+	}
+}
+
+// TxManager runs and acquires transactions. It is implemented by
+// TxProvider and by fake of dbqtest package, so services can depend on
+// TxManager and be tested without database.
+type TxManager interface {
+	Tx(ctx context.Context, fn func(TxContext) error) error
+	Acquire(ctx context.Context) (*Tx, error)
+}
+
+var _ TxManager = (*TxProvider)(nil)
+
 // Connector for sql database.
 type Connector interface {
 	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
@@ -79,27 +339,95 @@ type Connector interface {
 
 // TxProvider ...
 type TxProvider struct {
-	conn Connector
+	// acquire is first so its counters are 64-bit aligned on 32-bit
+	// platforms.
+	acquire acquireCounters
+
+	conn          Connector
+	commitRetries int
+	timePolicy    *TimePolicy
+	dialect       Dialect
+	masker        *Masker
+	stmtCache     *StmtCache
+	tracer        Tracer
+	metrics       MetricsCollector
+	shedder       *Shedder
+	interceptors  []Interceptor
+
+	acquireTimeout time.Duration
 }
 
+// ProviderOption configures TxProvider.
+type ProviderOption func(*TxProvider)
+
 // NewTxProvider ...
-func NewTxProvider(conn Connector) *TxProvider {
-	return &TxProvider{
+func NewTxProvider(conn Connector, opts ...ProviderOption) *TxProvider {
+	t := &TxProvider{
 		conn: conn,
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 // AcquireWithOpts transaction from db
 func (t *TxProvider) AcquireWithOpts(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
-	tx, err := t.conn.BeginTx(ctx, opts)
+	if t.shedder != nil {
+		if err := t.shedder.admit(ctx, txPriority(ctx)); err != nil {
+			return nil, err
+		}
+	}
+	var span Span
+	if t.tracer != nil {
+		ctx, span = startTxSpan(ctx, t.tracer, t.dialect)
+	}
+	tx, conn, cancel, err := t.beginTx(ctx, opts)
 	if err != nil {
+		if span != nil {
+			span.RecordError(err)
+			span.End()
+		}
 		return nil, err
 	}
 
-	return &Tx{
-		Context: context.WithValue(ctx, txKeyType{}, Access(tx)),
-		Tx:      tx,
-	}, nil
+	var access Access = tx
+	if n, ok := t.conn.(NativeConnector); ok {
+		access = n.NativeAccess(conn, tx)
+	}
+	ctx, interceptors := t.configure(ctx, access)
+	current := &Tx{
+		Tx:           tx,
+		interceptors: interceptors,
+		stash:        &stash{span: span, metrics: t.metrics, started: time.Now(), provider: t, cancel: cancel, conn: conn},
+	}
+	if t.metrics != nil {
+		t.metrics.TxStarted()
+	}
+	if t.stmtCache != nil {
+		current.stash.stmts = newStmtLRU(t.stmtCache, tx)
+	}
+	current.Context = context.WithValue(ctx, currentTxKey{}, current)
+	openTxs.Store(current.stash, OpenTx{Started: current.stash.started, Caller: caller()})
+	return current, nil
+}
+
+// configure returns ctx carrying access and provider settings and
+// interceptors of statements run on access.
+func (t *TxProvider) configure(ctx context.Context, access Access) (context.Context, []Interceptor) {
+	ctx = context.WithValue(ctx, txKeyType{}, access)
+	if t.timePolicy != nil {
+		ctx = context.WithValue(ctx, timePolicyKey{}, t.timePolicy)
+	}
+	if t.masker != nil {
+		ctx = context.WithValue(ctx, maskerKey{}, t.masker)
+	}
+	interceptors := t.interceptors
+	if t.dialect != nil {
+		ctx = context.WithValue(ctx, dialectKey{}, t.dialect)
+		interceptors = append(interceptors[:len(interceptors):len(interceptors)], Rebinding(t.dialect))
+	}
+	return ctx, interceptors
 }
 
 // Acquire transaction from db
@@ -107,8 +435,47 @@ func (t *TxProvider) Acquire(ctx context.Context) (*Tx, error) {
 	return t.AcquireWithOpts(ctx, &DefaultTxOpts)
 }
 
-// TxWithOpts ...
+// TxWithOpts runs fn in transaction with opts. When provider is configured
+// with CommitRetries, the whole fn is run again in new transaction if
+// COMMIT fails with retryable error. Panic in fn rolls back transaction
+// and is propagated to caller.
+//
+// When ctx belongs to transaction acquired from provider, fn runs in
+// savepoint of that transaction instead, see nested.
 func (t *TxProvider) TxWithOpts(ctx context.Context, fn func(TxContext) error, opts *sql.TxOptions) error {
+	return t.TxPropagation(ctx, PropagationNested, fn, opts)
+}
+
+// TxPropagation runs fn in transaction with opts, p selects how fn relates
+// to transaction of ctx acquired from provider. Transaction of ctx acquired
+// from another provider, e.g. of another database, is ignored and fn runs
+// in new transaction of provider. Error is returned for unknown p.
+func (t *TxProvider) TxPropagation(ctx context.Context, p Propagation, fn func(TxContext) error, opts *sql.TxOptions) error {
+	if p < PropagationNested || p > PropagationNever {
+		return fmt.Errorf("dbq: unknown propagation %d", int(p))
+	}
+	if outer, ok := ctx.Value(currentTxKey{}).(*Tx); ok && outer.stash.provider == t {
+		switch p {
+		case PropagationNested:
+			return outer.nested(ctx, fn)
+		case PropagationRequired:
+			return fn(outer.join(ctx))
+		case PropagationNever:
+			return ErrTxExists
+		case PropagationRequiresNew:
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		err := t.txWithOpts(ctx, fn, opts)
+		var cerr *CommitError
+		if err == nil || attempt >= t.commitRetries || ctx.Err() != nil ||
+			!errors.As(err, &cerr) || !IsRetryable(cerr.Err) {
+			return err
+		}
+	}
+}
+
+func (t *TxProvider) txWithOpts(ctx context.Context, fn func(TxContext) error, opts *sql.TxOptions) (err error) {
 	tx, err := t.AcquireWithOpts(ctx, opts)
 	if err != nil {
 		return err
@@ -117,13 +484,16 @@ func (t *TxProvider) TxWithOpts(ctx context.Context, fn func(TxContext) error, o
 	defer func() {
 		//nolint:gocritic
 		if r := recover(); r != nil {
-			log.Printf("Recovering from panic in TxWithOpts error is: %v \n", r)
+			// transaction is rolled back and panic is propagated to
+			// caller.
 			_ = tx.Rollback()
-			err, _ = r.(error)
+			panic(r)
 		} else if err != nil {
-			err = tx.Rollback()
-		} else {
-			err = tx.Commit()
+			_ = tx.Rollback()
+		} else if err = tx.runDeferred(); err != nil {
+			_ = tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			err = &CommitError{Err: TranslateError(err)}
 		}
 
 		if ctx.Err() != nil && errors.Is(err, context.DeadlineExceeded) {
@@ -136,7 +506,29 @@ func (t *TxProvider) TxWithOpts(ctx context.Context, fn func(TxContext) error, o
 	return err
 }
 
-// Tx runs fn in transaction.
+// nested runs fn in savepoint of transaction t. Error of fn rolls back only
+// changes made by fn, outer transaction can continue or return the error.
+// Options of nested transaction are ignored.
+func (t *Tx) nested(ctx context.Context, fn func(TxContext) error) error {
+	t.stash.mu.Lock()
+	t.stash.savepoints++
+	name := "dbq_sp_" + strconv.Itoa(t.stash.savepoints)
+	t.stash.mu.Unlock()
+
+	return t.join(ctx).WithSavepoint(name, fn)
+}
+
+// join returns transaction t with context ctx.
+func (t *Tx) join(ctx context.Context) *Tx {
+	return &Tx{
+		Context:      ctx,
+		Tx:           t.Tx,
+		interceptors: t.interceptors,
+		stash:        t.stash,
+	}
+}
+
+// Tx runs fn in transaction, nested calls use savepoints.
 func (t *TxProvider) Tx(ctx context.Context, fn func(TxContext) error) error {
 	return t.TxWithOpts(ctx, fn, &DefaultTxOpts)
 }
@@ -0,0 +1,128 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type auditKey struct{}
+
+func TestTxStash(t *testing.T) {
+	replayTx(t, &dbq.Recording{}, func(ctx dbq.TxContext) error {
+		tx := ctx.(*dbq.Tx) //nolint:forcetypeassert
+		if _, ok := tx.Get(auditKey{}); ok {
+			t.Error("stash should be empty")
+		}
+		tx.Set(auditKey{}, []string{"created user"})
+
+		derived := tx.WithValue("k", "v").(*dbq.Tx) //nolint:forcetypeassert
+		v, ok := derived.Get(auditKey{})
+		if !ok {
+			t.Fatal("value not visible in derived context")
+		}
+		derived.Set(auditKey{}, append(v.([]string), "updated user")) //nolint:forcetypeassert
+
+		if v, _ = tx.Get(auditKey{}); len(v.([]string)) != 2 { //nolint:forcetypeassert
+			t.Errorf("bad stash value %v", v)
+		}
+		return nil
+	})
+}
+
+func TestTxDefer(t *testing.T) {
+	rec := usersRecording()
+	var order []string
+	replayTx(t, rec, func(ctx dbq.TxContext) error {
+		tx := ctx.(*dbq.Tx) //nolint:forcetypeassert
+		tx.Defer(func(ctx dbq.TxContext) error {
+			order = append(order, "first")
+			tx.Defer(func(dbq.TxContext) error {
+				order = append(order, "nested")
+				return nil
+			})
+			_, err := ctx.Exec("INSERT INTO users (name) VALUES (?)", "jane")
+			return err
+		})
+		tx.Defer(func(dbq.TxContext) error {
+			order = append(order, "second")
+			return nil
+		})
+		_, err := dbq.Query(ctx, "SELECT id, name, created FROM users WHERE id > ?", userBinder, 0)
+		order = append(order, "fn")
+		return err
+	})
+	if strings.Join(order, ",") != "fn,first,second,nested" {
+		t.Errorf("bad order %v", order)
+	}
+}
+
+func TestTxDeferError(t *testing.T) {
+	errDeferred := errors.New("deferred")
+	db := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{}))
+	defer db.Close()
+	err := dbq.NewTxProvider(db).Tx(context.Background(), func(ctx dbq.TxContext) error {
+		ctx.(*dbq.Tx).Defer(func(dbq.TxContext) error { //nolint:forcetypeassert
+			return errDeferred
+		})
+		return nil
+	})
+	if !errors.Is(err, errDeferred) {
+		t.Errorf("expected deferred error, got %v", err)
+	}
+}
+
+func TestTxRepanic(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{}))
+	defer db.Close()
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("expected panic to propagate, got %v", r)
+		}
+		if txs := dbq.OpenTransactions(); len(txs) != 0 {
+			t.Errorf("transaction not rolled back %v", txs)
+		}
+	}()
+	_ = dbq.NewTxProvider(db).Tx(context.Background(), func(dbq.TxContext) error {
+		panic("boom")
+	})
+	t.Error("panic was swallowed")
+}
+
+func TestTxHooks(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{}))
+	defer db.Close()
+	provider := dbq.NewTxProvider(db)
+
+	var events []string
+	register := func(ctx dbq.TxContext, name string) {
+		tx := ctx.(*dbq.Tx) //nolint:forcetypeassert
+		tx.OnCommit(func() { events = append(events, name+" committed") })
+		tx.OnRollback(func() { events = append(events, name+" rolled back") })
+	}
+
+	maybePanic(provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		register(tx, "a")
+		if len(events) != 0 {
+			t.Error("hooks run before commit")
+		}
+		return nil
+	}))
+	errFailed := errors.New("failed")
+	_ = provider.Tx(context.Background(), func(tx dbq.TxContext) error {
+		register(tx, "b")
+		return errFailed
+	})
+	if strings.Join(events, ",") != "a committed,b rolled back" {
+		t.Errorf("bad events %v", events)
+	}
+}
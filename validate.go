@@ -0,0 +1,194 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NotNullError reports NOT NULL column which would be written as NULL.
+type NotNullError struct {
+	// Field is name of struct field, empty when column is not mapped.
+	Field  string
+	Column string
+}
+
+func (e NotNullError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("column %s is required", e.Column)
+	}
+	return fmt.Sprintf("column %s of field %s is required", e.Column, e.Field)
+}
+
+// Is matches ErrNotNull.
+func (e NotNullError) Is(target error) bool {
+	return target == ErrNotNull //nolint:errorlint
+}
+
+// ValidationErrors are errors of all NOT NULL columns without value.
+type ValidationErrors []NotNullError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, ne := range e {
+		msgs[i] = ne.Error()
+	}
+	return "dbq: validate: " + strings.Join(msgs, "; ")
+}
+
+// Is matches ErrNotNull.
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrNotNull //nolint:errorlint
+}
+
+// ValidateForInsert checks that NOT NULL columns of struct v have values
+// before v is inserted with Insert or InsertBatch, so caller gets field
+// level errors instead of constraint violation of database. Columns are
+// NOT NULL when they are marked with notnull tag option or listed in
+// notNull, e.g. as returned by NotNullColumns:
+//
+//	type User struct {
+//		ID    int64             `db:"id,identity"`
+//		Name  dbq.Null[string]  `db:"name,notnull"`
+//		Email dbq.Optional[string]
+//	}
+//
+//	if err := dbq.ValidateForInsert(u, "email"); err != nil {
+//		var verr dbq.ValidationErrors
+//		errors.As(err, &verr)
+//	}
+//
+// Null values which are not valid, nil pointers and zero values of
+// zeronull columns are NULL. Absent Optional values and identity and
+// generated columns are filled by database and are not checked. Returned
+// error is ValidationErrors and matches ErrNotNull.
+func ValidateForInsert[T any](v T, notNull ...string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	m, err := structMapOf(rv.Type())
+	if err != nil {
+		return err
+	}
+	required := make(map[string]bool, len(notNull))
+	for _, col := range notNull {
+		required[col] = true
+	}
+
+	var errs ValidationErrors
+	for _, f := range m.fields {
+		if f.generated || (!f.notNull && !required[f.column]) {
+			continue
+		}
+		delete(required, f.column)
+		fv, ok := fieldValue(rv, f.index)
+		if ok && isDefault(fv) {
+			continue
+		}
+		if !ok || isNullArg(f.arg(fv.Interface())) {
+			errs = append(errs, NotNullError{
+				Field:  fieldName(rv.Type(), f.index),
+				Column: f.column,
+			})
+		}
+	}
+	// listed columns which are not mapped are never written.
+	for _, col := range notNull {
+		if required[col] {
+			delete(required, col)
+			errs = append(errs, NotNullError{Column: col})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// isNullArg returns true for arguments written as NULL.
+func isNullArg(arg any) bool {
+	if arg == nil {
+		return true
+	}
+	if valuer, ok := arg.(driver.Valuer); ok {
+		rv := reflect.ValueOf(arg)
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return true
+		}
+		v, err := valuer.Value()
+		return err == nil && v == nil
+	}
+	rv := reflect.ValueOf(arg)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// fieldName returns dotted name of field at index of struct t.
+func fieldName(t reflect.Type, index []int) string {
+	names := make([]string, 0, len(index))
+	for _, i := range index {
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		f := t.Field(i)
+		names = append(names, f.Name)
+		t = f.Type
+	}
+	return strings.Join(names, ".")
+}
+
+const informationSchemaNotNull = `SELECT column_name FROM information_schema.columns
+WHERE table_name = %s AND is_nullable = 'NO' AND column_default IS NULL`
+
+// notNullQueries select NOT NULL columns without default which are not
+// filled by database.
+var notNullQueries = map[string]string{
+	"postgres": informationSchemaNotNull +
+		" AND table_schema = current_schema() AND is_identity = 'NO' AND is_generated = 'NEVER'",
+	"mysql": informationSchemaNotNull +
+		" AND table_schema = DATABASE() AND extra NOT LIKE '%%auto_increment%%' AND extra NOT LIKE '%%GENERATED%%'",
+	"sqlserver": informationSchemaNotNull +
+		" AND COLUMNPROPERTY(OBJECT_ID(table_schema + '.' + table_name), column_name, 'IsIdentity') = 0" +
+		" AND COLUMNPROPERTY(OBJECT_ID(table_schema + '.' + table_name), column_name, 'IsComputed') = 0",
+	// only single column INTEGER PRIMARY KEY, alias of rowid, is filled by
+	// SQLite, columns of composite primary key are not.
+	"sqlite": `SELECT name FROM (SELECT name, "notnull", dflt_value, pk, type, max(pk) OVER () AS pks` +
+		` FROM pragma_table_info(%s)) WHERE "notnull" = 1 AND dflt_value IS NULL` +
+		` AND NOT (pk = 1 AND pks = 1 AND upper(type) = 'INTEGER')`,
+}
+
+// NotNullColumns returns NOT NULL columns of table which must be given
+// value on insert: columns with default, identity and generated columns
+// are left out. Columns are discovered from information_schema or from
+// SQLite pragmas, result can be passed to ValidateForInsert.
+func NotNullColumns(ctx context.Context, db Access, d Dialect, table string) ([]string, error) {
+	if d == nil {
+		return nil, errors.New("dbq: NotNullColumns requires dialect")
+	}
+	query, ok := notNullQueries[d.Name()]
+	if !ok {
+		query = informationSchemaNotNull
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(query, d.Placeholder(1)), table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var col string
+		if err = rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return cols, rows.Err()
+}
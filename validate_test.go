@@ -0,0 +1,81 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type signup struct {
+	ID      int64                `db:"id,identity"`
+	Name    dbq.Null[string]     `db:"name,notnull"`
+	Email   string               `db:"email,zeronull,notnull"`
+	Plan    dbq.Optional[string] `db:"plan,notnull"`
+	Owner   *int64               `db:"owner_id"`
+	Comment dbq.Null[string]     `db:"comment"`
+}
+
+func TestValidateForInsert(t *testing.T) {
+	owner := int64(1)
+	valid := signup{
+		Name:  dbq.FromValue("acme"),
+		Email: "a@acme.test",
+		Owner: &owner,
+	}
+	if err := dbq.ValidateForInsert(valid, "owner_id"); err != nil {
+		t.Errorf("valid account: %v", err)
+	}
+	if err := dbq.ValidateForInsert(&valid); err != nil {
+		t.Errorf("pointer to valid account: %v", err)
+	}
+
+	invalid := signup{Plan: dbq.OptionalNull[string]()}
+	err := dbq.ValidateForInsert(invalid, "owner_id", "tenant_id")
+	if !errors.Is(err, dbq.ErrNotNull) {
+		t.Fatalf("expected ErrNotNull, got %v", err)
+	}
+	var verr dbq.ValidationErrors
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationErrors, got %T", err)
+	}
+	want := dbq.ValidationErrors{
+		{Field: "Name", Column: "name"},
+		{Field: "Email", Column: "email"},
+		{Field: "Plan", Column: "plan"},
+		{Field: "Owner", Column: "owner_id"},
+		{Column: "tenant_id"},
+	}
+	if !reflect.DeepEqual(verr, want) {
+		t.Errorf("bad errors %v", verr)
+	}
+}
+
+func TestNotNullColumns(t *testing.T) {
+	db := sql.OpenDB(dbq.NewReplayer(&dbq.Recording{Entries: []dbq.RecordedEntry{{
+		Query: `SELECT name FROM (SELECT name, "notnull", dflt_value, pk, type, max(pk) OVER () AS pks` +
+			` FROM pragma_table_info(?)) WHERE "notnull" = 1 AND dflt_value IS NULL` +
+			` AND NOT (pk = 1 AND pks = 1 AND upper(type) = 'INTEGER')`,
+		Args:    []dbq.RecordedValue{{V: "accounts"}},
+		Columns: []string{"name"},
+		Rows:    [][]dbq.RecordedValue{{{V: "name"}}, {{V: "email"}}},
+	}}}))
+	defer db.Close()
+
+	cols, err := dbq.NotNullColumns(context.Background(), db, dbq.SQLite, "accounts")
+	maybePanic(err)
+	if !reflect.DeepEqual(cols, []string{"name", "email"}) {
+		t.Errorf("bad columns %v", cols)
+	}
+
+	if _, err = dbq.NotNullColumns(context.Background(), db, nil, "accounts"); err == nil {
+		t.Error("err should be present without dialect")
+	}
+}
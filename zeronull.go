@@ -0,0 +1,136 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// isZeroArg returns true for nil and zero arguments, driver.Valuer is zero
+// when its value is.
+func isZeroArg(arg any) bool {
+	if arg == nil {
+		return true
+	}
+	if valuer, ok := arg.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return false
+		}
+		if v == nil {
+			return true
+		}
+		arg = v
+	}
+	return reflect.ValueOf(arg).IsZero()
+}
+
+// zeroAsNull replaces zero arguments written as column values by query
+// with nil, arguments of conditions, e.g. WHERE or LIMIT, are kept.
+func zeroAsNull(query string, args []any) []any {
+	written := valuePlaceholders(query)
+	out := make([]any, len(args))
+	for i, arg := range args {
+		if !written[i] || !isZeroArg(arg) {
+			out[i] = arg
+		}
+	}
+	return out
+}
+
+// valueEnd are keywords ending VALUES list or SET assignments.
+var valueEnd = map[string]bool{
+	"where": true, "from": true, "returning": true, "on": true, "select": true,
+	"output": true, "limit": true, "order": true,
+}
+
+// valuePlaceholders returns indexes of arguments of placeholders in VALUES
+// lists and SET assignments of query. ?, $n and @pn placeholders are
+// recognized, so query may be rebound already.
+func valuePlaceholders(query string) map[int]bool {
+	written := make(map[int]bool)
+	var (
+		n          int  // number of ? placeholders
+		inValues   bool // in VALUES list or SET assignments
+		valueDepth int  // parenthesis depth of VALUES or SET
+		depth      int
+		prev       string // previous keyword
+	)
+	for i := 0; i < len(query); i++ {
+		if j := skipQuoted(query, i); j > i {
+			i = j - 1
+			continue
+		}
+		c := query[i]
+		switch {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if inValues && depth < valueDepth {
+				inValues = false
+			}
+		case c == '?':
+			if inValues {
+				written[n] = true
+			}
+			n++
+		case (c == '$' || c == '@') && i+1 < len(query):
+			j := i + 1
+			if c == '@' && (query[j] == 'p' || query[j] == 'P') {
+				j++
+			}
+			k := j
+			for k < len(query) && query[k] >= '0' && query[k] <= '9' {
+				k++
+			}
+			if k > j {
+				if pos, err := strconv.Atoi(query[j:k]); err == nil && inValues {
+					written[pos-1] = true
+				}
+			}
+			i = k - 1
+		case isNameStart(c):
+			j := i
+			for j < len(query) && isNameChar(query[j]) {
+				j++
+			}
+			word := strings.ToLower(query[i:j])
+			switch {
+			case word == "values" || word == "set" || word == "update" && prev == "key":
+				// INSERT ... VALUES, UPDATE ... SET and MySQL ON DUPLICATE
+				// KEY UPDATE.
+				inValues, valueDepth = true, depth
+			case inValues && depth == valueDepth && valueEnd[word]:
+				inValues = false
+			}
+			prev = word
+			i = j - 1
+		}
+	}
+	return written
+}
+
+// arg returns value written for field, zero value of zeronull field is
+// written as NULL.
+func (f field) arg(v any) any {
+	if f.zeroNull && isZeroArg(v) {
+		return nil
+	}
+	return v
+}
+
+// ZeroAsNull writes zero arguments, e.g. empty string or 0, as NULL. It is
+// common requirement for nullable columns with unique constraint. Only
+// values written to columns, in VALUES lists and SET assignments, are
+// replaced, arguments of WHERE, LIMIT and other clauses are kept.
+func ZeroAsNull() QueryOption {
+	return func(o *QueryOptions) {
+		o.ZeroAsNull = true
+	}
+}
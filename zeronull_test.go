@@ -0,0 +1,62 @@
+// Copyright 2022 Enver Bisevac. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbq_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/enverbisevac/dbq"
+)
+
+type contact struct {
+	Name  string
+	Email string           `db:"email,zeronull"`
+	Phone dbq.Null[string] `db:"phone,zeronull"`
+	Age   int
+}
+
+func TestZeroNullTag(t *testing.T) {
+	_, args, err := dbq.Insert("contacts", contact{Name: "john", Phone: dbq.FromValue("")})
+	maybePanic(err)
+	if !reflect.DeepEqual(args, []any{"john", nil, nil, 0}) {
+		t.Errorf("bad insert args %#v", args)
+	}
+
+	_, args, err = dbq.Update("contacts", contact{Name: "john", Email: "j@example.com"}, "id = ?", 1)
+	maybePanic(err)
+	if !reflect.DeepEqual(args, []any{"john", "j@example.com", 0, 1}) {
+		t.Errorf("bad update args %#v", args)
+	}
+}
+
+func TestZeroAsNullOption(t *testing.T) {
+	access := &argsAccess{}
+	_, err := dbq.Intercept(access).ExecContext(context.Background(), "INSERT INTO t (a, b, c) VALUES (?, ?, ?)",
+		"", 0, "x", dbq.ZeroAsNull())
+	maybePanic(err)
+	if !reflect.DeepEqual(access.args, []any{nil, nil, "x"}) {
+		t.Errorf("bad args %#v", access.args)
+	}
+
+	for _, tt := range []struct {
+		query string
+		args  []any
+		want  []any
+	}{
+		{"UPDATE t SET a = ?, b = ? WHERE c = ? AND d = ?", []any{"", 0, "", 0}, []any{nil, nil, "", 0}},
+		{"SELECT a FROM t WHERE b = ? LIMIT ?", []any{0, 0}, []any{0, 0}},
+		{"INSERT INTO t (a, b) VALUES ($1, lower($2)) ON CONFLICT (a) DO UPDATE SET b = $3 WHERE t.c = $4",
+			[]any{"", "", "", ""}, []any{nil, nil, nil, ""}},
+	} {
+		access.args = nil
+		_, err = dbq.Intercept(access).ExecContext(context.Background(), tt.query, append(tt.args, dbq.ZeroAsNull())...)
+		maybePanic(err)
+		if !reflect.DeepEqual(access.args, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.query, access.args, tt.want)
+		}
+	}
+}